@@ -1,15 +1,21 @@
 package addrmanager
 
 import (
+	crand "crypto/rand"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"math/rand"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	peer2 "github.com/libp2p/go-libp2p-peer"
+	"github.com/ninjadotorg/constant/common"
 	"github.com/ninjadotorg/constant/peer"
 )
 
@@ -17,19 +23,30 @@ type AddrManager struct {
 	mtx       sync.Mutex
 	peersFile string
 	key       [32]byte
+	rand      *rand.Rand
 	started   int32
 	shutdown  int32
 	waitGroup sync.WaitGroup
 
 	cQuit chan struct{}
 
-	addrIndex map[string]*peer.Peer // address key to KnownAddress for all addrs.
+	addrIndex map[string]*KnownAddress // address key to KnownAddress for all addrs.
+	addrNew   [NewBucketCount]map[string]*KnownAddress
+	addrTried [TriedBucketCount][]*KnownAddress
+	nNew      int
+	nTried    int
 }
 
 type serializedKnownAddress struct {
-	Addr      string
-	Src       string
-	PublicKey string
+	Addr        string
+	Src         string
+	PublicKey   string
+	PeerID      string
+	Attempts    int
+	LastAttempt time.Time
+	LastSuccess time.Time
+	LastSeen    time.Time
+	Tried       bool
 }
 
 type serializedAddrManager struct {
@@ -51,13 +68,15 @@ func New(dataDir string) *AddrManager {
 // savePeers saves all the known addresses to a file so they can be read back
 // in at next run.
 func (self *AddrManager) savePeers() error {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
 
 	if len(self.addrIndex) == 0 {
 		return nil
 	}
 
 	sam := new(serializedAddrManager)
-	sam.Version = 1
+	sam.Version = Version
 	copy(sam.Key[:], self.key[:])
 
 	sam.Addresses = make([]*serializedKnownAddress, len(self.addrIndex))
@@ -66,8 +85,16 @@ func (self *AddrManager) savePeers() error {
 	for k, v := range self.addrIndex {
 		ska := new(serializedKnownAddress)
 		ska.Addr = k
-		ska.Src = v.PeerID.String()
-		ska.PublicKey = v.PublicKey
+		ska.Src = v.Src
+		ska.PublicKey = v.Peer.PublicKey
+		if v.Peer.PeerID != "" {
+			ska.PeerID = v.Peer.PeerID.Pretty()
+		}
+		ska.Attempts = v.Attempts
+		ska.LastAttempt = v.LastAttempt
+		ska.LastSuccess = v.LastSuccess
+		ska.LastSeen = v.LastSeen
+		ska.Tried = v.tried
 
 		sam.Addresses[i] = ska
 		i++
@@ -90,8 +117,9 @@ func (self *AddrManager) savePeers() error {
 // loadPeers loads the known address from the saved file.  If empty, missing, or
 // malformed file, just don't load anything and start fresh
 func (self *AddrManager) loadPeers() {
-	//self.mtx.Lock()
-	//defer self.mtx.Unlock()
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+
 	err := self.deserializePeers(self.peersFile)
 	if err != nil {
 		Logger.log.Errorf("Failed to parse file %s: %+v", self.peersFile, err)
@@ -105,25 +133,50 @@ func (self *AddrManager) loadPeers() {
 	Logger.log.Infof("Loaded %d addresses from file '%s'", self.numAddresses(), self.peersFile)
 }
 
-// NumAddresses returns the number of addresses known to the address manager.
+// numAddresses returns the number of addresses known to the address manager.
 func (self *AddrManager) numAddresses() int {
-	//return a.nTried + a.nNew
-	return len(self.addrIndex)
+	return self.nTried + self.nNew
+}
+
+// NumAddresses returns the number of addresses known to the address manager.
+func (self *AddrManager) NumAddresses() int {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+
+	return self.numAddresses()
+}
+
+// NeedMoreAddresses returns whether or not the address manager needs more
+// addresses.
+func (self *AddrManager) NeedMoreAddresses() bool {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+
+	return self.numAddresses() < NeedAddressThreshold
 }
 
 // reset resets the address manager by reinitialising the random source
 // and allocating fresh empty bucket storage.
 func (self *AddrManager) reset() {
-	//self.mtx.Lock()
-	//defer self.mtx.Unlock()
+	self.addrIndex = make(map[string]*KnownAddress)
+
+	// fill key with bytes from a good random source.
+	if _, err := crand.Read(self.key[:]); err != nil {
+		Logger.log.Errorf("Failed to generate address manager key: %+v", err)
+	}
+	self.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
 
-	self.addrIndex = make(map[string]*peer.Peer)
+	for i := range self.addrNew {
+		self.addrNew[i] = make(map[string]*KnownAddress)
+	}
+	for i := range self.addrTried {
+		self.addrTried[i] = nil
+	}
+	self.nNew = 0
+	self.nTried = 0
 }
 
 func (self *AddrManager) deserializePeers(filePath string) error {
-	//self.mtx.Lock()
-	//defer self.mtx.Unlock()
-
 	_, err := os.Stat(filePath)
 	if os.IsNotExist(err) {
 		return nil
@@ -141,19 +194,58 @@ func (self *AddrManager) deserializePeers(filePath string) error {
 		return fmt.Errorf("error reading %s: %+v", filePath, err)
 	}
 
-	if sam.Version != Version {
+	// version 1 only kept a flat list of addresses without any statistics,
+	// those addresses are moved into new buckets
+	if sam.Version != Version && sam.Version != 1 {
 		return fmt.Errorf("unknown version %+v in serialized addrmanager", sam.Version)
 	}
-	copy(self.key[:], sam.Key[:])
+	if sam.Version == Version {
+		copy(self.key[:], sam.Key[:])
+	}
 
 	for _, v := range sam.Addresses {
+		if _, ok := self.addrIndex[v.Addr]; ok || v.Addr == "" {
+			continue
+		}
 		peer := new(peer.Peer)
-		peer.PeerID = peer2.ID(v.Src)
 		peer.RawAddress = v.Addr
 		peer.PublicKey = v.PublicKey
 
-		self.addrIndex[peer.RawAddress] = peer
+		ka := &KnownAddress{
+			Peer: peer,
+			Src:  v.Addr,
+		}
+		if sam.Version == Version {
+			if v.PeerID != "" {
+				peerID, err := peer2.IDB58Decode(v.PeerID)
+				if err != nil {
+					return fmt.Errorf("invalid peer id %s of %s: %+v", v.PeerID, v.Addr, err)
+				}
+				peer.PeerID = peerID
+			}
+			if v.Src != "" {
+				ka.Src = v.Src
+			}
+			ka.Attempts = v.Attempts
+			ka.LastAttempt = v.LastAttempt
+			ka.LastSuccess = v.LastSuccess
+			ka.LastSeen = v.LastSeen
+		}
 
+		// buckets depend on the key, so they are recalculated rather than
+		// being trusted from the file
+		if v.Tried && sam.Version == Version {
+			bucket := self.getTriedBucket(v.Addr)
+			if len(self.addrTried[bucket]) < TriedBucketSize {
+				ka.tried = true
+				ka.bucket = bucket
+				self.addrTried[bucket] = append(self.addrTried[bucket], ka)
+				self.addrIndex[v.Addr] = ka
+				self.nTried++
+				continue
+			}
+		}
+		self.addNew(ka)
 	}
 	return nil
 }
@@ -209,14 +301,144 @@ out:
 	Logger.log.Infof("Address handler done")
 }
 
-// Good marks the given address as good.  To be called after a successful
-// connection and version exchange.  If the address is unknown to the address
-// manager it will be ignored.
-func (self *AddrManager) Good(addr *peer.Peer) {
+// groupKey returns the network group of a raw multiaddress, addresses of the
+// same /16 (ip4) or /32 (ip6) network share a group so one network can only
+// occupy a limited number of buckets.
+func groupKey(rawAddr string) string {
+	parts := strings.Split(rawAddr, "/")
+	if len(parts) < 3 {
+		return "unroutable"
+	}
+	switch parts[1] {
+	case "ip4", "ip6":
+		ip := net.ParseIP(parts[2])
+		if ip == nil {
+			return "unroutable"
+		}
+		if ip.IsLoopback() || ip.IsUnspecified() {
+			return "local"
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4.Mask(net.CIDRMask(16, 32)).String()
+		}
+		return ip.Mask(net.CIDRMask(32, 128)).String()
+	case "dns4", "dns6", "dnsaddr":
+		return parts[2]
+	}
+	return "unroutable"
+}
+
+// hashUint64 returns the first 8 bytes of the double sha256 of the address
+// manager key followed by the given data as an uint64.
+func (self *AddrManager) hashUint64(data ...[]byte) uint64 {
+	buf := append([]byte{}, self.key[:]...)
+	for _, d := range data {
+		buf = append(buf, d...)
+	}
+	return binary.LittleEndian.Uint64(common.DoubleHashB(buf)[:8])
+}
+
+// getNewBucket returns the new bucket index of an address learnt from src,
+// addresses announced by one source group spread over NewBucketsPerGroup
+// buckets at most.
+func (self *AddrManager) getNewBucket(rawAddr string, src string) int {
+	srcGroup := []byte(groupKey(src))
+	hash1 := self.hashUint64([]byte(groupKey(rawAddr)), srcGroup) % NewBucketsPerGroup
+	hashBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(hashBuf, hash1)
+	return int(self.hashUint64(srcGroup, hashBuf) % NewBucketCount)
+}
+
+// getTriedBucket returns the tried bucket index of an address, addresses of
+// one group spread over TriedBucketsPerGroup buckets at most.
+func (self *AddrManager) getTriedBucket(rawAddr string) int {
+	hash1 := self.hashUint64([]byte(rawAddr)) % TriedBucketsPerGroup
+	hashBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(hashBuf, hash1)
+	return int(self.hashUint64([]byte(groupKey(rawAddr)), hashBuf) % TriedBucketCount)
+}
+
+// expireNew makes space in the new bucket by expiring the bad addresses, or
+// when there is none the oldest seen one.
+func (self *AddrManager) expireNew(bucket int) {
+	var oldest *KnownAddress
+	for k, v := range self.addrNew[bucket] {
+		if v.isBad() {
+			Logger.log.Infof("Expiring bad address %s", k)
+			delete(self.addrNew[bucket], k)
+			delete(self.addrIndex, k)
+			self.nNew--
+			continue
+		}
+		if oldest == nil || v.LastSeen.Before(oldest.LastSeen) {
+			oldest = v
+		}
+	}
+
+	if len(self.addrNew[bucket]) >= NewBucketSize && oldest != nil {
+		Logger.log.Infof("Expiring oldest address %s", oldest.Peer.RawAddress)
+		delete(self.addrNew[bucket], oldest.Peer.RawAddress)
+		delete(self.addrIndex, oldest.Peer.RawAddress)
+		self.nNew--
+	}
+}
+
+// addNew puts a known address into its new bucket, expiring old entries of
+// the bucket when it is full.
+func (self *AddrManager) addNew(ka *KnownAddress) {
+	rawAddr := ka.Peer.RawAddress
+	bucket := self.getNewBucket(rawAddr, ka.Src)
+	if len(self.addrNew[bucket]) >= NewBucketSize {
+		self.expireNew(bucket)
+	}
+	ka.tried = false
+	ka.bucket = bucket
+	self.addrNew[bucket][rawAddr] = ka
+	self.addrIndex[rawAddr] = ka
+	self.nNew++
+}
+
+// updateAddress adds an address learnt from src, or refreshes it when it is
+// already known.
+func (self *AddrManager) updateAddress(addr *peer.Peer, src string) {
+	if addr == nil || addr.RawAddress == "" {
+		return
+	}
+	ka, ok := self.addrIndex[addr.RawAddress]
+	if ok {
+		ka.LastSeen = time.Now()
+		if ka.Peer.PublicKey == "" && addr.PublicKey != "" {
+			ka.Peer.PublicKey = addr.PublicKey
+		}
+		return
+	}
+
+	if src == "" {
+		src = addr.RawAddress
+	}
+	self.addNew(&KnownAddress{
+		Peer:     addr,
+		Src:      src,
+		LastSeen: time.Now(),
+	})
+}
+
+// AddAddress adds a new address learnt from the peer with raw address src.
+func (self *AddrManager) AddAddress(addr *peer.Peer, src string) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+
+	self.updateAddress(addr, src)
+}
+
+// AddAddresses adds new addresses learnt from the peer with raw address src.
+func (self *AddrManager) AddAddresses(addrs []*peer.Peer, src string) {
 	self.mtx.Lock()
 	defer self.mtx.Unlock()
 
-	self.addrIndex[addr.RawAddress] = addr
+	for _, addr := range addrs {
+		self.updateAddress(addr, src)
+	}
 }
 
 func (self *AddrManager) AddAddressesStr(addrs []string) {
@@ -227,8 +449,164 @@ func (self *AddrManager) AddAddressesStr(addrs []string) {
 		peer := peer.Peer{
 			RawAddress: addr,
 		}
-		self.addrIndex[addr] = &peer
+		self.updateAddress(&peer, addr)
+	}
+}
+
+// Attempt increases the given address' attempt counter and updates
+// the last attempt time.
+func (self *AddrManager) Attempt(rawAddr string) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+
+	ka, ok := self.addrIndex[rawAddr]
+	if !ok {
+		return
+	}
+	ka.Attempts++
+	ka.LastAttempt = time.Now()
+}
+
+// Connected marks the given address as currently connected and working at the
+// current time.  The address must already be known to AddrManager else it will
+// be ignored.
+func (self *AddrManager) Connected(rawAddr string) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+
+	ka, ok := self.addrIndex[rawAddr]
+	if !ok {
+		return
+	}
+	ka.LastSeen = time.Now()
+}
+
+// Good marks the given address as good.  To be called after a successful
+// connection and version exchange.  The address is moved into a tried bucket,
+// when that bucket is full its oldest address goes back to the new buckets.
+func (self *AddrManager) Good(addr *peer.Peer) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+
+	if addr == nil || addr.RawAddress == "" {
+		return
+	}
+	ka, ok := self.addrIndex[addr.RawAddress]
+	if !ok {
+		self.updateAddress(addr, addr.RawAddress)
+		ka = self.addrIndex[addr.RawAddress]
+	}
+
+	now := time.Now()
+	// keep the latest identity of the address, such as its public key
+	ka.Peer = addr
+	ka.LastSuccess = now
+	ka.LastAttempt = now
+	ka.LastSeen = now
+	ka.Attempts = 0
+
+	if ka.tried {
+		return
+	}
+
+	delete(self.addrNew[ka.bucket], addr.RawAddress)
+	self.nNew--
+
+	bucket := self.getTriedBucket(addr.RawAddress)
+	if len(self.addrTried[bucket]) < TriedBucketSize {
+		ka.tried = true
+		ka.bucket = bucket
+		self.addrTried[bucket] = append(self.addrTried[bucket], ka)
+		self.nTried++
+		return
+	}
+
+	// tried bucket is full, swap its oldest address back to new buckets
+	oldestIndex := 0
+	for i, v := range self.addrTried[bucket] {
+		if v.LastSuccess.Before(self.addrTried[bucket][oldestIndex].LastSuccess) {
+			oldestIndex = i
+		}
 	}
+	oldest := self.addrTried[bucket][oldestIndex]
+	ka.tried = true
+	ka.bucket = bucket
+	self.addrTried[bucket][oldestIndex] = ka
+
+	Logger.log.Infof("Replacing tried address %s by %s", oldest.Peer.RawAddress, addr.RawAddress)
+	self.addNew(oldest)
+}
+
+// GetAddress returns a single address that should be routable.  It picks a
+// random one from the tried or new buckets, biased towards the addresses which
+// were not recently attempted and have not failed much.
+func (self *AddrManager) GetAddress() *KnownAddress {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+
+	if self.numAddresses() == 0 {
+		return nil
+	}
+
+	// Use a 50% chance for choosing between tried and new table entries.
+	factor := 1.0
+	if self.nTried > 0 && (self.nNew == 0 || self.rand.Intn(2) == 0) {
+		for {
+			bucket := self.addrTried[self.rand.Intn(TriedBucketCount)]
+			if len(bucket) == 0 {
+				continue
+			}
+			ka := bucket[self.rand.Intn(len(bucket))]
+			if self.rand.Float64() < factor*ka.chance() {
+				return ka
+			}
+			factor *= 1.2
+		}
+	}
+
+	for {
+		bucket := self.addrNew[self.rand.Intn(NewBucketCount)]
+		if len(bucket) == 0 {
+			continue
+		}
+		nth := self.rand.Intn(len(bucket))
+		var ka *KnownAddress
+		for _, v := range bucket {
+			if nth == 0 {
+				ka = v
+				break
+			}
+			nth--
+		}
+		if self.rand.Float64() < factor*ka.chance() {
+			return ka
+		}
+		factor *= 1.2
+	}
+}
+
+// GetAddressByPublicKey returns the tried address which the peer of publicKey
+// was last connected at, or an empty string. Public keys of tried addresses
+// were proven in version handshake so they are not taken from announcements.
+func (self *AddrManager) GetAddressByPublicKey(publicKey string) string {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+
+	var result *KnownAddress
+	for _, bucket := range self.addrTried {
+		for _, ka := range bucket {
+			if ka.Peer.PublicKey != publicKey {
+				continue
+			}
+			if result == nil || ka.LastSuccess.After(result.LastSuccess) {
+				result = ka
+			}
+		}
+	}
+	if result == nil {
+		return ""
+	}
+	return result.Peer.RawAddress
 }
 
 // AddressCache returns the current address cache.  It must be treated as
@@ -244,7 +622,16 @@ func (self *AddrManager) AddressCache() []*peer.Peer {
 	allAddr := make([]*peer.Peer, 0, addrIndexLen)
 	// Iteration order is undefined here, but we randomise it anyway.
 	for _, v := range self.addrIndex {
-		allAddr = append(allAddr, v)
+		if v.isBad() {
+			continue
+		}
+		allAddr = append(allAddr, v.Peer)
+	}
+	self.rand.Shuffle(len(allAddr), func(i, j int) {
+		allAddr[i], allAddr[j] = allAddr[j], allAddr[i]
+	})
+	if len(allAddr) > GetAddrMax {
+		allAddr = allAddr[:GetAddrMax]
 	}
 	return allAddr
 }
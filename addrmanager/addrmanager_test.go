@@ -0,0 +1,147 @@
+package addrmanager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ninjadotorg/constant/peer"
+)
+
+const testPeerID = "QmRWYJ1E5RxZvZYXLpd7cJhNjQmMmwJXMFZXfrTM2Phc1z"
+
+func testRawAddress(a, b int) string {
+	return fmt.Sprintf("/ip4/10.%d.0.%d/tcp/9333/ipfs/%s", a, b, testPeerID)
+}
+
+func TestGroupKey(t *testing.T) {
+	if groupKey("/ip4/10.1.2.3/tcp/9333") != groupKey("/ip4/10.1.200.4/tcp/9334") {
+		t.Error("addresses of the same /16 must share a group")
+	}
+	if groupKey("/ip4/10.1.2.3/tcp/9333") == groupKey("/ip4/10.2.2.3/tcp/9333") {
+		t.Error("addresses of different /16 must not share a group")
+	}
+	if groupKey("/ip4/127.0.0.1/tcp/9333") != "local" {
+		t.Error("loopback address must be in local group")
+	}
+	if groupKey("garbage") != "unroutable" {
+		t.Error("invalid address must be unroutable")
+	}
+}
+
+func TestAddAndGood(t *testing.T) {
+	amgr := New(os.TempDir())
+	src := testRawAddress(0, 1)
+	for i := 0; i < 10; i++ {
+		amgr.AddAddress(&peer.Peer{RawAddress: testRawAddress(i, 2)}, src)
+	}
+	// known addresses are only refreshed
+	amgr.AddAddress(&peer.Peer{RawAddress: testRawAddress(0, 2)}, src)
+	if amgr.NumAddresses() != 10 {
+		t.Fatalf("expected 10 addresses, got %d", amgr.NumAddresses())
+	}
+	if amgr.nNew != 10 || amgr.nTried != 0 {
+		t.Fatalf("expected 10 new and 0 tried, got %d and %d", amgr.nNew, amgr.nTried)
+	}
+
+	amgr.Attempt(testRawAddress(3, 2))
+	amgr.Good(&peer.Peer{RawAddress: testRawAddress(3, 2), PublicKey: "pubkey"})
+	if amgr.nNew != 9 || amgr.nTried != 1 {
+		t.Fatalf("expected 9 new and 1 tried, got %d and %d", amgr.nNew, amgr.nTried)
+	}
+	ka := amgr.addrIndex[testRawAddress(3, 2)]
+	if !ka.tried || ka.Attempts != 0 || ka.Peer.PublicKey != "pubkey" {
+		t.Errorf("good address is not updated: %+v", ka)
+	}
+
+	if amgr.GetAddressByPublicKey("pubkey") != testRawAddress(3, 2) {
+		t.Error("expected tried address of public key")
+	}
+	// public keys of new addresses are only announced, not proven
+	amgr.AddAddress(&peer.Peer{RawAddress: testRawAddress(4, 2), PublicKey: "announced"}, src)
+	if amgr.GetAddressByPublicKey("announced") != "" {
+		t.Error("expected no address of announced public key")
+	}
+
+	if amgr.GetAddress() == nil {
+		t.Error("expected an address")
+	}
+	if len(amgr.AddressCache()) != 10 {
+		t.Errorf("expected 10 cached addresses, got %d", len(amgr.AddressCache()))
+	}
+}
+
+func TestNewBucketLimit(t *testing.T) {
+	amgr := New(os.TempDir())
+	// a single source group can only spread over NewBucketsPerGroup buckets
+	src := testRawAddress(0, 1)
+	buckets := make(map[int]bool)
+	for i := 0; i < 255; i++ {
+		buckets[amgr.getNewBucket(testRawAddress(i, 2), src)] = true
+	}
+	if len(buckets) > NewBucketsPerGroup {
+		t.Errorf("expected at most %d buckets, got %d", NewBucketsPerGroup, len(buckets))
+	}
+}
+
+func TestInboundSource(t *testing.T) {
+	amgr := New(os.TempDir())
+	// an inbound peer has no dialed address, addresses it announces are
+	// learnt from its observed address, so however many groups they span
+	// they stay in the buckets of its own group
+	inbound := &peer.PeerConn{RemoteObservedAddress: "/ip4/10.0.0.1/tcp/52143"}
+	addrs := make([]*peer.Peer, 0, 255)
+	for i := 0; i < 255; i++ {
+		addrs = append(addrs, &peer.Peer{RawAddress: testRawAddress(i, 2)})
+	}
+	amgr.AddAddresses(addrs, inbound.SourceAddress())
+	buckets := make(map[int]bool)
+	for _, ka := range amgr.addrIndex {
+		if ka.Src != inbound.RemoteObservedAddress {
+			t.Fatalf("expected source %s, got %s", inbound.RemoteObservedAddress, ka.Src)
+		}
+		buckets[ka.bucket] = true
+	}
+	if len(buckets) > NewBucketsPerGroup {
+		t.Errorf("expected at most %d buckets, got %d", NewBucketsPerGroup, len(buckets))
+	}
+
+	outbound := &peer.PeerConn{RemoteRawAddress: testRawAddress(0, 1), RemoteObservedAddress: "/ip4/10.0.0.1/tcp/9333"}
+	if outbound.SourceAddress() != testRawAddress(0, 1) {
+		t.Error("expected dialed address as source of outbound peer")
+	}
+}
+
+func TestSaveLoadPeers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "addrmanager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	amgr := New(dir)
+	src := testRawAddress(0, 1)
+	amgr.AddAddress(&peer.Peer{RawAddress: testRawAddress(1, 2)}, src)
+	amgr.AddAddress(&peer.Peer{RawAddress: testRawAddress(2, 2)}, src)
+	amgr.Good(&peer.Peer{RawAddress: testRawAddress(2, 2)})
+	amgr.Attempt(testRawAddress(1, 2))
+	if err := amgr.savePeers(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := New(dir)
+	if err := loaded.deserializePeers(loaded.peersFile); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.nNew != 1 || loaded.nTried != 1 {
+		t.Fatalf("expected 1 new and 1 tried, got %d and %d", loaded.nNew, loaded.nTried)
+	}
+	if loaded.key != amgr.key {
+		t.Error("bucket key is not restored")
+	}
+	ka := loaded.addrIndex[testRawAddress(1, 2)]
+	if ka == nil || ka.Attempts != 1 || ka.Src != src {
+		t.Errorf("known address is not restored: %+v", ka)
+	}
+}
@@ -3,7 +3,7 @@ package addrmanager
 import "time"
 
 const (
-	Version = 1
+	Version = 2
 
 	// DumpAddressInterval is the interval used to dump the address
 	// cache to disk for future use.
@@ -16,4 +16,44 @@ const (
 	// TriedBucketCount is the number of buckets we split tried
 	// addresses over.
 	TriedBucketCount = 64
+
+	// NewBucketSize is the maximum number of addresses in each new address
+	// bucket.
+	NewBucketSize = 64
+
+	// TriedBucketSize is the maximum number of addresses in each tried
+	// address bucket.
+	TriedBucketSize = 256
+
+	// NewBucketsPerGroup is the number of new buckets over which addresses
+	// of the same source group are spread, so that a single source can
+	// not fill the whole new table.
+	NewBucketsPerGroup = 32
+
+	// TriedBucketsPerGroup is the number of tried buckets over which
+	// addresses of the same group are spread.
+	TriedBucketsPerGroup = 8
+
+	// NumMissingDays is the number of days after which an address that
+	// has not been seen is considered bad.
+	NumMissingDays = 30
+
+	// NumRetries is the number of tries without a single success after
+	// which an address is considered bad.
+	NumRetries = 3
+
+	// MaxFailures is the maximum number of failures since last success
+	// after which an address is considered bad.
+	MaxFailures = 10
+
+	// MinBadDays is the number of days since the last success before an
+	// address with failures is considered bad.
+	MinBadDays = 7
+
+	// GetAddrMax is the most addresses returned by AddressCache.
+	GetAddrMax = 2500
+
+	// NeedAddressThreshold is the number of addresses under which the
+	// address manager will claim to need more addresses.
+	NeedAddressThreshold = 1000
 )
@@ -0,0 +1,77 @@
+package addrmanager
+
+import (
+	"time"
+
+	"github.com/ninjadotorg/constant/peer"
+)
+
+// KnownAddress tracks information about a known network address that is used
+// to determine how viable an address is.
+type KnownAddress struct {
+	Peer        *peer.Peer
+	Src         string // raw address of the peer which told us this address
+	Attempts    int    // connection attempts since last success
+	LastAttempt time.Time
+	LastSuccess time.Time
+	LastSeen    time.Time
+	tried       bool
+	bucket      int // index of the new or tried bucket holding the address
+}
+
+// chance returns the selection probability for a known address.  The priority
+// depends upon how recently the address has been seen, how recently it was last
+// attempted and how often attempts to connect to it have failed.
+func (self *KnownAddress) chance() float64 {
+	now := time.Now()
+	lastAttempt := now.Sub(self.LastAttempt)
+
+	if lastAttempt < 0 {
+		lastAttempt = 0
+	}
+
+	c := 1.0
+
+	// Very recent attempts are less likely to be retried.
+	if lastAttempt < 10*time.Minute {
+		c *= 0.01
+	}
+
+	// Failed attempts deprioritise.
+	for i := self.Attempts; i > 0; i-- {
+		c /= 1.5
+	}
+
+	return c
+}
+
+// isBad returns true if the address in question has not been tried in the last
+// minute and meets one of the following criteria:
+// 1) It has not been seen in over a month
+// 2) It has failed at least NumRetries times and never succeeded
+// 3) It has failed MaxFailures times in the last MinBadDays
+// All addresses that meet these criteria are assumed to be worthless and not
+// worth keeping hold of.
+func (self *KnownAddress) isBad() bool {
+	if self.LastAttempt.After(time.Now().Add(-1 * time.Minute)) {
+		return false
+	}
+
+	// Over a month old?
+	if !self.LastSeen.IsZero() && self.LastSeen.Before(time.Now().Add(-1*NumMissingDays*time.Hour*24)) {
+		return true
+	}
+
+	// Never succeeded?
+	if self.LastSuccess.IsZero() && self.Attempts >= NumRetries {
+		return true
+	}
+
+	// Hasn't succeeded in too long?
+	if !self.LastSuccess.After(time.Now().Add(-1*MinBadDays*time.Hour*24)) &&
+		self.Attempts >= MaxFailures {
+		return true
+	}
+
+	return false
+}
@@ -54,7 +54,8 @@ func (self *BestState) Init(block *Block, tree *client.IncMerkleTree) {
 }
 
 func (self *BestState) Update(block *Block) error {
-	tree := self.CmTree
+	// commitments of block go into a copy, snapshots keep the tree of their block
+	tree := self.CmTree.MakeCopy()
 	err := UpdateMerkleTreeForBlock(tree, block)
 	if err != nil {
 		return NewBlockChainError(UnExpectedError, err)
@@ -22,6 +22,7 @@ type Block struct {
 	Transactions     []transaction.Transaction
 	BlockProducer    string // in base58check.encode
 	BlockProducerSig string
+	Evidences        []DoubleSignEvidence // proofs of double signing which are punished by this block
 
 	blockHash *common.Hash
 }
@@ -149,34 +150,72 @@ func (self Block) Hash() *common.Hash {
 		return self.blockHash
 	}
 
+	txHashes := make([]common.Hash, 0, len(self.Transactions))
+	for _, tx := range self.Transactions {
+		txHashes = append(txHashes, *tx.Hash())
+	}
+	evidenceHashes := make([]common.Hash, 0, len(self.Evidences))
+	for _, evidence := range self.Evidences {
+		evidenceHashes = append(evidenceHashes, *evidence.Hash())
+	}
+	hash := hashBlockData(&self.Header, self.BlockProducer, self.BlockProducerSig, txHashes, evidenceHashes)
+	self.blockHash = &hash
+	return self.blockHash
+}
+
+// hashBlockData hashes a block from its header and hashes of its body
+func hashBlockData(header *BlockHeader, blockProducer string, blockProducerSig string, txHashes []common.Hash, evidenceHashes []common.Hash) common.Hash {
 	record := common.EmptyString
 
 	// add data from header
-	record += strconv.FormatInt(self.Header.Timestamp, 10) +
-		string(self.Header.ChainID) +
-		self.Header.MerkleRoot.String() +
-		self.Header.MerkleRootCommitments.String() +
-		self.Header.PrevBlockHash.String() +
-		strconv.Itoa(int(self.Header.SalaryFund)) +
-		strconv.Itoa(int(self.Header.GOVConstitution.GOVParams.SalaryPerTx)) +
-		strconv.Itoa(int(self.Header.GOVConstitution.GOVParams.BasicSalary)) +
-		strings.Join(self.Header.Committee, ",")
+	record += strconv.FormatInt(header.Timestamp, 10) +
+		string(header.ChainID) +
+		header.MerkleRoot.String() +
+		header.MerkleRootCommitments.String() +
+		header.PrevBlockHash.String() +
+		strconv.Itoa(int(header.SalaryFund)) +
+		strconv.Itoa(int(header.GOVConstitution.GOVParams.SalaryPerTx)) +
+		strconv.Itoa(int(header.GOVConstitution.GOVParams.BasicSalary)) +
+		strings.Join(header.Committee, ",")
+
+	// add committee swaps, blocks without swap keep their hash
+	for _, swap := range header.CommitteeSwaps {
+		record += swap.String()
+	}
+
+	// add fee schedule, blocks without one keep their hash
+	if feeSchedule := header.GOVConstitution.GOVParams.FeeSchedule; feeSchedule != nil {
+		record += feeSchedule.String()
+	}
 
 	// add data from body
-	record += strconv.Itoa(self.Header.Version) +
-		self.BlockProducer +
-		self.BlockProducerSig +
-		strconv.Itoa(len(self.Transactions)) +
-		strconv.Itoa(int(self.Header.Height))
+	record += strconv.Itoa(header.Version) +
+		blockProducer +
+		blockProducerSig +
+		strconv.Itoa(len(txHashes)) +
+		strconv.Itoa(int(header.Height))
 
 	// add data from tx
-	for _, tx := range self.Transactions {
-		record += tx.Hash().String()
+	for _, txHash := range txHashes {
+		record += txHash.String()
 	}
 
-	hash := common.DoubleHashH([]byte(record))
-	self.blockHash = &hash
-	return self.blockHash
+	// add data from evidence, blocks without evidence keep their hash
+	for _, evidenceHash := range evidenceHashes {
+		record += evidenceHash.String()
+	}
+
+	return common.DoubleHashH([]byte(record))
+}
+
+/*
+SigningHash - hash of block which is multi-signed by committee, producer signs
+header after that so its signature is left out
+*/
+func (self Block) SigningHash() *common.Hash {
+	self.BlockProducerSig = common.EmptyString
+	self.blockHash = nil
+	return self.Hash()
 }
 
 func (block *Block) updateDCBConstitution(tx transaction.Transaction, blockgen *BlkTmplGenerator) error {
@@ -208,7 +247,12 @@ func (block *Block) updateGOVConstitution(tx transaction.Transaction, blockgen *
 	block.Header.GOVConstitution.ProposalTXID = txAcceptGOVProposal.GOVProposalTXID
 	block.Header.GOVConstitution.CurrentGOVNationalWelfare = GetOracleGOVNationalWelfare()
 
+	// a proposal without fee schedule keeps the current one
 	proposalParams := GOVProposal.GOVProposalData.GOVParams
+	feeSchedule := block.Header.GOVConstitution.GOVParams.FeeSchedule
+	if votedFeeSchedule := proposalFeeSchedule(GOVProposal); votedFeeSchedule != nil {
+		feeSchedule = votedFeeSchedule
+	}
 	block.Header.GOVConstitution.GOVParams = GOVParams{
 		proposalParams.SalaryPerTx,
 		proposalParams.BasicSalary,
@@ -224,6 +268,7 @@ func (block *Block) updateGOVConstitution(tx transaction.Transaction, blockgen *
 			proposalParams.RefundInfo.ThresholdToLargeTx,
 			proposalParams.RefundInfo.RefundAmount,
 		},
+		feeSchedule,
 	}
 	return nil
 }
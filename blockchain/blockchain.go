@@ -64,6 +64,11 @@ type Config struct {
 	Wallet *wallet.Wallet
 	//snapshot reward
 	customTokenRewardSnapshot map[string]uint64
+
+	// Clock is the time of new blocks and of their waits for transactions.
+	//
+	// This field can be nil, the wall clock is used then.
+	Clock common.Clock
 }
 
 /*
@@ -79,6 +84,9 @@ func (self *BlockChain) Init(config *Config) error {
 	}
 
 	self.config = *config
+	if self.config.Clock == nil {
+		self.config.Clock = common.SystemClock{}
+	}
 
 	// Initialize the chain state from the passed database.  When the db
 	// does not yet contain any chain state, both it and the chain state
@@ -141,7 +149,9 @@ func (self *BlockChain) createChainState(chainId byte) error {
 	// Create a new block from genesis block and set it as best block of chain
 	var initBlock *Block
 	if chainId == 0 {
-		initBlock = self.config.ChainParams.GenesisBlock
+		// params may be shared by chains of several nodes, each gets its own genesis block
+		genesisBlock := *self.config.ChainParams.GenesisBlock
+		initBlock = &genesisBlock
 	} else {
 		initBlock = &Block{}
 		initBlock.Header = self.config.ChainParams.GenesisBlock.Header
@@ -251,6 +261,31 @@ func (self *BlockChain) StoreBestState(chainId byte) error {
 	return self.config.DataBase.StoreBestState(self.BestState[chainId], chainId)
 }
 
+/*
+BestSnapshot - copy of best state of a chain, it is safe while blocks are
+connected. Its merkle tree, candidates and loan ids are shared with the chain
+and must be treated as immutable.
+*/
+func (self *BlockChain) BestSnapshot(chainId byte) *BestState {
+	self.chainLock.RLock()
+	defer self.chainLock.RUnlock()
+	bestState := *self.BestState[chainId]
+	return &bestState
+}
+
+/*
+UpdateBestState - move best state of chain of block to the block and store it
+*/
+func (self *BlockChain) UpdateBestState(block *Block) error {
+	self.chainLock.Lock()
+	defer self.chainLock.Unlock()
+	err := self.BestState[block.Header.ChainID].Update(block)
+	if err != nil {
+		return err
+	}
+	return self.StoreBestState(block.Header.ChainID)
+}
+
 /*
 GetBestState - return a best state from a chain
 */
@@ -950,6 +985,23 @@ func (self *BlockChain) GetCommitteCandidate(pubkeyParam string) *CommitteeCandi
 	return nil
 }
 
+/*
+RemoveCommitteeCandidate - remove candidate from candidate list of every chain
+*/
+func (self *BlockChain) RemoveCommitteeCandidate(pubKey string) error {
+	for chainID, bestState := range self.BestState {
+		if _, ok := bestState.Candidates[pubKey]; !ok {
+			continue
+		}
+		bestState.RemoveCandidate(pubKey)
+		err := self.StoreBestState(byte(chainID))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 /*
 Get Candidate List from all chain and merge all to one - return pubkey of them
 */
@@ -10,6 +10,7 @@ type GOVParams struct {
 	BasicSalary  uint64 // basic salary per block(mili constant)
 	SellingBonds *SellingBonds
 	RefundInfo   *RefundInfo
+	FeeSchedule  *FeeSchedule
 }
 
 type DCBParams struct {
@@ -23,6 +24,16 @@ type RefundInfo struct {
 	RefundAmount       uint64
 }
 
+// FeeSchedule is min fee per kb (mili constant) of txs accepted into mempool,
+// by tx type
+type FeeSchedule struct {
+	Normal      uint64
+	CustomToken uint64
+	Loan        uint64 // loan request, response, payment and withdraw
+	BuyBack     uint64 // buy-back and buy-sell requests
+	Voting      uint64 // proposals and votes of DCB and GOV
+}
+
 type SellingBonds struct {
 	BondsToSell    uint64
 	BondPrice      uint64 // in Constant unit
@@ -54,11 +65,13 @@ type BlockHeader struct {
 	Timestamp int64
 
 	// Parallel PoS
-	BlockCommitteeSigs []string //Include producer and validators signature
-	Committee          []string //Voted committee for the next block
-	ChainID            byte
-	ChainsHeight       []int //height of 20 chain when this block is created
-	CandidateHash      common.Hash
+	AggregatedSig  string          //Multi-signature of block hash by committee members in SignersBitmap
+	SignersBitmap  []byte          //Bit i is set when Committee[i] is one of signers, include producer
+	Committee      []string        //Voted committee for the next block
+	CommitteeSwaps []CommitteeSwap //Swaps which turn committee of the previous block into Committee
+	ChainID        byte
+	ChainsHeight   []int //height of 20 chain when this block is created
+	CandidateHash  common.Hash
 
 	SalaryFund uint64 // use to pay salary for miners(block producer or current leader) in chain
 	BankFund   uint64 // for DBank
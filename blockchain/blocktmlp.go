@@ -39,6 +39,10 @@ type TxPool interface {
 	// transactions in the source pool.
 	MiningDescs() []*transaction.TxDesc
 
+	// ChainMiningDescs returns a slice of mining descriptors for the
+	// transactions sent from a chain.
+	ChainMiningDescs(chainID byte) []*transaction.TxDesc
+
 	// HaveTransaction returns whether or not the passed transaction hash
 	// exists in the source pool.
 	HaveTransaction(hash *common.Hash) bool
@@ -68,20 +72,25 @@ func (self BlkTmplGenerator) Init(txPool TxPool, chain *BlockChain, rewardAgent
 
 func (blockgen *BlkTmplGenerator) NewBlockTemplate(payToAddress privacy.PaymentAddress, chainID byte) (*Block, error) {
 
-	prevBlock := blockgen.chain.BestState[chainID].BestBlock
-	prevBlockHash := blockgen.chain.BestState[chainID].BestBlock.Hash()
-	prevCmTree := blockgen.chain.BestState[chainID].CmTree.MakeCopy()
-	sourceTxns := blockgen.txPool.MiningDescs()
+	bestState := blockgen.chain.BestSnapshot(chainID)
+	prevBlock := bestState.BestBlock
+	prevBlockHash := bestState.BestBlockHash
+	prevCmTree := bestState.CmTree.MakeCopy()
+	sourceTxns := blockgen.txPool.ChainMiningDescs(chainID)
+	var govTxns []*transaction.TxDesc
 
 	var txsToAdd []transaction.Transaction
 	var txToRemove []transaction.Transaction
 	var buySellReqTxs []transaction.Transaction
-	var txTokenVouts map[*common.Hash]*transaction.TxTokenVout
+	txTokenVouts := make(map[*common.Hash]*transaction.TxTokenVout)
 	bondsSold := uint64(0)
 	incomeFromBonds := uint64(0)
 	totalFee := uint64(0)
 	buyBackCoins := uint64(0)
 
+	room := newTxRoom()
+	var selector *txSelector
+
 	// Get salary per tx
 	salaryPerTx := blockgen.rewardAgent.GetSalaryPerTx(chainID)
 	// Get basic salary on block
@@ -96,7 +105,7 @@ func (blockgen *BlkTmplGenerator) NewBlockTemplate(payToAddress privacy.PaymentA
 			Logger.log.Error(err)
 			return nil, err
 		}
-		sourceTxns = append(sourceTxns, tx)
+		govTxns = append(govTxns, tx)
 	}
 	if blockgen.neededNewGovConstitution(chainID) {
 		tx, err := blockgen.createRequestConstitutionTxDecs(chainID, GOVConstitutionHelper{})
@@ -104,43 +113,27 @@ func (blockgen *BlkTmplGenerator) NewBlockTemplate(payToAddress privacy.PaymentA
 			Logger.log.Error(err)
 			return nil, err
 		}
-		sourceTxns = append(sourceTxns, tx)
+		govTxns = append(govTxns, tx)
 	}
 
-	if len(sourceTxns) < common.MinTxsInBlock {
-		// if len of sourceTxns < MinTxsInBlock -> wait for more transactions
-		Logger.log.Info("not enough transactions. Wait for more...")
-		<-time.Tick(common.MinBlockWaitTime * time.Second)
-		sourceTxns = blockgen.txPool.MiningDescs()
-		if len(sourceTxns) == 0 {
-			<-time.Tick(common.MaxBlockWaitTime * time.Second)
-			sourceTxns = blockgen.txPool.MiningDescs()
-			if len(sourceTxns) == 0 {
-				// return nil, errors.New("No Tx")
-				Logger.log.Info("Creating empty block...")
-				goto concludeBlock
-			}
-		}
-	}
-
-	for _, txDesc := range sourceTxns {
-		tx := txDesc.Tx
+	// addTx checks a candidate tx and accounts buy-sell and buy-back requests,
+	// txs which can not be added any more are removed from pool
+	addTx := func(tx transaction.Transaction) bool {
 		txChainID, _ := common.GetTxSenderChain(tx.GetSenderAddrLastByte())
 		if txChainID != chainID {
-			continue
+			return false
 		}
 		// ValidateTransaction vote and propose transaction
-
 		if !blockgen.txPool.ValidateTxByItSelf(tx) {
-			txToRemove = append(txToRemove, transaction.Transaction(tx))
-			continue
+			txToRemove = append(txToRemove, tx)
+			return false
 		}
 
 		if tx.GetType() == common.TxBuyFromGOVRequest {
 			income, soldAmt, addable := blockgen.checkBuyFromGOVReqTx(chainID, tx, bondsSold)
 			if !addable {
 				txToRemove = append(txToRemove, tx)
-				continue
+				return false
 			}
 			bondsSold += soldAmt
 			incomeFromBonds += income
@@ -151,7 +144,7 @@ func (blockgen *BlkTmplGenerator) NewBlockTemplate(payToAddress privacy.PaymentA
 			txTokenVout, buyBackReqTxID, addable := blockgen.checkBuyBackReqTx(chainID, tx, buyBackCoins)
 			if !addable {
 				txToRemove = append(txToRemove, tx)
-				continue
+				return false
 			}
 			buyBackCoins += txTokenVout.Value * txTokenVout.BuySellResponse.BuyBackInfo.BuyBackPrice
 			txTokenVouts[buyBackReqTxID] = txTokenVout
@@ -159,9 +152,48 @@ func (blockgen *BlkTmplGenerator) NewBlockTemplate(payToAddress privacy.PaymentA
 
 		totalFee += tx.GetTxFee()
 		txsToAdd = append(txsToAdd, tx)
-		if len(txsToAdd) == common.MaxTxsInBlock {
+		return true
+	}
+
+	if len(sourceTxns) < common.MinTxsInBlock {
+		// if len of sourceTxns < MinTxsInBlock -> wait for more transactions
+		Logger.log.Info("not enough transactions. Wait for more...")
+		<-blockgen.chain.config.Clock.After(common.MinBlockWaitTime * time.Second)
+		sourceTxns = blockgen.txPool.ChainMiningDescs(chainID)
+		if len(sourceTxns) == 0 && len(govTxns) == 0 {
+			<-blockgen.chain.config.Clock.After(common.MaxBlockWaitTime * time.Second)
+			sourceTxns = blockgen.txPool.ChainMiningDescs(chainID)
+			if len(sourceTxns) == 0 && len(govTxns) == 0 {
+				// return nil, errors.New("No Tx")
+				Logger.log.Info("Creating empty block...")
+				goto concludeBlock
+			}
+		}
+	}
+
+	// governance txs come first, then txs from pool by fee per KB
+	for _, txDesc := range govTxns {
+		if addTx(txDesc.Tx) {
+			room.Take(txDesc.Tx.GetTxVirtualSize(), 1)
+		}
+	}
+	selector = newTxSelector(sourceTxns)
+	for !room.Full() {
+		next := selector.Next()
+		if next == nil {
 			break
 		}
+		size, count := next.Room()
+		if !room.Fits(size, count) {
+			// a smaller tx may still fit
+			continue
+		}
+		tx := next.txDesc.Tx
+		if !addTx(tx) {
+			continue
+		}
+		room.Take(size, count)
+		selector.Selected(tx)
 	}
 
 	for _, tx := range txToRemove {
@@ -219,7 +251,7 @@ concludeBlock:
 	buySellResTxs := blockgen.buildBuySellResponsesTx(
 		common.TxBuyFromGOVResponse,
 		buySellReqTxs,
-		blockgen.chain.BestState[0].BestBlock.Header.GOVConstitution.GOVParams.SellingBonds,
+		blockgen.chain.BestSnapshot(0).BestBlock.Header.GOVConstitution.GOVParams.SellingBonds,
 	)
 	// create buy-back response txs to distribute constants to buy-back requesters
 	buyBackResTxs, err := blockgen.buildBuyBackResponsesTx(common.TxBuyBackResponse, txTokenVouts, chainID)
@@ -228,17 +260,17 @@ concludeBlock:
 	remainingFund := currentSalaryFund + totalFee + salaryFundAdd + incomeFromBonds - (totalSalary + buyBackCoins)
 	refundTxs, totalRefundAmt := blockgen.buildRefundTxs(chainID, remainingFund)
 
-	coinbases := []transaction.Transaction{salaryTx}
+	// salary tx first, responses come after their requests
+	txsToAdd = append([]transaction.Transaction{salaryTx}, txsToAdd...)
 	for _, resTx := range buySellResTxs {
-		coinbases = append(coinbases, resTx)
+		txsToAdd = append(txsToAdd, resTx)
 	}
 	for _, resTx := range buyBackResTxs {
-		coinbases = append(coinbases, resTx)
+		txsToAdd = append(txsToAdd, resTx)
 	}
 	for _, refundTx := range refundTxs {
-		coinbases = append(coinbases, refundTx)
+		txsToAdd = append(txsToAdd, refundTx)
 	}
-	txsToAdd = append(coinbases, txsToAdd...)
 
 	// Check for final balance of DCB and GOV
 	if currentSalaryFund+totalFee+salaryFundAdd+incomeFromBonds < totalSalary+govPayoutAmount+buyBackCoins+totalRefundAmt {
@@ -262,8 +294,7 @@ concludeBlock:
 		PrevBlockHash:         *prevBlockHash,
 		MerkleRoot:            *merkleRoot,
 		MerkleRootCommitments: common.Hash{},
-		Timestamp:             time.Now().Unix(),
-		BlockCommitteeSigs:    make([]string, common.TotalValidators),
+		Timestamp:             blockgen.chain.config.Clock.Now().Unix(),
 		Committee:             make([]string, common.TotalValidators),
 		ChainID:               chainID,
 		SalaryFund:            currentSalaryFund + incomeFromBonds + totalFee + salaryFundAdd - totalSalary - govPayoutAmount - buyBackCoins - totalRefundAmt,
@@ -272,8 +303,11 @@ concludeBlock:
 		DCBConstitution:       prevBlock.Header.DCBConstitution, // TODO: need get from dcb-params tx
 		LoanParams:            prevBlock.Header.LoanParams,
 	}
-	if block.Header.GOVConstitution.GOVParams.SellingBonds != nil {
-		block.Header.GOVConstitution.GOVParams.SellingBonds.BondsToSell -= bondsSold
+	// selling bonds of previous block are shared, bonds left to sell go into a copy
+	if sellingBonds := block.Header.GOVConstitution.GOVParams.SellingBonds; sellingBonds != nil {
+		bonds := *sellingBonds
+		bonds.BondsToSell -= bondsSold
+		block.Header.GOVConstitution.GOVParams.SellingBonds = &bonds
 	}
 	for _, tx := range txsToAdd {
 		if err := block.AddTransaction(tx); err != nil {
@@ -314,7 +348,7 @@ func GetOracleGOVNationalWelfare() int32 {
 //1. Current National welfare (NW)  < lastNW * 0.9 (Emergency case)
 //2. Block height == last constitution start time + last constitution window
 func (blockgen *BlkTmplGenerator) neededNewDCBConstitution(chainID byte) bool {
-	BestBlock := blockgen.chain.BestState[chainID].BestBlock
+	BestBlock := blockgen.chain.BestSnapshot(chainID).BestBlock
 	lastDCBConstitution := BestBlock.Header.DCBConstitution
 	if GetOracleDCBNationalWelfare() < lastDCBConstitution.CurrentDCBNationalWelfare*ThresholdRatioOfDCBCrisis/100 ||
 		BestBlock.Header.Height+1 == lastDCBConstitution.StartedBlockHeight+lastDCBConstitution.ExecuteDuration {
@@ -323,7 +357,7 @@ func (blockgen *BlkTmplGenerator) neededNewDCBConstitution(chainID byte) bool {
 	return false
 }
 func (blockgen *BlkTmplGenerator) neededNewGovConstitution(chainID byte) bool {
-	BestBlock := blockgen.chain.BestState[chainID].BestBlock
+	BestBlock := blockgen.chain.BestSnapshot(chainID).BestBlock
 	lastGovConstitution := BestBlock.Header.GOVConstitution
 	if GetOracleGOVNationalWelfare() < lastGovConstitution.CurrentGOVNationalWelfare*ThresholdRatioOfGovCrisis/100 ||
 		BestBlock.Header.Height+1 == lastGovConstitution.StartedBlockHeight+lastGovConstitution.ExecuteDuration {
@@ -336,7 +370,7 @@ func (blockgen *BlkTmplGenerator) createRequestConstitutionTxDecs(
 	chainID byte,
 	ConstitutionHelper ConstitutionHelper,
 ) (*transaction.TxDesc, error) {
-	BestBlock := blockgen.chain.BestState[chainID].BestBlock
+	BestBlock := blockgen.chain.BestSnapshot(chainID).BestBlock
 
 	// count vote from lastConstitution.StartedBlockHeight to Bestblock height
 	CountVote := make(map[common.Hash]int64)
@@ -477,7 +511,7 @@ func (blockgen *BlkTmplGenerator) checkBuyFromGOVReqTx(
 	tx transaction.Transaction,
 	bondsSold uint64,
 ) (uint64, uint64, bool) {
-	prevBlock := blockgen.chain.BestState[chainID].BestBlock
+	prevBlock := blockgen.chain.BestSnapshot(chainID).BestBlock
 	sellingBondsParams := prevBlock.Header.GOVConstitution.GOVParams.SellingBonds
 	if uint32(prevBlock.Header.Height)+1 > sellingBondsParams.StartSellingAt+sellingBondsParams.SellingWithin {
 		return 0, 0, false
@@ -550,7 +584,7 @@ func (blockgen *BlkTmplGenerator) checkBuyBackReqTx(
 		Logger.log.Error("Missing buy-back info")
 		return nil, nil, false
 	}
-	prevBlock := blockgen.chain.BestState[chainID].BestBlock
+	prevBlock := blockgen.chain.BestSnapshot(chainID).BestBlock
 
 	if buyBackInfo.StartSellingAt+buyBackInfo.Maturity > uint32(prevBlock.Header.Height)+1 {
 		Logger.log.Error("The token is not overdued yet.")
@@ -573,7 +607,7 @@ func (blockgen *BlkTmplGenerator) buildBuyBackResponsesTx(
 		return []*transaction.Tx{}, nil
 	}
 
-	prevBlock := blockgen.chain.BestState[chainID].BestBlock
+	prevBlock := blockgen.chain.BestSnapshot(chainID).BestBlock
 	rt := prevBlock.Header.MerkleRootCommitments.CloneBytes()
 	var buyBackResTxs []*transaction.Tx
 	for buyBackReqTxID, txTokenReqVout := range txTokenReqVouts {
@@ -624,7 +658,7 @@ func (blockgen *BlkTmplGenerator) buildRefundTxs(
 		Logger.log.Info("GOV fund is not enough for refund.")
 		return []*transaction.Tx{}, 0
 	}
-	prevBlock := blockgen.chain.BestState[chainID].BestBlock
+	prevBlock := blockgen.chain.BestSnapshot(chainID).BestBlock
 	header := prevBlock.Header
 	govParams := header.GOVConstitution.GOVParams
 	refundInfo := govParams.RefundInfo
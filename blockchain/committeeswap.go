@@ -0,0 +1,29 @@
+package blockchain
+
+import (
+	"strconv"
+)
+
+/*
+CommitteeSwap puts Candidate in committee seat ChainID instead of Outgoing.
+Anchor is a block of any chain, its ChainsHeight fixes which blocks of every
+chain are looked at, so every node having those blocks computes the same swap.
+*/
+type CommitteeSwap struct {
+	ChainID       byte
+	Outgoing      string
+	Candidate     string
+	AnchorChainID byte
+	AnchorHeight  int32
+	AnchorHash    string
+}
+
+// String - data of swap which is hashed with its block
+func (self CommitteeSwap) String() string {
+	return strconv.Itoa(int(self.ChainID)) +
+		self.Outgoing +
+		self.Candidate +
+		strconv.Itoa(int(self.AnchorChainID)) +
+		strconv.Itoa(int(self.AnchorHeight)) +
+		self.AnchorHash
+}
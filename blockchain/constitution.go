@@ -1,6 +1,10 @@
 package blockchain
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/ninjadotorg/constant/common"
 	"github.com/ninjadotorg/constant/transaction"
 )
@@ -23,6 +27,104 @@ type DCBConstitution struct {
 	DCBParams                 DCBParams
 }
 
+/*
+FeePerKb - min fee per kb of a tx type, false when txs of type are not sent by
+users (e.g. salary or response txs of producers)
+*/
+func (self FeeSchedule) FeePerKb(txType string) (uint64, bool) {
+	switch txType {
+	case common.TxNormalType:
+		return self.Normal, true
+	case common.TxCustomTokenType, common.TxCrowdsale:
+		return self.CustomToken, true
+	case common.TxLoanRequest, common.TxLoanResponse, common.TxLoanPayment, common.TxLoanWithdraw:
+		return self.Loan, true
+	case common.TxBuyBackRequest, common.TxBuyFromGOVRequest, common.TxBuySellDCBRequest:
+		return self.BuyBack, true
+	case common.TxSubmitDCBProposal, common.TxSubmitGOVProposal, common.TxVoteDCBProposal, common.TxVoteGOVProposal, common.TxVoteDCBBoard, common.TxVoteGOVBoard:
+		return self.Voting, true
+	}
+	return 0, false
+}
+
+/*
+MinFee - min fee of a tx of a type and size (kb) by fee schedule of GOV
+params. Params voted before fee schedules existed have none, basic salary is
+min fee of every tx then
+*/
+func (self GOVParams) MinFee(txType string, size uint64) (uint64, bool) {
+	if self.FeeSchedule == nil {
+		_, ok := FeeSchedule{}.FeePerKb(txType)
+		return self.BasicSalary, ok
+	}
+	feePerKb, ok := self.FeeSchedule.FeePerKb(txType)
+	return feePerKb * size, ok
+}
+
+// String - fee per kb of every tx type, it is a part of block hash
+func (self FeeSchedule) String() string {
+	return strings.Join([]string{
+		strconv.FormatUint(self.Normal, 10),
+		strconv.FormatUint(self.CustomToken, 10),
+		strconv.FormatUint(self.Loan, 10),
+		strconv.FormatUint(self.BuyBack, 10),
+		strconv.FormatUint(self.Voting, 10),
+	}, ",")
+}
+
+// proposalFeeSchedule - fee schedule voted by a GOV proposal, nil when the
+// proposal keeps the current one
+func proposalFeeSchedule(proposal *transaction.TxSubmitGOVProposal) *FeeSchedule {
+	voted := proposal.GOVProposalData.GOVParams.FeeSchedule
+	if voted == nil {
+		return nil
+	}
+	return &FeeSchedule{voted.Normal, voted.CustomToken, voted.Loan, voted.BuyBack, voted.Voting}
+}
+
+// acceptedGOVProposalTxID - id of the GOV proposal accepted by tx, false when
+// tx accepts none
+func acceptedGOVProposalTxID(tx transaction.Transaction) (*common.Hash, bool) {
+	switch tx := tx.(type) {
+	case *transaction.TxAcceptGOVProposal:
+		return tx.GOVProposalTXID, true
+	case transaction.TxAcceptGOVProposal:
+		return tx.GOVProposalTXID, true
+	}
+	return nil, false
+}
+
+/*
+ValidateFeeSchedule - fee schedule of block is the one of prevBlock, unless
+block accepts a GOV proposal which votes a new one. A producer can't change
+min fees of mempools on its own
+*/
+func (self *BlockChain) ValidateFeeSchedule(block *Block, prevBlock *Block) error {
+	expected := prevBlock.Header.GOVConstitution.GOVParams.FeeSchedule
+	for _, tx := range block.Transactions {
+		proposalTxID, ok := acceptedGOVProposalTxID(tx)
+		if !ok {
+			continue
+		}
+		_, _, _, proposalTx, err := self.GetTransactionByHash(proposalTxID)
+		if err != nil {
+			return NewBlockChainError(InvalidFeeScheduleError, err)
+		}
+		proposal, ok := proposalTx.(*transaction.TxSubmitGOVProposal)
+		if !ok {
+			return NewBlockChainError(InvalidFeeScheduleError, fmt.Errorf("%+v is not a GOV proposal", proposalTxID.String()))
+		}
+		if feeSchedule := proposalFeeSchedule(proposal); feeSchedule != nil {
+			expected = feeSchedule
+		}
+	}
+	feeSchedule := block.Header.GOVConstitution.GOVParams.FeeSchedule
+	if (feeSchedule == nil) != (expected == nil) || (feeSchedule != nil && *feeSchedule != *expected) {
+		return NewBlockChainError(InvalidFeeScheduleError, fmt.Errorf("block has fee schedule %+v instead of %+v", feeSchedule, expected))
+	}
+	return nil
+}
+
 type DCBConstitutionHelper struct{}
 type GOVConstitutionHelper struct{}
 
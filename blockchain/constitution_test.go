@@ -0,0 +1,82 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/transaction"
+	"github.com/ninjadotorg/constant/voting"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGOVParams_MinFee(t *testing.T) {
+	// params without fee schedule ask basic salary of every tx users send
+	params := GOVParams{BasicSalary: 7}
+	minFee, ok := params.MinFee(common.TxLoanRequest, 3)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(7), minFee)
+	_, ok = params.MinFee(common.TxSalaryType, 3)
+	assert.False(t, ok)
+
+	params.FeeSchedule = &FeeSchedule{Normal: 1, CustomToken: 2, Loan: 3, BuyBack: 4, Voting: 5}
+	for txType, feePerKb := range map[string]uint64{
+		common.TxNormalType:        1,
+		common.TxCrowdsale:         2,
+		common.TxLoanWithdraw:      3,
+		common.TxBuySellDCBRequest: 4,
+		common.TxVoteGOVBoard:      5,
+	} {
+		minFee, ok := params.MinFee(txType, 3)
+		assert.True(t, ok)
+		assert.Equal(t, 3*feePerKb, minFee, txType)
+	}
+	// txs of producers are never in mempool
+	_, ok = params.MinFee(common.TxSalaryType, 3)
+	assert.False(t, ok)
+}
+
+func TestBlockHash_FeeSchedule(t *testing.T) {
+	block := &Block{}
+	hash := *block.Hash()
+
+	// a fee schedule is a part of block hash, so it is signed by committee
+	block = &Block{}
+	block.Header.GOVConstitution.GOVParams.FeeSchedule = &FeeSchedule{Normal: 1}
+	withFeeSchedule := *block.Hash()
+	assert.NotEqual(t, hash, withFeeSchedule)
+	block = &Block{}
+	block.Header.GOVConstitution.GOVParams.FeeSchedule = &FeeSchedule{Normal: 2}
+	assert.NotEqual(t, withFeeSchedule, *block.Hash())
+	block = &Block{}
+	block.Header.GOVConstitution.GOVParams.FeeSchedule = &FeeSchedule{Voting: 1}
+	assert.NotEqual(t, withFeeSchedule, *block.Hash())
+}
+
+func TestValidateFeeSchedule(t *testing.T) {
+	chain := &BlockChain{}
+	prevBlock := &Block{}
+	block := &Block{}
+	assert.Nil(t, chain.ValidateFeeSchedule(block, prevBlock))
+
+	// a producer can't change fee schedule without an accepted proposal
+	prevBlock.Header.GOVConstitution.GOVParams.FeeSchedule = &FeeSchedule{Normal: 10}
+	err := chain.ValidateFeeSchedule(block, prevBlock)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrCodeMessage[InvalidFeeScheduleError].code, err.(*BlockChainError).Code)
+	block.Header.GOVConstitution.GOVParams.FeeSchedule = &FeeSchedule{Normal: 1}
+	assert.NotNil(t, chain.ValidateFeeSchedule(block, prevBlock))
+	block.Header.GOVConstitution.GOVParams.FeeSchedule = &FeeSchedule{Normal: 10}
+	assert.Nil(t, chain.ValidateFeeSchedule(block, prevBlock))
+
+	// an accepted proposal gives the fee schedule it votes, or keeps it
+	proposal := &transaction.TxSubmitGOVProposal{}
+	assert.Nil(t, proposalFeeSchedule(proposal))
+	proposal.GOVProposalData.GOVParams.FeeSchedule = &voting.FeeSchedule{Normal: 1, CustomToken: 2, Loan: 3, BuyBack: 4, Voting: 5}
+	assert.Equal(t, &FeeSchedule{Normal: 1, CustomToken: 2, Loan: 3, BuyBack: 4, Voting: 5}, proposalFeeSchedule(proposal))
+	proposalTxID := common.HashH([]byte("proposal"))
+	txID, ok := acceptedGOVProposalTxID(&transaction.TxAcceptGOVProposal{GOVProposalTXID: &proposalTxID})
+	assert.True(t, ok)
+	assert.Equal(t, &proposalTxID, txID)
+	_, ok = acceptedGOVProposalTxID(&transaction.Tx{})
+	assert.False(t, ok)
+}
@@ -11,6 +11,7 @@ const (
 	UpdateMerkleTreeForBlockError
 	UnmashallJsonBlockError
 	CanNotCheckDoubleSpendError
+	InvalidFeeScheduleError
 )
 
 var ErrCodeMessage = map[int]struct {
@@ -21,6 +22,7 @@ var ErrCodeMessage = map[int]struct {
 	UpdateMerkleTreeForBlockError: {-2, "Update Merkle Commitments Tree For Block is failed"},
 	UnmashallJsonBlockError:       {-3, "Unmarshall json block is failed"},
 	CanNotCheckDoubleSpendError:   {-4, "Unmarshall json block is failed"},
+	InvalidFeeScheduleError:       {-5, "Fee schedule is not the one of GOV params"},
 }
 
 type BlockChainError struct {
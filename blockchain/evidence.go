@@ -0,0 +1,132 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/common/base58"
+	"github.com/ninjadotorg/constant/privacy-protocol"
+)
+
+/*
+SignedBlockHeader is a block without its body, only hashes of txs and
+evidences are kept so the committee signature of the block can still be checked
+*/
+type SignedBlockHeader struct {
+	Header         BlockHeader
+	BlockProducer  string
+	TxHashes       []common.Hash
+	EvidenceHashes []common.Hash
+}
+
+func NewSignedBlockHeader(block *Block) SignedBlockHeader {
+	result := SignedBlockHeader{
+		Header:         block.Header,
+		BlockProducer:  block.BlockProducer,
+		TxHashes:       make([]common.Hash, 0, len(block.Transactions)),
+		EvidenceHashes: make([]common.Hash, 0, len(block.Evidences)),
+	}
+	for _, tx := range block.Transactions {
+		result.TxHashes = append(result.TxHashes, *tx.Hash())
+	}
+	for _, evidence := range block.Evidences {
+		result.EvidenceHashes = append(result.EvidenceHashes, *evidence.Hash())
+	}
+	return result
+}
+
+/*
+SigningHash - same as SigningHash of the block
+*/
+func (self SignedBlockHeader) SigningHash() common.Hash {
+	return hashBlockData(&self.Header, self.BlockProducer, common.EmptyString, self.TxHashes, self.EvidenceHashes)
+}
+
+/*
+Signers - check committee multi-signature of block and return public keys of
+its signers
+*/
+func (self SignedBlockHeader) Signers() ([]string, error) {
+	signers := make([]string, 0, len(self.Header.Committee))
+	pubKeys := make([][]byte, 0, len(self.Header.Committee))
+	for idx := 0; idx < len(self.Header.SignersBitmap)*8; idx++ {
+		if self.Header.SignersBitmap[idx/8]&(1<<uint(idx%8)) == 0 {
+			continue
+		}
+		if idx >= len(self.Header.Committee) {
+			return nil, errors.New("signers bitmap is out of committee")
+		}
+		pubKey, _, err := base58.Base58Check{}.Decode(self.Header.Committee[idx])
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, self.Header.Committee[idx])
+		pubKeys = append(pubKeys, pubKey)
+	}
+	sig, _, err := base58.Base58Check{}.Decode(self.Header.AggregatedSig)
+	if err != nil {
+		return nil, err
+	}
+	hash := self.SigningHash()
+	if len(pubKeys) == 0 || !privacy.MultiSigVerify(sig, hash[:], pubKeys) {
+		return nil, errors.New("invalid committee signature")
+	}
+	return signers, nil
+}
+
+/*
+DoubleSignEvidence is proof that two different blocks of one chain are signed
+at the same height, committee members who signed both are the offenders
+*/
+type DoubleSignEvidence struct {
+	BlockA SignedBlockHeader
+	BlockB SignedBlockHeader
+}
+
+/*
+Hash - hash of evidence, it does not depend on order of the two blocks
+*/
+func (self DoubleSignEvidence) Hash() *common.Hash {
+	hashA := self.BlockA.SigningHash()
+	hashB := self.BlockB.SigningHash()
+	if bytes.Compare(hashA[:], hashB[:]) > 0 {
+		hashA, hashB = hashB, hashA
+	}
+	hash := common.DoubleHashH(append(hashA[:], hashB[:]...))
+	return &hash
+}
+
+/*
+Verify - check that evidence is a real double signing and return public keys of
+offenders
+*/
+func (self DoubleSignEvidence) Verify() ([]string, error) {
+	headerA := self.BlockA.Header
+	headerB := self.BlockB.Header
+	if headerA.ChainID != headerB.ChainID || headerA.Height != headerB.Height {
+		return nil, errors.New("blocks are not at the same height of a chain")
+	}
+	if self.BlockA.SigningHash() == self.BlockB.SigningHash() {
+		return nil, errors.New("blocks are the same")
+	}
+	signersA, err := self.BlockA.Signers()
+	if err != nil {
+		return nil, err
+	}
+	signersB, err := self.BlockB.Signers()
+	if err != nil {
+		return nil, err
+	}
+
+	offenders := make([]string, 0)
+	for _, signer := range signersA {
+		if common.IndexOfStr(signer, signersB) != -1 && common.IndexOfStr(signer, offenders) == -1 {
+			offenders = append(offenders, signer)
+		}
+	}
+	if len(offenders) == 0 {
+		return nil, errors.New("no one signed both blocks")
+	}
+	return offenders, nil
+}
@@ -0,0 +1,164 @@
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/common/base58"
+	"github.com/ninjadotorg/constant/privacy-protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCommittee struct {
+	spendingKeys [][]byte
+	pubKeys      [][]byte
+	members      []string
+}
+
+func newTestCommittee(n int) *testCommittee {
+	committee := &testCommittee{}
+	for i := 0; i < n; i++ {
+		spendingKey := privacy.GenerateSpendingKey(new(big.Int).SetInt64(int64(i + 1)).Bytes())
+		pubKey := privacy.GeneratePublicKey(spendingKey)
+		committee.spendingKeys = append(committee.spendingKeys, spendingKey)
+		committee.pubKeys = append(committee.pubKeys, pubKey)
+		committee.members = append(committee.members, base58.Base58Check{}.Encode(pubKey, byte(0x00)))
+	}
+	return committee
+}
+
+// sign multi-signs header by committee members at signers
+func (self *testCommittee) sign(t *testing.T, header *SignedBlockHeader, signers []int) {
+	header.Header.Committee = self.members
+	header.Header.SignersBitmap = make([]byte, (len(self.members)+7)/8)
+	pubKeys := make([][]byte, 0, len(signers))
+	nonces := make([]*privacy.MultiSigNonce, 0, len(signers))
+	publicNonces := make([][]byte, 0, len(signers))
+	for _, idx := range signers {
+		header.Header.SignersBitmap[idx/8] |= 1 << uint(idx%8)
+	}
+	for idx := range self.members {
+		if header.Header.SignersBitmap[idx/8]&(1<<uint(idx%8)) == 0 {
+			continue
+		}
+		nonce := privacy.NewMultiSigNonce()
+		pubKeys = append(pubKeys, self.pubKeys[idx])
+		nonces = append(nonces, nonce)
+		publicNonces = append(publicNonces, nonce.PublicNonce())
+	}
+	aggNonce, err := privacy.AggregatePublicNonces(publicNonces)
+	assert.Nil(t, err)
+
+	hash := header.SigningHash()
+	partials := make([][]byte, 0, len(signers))
+	i := 0
+	for idx := range self.members {
+		if header.Header.SignersBitmap[idx/8]&(1<<uint(idx%8)) == 0 {
+			continue
+		}
+		partial, err := privacy.MultiSigPartialSign(hash[:], self.spendingKeys[idx], nonces[i], pubKeys, aggNonce)
+		assert.Nil(t, err)
+		partials = append(partials, partial)
+		i++
+	}
+	sig, err := privacy.MultiSigCombine(hash[:], partials, pubKeys, aggNonce)
+	assert.Nil(t, err)
+	header.Header.AggregatedSig = base58.Base58Check{}.Encode(sig, byte(0x00))
+}
+
+func newTestSignedHeader(chainID byte, height int32, timestamp int64) SignedBlockHeader {
+	return SignedBlockHeader{
+		Header: BlockHeader{
+			ChainID:   chainID,
+			Height:    height,
+			Timestamp: timestamp,
+		},
+		BlockProducer: "producer",
+	}
+}
+
+func TestSignedBlockHeader_Signers(t *testing.T) {
+	committee := newTestCommittee(4)
+	header := newTestSignedHeader(1, 10, 1000)
+	committee.sign(t, &header, []int{0, 2, 3})
+
+	signers, err := header.Signers()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{committee.members[0], committee.members[2], committee.members[3]}, signers)
+
+	// signature doesn't match the bitmap
+	wrongSigner := header
+	wrongSigner.Header.SignersBitmap = []byte{0x07}
+	_, err = wrongSigner.Signers()
+	assert.NotNil(t, err)
+
+	// bitmap is out of committee
+	outOfCommittee := header
+	outOfCommittee.Header.SignersBitmap = []byte{0x11}
+	_, err = outOfCommittee.Signers()
+	assert.NotNil(t, err)
+
+	// header is changed after signing
+	changed := header
+	changed.TxHashes = []common.Hash{common.HashH([]byte("tx"))}
+	_, err = changed.Signers()
+	assert.NotNil(t, err)
+}
+
+func TestDoubleSignEvidence_Verify(t *testing.T) {
+	committee := newTestCommittee(5)
+	blockA := newTestSignedHeader(1, 10, 1000)
+	blockB := newTestSignedHeader(1, 10, 2000)
+	committee.sign(t, &blockA, []int{0, 1, 2})
+	committee.sign(t, &blockB, []int{1, 2, 3})
+
+	evidence := DoubleSignEvidence{BlockA: blockA, BlockB: blockB}
+	offenders, err := evidence.Verify()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{committee.members[1], committee.members[2]}, offenders)
+
+	// same block twice is not a double signing
+	_, err = DoubleSignEvidence{BlockA: blockA, BlockB: blockA}.Verify()
+	assert.NotNil(t, err)
+
+	// blocks of different heights or chains
+	otherHeight := newTestSignedHeader(1, 11, 2000)
+	committee.sign(t, &otherHeight, []int{1, 2, 3})
+	_, err = DoubleSignEvidence{BlockA: blockA, BlockB: otherHeight}.Verify()
+	assert.NotNil(t, err)
+	otherChain := newTestSignedHeader(2, 10, 2000)
+	committee.sign(t, &otherChain, []int{1, 2, 3})
+	_, err = DoubleSignEvidence{BlockA: blockA, BlockB: otherChain}.Verify()
+	assert.NotNil(t, err)
+
+	// a block claims a signer who didn't sign it
+	forged := blockB
+	forged.Header.SignersBitmap = []byte{0x0f}
+	_, err = DoubleSignEvidence{BlockA: blockA, BlockB: forged}.Verify()
+	assert.NotNil(t, err)
+
+	// no one signed both blocks
+	disjoint := newTestSignedHeader(1, 10, 3000)
+	committee.sign(t, &disjoint, []int{3, 4})
+	_, err = DoubleSignEvidence{BlockA: blockA, BlockB: disjoint}.Verify()
+	assert.NotNil(t, err)
+}
+
+func TestDoubleSignEvidence_Hash(t *testing.T) {
+	committee := newTestCommittee(3)
+	blockA := newTestSignedHeader(1, 10, 1000)
+	blockB := newTestSignedHeader(1, 10, 2000)
+	blockC := newTestSignedHeader(1, 10, 3000)
+	committee.sign(t, &blockA, []int{0, 1})
+	committee.sign(t, &blockB, []int{0, 1})
+	committee.sign(t, &blockC, []int{0, 1})
+
+	// order of blocks doesn't change the evidence
+	hashAB := DoubleSignEvidence{BlockA: blockA, BlockB: blockB}.Hash()
+	hashBA := DoubleSignEvidence{BlockA: blockB, BlockB: blockA}.Hash()
+	assert.Equal(t, *hashAB, *hashBA)
+
+	hashAC := DoubleSignEvidence{BlockA: blockA, BlockB: blockC}.Hash()
+	assert.NotEqual(t, *hashAB, *hashAC)
+}
@@ -0,0 +1,270 @@
+package blockchain
+
+import (
+	"container/heap"
+	"encoding/hex"
+	"strings"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/transaction"
+)
+
+// txPriorityItem is a tx waiting to be selected into a block template
+type txPriorityItem struct {
+	txDesc  *transaction.TxDesc
+	feeRate float64 // fee per KB
+	size    uint64  // KB
+
+	// number of parents of tx which are candidates and not selected yet
+	pendingParents int
+}
+
+// Room returns size and number of txs which item takes in a block, a request
+// takes room for its response tx built by the block template too
+func (item *txPriorityItem) Room() (uint64, int) {
+	switch item.txDesc.Tx.GetType() {
+	case common.TxBuyFromGOVRequest, common.TxBuyBackRequest:
+		return 2 * item.size, 2
+	}
+	return item.size, 1
+}
+
+// txPriorityQueue orders txs by fee per KB, the oldest first among equal ones
+type txPriorityQueue []*txPriorityItem
+
+func (pq txPriorityQueue) Len() int { return len(pq) }
+
+func (pq txPriorityQueue) Less(i, j int) bool {
+	if pq[i].feeRate == pq[j].feeRate {
+		return pq[i].txDesc.Added.Before(pq[j].txDesc.Added)
+	}
+	return pq[i].feeRate > pq[j].feeRate
+}
+
+func (pq txPriorityQueue) Swap(i, j int) { pq[i], pq[j] = pq[j], pq[i] }
+
+func (pq *txPriorityQueue) Push(x interface{}) {
+	*pq = append(*pq, x.(*txPriorityItem))
+}
+
+func (pq *txPriorityQueue) Pop() interface{} {
+	old := *pq
+	n := len(old)
+	item := old[n-1]
+	*pq = old[:n-1]
+	return item
+}
+
+/*
+txSelector returns candidate txs of a block template by fee per KB. A tx
+responding to another candidate (a loan response to a loan request, a response
+to a buy-sell request...) is returned only after its request is selected, so
+requests always come first in the block.
+*/
+type txSelector struct {
+	queue txPriorityQueue
+
+	// candidates waiting for a parent, by key of the parent
+	children map[string][]*txPriorityItem
+}
+
+func newTxSelector(txDescs []*transaction.TxDesc) *txSelector {
+	self := &txSelector{
+		queue:    txPriorityQueue{},
+		children: make(map[string][]*txPriorityItem),
+	}
+	keys := make(map[string]bool)
+	for _, txDesc := range txDescs {
+		for _, key := range TxDependencyKeys(txDesc.Tx) {
+			keys[key] = true
+		}
+	}
+	for _, txDesc := range txDescs {
+		size := txDesc.Tx.GetTxVirtualSize()
+		item := &txPriorityItem{
+			txDesc:  txDesc,
+			feeRate: float64(txDesc.Fee) / float64(common.Max(1, int(size))),
+			size:    size,
+		}
+		for _, key := range TxParentKeys(txDesc.Tx) {
+			if keys[key] {
+				item.pendingParents++
+				self.children[key] = append(self.children[key], item)
+			}
+		}
+		if item.pendingParents == 0 {
+			self.queue = append(self.queue, item)
+		}
+	}
+	heap.Init(&self.queue)
+	return self
+}
+
+// Next returns the candidate with the highest fee per KB, nil when no one is
+// left
+func (self *txSelector) Next() *txPriorityItem {
+	if self.queue.Len() == 0 {
+		return nil
+	}
+	return heap.Pop(&self.queue).(*txPriorityItem)
+}
+
+// Selected releases candidates waiting for tx, a candidate which is skipped
+// keeps its children out of the block
+func (self *txSelector) Selected(tx transaction.Transaction) {
+	for _, key := range TxDependencyKeys(tx) {
+		for _, child := range self.children[key] {
+			child.pendingParents--
+			if child.pendingParents == 0 {
+				heap.Push(&self.queue, child)
+			}
+		}
+		delete(self.children, key)
+	}
+}
+
+/*
+txRoom is room of a block template for txs, the rest of a block is kept for
+governance, salary, response and refund txs
+*/
+type txRoom struct {
+	maxSize uint64 // KB
+	maxTxs  int
+	size    uint64
+	txs     int
+}
+
+func newTxRoom() *txRoom {
+	return &txRoom{
+		maxSize: uint64(common.MaxBlockSize-common.ReservedBlockSize) / 1024,
+		maxTxs:  common.MaxTxsInBlock - common.ReservedTxsInBlock,
+	}
+}
+
+// Fits returns true when txs of size KB and count fit into room
+func (self *txRoom) Fits(size uint64, count int) bool {
+	return self.size+size <= self.maxSize && self.txs+count <= self.maxTxs
+}
+
+// Take takes room of txs of size KB and count
+func (self *txRoom) Take(size uint64, count int) {
+	self.size += size
+	self.txs += count
+}
+
+// Full returns true when no tx fits into room any more
+func (self *txRoom) Full() bool {
+	return self.txs >= self.maxTxs
+}
+
+// prefixes of keys by which txs depend on each other
+const (
+	txDependencyPrefix           = "tx:"
+	loanRequestDependencyPrefix  = "loan:"
+	loanResponseDependencyPrefix = "loanres:"
+)
+
+// LoanRequestDependency is the key of the loan request of loanID
+func LoanRequestDependency(loanID []byte) string {
+	return loanRequestDependencyPrefix + hex.EncodeToString(loanID)
+}
+
+// LoanResponseDependency is the key of the loan response of loanID
+func LoanResponseDependency(loanID []byte) string {
+	return loanResponseDependencyPrefix + hex.EncodeToString(loanID)
+}
+
+// TxDependencyKeys returns keys by which other txs refer to tx
+func TxDependencyKeys(tx transaction.Transaction) []string {
+	keys := []string{txDependencyPrefix + tx.Hash().String()}
+	switch tx := tx.(type) {
+	case *transaction.TxLoanRequest:
+		if tx.LoanRequest != nil {
+			keys = append(keys, LoanRequestDependency(tx.LoanID))
+		}
+	case *transaction.TxLoanResponse:
+		if tx.LoanResponse != nil {
+			keys = append(keys, LoanResponseDependency(tx.LoanID))
+		}
+	}
+	return keys
+}
+
+// TxParentKeys returns keys of txs which must come before tx, a loan response
+// comes after its request and a payment or withdraw after the response
+func TxParentKeys(tx transaction.Transaction) []string {
+	keys := []string{}
+	var requestedTxID *common.Hash
+	switch tx := tx.(type) {
+	case *transaction.Tx:
+		requestedTxID = tx.RequestedTxID
+	case *transaction.TxCustomToken:
+		requestedTxID = tx.RequestedTxID
+	case *transaction.TxLoanResponse:
+		requestedTxID = tx.RequestedTxID
+		if tx.LoanResponse != nil {
+			keys = append(keys, LoanRequestDependency(tx.LoanID))
+		}
+	case *transaction.TxLoanPayment:
+		requestedTxID = tx.RequestedTxID
+		if tx.LoanPayment != nil {
+			keys = append(keys, LoanResponseDependency(tx.LoanID))
+		}
+	case *transaction.TxLoanWithdraw:
+		requestedTxID = tx.RequestedTxID
+		if tx.LoanWithdraw != nil {
+			keys = append(keys, LoanResponseDependency(tx.LoanID))
+		}
+	}
+	if requestedTxID != nil {
+		keys = append(keys, txDependencyPrefix+requestedTxID.String())
+	}
+	return keys
+}
+
+/*
+GetTxDependency - the tx in blockchain which other txs refer to by key (see
+TxDependencyKeys), nil when no tx in blockchain has the key
+*/
+func (self *BlockChain) GetTxDependency(key string) transaction.Transaction {
+	if strings.HasPrefix(key, txDependencyPrefix) {
+		hash, err := common.Hash{}.NewHashFromStr(strings.TrimPrefix(key, txDependencyPrefix))
+		if err != nil {
+			return nil
+		}
+		_, _, _, tx, err := self.GetTransactionByHash(hash)
+		if err != nil {
+			return nil
+		}
+		return tx
+	}
+
+	var loanID string
+	var txType string
+	if strings.HasPrefix(key, loanRequestDependencyPrefix) {
+		loanID = strings.TrimPrefix(key, loanRequestDependencyPrefix)
+		txType = common.TxLoanRequest
+	} else if strings.HasPrefix(key, loanResponseDependencyPrefix) {
+		loanID = strings.TrimPrefix(key, loanResponseDependencyPrefix)
+		txType = common.TxLoanResponse
+	} else {
+		return nil
+	}
+	loanIDBytes, err := hex.DecodeString(loanID)
+	if err != nil {
+		return nil
+	}
+	txHashes, err := self.config.DataBase.GetLoanTxs(loanIDBytes)
+	if err != nil {
+		return nil
+	}
+	for _, txHash := range txHashes {
+		hash := &common.Hash{}
+		copy(hash[:], txHash)
+		_, _, _, tx, err := self.GetTransactionByHash(hash)
+		if err == nil && tx != nil && tx.GetType() == txType {
+			return tx
+		}
+	}
+	return nil
+}
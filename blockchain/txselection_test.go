@@ -0,0 +1,143 @@
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/privacy-protocol"
+	"github.com/ninjadotorg/constant/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestTxDesc returns a desc of tx paying feeRate per KB, added age ago
+func newTestTxDesc(tx transaction.Transaction, feeRate uint64, age time.Duration) *transaction.TxDesc {
+	return &transaction.TxDesc{
+		Tx:    tx,
+		Added: time.Now().Add(-age),
+		Fee:   feeRate * tx.GetTxVirtualSize(),
+	}
+}
+
+func newTestTx(txType string, lockTime int64) *transaction.Tx {
+	return &transaction.Tx{Type: txType, LockTime: lockTime}
+}
+
+func newTestLoanRequest(loanID []byte) *transaction.TxLoanRequest {
+	return &transaction.TxLoanRequest{
+		Tx: *newTestTx(common.TxLoanRequest, 1),
+		LoanRequest: &transaction.LoanRequest{
+			LoanID:           loanID,
+			CollateralAmount: big.NewInt(1),
+			ReceiveAddress:   &privacy.PaymentAddress{Pk: make([]byte, 33), Tk: make([]byte, 33)},
+		},
+	}
+}
+
+// selectAll returns txs of selector in order, every tx is selected
+func selectAll(selector *txSelector) []transaction.Transaction {
+	txs := []transaction.Transaction{}
+	for next := selector.Next(); next != nil; next = selector.Next() {
+		txs = append(txs, next.txDesc.Tx)
+		selector.Selected(next.txDesc.Tx)
+	}
+	return txs
+}
+
+func TestTxSelector_Order(t *testing.T) {
+	low := newTestTx(common.TxNormalType, 1)
+	high := newTestTx(common.TxNormalType, 2)
+	older := newTestTx(common.TxNormalType, 3)
+	newer := newTestTx(common.TxNormalType, 4)
+	selector := newTxSelector([]*transaction.TxDesc{
+		newTestTxDesc(low, 1, time.Hour),
+		newTestTxDesc(newer, 5, time.Minute),
+		newTestTxDesc(high, 10, 0),
+		newTestTxDesc(older, 5, time.Hour),
+	})
+	// by fee per KB, the oldest first among equal ones
+	assert.Equal(t, []transaction.Transaction{high, older, newer, low}, selectAll(selector))
+	assert.Nil(t, selector.Next())
+}
+
+func TestTxSelector_ParentBeforeChild(t *testing.T) {
+	loanID := []byte{1}
+	request := newTestLoanRequest(loanID)
+	response := &transaction.TxLoanResponse{
+		Tx:           *newTestTx(common.TxLoanResponse, 2),
+		LoanResponse: &transaction.LoanResponse{LoanID: loanID, Response: transaction.Accept},
+	}
+	payment := &transaction.TxLoanPayment{
+		Tx:          *newTestTx(common.TxLoanPayment, 3),
+		LoanPayment: &transaction.LoanPayment{LoanID: loanID},
+	}
+	other := newTestTx(common.TxNormalType, 4)
+	txDescs := []*transaction.TxDesc{
+		newTestTxDesc(payment, 30, 0),
+		newTestTxDesc(response, 20, 0),
+		newTestTxDesc(request, 1, 0),
+		newTestTxDesc(other, 10, 0),
+	}
+
+	// children wait for their parents whatever fee they pay
+	selector := newTxSelector(txDescs)
+	assert.Equal(t, []transaction.Transaction{other, request, response, payment}, selectAll(selector))
+
+	// a skipped parent keeps its children out
+	selector = newTxSelector(txDescs)
+	assert.Equal(t, other, selector.Next().txDesc.Tx)
+	assert.Equal(t, request, selector.Next().txDesc.Tx)
+	assert.Nil(t, selector.Next())
+
+	// a parent which isn't a candidate doesn't hold a child back
+	selector = newTxSelector(txDescs[:2])
+	assert.Equal(t, []transaction.Transaction{response, payment}, selectAll(selector))
+}
+
+func TestTxDependencyKeys(t *testing.T) {
+	loanID := []byte{1}
+	request := newTestLoanRequest(loanID)
+	response := &transaction.TxLoanResponse{
+		Tx:           *newTestTx(common.TxLoanResponse, 2),
+		LoanResponse: &transaction.LoanResponse{LoanID: loanID},
+	}
+	assert.Equal(t, []string{txDependencyPrefix + request.Hash().String(), LoanRequestDependency(loanID)}, TxDependencyKeys(request))
+	assert.Equal(t, []string{txDependencyPrefix + response.Hash().String(), LoanResponseDependency(loanID)}, TxDependencyKeys(response))
+	assert.Equal(t, []string{}, TxParentKeys(request))
+	assert.Equal(t, []string{LoanRequestDependency(loanID)}, TxParentKeys(response))
+
+	// a tx refers to the tx it responds to by hash
+	requestedTx := newTestTx(common.TxNormalType, 3)
+	responseTx := newTestTx(common.TxNormalType, 4)
+	responseTx.RequestedTxID = requestedTx.Hash()
+	assert.Equal(t, []string{TxDependencyKeys(requestedTx)[0]}, TxParentKeys(responseTx))
+}
+
+func TestTxRoom(t *testing.T) {
+	room := newTxRoom()
+	// room of reserved txs is left out
+	assert.Equal(t, uint64(common.MaxBlockSize-common.ReservedBlockSize)/1024, room.maxSize)
+	assert.Equal(t, common.MaxTxsInBlock-common.ReservedTxsInBlock, room.maxTxs)
+
+	// a request takes room for its response
+	normal := &txPriorityItem{txDesc: newTestTxDesc(newTestTx(common.TxNormalType, 1), 1, 0), size: 3}
+	size, count := normal.Room()
+	assert.Equal(t, uint64(3), size)
+	assert.Equal(t, 1, count)
+	request := &txPriorityItem{txDesc: newTestTxDesc(newTestTx(common.TxBuyFromGOVRequest, 2), 1, 0), size: 3}
+	size, count = request.Room()
+	assert.Equal(t, uint64(6), size)
+	assert.Equal(t, 2, count)
+
+	room.Take(room.maxSize-5, room.maxTxs-2)
+	assert.False(t, room.Full())
+	assert.True(t, room.Fits(normal.Room()))
+	assert.False(t, room.Fits(request.Room()))
+	room.Take(normal.Room())
+	assert.False(t, room.Fits(normal.Room()))
+	assert.True(t, room.Fits(2, 1))
+	room.Take(2, 1)
+	assert.True(t, room.Full())
+	assert.False(t, room.Fits(0, 1))
+}
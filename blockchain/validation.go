@@ -52,23 +52,17 @@ func (self *BlockChain) ValidateDoubleSpend(tx transaction.Transaction, chainID
 		return err
 	}
 	nullifierDb := txViewPoint.ListNullifiers()
-	var descs []*transaction.JoinSplitDesc
-	if tx.GetType() == common.TxNormalType {
-		descs = tx.(*transaction.Tx).Descs
-	}
-	for _, desc := range descs {
-		for _, nullifer := range desc.Nullifiers {
-			existed, err := common.SliceBytesExists(nullifierDb, nullifer)
-			if err != nil {
-				str := fmt.Sprintf("Can not check double spend for tx")
-				err := NewBlockChainError(CanNotCheckDoubleSpendError, errors.New(str))
-				return err
-			}
-			if existed {
-				str := fmt.Sprintf("Nullifiers of transaction %+v already existed", txHash.String())
-				err := NewBlockChainError(CanNotCheckDoubleSpendError, errors.New(str))
-				return err
-			}
+	for _, nullifer := range tx.ListNullifiers() {
+		existed, err := common.SliceBytesExists(nullifierDb, nullifer)
+		if err != nil {
+			str := fmt.Sprintf("Can not check double spend for tx")
+			err := NewBlockChainError(CanNotCheckDoubleSpendError, errors.New(str))
+			return err
+		}
+		if existed {
+			str := fmt.Sprintf("Nullifiers of transaction %+v already existed", txHash.String())
+			err := NewBlockChainError(CanNotCheckDoubleSpendError, errors.New(str))
+			return err
 		}
 	}
 	return nil
@@ -196,7 +190,7 @@ func (self *BlockChain) ValidateTxLoanWithdraw(tx transaction.Transaction, chain
 		case common.TxLoanRequest:
 			{
 				// Check if key is correct
-				txRequest, ok := tx.(*transaction.TxLoanRequest)
+				txRequest, ok := txOld.(*transaction.TxLoanRequest)
 				if !ok {
 					return fmt.Errorf("Error parsing corresponding loan request")
 				}
@@ -209,11 +203,11 @@ func (self *BlockChain) ValidateTxLoanWithdraw(tx transaction.Transaction, chain
 		case common.TxLoanResponse:
 			{
 				// Check if loan is accepted
-				txResponse, ok := tx.(*transaction.TxLoanResponse)
+				txResponse, ok := txOld.(*transaction.TxLoanResponse)
 				if !ok {
 					return fmt.Errorf("Error parsing corresponding loan response")
 				}
-				if txResponse.Response != transaction.Accept {
+				if txResponse.Response == transaction.Accept {
 					foundResponse = true
 				}
 			}
@@ -435,6 +429,35 @@ func (bc *BlockChain) VerifyCustomTokenSigns(tx transaction.Transaction) bool {
 }
 
 func (self *BlockChain) ValidateTxBuyRequest(tx transaction.Transaction, chainID byte) error {
+	_, ok := tx.(*transaction.TxBuySellRequest)
+	if !ok {
+		return fmt.Errorf("Fail parsing BuySellRequest transaction")
+	}
+	return nil
+}
+
+func (self *BlockChain) ValidateTxBuyBackRequest(tx transaction.Transaction, chainID byte) error {
+	txBuyBack, ok := tx.(*transaction.TxBuyBackRequest)
+	if !ok {
+		return fmt.Errorf("Fail parsing BuyBackRequest transaction")
+	}
+
+	// Check if the bought token is in a vout with buy-back info
+	_, _, _, fromTx, err := self.GetTransactionByHash(txBuyBack.BuyBackFromTxID)
+	if fromTx == nil || err != nil {
+		return fmt.Errorf("Error finding tx %+v to buy back", txBuyBack.BuyBackFromTxID)
+	}
+	customTokenTx, ok := fromTx.(*transaction.TxCustomToken)
+	if !ok {
+		return fmt.Errorf("Tx to buy back is not a custom token tx")
+	}
+	if txBuyBack.VoutIndex < 0 || txBuyBack.VoutIndex >= len(customTokenTx.TxTokenData.Vouts) {
+		return fmt.Errorf("Vout index %d of tx to buy back is out of range", txBuyBack.VoutIndex)
+	}
+	vout := customTokenTx.TxTokenData.Vouts[txBuyBack.VoutIndex]
+	if vout.BuySellResponse == nil || vout.BuySellResponse.BuyBackInfo == nil {
+		return fmt.Errorf("Vout %d of tx to buy back has no buy-back info", txBuyBack.VoutIndex)
+	}
 	return nil
 }
 
@@ -487,7 +510,10 @@ func (self *BlockChain) ValidateDoubleSpendCustomToken(tx *transaction.TxCustomT
 }
 
 func (self *BlockChain) ValidateDoubleSpendCustomTokenOnTx(tx *transaction.TxCustomToken, txInBlock transaction.Transaction) (error) {
-	temp := txInBlock.(*transaction.TxCustomToken)
+	temp, ok := txInBlock.(*transaction.TxCustomToken)
+	if !ok {
+		return nil
+	}
 	for _, vin := range temp.TxTokenData.Vins {
 		for _, item := range tx.TxTokenData.Vins {
 			if vin.TxCustomTokenID.String() == item.TxCustomTokenID.String() {
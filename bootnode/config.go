@@ -8,7 +8,9 @@ import (
 
 // See loadConfig for details on the configuration load process.
 type config struct {
-	RPCPort int `long:"rpcport" short:"p" description:"Max number of RPC clients for standard connections"`
+	RPCPort   int      `long:"rpcport" short:"p" description:"Max number of RPC clients for standard connections"`
+	Bootnodes []string `long:"bootnode" description:"Add another bootnode (host:port) to sync peers with"`
+	DataDir   string   `long:"datadir" description:"Directory to store peers, peers are kept in memory when it is empty"`
 }
 
 // newConfigParser returns a new command line flags parser.
@@ -3,4 +3,5 @@ package main
 const (
 	Version       = "1.0.0"
 	RpcServerPort = 9330
+	PeersFile     = "peers.json"
 )
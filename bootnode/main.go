@@ -3,6 +3,8 @@ package main
 import (
 	"github.com/ninjadotorg/constant/bootnode/server"
 	"log"
+	"os"
+	"path/filepath"
 )
 
 var (
@@ -22,7 +24,16 @@ func main() {
 	cfg = tcfg
 
 	rpcConfig := server.RpcServerConfig{
-		Port: cfg.RPCPort,
+		Port:      cfg.RPCPort,
+		Bootnodes: cfg.Bootnodes,
+	}
+	if cfg.DataDir != "" {
+		err = os.MkdirAll(cfg.DataDir, 0700)
+		if err != nil {
+			log.Println("Create data dir error", err.Error())
+			return
+		}
+		rpcConfig.DataFile = filepath.Join(cfg.DataDir, PeersFile)
 	}
 	server := &server.RpcServer{}
 	err = server.Init(&rpcConfig)
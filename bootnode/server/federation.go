@@ -0,0 +1,163 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/rpc"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	// syncInterval is how often peer table is exchanged with other bootnodes
+	syncInterval = 10
+)
+
+// SyncArgs is sent by a bootnode to another one with its own peer table
+type SyncArgs struct {
+	Peers []SignedRawPeer
+}
+
+/*
+SyncPeers - merge peer table of another bootnode and reply with our own
+*/
+func (s Handler) SyncPeers(args *SyncArgs, peers *[]SignedRawPeer) error {
+	s.server.MergePeers(args.Peers)
+	*peers = s.server.SignedPeers()
+	return nil
+}
+
+/*
+SignedPeers - copy of the peer table which is sent to nodes and bootnodes
+*/
+func (self *RpcServer) SignedPeers() []SignedRawPeer {
+	self.peersMtx.Lock()
+	defer self.peersMtx.Unlock()
+
+	result := make([]SignedRawPeer, 0, len(self.Peers))
+	for _, p := range self.Peers {
+		result = append(result, SignedRawPeer{p.RawAddress, p.PublicKey, p.PeerID, p.Timestamp, p.Signature})
+	}
+	return result
+}
+
+/*
+MergePeers - add peers learnt from another bootnode, every registration must
+be signed by its producer key and only newer ones replace what we know.
+Unsigned registrations are only known by the bootnode they pinged
+*/
+func (self *RpcServer) MergePeers(peers []SignedRawPeer) {
+	self.peersMtx.Lock()
+	defer self.peersMtx.Unlock()
+
+	now := time.Now().Local()
+	for _, p := range peers {
+		lastPing := time.Unix(p.Timestamp, 0).Local()
+		if lastPing.After(now) {
+			lastPing = now
+		}
+		if now.Sub(lastPing).Seconds() > heartbeatTimeout {
+			continue
+		}
+		if p.PublicKey == "" || p.Signature == "" {
+			continue
+		}
+		if err := VerifyPing(p.RawAddress, p.PublicKey, p.PeerID, p.Timestamp, p.Signature); err != nil {
+			fmt.Println("Invalid synced peer", p.RawAddress, err)
+			continue
+		}
+
+		args := &PingArgs{p.RawAddress, p.PublicKey, p.PeerID, p.Timestamp, p.Signature}
+		if self.addOrUpdatePeer(args, lastPing) == nil {
+			self.dirty = true
+		}
+	}
+}
+
+/*
+syncHandler - exchange peer table with other bootnodes, a bootnode which is
+down is simply retried on next round
+*/
+func (self *RpcServer) syncHandler() {
+	clients := make(map[string]*rpc.Client)
+	for {
+		for _, address := range self.Config.Bootnodes {
+			client, ok := clients[address]
+			if !ok {
+				var err error
+				client, err = rpc.Dial("tcp", address)
+				if err != nil {
+					fmt.Println("Can't connect bootnode", address, err)
+					continue
+				}
+				clients[address] = client
+			}
+
+			var reply []SignedRawPeer
+			err := client.Call("Handler.SyncPeers", &SyncArgs{self.SignedPeers()}, &reply)
+			if err != nil {
+				fmt.Println("Can't sync peers with bootnode", address, err)
+				client.Close()
+				delete(clients, address)
+				continue
+			}
+			self.MergePeers(reply)
+		}
+		time.Sleep(syncInterval * time.Second)
+	}
+}
+
+/*
+savePeers - persist peer table into data file
+*/
+func (self *RpcServer) savePeers() error {
+	self.peersMtx.Lock()
+	defer self.peersMtx.Unlock()
+
+	if self.Config.DataFile == "" || !self.dirty {
+		return nil
+	}
+	data, err := json.Marshal(self.Peers)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(self.Config.DataFile, data, 0600)
+	if err != nil {
+		return err
+	}
+	self.dirty = false
+	return nil
+}
+
+/*
+loadPeers - restore peer table from data file, expired entries are removed by
+the heartbeat afterward
+*/
+func (self *RpcServer) loadPeers() error {
+	if self.Config.DataFile == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(self.Config.DataFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	peers := make([]*Peer, 0)
+	err = json.Unmarshal(data, &peers)
+	if err != nil {
+		return err
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].FirstPing.Sub(peers[j].FirstPing) <= 0
+	})
+
+	self.peersMtx.Lock()
+	self.Peers = peers
+	self.peersMtx.Unlock()
+	return nil
+}
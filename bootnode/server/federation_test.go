@@ -0,0 +1,34 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ninjadotorg/constant/cashec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePeers(t *testing.T) {
+	server := &RpcServer{Peers: make([]*Peer, 0)}
+	keySet := (&cashec.KeySet{}).GenerateKey([]byte("producer"))
+	rawAddress := "/ip4/10.0.0.1/tcp/9333/ipfs/" + testPeerID
+	now := time.Now().Unix()
+	signed := newTestPing(keySet, rawAddress, now)
+	forged := newTestPing(keySet, "/ip4/10.0.0.2/tcp/9333/ipfs/"+testPeerID, now+1)
+	forged.RawAddress = "/ip4/10.0.0.3/tcp/9333/ipfs/" + testPeerID
+	expired := newTestPing((&cashec.KeySet{}).GenerateKey([]byte("expired")), rawAddress, now-2*heartbeatTimeout)
+
+	var peers []SignedRawPeer
+	err := Handler{server}.SyncPeers(&SyncArgs{Peers: []SignedRawPeer{
+		{signed.RawAddress, signed.PublicKey, signed.PeerID, signed.Timestamp, signed.Signature},
+		{forged.RawAddress, forged.PublicKey, forged.PeerID, forged.Timestamp, forged.Signature},
+		{expired.RawAddress, expired.PublicKey, expired.PeerID, expired.Timestamp, expired.Signature},
+		// unsigned entries are never synced
+		{RawAddress: "/ip4/10.0.0.4/tcp/9333/ipfs/" + testPeerID, PeerID: testPeerID, Timestamp: now},
+		{RawAddress: "/ip4/10.0.0.5/tcp/9333/ipfs/" + testPeerID, PublicKey: signed.PublicKey, PeerID: testPeerID, Timestamp: now + 2},
+	}}, &peers)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(peers))
+	assert.Equal(t, rawAddress, peers[0].RawAddress)
+	assert.Equal(t, signed.Timestamp, peers[0].Timestamp)
+}
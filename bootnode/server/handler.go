@@ -1,26 +1,82 @@
 package server
 
 import (
+	"errors"
 	"fmt"
-	"github.com/ninjadotorg/constant/wire"
+	"strings"
+	"time"
+
+	"github.com/ninjadotorg/constant/cashec"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/signer"
 )
 
 type Handler struct {
 	server *RpcServer
 }
 
+// PingArgs is sent by a node to register itself, a node with a producer key
+// signs (RawAddress, PeerID, Timestamp) with it so nobody else can register
+// that key at another address
 type PingArgs struct {
 	RawAddress string
-	PublicKey string
+	PublicKey  string
+	PeerID     string
+	Timestamp  int64
+	Signature  string
+}
+
+// SignedRawPeer is a registered peer returned by Ping, along with the signed
+// registration which lets the node check it by itself
+type SignedRawPeer struct {
+	RawAddress string
+	PublicKey  string
+	PeerID     string
+	Timestamp  int64
+	Signature  string
+}
+
+/*
+PingData - data which is signed by producer key of a ping
+*/
+func PingData(rawAddress string, peerID string, timestamp int64) []byte {
+	return []byte(fmt.Sprintf("%s|%s|%d", rawAddress, peerID, timestamp))
 }
-func (s Handler) Ping(args *PingArgs, peers *[]wire.RawPeer) error {
+
+/*
+VerifyPing - check that peerID is the ipfs part of raw address and the
+signature is made by public key
+*/
+func VerifyPing(rawAddress string, publicKey string, peerID string, timestamp int64, signature string) error {
+	if peerID == common.EmptyString || !strings.HasSuffix(rawAddress, "/ipfs/"+peerID) {
+		return errors.New("peer id " + peerID + " does not match address " + rawAddress)
+	}
+	return cashec.ValidateDataB58(publicKey, signature, signer.SignedData(signer.SignMessage, PingData(rawAddress, peerID, timestamp)))
+}
+
+func (s Handler) Ping(args *PingArgs, peers *[]SignedRawPeer) error {
 	fmt.Println("Ping", args)
-	s.server.AddOrUpdatePeer(args.RawAddress, args.PublicKey)
-	for _, p := range s.server.Peers {
-		*peers = append(*peers, wire.RawPeer{p.RawAddress, p.PublicKey})
+	if args.PublicKey != common.EmptyString {
+		err := VerifyPing(args.RawAddress, args.PublicKey, args.PeerID, args.Timestamp, args.Signature)
+		if err != nil {
+			return err
+		}
+		age := time.Since(time.Unix(args.Timestamp, 0))
+		if age > MaxPingAge || age < -MaxPingAge {
+			return errors.New("ping timestamp is out of range")
+		}
+	} else {
+		// unsigned registrations are stamped by the bootnode, so they expire
+		// the same way on the other bootnodes
+		args.Timestamp = time.Now().Unix()
+	}
+	err := s.server.AddOrUpdatePeer(args)
+	if err != nil {
+		return err
 	}
+	*peers = s.server.SignedPeers()
 
 	fmt.Println("Response", *peers)
 
 	return nil
-}
\ No newline at end of file
+}
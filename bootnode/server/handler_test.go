@@ -0,0 +1,76 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ninjadotorg/constant/cashec"
+	"github.com/ninjadotorg/constant/common/base58"
+	"github.com/ninjadotorg/constant/signer"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPeerID = "QmRWYJ1E5RxZvZYXLpd7cJhNjQmMmwJXMFZXfrTM2Phc1z"
+
+// newTestPing returns a ping of rawAddress signed by keySet at timestamp
+func newTestPing(keySet *cashec.KeySet, rawAddress string, timestamp int64) *PingArgs {
+	args := &PingArgs{
+		RawAddress: rawAddress,
+		PublicKey:  base58.Base58Check{}.Encode(keySet.PaymentAddress.Pk, byte(0x00)),
+		PeerID:     testPeerID,
+		Timestamp:  timestamp,
+	}
+	sig, _ := keySet.Sign(signer.SignedData(signer.SignMessage, PingData(args.RawAddress, args.PeerID, args.Timestamp)))
+	args.Signature = base58.Base58Check{}.Encode(sig, byte(0x00))
+	return args
+}
+
+func TestVerifyPing(t *testing.T) {
+	keySet := (&cashec.KeySet{}).GenerateKey([]byte("producer"))
+	other := (&cashec.KeySet{}).GenerateKey([]byte("attacker"))
+	rawAddress := "/ip4/10.0.0.1/tcp/9333/ipfs/" + testPeerID
+	args := newTestPing(keySet, rawAddress, time.Now().Unix())
+	assert.Nil(t, VerifyPing(args.RawAddress, args.PublicKey, args.PeerID, args.Timestamp, args.Signature))
+
+	// the signed address can not be changed
+	assert.NotNil(t, VerifyPing("/ip4/10.0.0.2/tcp/9333/ipfs/"+testPeerID, args.PublicKey, args.PeerID, args.Timestamp, args.Signature))
+	// nor the peer id
+	assert.NotNil(t, VerifyPing(args.RawAddress, args.PublicKey, "QmOther", args.Timestamp, args.Signature))
+	// nor the timestamp
+	assert.NotNil(t, VerifyPing(args.RawAddress, args.PublicKey, args.PeerID, args.Timestamp+1, args.Signature))
+	// a victim key can not be registered with a signature of another key
+	forged := newTestPing(other, rawAddress, args.Timestamp)
+	assert.NotNil(t, VerifyPing(args.RawAddress, args.PublicKey, args.PeerID, args.Timestamp, forged.Signature))
+}
+
+func TestPing(t *testing.T) {
+	handler := Handler{&RpcServer{Peers: make([]*Peer, 0)}}
+	keySet := (&cashec.KeySet{}).GenerateKey([]byte("producer"))
+	rawAddress := "/ip4/10.0.0.1/tcp/9333/ipfs/" + testPeerID
+	now := time.Now().Unix()
+
+	var peers []SignedRawPeer
+	args := newTestPing(keySet, rawAddress, now)
+	assert.Nil(t, handler.Ping(args, &peers))
+	assert.Equal(t, []SignedRawPeer{{args.RawAddress, args.PublicKey, args.PeerID, args.Timestamp, args.Signature}}, peers)
+
+	// replayed and old pings are rejected
+	assert.NotNil(t, handler.Ping(newTestPing(keySet, rawAddress, now), &peers))
+	assert.NotNil(t, handler.Ping(newTestPing(keySet, rawAddress, now-1), &peers))
+	// pings out of time range are rejected
+	assert.NotNil(t, handler.Ping(newTestPing(keySet, rawAddress, now+int64(2*MaxPingAge/time.Second)), &peers))
+	// a ping with bad signature is rejected
+	bad := newTestPing(keySet, rawAddress, now+1)
+	bad.Timestamp++
+	assert.NotNil(t, handler.Ping(bad, &peers))
+
+	// a newer ping moves the key to another address
+	movedAddress := "/ip4/10.0.0.2/tcp/9333/ipfs/" + testPeerID
+	assert.Nil(t, handler.Ping(newTestPing(keySet, movedAddress, now+1), &peers))
+	assert.Equal(t, 1, len(peers))
+	assert.Equal(t, movedAddress, peers[0].RawAddress)
+
+	// nodes without producer key register unsigned
+	assert.Nil(t, handler.Ping(&PingArgs{RawAddress: "/ip4/10.0.0.3/tcp/9333/ipfs/" + testPeerID}, &peers))
+	assert.Equal(t, 2, len(peers))
+}
@@ -1,17 +1,24 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/rpc"
 	"sort"
+	"sync"
 	"time"
 )
 
 const (
 	heartbeatInterval = 5
-	heartbeatTimeout = 60
+	// nodes ping every minute, keep them for a few missed pings so synced
+	// entries do not expire before being refreshed
+	heartbeatTimeout = 180
+
+	// MaxPingAge is how far the timestamp of a signed ping may be from now
+	MaxPingAge = 2 * time.Minute
 )
 
 // timeZeroVal is simply the zero value for a time.Time and is used to avoid
@@ -26,25 +33,41 @@ type Peer struct {
 	ID string
 	RawAddress string
 	PublicKey string
+	PeerID string
+	Timestamp int64
+	Signature string
 	FirstPing time.Time
 	LastPing time.Time
 }
 
 // rpcServer provides a concurrent safe RPC server to a chain server.
 type RpcServer struct {
-	Peers []*Peer
+	Peers    []*Peer
+	peersMtx sync.Mutex
+	dirty    bool
 
 	Config RpcServerConfig
 }
 
 type RpcServerConfig struct {
 	Port int
+	// Bootnodes are addresses of other bootnodes to sync peer table with
+	Bootnodes []string
+	// DataFile persists peer table across restarts, empty keeps it in memory
+	DataFile string
 }
 
 func (self *RpcServer) Init(config *RpcServerConfig) (error) {
 	self.Config = *config
 	self.Peers = make([]*Peer, 0)
+	err := self.loadPeers()
+	if err != nil {
+		return err
+	}
 	go self.PeerHeartBeat()
+	if len(self.Config.Bootnodes) > 0 {
+		go self.syncHandler()
+	}
 	return nil
 }
 
@@ -59,24 +82,64 @@ func (self *RpcServer) Start() {
 	server.Accept(l)
 }
 
-func (self *RpcServer) AddOrUpdatePeer(rawAddress string, publicKey string) {
+func (self *RpcServer) AddOrUpdatePeer(args *PingArgs) error {
+	self.peersMtx.Lock()
+	defer self.peersMtx.Unlock()
+
+	err := self.addOrUpdatePeer(args, time.Now().Local())
+	if err == nil {
+		self.dirty = true
+	}
+	return err
+}
+
+func (self *RpcServer) addOrUpdatePeer(args *PingArgs, lastPing time.Time) error {
+	// a signed registration is only accepted when it is newer than the last
+	// one of its public key, so old pings can not be replayed
+	if args.PublicKey != "" {
+		for _, peer := range self.Peers {
+			if peer.PublicKey == args.PublicKey && args.Timestamp <= peer.Timestamp {
+				return errors.New("replayed ping of " + args.PublicKey)
+			}
+		}
+	}
+
 	exist := false
 	for _, peer := range self.Peers {
-		if self.CombineID(rawAddress, publicKey) == peer.ID {
+		if self.CombineID(args.RawAddress, args.PublicKey) == peer.ID {
 			exist = true
-			peer.LastPing = time.Now().Local()
+			if lastPing.After(peer.LastPing) {
+				peer.LastPing = lastPing
+			}
+			if args.Timestamp > peer.Timestamp {
+				peer.PeerID = args.PeerID
+				peer.Timestamp = args.Timestamp
+				peer.Signature = args.Signature
+			}
 		}
 	}
 
 	if !exist {
-		self.Peers = append(self.Peers, &Peer{self.CombineID(rawAddress, publicKey), rawAddress, publicKey,time.Now().Local(), time.Now().Local()})
+		// the public key moved to another address, drop the old registration
+		if args.PublicKey != "" {
+			for idx := len(self.Peers) - 1; idx >= 0; idx-- {
+				if self.Peers[idx].PublicKey == args.PublicKey {
+					self.RemovePeerByIdx(idx)
+				}
+			}
+		}
+		self.Peers = append(self.Peers, &Peer{self.CombineID(args.RawAddress, args.PublicKey), args.RawAddress, args.PublicKey, args.PeerID, args.Timestamp, args.Signature, lastPing, lastPing})
 		sort.Slice(self.Peers, func(i, j int) bool {
 			return self.Peers[i].FirstPing.Sub(self.Peers[j].FirstPing) <= 0
 		})
 	}
+	return nil
 }
 
 func (self *RpcServer) RemovePeer(ID string) {
+	self.peersMtx.Lock()
+	defer self.peersMtx.Unlock()
+
 	removeIdx := -1
 	for idx, peer := range self.Peers {
 		if peer.ID == ID {
@@ -89,6 +152,7 @@ func (self *RpcServer) RemovePeer(ID string) {
 	}
 }
 
+// RemovePeerByIdx removes peer at idx, caller must hold peersMtx
 func (self *RpcServer) RemovePeerByIdx(idx int) {
 	self.Peers = append(self.Peers[:idx], self.Peers[idx+1:]...)
 }
@@ -100,15 +164,19 @@ func (self *RpcServer) CombineID(rawAddress string, publicKey string) string {
 func (self *RpcServer) PeerHeartBeat() {
 	for {
 		now := time.Now().Local()
-		if len(self.Peers) > 0 {
-		loop:
-			for idx, peer := range self.Peers {
-				if now.Sub(peer.LastPing).Seconds() > heartbeatTimeout {
-					self.RemovePeerByIdx(idx)
-					goto loop
-				}
+		self.peersMtx.Lock()
+		for idx := len(self.Peers) - 1; idx >= 0; idx-- {
+			if now.Sub(self.Peers[idx].LastPing).Seconds() > heartbeatTimeout {
+				self.RemovePeerByIdx(idx)
+				self.dirty = true
 			}
 		}
+		self.peersMtx.Unlock()
+
+		err := self.savePeers()
+		if err != nil {
+			fmt.Println("Can't save peers", err)
+		}
 		time.Sleep(heartbeatInterval * time.Second)
 	}
 }
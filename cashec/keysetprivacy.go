@@ -95,7 +95,7 @@ func ValidateDataB58(pubkey string, sig string, data []byte) error {
 	}
 
 	validatorKp := KeySet{}
-	copy(validatorKp.PaymentAddress.Pk[:], decPubkey)
+	validatorKp.PaymentAddress.Pk = decPubkey
 	decSig, _, err := base58.Base58Check{}.Decode(sig)
 	if err != nil {
 		return errors.New("can't decode signature: " + err.Error())
@@ -0,0 +1,25 @@
+package common
+
+import "time"
+
+/*
+Clock is the time of block production and consensus, tests replace the wall
+clock to move time on their own
+*/
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// After sends the current time on the returned channel once d passed
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the wall clock
+type SystemClock struct{}
+
+func (self SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (self SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -248,6 +248,18 @@ func IntArrayEquals(a []int, b []int) bool {
 	return true
 }
 
+func StringArrayEquals(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, v := range a {
+		if v != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func IndexOfStr(item string, list []string) int {
 	for k, v := range list {
 		if item == v {
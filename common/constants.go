@@ -38,6 +38,8 @@ const (
 const (
 	MaxBlockSize          = 5000000 //byte 5MB
 	MaxTxsInBlock         = 1000
+	ReservedBlockSize     = 500000                // byte, kept in a block for governance, salary, response and refund txs
+	ReservedTxsInBlock    = 50                    // txs kept in a block for governance, salary, response and refund txs
 	MinTxsInBlock         = 10                    // minium txs for block to get immediate process (meaning no wait time)
 	MinBlockWaitTime      = 3                     // second
 	MaxBlockWaitTime      = 20 - MinBlockWaitTime // second
@@ -12,11 +12,13 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/jessevdk/go-flags"
 	"github.com/ninjadotorg/constant/cashec"
 	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/signer"
 	"github.com/ninjadotorg/constant/wallet"
 )
 
@@ -29,11 +31,16 @@ const (
 	defaultLogDirname      = "logs"
 	defaultLogFilename     = "log.log"
 	defaultMaxPeers        = 125
+	defaultBanDuration     = time.Hour * 24
 	defaultMaxRPCClients   = 10
 	defaultGenerate        = false
 	sampleConfigFilename   = "sample-config.conf"
 	defaultDisableRpcTls   = true
 	defaultFastMode        = true
+	defaultMaxMempoolSize  = 300 // megabytes
+	defaultMaxMempoolTxs   = 50000
+	defaultMempoolExpiry   = time.Hour * 72
+	defaultMaxOrphanTxs    = 100
 	// For wallet
 	defaultWalletName = "wallet"
 )
@@ -60,15 +67,16 @@ type config struct {
 	LogDir      string `short:"L" long:"logdir" description:"Directory to log output."`
 	LogLevel    string `short:"l" long:"loglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
 
-	AddPeers             []string `short:"a" long:"addpeer" description:"Add a peer to connect with at startup"`
-	ConnectPeers         []string `short:"c" long:"connect" description:"Connect only to the specified peers at startup"`
-	DisableListen        bool     `long:"nolisten" description:"Disable listening for incoming connections -- NOTE: Listening is automatically disabled if the --connect or --proxy options are used without also specifying listen interfaces via --listen"`
-	Listeners            []string `long:"listen" description:"Add an interface/port to listen for connections (default all interfaces port: 9333, testnet: 9444)"`
-	MaxPeers             int      `long:"maxpeers" description:"Max number of inbound and outbound peers"`
-	MaxOutPeers          int      `long:"maxoutpeers" description:"Max number of outbound peers"`
-	MaxInPeers           int      `long:"maxinpeers" description:"Max number of inbound peers"`
-	DiscoverPeers        bool     `long:"discoverpeers" description:"Enable discover peers"`
-	DiscoverPeersAddress string   `long:"discoverpeersaddress" description:"Url to connect discover peers server"`
+	AddPeers             []string      `short:"a" long:"addpeer" description:"Add a peer to connect with at startup"`
+	ConnectPeers         []string      `short:"c" long:"connect" description:"Connect only to the specified peers at startup"`
+	DisableListen        bool          `long:"nolisten" description:"Disable listening for incoming connections -- NOTE: Listening is automatically disabled if the --connect or --proxy options are used without also specifying listen interfaces via --listen"`
+	Listeners            []string      `long:"listen" description:"Add an interface/port to listen for connections (default all interfaces port: 9333, testnet: 9444)"`
+	MaxPeers             int           `long:"maxpeers" description:"Max number of inbound and outbound peers"`
+	MaxOutPeers          int           `long:"maxoutpeers" description:"Max number of outbound peers"`
+	MaxInPeers           int           `long:"maxinpeers" description:"Max number of inbound peers"`
+	DiscoverPeers        bool          `long:"discoverpeers" description:"Enable discover peers"`
+	DiscoverPeersAddress string        `long:"discoverpeersaddress" description:"Comma separated urls of discover peers servers, the next one is used when one is down"`
+	BanDuration          time.Duration `long:"banduration" description:"How long to ban misbehaving peers. Valid time units are {s, m, h}. Minimum 1 second"`
 
 	RPCDisableAuth bool     `long:"norpcauth" description:"Disable RPC authorization by username/password"`
 	RPCUser        string   `short:"u" long:"rpcuser" description:"Username for RPC connections"`
@@ -95,12 +103,21 @@ type config struct {
 
 	// PoS config
 	ProducerSpendingKey string `long:"producerspendingkey" description:"!!!WARNING Leave this if you don't know what this is"`
+	ProducerSigner      string `long:"producersigner" description:"Unix socket of a remotesigner process which keeps the producer key, use it instead of producerspendingkey"`
 	// For Wallet
 	Wallet           bool   `long:"enablewallet" description:"Enable wallet"`
 	WalletName       string `long:"wallet" description:"Wallet Database Name file, default is 'wallet'"`
 	WalletPassphrase string `long:"walletpassphrase" description:"Wallet passphrase"`
 
 	FastMode bool `long:"fastmode" description:"Load existed chain dependencies instead of rebuild from block data"`
+
+	// Mempool config
+	MaxMempoolSize uint64        `long:"maxmempoolsize" description:"Max size of txs in mempool in megabytes, txs with the lowest fee rate are evicted over it"`
+	MaxMempoolTxs  int           `long:"maxmempooltxs" description:"Max number of txs in mempool, txs with the lowest fee rate are evicted over it"`
+	MempoolExpiry  time.Duration `long:"mempoolexpiry" description:"How long a tx is kept in mempool before it expires, 0 to keep txs until they are included. Valid time units are {s, m, h}"`
+	MempoolReplace bool          `long:"mempoolreplacement" description:"Replace txs in mempool by txs spending the same nullifiers with a higher fee and fee rate"`
+	NoPersistPool  bool          `long:"nopersistmempool" description:"Do not save txs of mempool on shutdown and load them on startup"`
+	MaxOrphanTxs   int           `long:"maxorphantx" description:"Max number of orphan txs (e.g. a loan response before its request) held in mempool until their parent comes, 0 to reject orphans"`
 }
 
 // serviceOptions defines the configuration options for the daemon as a service on
@@ -271,7 +288,12 @@ func loadConfig() (*config, []string, error) {
 		DiscoverPeers:        false,
 		TestNet:              false,
 		DiscoverPeersAddress: "35.230.8.182:9339",
+		BanDuration:          defaultBanDuration,
 		FastMode:             defaultFastMode,
+		MaxMempoolSize:       defaultMaxMempoolSize,
+		MaxMempoolTxs:        defaultMaxMempoolTxs,
+		MempoolExpiry:        defaultMempoolExpiry,
+		MaxOrphanTxs:         defaultMaxOrphanTxs,
 	}
 
 	// Service options which are only added on Windows.
@@ -484,13 +506,20 @@ func loadConfig() (*config, []string, error) {
 
 	// Ensure there is at least one mining address when the generate flag is
 	// set.
-	if cfg.Generate && len(cfg.ProducerSpendingKey) == 0 {
+	if cfg.Generate && len(cfg.ProducerSpendingKey) == 0 && len(cfg.ProducerSigner) == 0 {
 		str := "%s: the generate flag is set, but there are no producer's key specified "
 		err := fmt.Errorf(str, funcName)
 		fmt.Fprintln(os.Stderr, err)
 		fmt.Fprintln(os.Stderr, usageMessage)
 		return nil, nil, err
 	}
+	if len(cfg.ProducerSpendingKey) > 0 && len(cfg.ProducerSigner) > 0 {
+		str := "%s: the producerspendingkey and producersigner options can not be used together"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
 
 	// Add default port to all listener addresses if needed and remove
 	// duplicate addresses.
@@ -532,6 +561,15 @@ func loadConfig() (*config, []string, error) {
 		}
 	}
 
+	// Don't allow ban durations that are too short.
+	if cfg.BanDuration < time.Second {
+		str := "%s: The banduration option may not be less than 1s -- parsed [%v]"
+		err := fmt.Errorf(str, funcName, cfg.BanDuration)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	if cfg.DiscoverPeers {
 		if cfg.DiscoverPeersAddress == "" {
 			err := fmt.Errorf("Discover peers server is empty")
@@ -644,3 +682,44 @@ func (self *config) GetProducerKeySet() (*cashec.KeySet, error) {
 	Logger.log.Info("chainID: ", chainIdSender)
 	return KeySetProducer, nil
 }
+
+/*
+GetProducerSigner - signer of producer key, a remote signer when producersigner
+is set, nil when node runs without producer key
+*/
+func (self *config) GetProducerSigner() (signer.Signer, error) {
+	if self.ProducerSigner != "" {
+		remoteSigner, err := signer.DialRemoteSigner("unix", self.ProducerSigner)
+		if err != nil {
+			return nil, err
+		}
+		return remoteSigner, nil
+	}
+	if self.ProducerSpendingKey == "" {
+		return nil, nil
+	}
+	keySet, err := self.GetProducerKeySet()
+	if err != nil {
+		return nil, err
+	}
+	protection, err := signer.NewProtection(common.EmptyString)
+	if err != nil {
+		return nil, err
+	}
+	return signer.NewLocalSigner(*keySet, protection), nil
+}
+
+/*
+discoverPeersAddresses - bootnodes of discoverpeersaddress, which is a comma
+separated list
+*/
+func (self *config) discoverPeersAddresses() []string {
+	addresses := []string{}
+	for _, address := range strings.Split(self.DiscoverPeersAddress, ",") {
+		address = strings.TrimSpace(address)
+		if address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
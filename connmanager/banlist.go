@@ -0,0 +1,162 @@
+package connmanager
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+type BannedPeer struct {
+	PeerID  string
+	Reason  string
+	BanTime time.Time
+	Until   time.Time
+}
+
+/*
+BanList - peer ids which are refused to connect, persisted in data dir
+so that bans survive restart
+*/
+type BanList struct {
+	mtx     sync.Mutex
+	banFile string
+	banned  map[string]*BannedPeer
+}
+
+func NewBanList(dataDir string) *BanList {
+	banList := &BanList{
+		banned: make(map[string]*BannedPeer),
+	}
+	if dataDir != EmptyString {
+		banList.banFile = filepath.Join(dataDir, BanListFile)
+		err := banList.load()
+		if err != nil {
+			Logger.log.Errorf("Failed to load ban list %s: %+v", banList.banFile, err)
+		}
+	}
+	return banList
+}
+
+// load reads ban list file, a missing file means empty list
+func (self *BanList) load() error {
+	r, err := os.Open(self.banFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return NewConnManagerError(BanListFileErr, err)
+	}
+	defer r.Close()
+
+	banned := []*BannedPeer{}
+	err = json.NewDecoder(r).Decode(&banned)
+	if err != nil {
+		return NewConnManagerError(BanListFileErr, err)
+	}
+	now := time.Now()
+	for _, item := range banned {
+		if item.Until.After(now) {
+			self.banned[item.PeerID] = item
+		}
+	}
+	return nil
+}
+
+// save writes ban list file, caller must hold mtx
+func (self *BanList) save() error {
+	if self.banFile == EmptyString {
+		return nil
+	}
+	w, err := os.Create(self.banFile)
+	if err != nil {
+		return NewConnManagerError(BanListFileErr, err)
+	}
+	defer w.Close()
+	err = json.NewEncoder(w).Encode(self.list())
+	if err != nil {
+		return NewConnManagerError(BanListFileErr, err)
+	}
+	return nil
+}
+
+// list returns banned peers sorted by ban time, expired bans are dropped.
+// caller must hold mtx
+func (self *BanList) list() []*BannedPeer {
+	now := time.Now()
+	result := make([]*BannedPeer, 0, len(self.banned))
+	for peerID, item := range self.banned {
+		if !item.Until.After(now) {
+			delete(self.banned, peerID)
+			continue
+		}
+		result = append(result, item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].BanTime.Before(result[j].BanTime)
+	})
+	return result
+}
+
+/*
+Ban - ban a peer id for duration. Banning an already banned peer
+overrides the previous ban
+*/
+func (self *BanList) Ban(peerID string, duration time.Duration, reason string) error {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	now := time.Now()
+	self.banned[peerID] = &BannedPeer{
+		PeerID:  peerID,
+		Reason:  reason,
+		BanTime: now,
+		Until:   now.Add(duration),
+	}
+	return self.save()
+}
+
+// Unban removes a peer id from list, returns false when it was not banned
+func (self *BanList) Unban(peerID string) (bool, error) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	_, ok := self.banned[peerID]
+	if !ok {
+		return false, nil
+	}
+	delete(self.banned, peerID)
+	return true, self.save()
+}
+
+func (self *BanList) Clear() error {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.banned = make(map[string]*BannedPeer)
+	return self.save()
+}
+
+func (self *BanList) IsBanned(peerID string) bool {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	item, ok := self.banned[peerID]
+	if !ok {
+		return false
+	}
+	if !item.Until.After(time.Now()) {
+		delete(self.banned, peerID)
+		return false
+	}
+	return true
+}
+
+// List returns a copy of current bans
+func (self *BanList) List() []BannedPeer {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	result := []BannedPeer{}
+	for _, item := range self.list() {
+		result = append(result, *item)
+	}
+	return result
+}
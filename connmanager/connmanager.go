@@ -13,12 +13,11 @@ import (
 	libpeer "github.com/libp2p/go-libp2p-peer"
 	pstore "github.com/libp2p/go-libp2p-peerstore"
 	ma "github.com/multiformats/go-multiaddr"
+	"github.com/ninjadotorg/constant/addrmanager"
 	"github.com/ninjadotorg/constant/bootnode/server"
-	"github.com/ninjadotorg/constant/cashec"
 	"github.com/ninjadotorg/constant/common/base58"
 	"github.com/ninjadotorg/constant/peer"
-	"github.com/ninjadotorg/constant/wire"
-	"github.com/ninjadotorg/constant/wallet"
+	"github.com/ninjadotorg/constant/signer"
 )
 
 // ConnState represents the state of the requested connection.
@@ -41,6 +40,10 @@ type ConnManager struct {
 	Config Config
 
 	ListeningPeers map[libpeer.ID]*peer.Peer
+
+	BanList *BanList
+
+	validators *validatorTable
 }
 
 type Config struct {
@@ -65,8 +68,28 @@ type Config struct {
 	//OnOutboundDisconnection is a callback that is fired when an outbound connection is disconnected
 	OnOutboundDisconnection func(peerConn *peer.PeerConn)
 
-	DiscoverPeers        bool
-	DiscoverPeersAddress string
+	DiscoverPeers bool
+	// DiscoverPeersAddresses are bootnodes, they are used in order and the
+	// next one is tried when one is down
+	DiscoverPeersAddresses []string
+
+	// DataDir is where ban list is persisted, empty means keeping it in memory
+	DataDir string
+	// BanDuration is how long a peer reaching ban threshold is banned
+	BanDuration time.Duration
+
+	// AddrManager provides the addresses outbound connections are picked
+	// from, nil disables automatic outbound connections
+	AddrManager *addrmanager.AddrManager
+	// TargetOutbound is the number of outbound connections to maintain
+	TargetOutbound int
+
+	// Committee provides current and next committee members which are kept
+	// connected over outbound limits, nil disables it
+	Committee interface {
+		GetCommittee() []string
+		GetNextCommittee() []string
+	}
 }
 
 type DiscoverPeerInfo struct {
@@ -100,6 +123,12 @@ func (self ConnManager) New(cfg *Config) *ConnManager {
 
 	self.ListeningPeers = map[libpeer.ID]*peer.Peer{}
 
+	if self.Config.BanDuration <= 0 {
+		self.Config.BanDuration = DefaultBanDuration
+	}
+	self.BanList = NewBanList(self.Config.DataDir)
+	self.validators = newValidatorTable()
+
 	return &self
 }
 
@@ -144,6 +173,10 @@ func (self ConnManager) GetPeerIDStr(addr string) (string, error) {
 // Connect assigns an id and dials a connection to the address of the
 // connection request.
 func (self *ConnManager) Connect(addr string, pubKey string) {
+	self.connect(addr, pubKey, false)
+}
+
+func (self *ConnManager) connect(addr string, pubKey string, priority bool) {
 	if atomic.LoadInt32(&self.stop) != 0 {
 		return
 	}
@@ -177,6 +210,8 @@ func (self *ConnManager) Connect(addr string, pubKey string) {
 		listen.HandleConnected = self.handleConnected
 		listen.HandleDisconnected = self.handleDisconnected
 		listen.HandleFailed = self.handleFailed
+		listen.HandleBanned = self.handleBanned
+		listen.CheckBanned = self.checkBanned
 
 		peer := peer.Peer{
 			TargetAddress:      targetAddr,
@@ -188,6 +223,7 @@ func (self *ConnManager) Connect(addr string, pubKey string) {
 			HandleConnected:    self.handleConnected,
 			HandleDisconnected: self.handleDisconnected,
 			HandleFailed:       self.handleFailed,
+			Priority:           priority,
 		}
 
 		if pubKey != EmptyString {
@@ -201,7 +237,7 @@ func (self *ConnManager) Connect(addr string, pubKey string) {
 	}
 }
 
-func (self *ConnManager) Start(discoverPeerAddress string) {
+func (self *ConnManager) Start(discoverPeerAddresses []string) {
 	// Already started?
 	if atomic.AddInt32(&self.start, 1) != 1 {
 		return
@@ -218,14 +254,24 @@ func (self *ConnManager) Start(discoverPeerAddress string) {
 			listner.HandleConnected = self.handleConnected
 			listner.HandleDisconnected = self.handleDisconnected
 			listner.HandleFailed = self.handleFailed
+			listner.HandleBanned = self.handleBanned
+			listner.CheckBanned = self.checkBanned
 			go self.listenHandler(listner)
 
 			self.ListeningPeers[listner.PeerID] = listner
 		}
 
-		if self.Config.DiscoverPeers && self.Config.DiscoverPeersAddress != EmptyString {
-			Logger.log.Infof("DiscoverPeers: true\n----------------------------------------------------------------\n|               Discover peer url: %s               |\n----------------------------------------------------------------", self.Config.DiscoverPeersAddress)
-			go self.DiscoverPeers(discoverPeerAddress)
+		if self.Config.AddrManager != nil && self.Config.TargetOutbound > 0 {
+			go self.outboundHandler()
+		}
+
+		if self.Config.Committee != nil {
+			go self.validatorHandler()
+		}
+
+		if self.Config.DiscoverPeers && len(self.Config.DiscoverPeersAddresses) > 0 {
+			Logger.log.Infof("DiscoverPeers: true\n----------------------------------------------------------------\n|               Discover peer url: %s               |\n----------------------------------------------------------------", strings.Join(self.Config.DiscoverPeersAddresses, ","))
+			go self.DiscoverPeers(discoverPeerAddresses)
 		}
 	}
 }
@@ -241,6 +287,10 @@ func (self *ConnManager) handleConnected(peerConn *peer.PeerConn) {
 	if peerConn.IsOutbound {
 		Logger.log.Infof("handleConnected OUTBOUND %s", peerConn.RemotePeerID.String())
 
+		if self.Config.AddrManager != nil {
+			self.Config.AddrManager.Connected(peerConn.RemoteRawAddress)
+		}
+
 		if self.Config.OnOutboundConnection != nil {
 			self.Config.OnOutboundConnection(peerConn)
 		}
@@ -258,6 +308,110 @@ func (self *ConnManager) handleFailed(peerConn *peer.PeerConn) {
 	Logger.log.Infof("handleFailed %s", peerConn.RemotePeerID.String())
 }
 
+func (self *ConnManager) handleBanned(peerConn *peer.PeerConn, reason string) {
+	Logger.log.Infof("handleBanned %s %s", peerConn.RemotePeerID.Pretty(), reason)
+	err := self.BanList.Ban(peerConn.RemotePeerID.Pretty(), self.Config.BanDuration, reason)
+	if err != nil {
+		Logger.log.Error(err)
+	}
+}
+
+func (self *ConnManager) checkBanned(peerID libpeer.ID) bool {
+	return self.BanList.IsBanned(peerID.Pretty())
+}
+
+/*
+BanPeer - ban a peer id manually and drop its current connections
+*/
+func (self *ConnManager) BanPeer(peerID string, duration time.Duration, reason string) error {
+	if duration <= 0 {
+		duration = self.Config.BanDuration
+	}
+	err := self.BanList.Ban(peerID, duration, reason)
+	if err != nil {
+		return err
+	}
+	for _, listener := range self.Config.ListenerPeers {
+		for _, peerConn := range listener.PeerConns {
+			if peerConn.RemotePeerID.Pretty() == peerID && peerConn.IsConnected {
+				peerConn.Close()
+			}
+		}
+	}
+	return nil
+}
+
+// UnbanPeer lifts ban of a peer id, returns false when it was not banned
+func (self *ConnManager) UnbanPeer(peerID string) (bool, error) {
+	return self.BanList.Unban(peerID)
+}
+
+func (self *ConnManager) ClearBanned() error {
+	return self.BanList.Clear()
+}
+
+func (self *ConnManager) BannedPeers() []BannedPeer {
+	return self.BanList.List()
+}
+
+/*
+outboundHandler - keep TargetOutbound outbound connections, picking addresses
+from the address manager. It must be run as a goroutine.
+*/
+func (self *ConnManager) outboundHandler() {
+	ticker := time.NewTicker(OutboundConnectInterval)
+	defer ticker.Stop()
+	for {
+		self.fillOutbound()
+		select {
+		case <-ticker.C:
+		case <-self.cQuit:
+			return
+		}
+	}
+}
+
+/*
+fillOutbound - connect to addresses of the address manager until every
+listener reaches TargetOutbound outbound connections
+*/
+func (self *ConnManager) fillOutbound() {
+	for _, listener := range self.Config.ListenerPeers {
+		need := self.Config.TargetOutbound - listener.NumOutbound()
+		picked := make(map[string]bool)
+		for tries := 0; need > 0 && tries < MaxOutboundPickTries; tries++ {
+			ka := self.Config.AddrManager.GetAddress()
+			if ka == nil {
+				break
+			}
+			rawAddress := ka.Peer.RawAddress
+			peerID := self.GetPeerId(rawAddress)
+			if peerID == EmptyString || picked[peerID] || !self.canConnectOutbound(listener, peerID) {
+				continue
+			}
+			picked[peerID] = true
+			need--
+
+			self.Config.AddrManager.Attempt(rawAddress)
+			go self.Connect(rawAddress, ka.Peer.PublicKey)
+		}
+	}
+}
+
+// canConnectOutbound returns false for the listener itself, banned peers and
+// peers which already have a connection
+func (self *ConnManager) canConnectOutbound(listener *peer.Peer, peerID string) bool {
+	if peerID == listener.PeerID.Pretty() || self.BanList.IsBanned(peerID) {
+		return false
+	}
+	for _, peerConn := range listener.PeerConns {
+		if peerConn.RemotePeerID.Pretty() == peerID {
+			return false
+		}
+	}
+	return true
+}
+
 /*func (self *ConnManager) SeedFromDNS(hosts []string, seedFn func(addrs []string)) {
 	addrs := []string{}
 	for _, host := range hosts {
@@ -301,35 +455,40 @@ func (self *ConnManager) handleFailed(peerConn *peer.PeerConn) {
 	seedFn(addrs)
 }*/
 
-func (self *ConnManager) DiscoverPeers(discoverPeerAddress string) {
+/*
+DiscoverPeers - ping bootnodes with our listeners and connect to the returned
+peers, when a bootnode is down the next one of the list is used
+*/
+func (self *ConnManager) DiscoverPeers(discoverPeerAddresses []string) {
 	Logger.log.Info("Start Discover Peers")
+	if len(discoverPeerAddresses) == 0 {
+		return
+	}
 	var client *rpc.Client
 	var err error
+	current := 0
+	discoverPeerAddress := discoverPeerAddresses[current]
 
 listen:
 	for {
 		//Logger.log.Infof("Peers", self.discoveredPeers)
 		if client == nil {
+			discoverPeerAddress = discoverPeerAddresses[current]
 			client, err = rpc.Dial("tcp", discoverPeerAddress)
 			if err != nil {
-				Logger.log.Error("[Exchange Peers] re-connect:")
+				Logger.log.Errorf("[Exchange Peers] re-connect %s:", discoverPeerAddress)
 				Logger.log.Error(err)
+				client = nil
+				current = (current + 1) % len(discoverPeerAddresses)
+				time.Sleep(time.Second * 2)
+				continue
 			}
 		}
 		if client != nil {
 			for _, listener := range self.Config.ListenerPeers {
-				var response []wire.RawPeer
+				var response []server.SignedRawPeer
 
-				var publicKey string
-
-				if listener.Config.ProducerPrvKey != EmptyString {
-					keySet := &cashec.KeySet{}
-					key, _ := wallet.Base58CheckDeserialize(listener.Config.ProducerPrvKey)
-					keySet.ImportFromPrivateKey(&key.KeySet.PrivateKey)
-					if err == nil {
-						publicKey = base58.Base58Check{}.Encode(keySet.PaymentAddress.Pk, byte(0x00))
-					}
-				}
+				publicKey := getProducerPublicKey(listener)
 
 				// remove later
 				rawAddress := listener.RawAddress
@@ -342,14 +501,31 @@ listen:
 					}
 				}
 
-				args := &server.PingArgs{rawAddress, publicKey}
+				args := &server.PingArgs{
+					RawAddress: rawAddress,
+					PublicKey:  publicKey,
+					PeerID:     listener.PeerID.Pretty(),
+					Timestamp:  time.Now().Unix(),
+				}
+				if listener.Config.ProducerSigner != nil {
+					sig, err := listener.Config.ProducerSigner.Sign(signer.SignRequest{
+						Type: signer.SignMessage,
+						Data: server.PingData(args.RawAddress, args.PeerID, args.Timestamp),
+					})
+					if err != nil {
+						Logger.log.Error("[Exchange Peers] Sign ping:")
+						Logger.log.Error(err)
+						continue
+					}
+					args.Signature = base58.Base58Check{}.Encode(sig, byte(0x00))
+				}
 				Logger.log.Infof("[Exchange Peers] Ping", args)
 
 				Logger.log.Info("Dump PeerConns", len(listener.PeerConns))
 				for pubK, info := range self.discoveredPeers {
 					var result []string
 					for _, peerConn := range listener.PeerConns {
-						if peerConn.RemotePeer.PublicKey == pubK {
+						if peerConn.AuthPublicKey() == pubK {
 							result = append(result, peerConn.RemotePeer.PeerID.Pretty())
 						}
 					}
@@ -362,15 +538,24 @@ listen:
 
 				err := client.Call("Handler.Ping", args, &response)
 				if err != nil {
-					Logger.log.Error("[Exchange Peers] Ping:")
+					Logger.log.Errorf("[Exchange Peers] Ping %s:", discoverPeerAddress)
 					Logger.log.Error(err)
+					client.Close()
 					client = nil
+					current = (current + 1) % len(discoverPeerAddresses)
 					time.Sleep(time.Second * 2)
 
 					goto listen
 				}
 				for _, rawPeer := range response {
 					if rawPeer.PublicKey != EmptyString && !strings.Contains(rawPeer.RawAddress, listener.PeerID.String()) {
+						// bootnode could be lying, only trust registrations signed by the producer key
+						err := server.VerifyPing(rawPeer.RawAddress, rawPeer.PublicKey, rawPeer.PeerID, rawPeer.Timestamp, rawPeer.Signature)
+						if err != nil {
+							Logger.log.Errorf("[Exchange Peers] Invalid registration of %s: %+v", rawPeer.PublicKey, err)
+							continue
+						}
+						self.validators.setAddress(rawPeer.PublicKey, rawPeer.RawAddress)
 						_, exist := self.discoveredPeers[rawPeer.PublicKey]
 						//Logger.log.Info("Discovered peer", rawPeer.PaymentAddress, rawPeer.RemoteRawAddress, exist)
 						if !exist {
@@ -395,6 +580,13 @@ listen:
 							}
 
 							self.discoveredPeers[rawPeer.PublicKey] = &DiscoverPeerInfo{rawPeer.PublicKey, rawPeer.RawAddress, peerId}
+							if self.Config.AddrManager != nil {
+								self.Config.AddrManager.AddAddress(&peer.Peer{
+									RawAddress: rawPeer.RawAddress,
+									PublicKey:  rawPeer.PublicKey,
+									PeerID:     peerId,
+								}, discoverPeerAddress)
+							}
 							//Logger.log.Info("Start connect to peer", rawPeer.PaymentAddress, rawPeer.RemoteRawAddress, exist)
 							go self.Connect(rawPeer.RawAddress, rawPeer.PublicKey)
 						} else {
@@ -417,7 +609,8 @@ func (self *ConnManager) getPeerIdsFromPublicKey(pubKey string) []libpeer.ID {
 	for _, listener := range self.Config.ListenerPeers {
 		for _, peerConn := range listener.PeerConns {
 			// Logger.log.Info("Test PeerConn", peerConn.RemotePeer.PaymentAddress)
-			if peerConn.RemotePeer.PublicKey == pubKey {
+			// only a key proven in handshake, a dialed key is unverified
+			if pubKey != EmptyString && peerConn.AuthPublicKey() == pubKey {
 				exist := false
 				for _, item := range result {
 					if item.Pretty() == peerConn.RemotePeer.PeerID.Pretty() {
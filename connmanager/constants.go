@@ -1,5 +1,25 @@
 package connmanager
 
+import "time"
+
 const (
 	EmptyString = ""
+
+	// BanListFile is the file in data dir which keeps banned peers
+	BanListFile = "banned.json"
+	// DefaultBanDuration is how long a misbehaving peer is banned
+	DefaultBanDuration = 24 * time.Hour
+
+	// OutboundConnectInterval is how often missing outbound connections are
+	// filled from the address manager
+	OutboundConnectInterval = 30 * time.Second
+	// MaxOutboundPickTries bounds the addresses picked in one fill round
+	MaxOutboundPickTries = 100
+
+	// ValidatorCheckInterval is how often connections to committee members
+	// are checked
+	ValidatorCheckInterval = 5 * time.Second
+	// ValidatorRetryInterval is the least time between two dials of a
+	// committee member which is not connected
+	ValidatorRetryInterval = 15 * time.Second
 )
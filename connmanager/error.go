@@ -0,0 +1,34 @@
+package connmanager
+
+import "fmt"
+
+const (
+	UnexpectedError = iota
+	BanListFileErr
+)
+
+var ErrCodeMessage = map[int]struct {
+	code    int
+	message string
+}{
+	UnexpectedError: {-1, "Unexpected error"},
+	BanListFileErr:  {-2, "Can not access ban list file"},
+}
+
+type ConnManagerError struct {
+	Code    int
+	Message string
+	Err     error
+}
+
+func (e ConnManagerError) Error() string {
+	return fmt.Sprintf("%d: %s %+v", e.Code, e.Message, e.Err)
+}
+
+func NewConnManagerError(key int, err error) *ConnManagerError {
+	return &ConnManagerError{
+		Code:    ErrCodeMessage[key].code,
+		Message: ErrCodeMessage[key].message,
+		Err:     err,
+	}
+}
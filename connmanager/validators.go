@@ -0,0 +1,155 @@
+package connmanager
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ninjadotorg/constant/peer"
+)
+
+// ValidatorConn is connectivity state of a current or next committee member
+type ValidatorConn struct {
+	PublicKey     string
+	IsCurrent     bool
+	IsNext        bool
+	RawAddress    string
+	PeerIDs       []string
+	Attempts      int // dial attempts since last time it was connected
+	LastAttempt   time.Time
+	LastConnected time.Time
+}
+
+// Connected returns true when at least one peer conn of the validator is alive
+func (self ValidatorConn) Connected() bool {
+	return len(self.PeerIDs) > 0
+}
+
+// validatorTable keeps committee members and the signed addresses they are
+// reachable at, it is shared between discovery and the validator handler
+type validatorTable struct {
+	sync.Mutex
+	validators map[string]*ValidatorConn
+	addresses  map[string]string
+}
+
+func newValidatorTable() *validatorTable {
+	return &validatorTable{
+		validators: make(map[string]*ValidatorConn),
+		addresses:  make(map[string]string),
+	}
+}
+
+func (self *validatorTable) setAddress(publicKey string, rawAddress string) {
+	self.Lock()
+	defer self.Unlock()
+	self.addresses[publicKey] = rawAddress
+}
+
+/*
+getProducerPublicKey - public key of producer key of a listener, empty when
+node does not run with a producer key
+*/
+func getProducerPublicKey(listener *peer.Peer) string {
+	if listener.Config.ProducerSigner == nil {
+		return EmptyString
+	}
+	return listener.Config.ProducerSigner.PublicKey()
+}
+
+/*
+validatorHandler - keep connections to every current and next committee
+member, a validator without connection is redialed every
+ValidatorRetryInterval. It must be run as a goroutine.
+*/
+func (self *ConnManager) validatorHandler() {
+	myPublicKeys := make(map[string]bool)
+	for _, listener := range self.Config.ListenerPeers {
+		publicKey := getProducerPublicKey(listener)
+		if publicKey != EmptyString {
+			myPublicKeys[publicKey] = true
+		}
+	}
+
+	ticker := time.NewTicker(ValidatorCheckInterval)
+	defer ticker.Stop()
+	for {
+		self.connectValidators(myPublicKeys)
+		select {
+		case <-ticker.C:
+		case <-self.cQuit:
+			return
+		}
+	}
+}
+
+func (self *ConnManager) connectValidators(myPublicKeys map[string]bool) {
+	committee := make(map[string]*ValidatorConn)
+	for _, publicKey := range self.Config.Committee.GetCommittee() {
+		if publicKey != EmptyString && !myPublicKeys[publicKey] {
+			committee[publicKey] = &ValidatorConn{PublicKey: publicKey, IsCurrent: true}
+		}
+	}
+	for _, publicKey := range self.Config.Committee.GetNextCommittee() {
+		if publicKey == EmptyString || myPublicKeys[publicKey] {
+			continue
+		}
+		if _, ok := committee[publicKey]; !ok {
+			committee[publicKey] = &ValidatorConn{PublicKey: publicKey}
+		}
+		committee[publicKey].IsNext = true
+	}
+
+	now := time.Now()
+	self.validators.Lock()
+	defer self.validators.Unlock()
+	for publicKey, validator := range committee {
+		if old, ok := self.validators.validators[publicKey]; ok {
+			validator.Attempts = old.Attempts
+			validator.LastAttempt = old.LastAttempt
+			validator.LastConnected = old.LastConnected
+			validator.PeerIDs = old.PeerIDs
+		}
+		wasConnected := validator.Connected()
+		validator.RawAddress = self.validators.addresses[publicKey]
+		if validator.RawAddress == EmptyString && self.Config.AddrManager != nil {
+			// bootnode is unavailable or does not know the validator, use
+			// the address it was connected at before
+			validator.RawAddress = self.Config.AddrManager.GetAddressByPublicKey(publicKey)
+		}
+
+		validator.PeerIDs = []string{}
+		for _, peerID := range self.getPeerIdsFromPublicKey(publicKey) {
+			validator.PeerIDs = append(validator.PeerIDs, peerID.Pretty())
+		}
+		if validator.Connected() {
+			validator.Attempts = 0
+			validator.LastConnected = now
+			continue
+		}
+
+		if wasConnected {
+			Logger.log.Warnf("Validator %s is unreachable", publicKey)
+		}
+		if validator.RawAddress == EmptyString || now.Sub(validator.LastAttempt) < ValidatorRetryInterval {
+			continue
+		}
+		validator.Attempts++
+		validator.LastAttempt = now
+		go self.connect(validator.RawAddress, publicKey, true)
+	}
+	self.validators.validators = committee
+}
+
+/*
+ValidatorConns - connectivity report of current and next committee members
+*/
+func (self *ConnManager) ValidatorConns() []ValidatorConn {
+	self.validators.Lock()
+	defer self.validators.Unlock()
+
+	result := make([]ValidatorConn, 0, len(self.validators.validators))
+	for _, validator := range self.validators.validators {
+		result = append(result, *validator)
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package connmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	libpeer "github.com/libp2p/go-libp2p-peer"
+	"github.com/ninjadotorg/constant/addrmanager"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCommittee struct {
+	current []string
+	next    []string
+}
+
+func (self testCommittee) GetCommittee() []string {
+	return self.current
+}
+
+func (self testCommittee) GetNextCommittee() []string {
+	return self.next
+}
+
+func TestConnectValidators(t *testing.T) {
+	Logger.Init(common.Disabled)
+	dir, err := ioutil.TempDir("", "connmanager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	addrManager := addrmanager.New(dir)
+	// a validator is connected by a key proven in handshake only, a peer
+	// announcing a key of another validator isn't that validator
+	connected := &peer.PeerConn{RemotePeer: &peer.Peer{PeerID: libpeer.ID("connected"), PublicKey: "connected"}}
+	connected.SetAuthPublicKey("connected")
+	impostor := &peer.PeerConn{RemotePeer: &peer.Peer{PeerID: libpeer.ID("impostor"), PublicKey: "discovered"}}
+	listener := &peer.Peer{PeerConns: map[string]*peer.PeerConn{
+		"connected": connected,
+		"impostor":  impostor,
+	}}
+	connManager := ConnManager{}.New(&Config{
+		ListenerPeers: []*peer.Peer{listener},
+		AddrManager:   addrManager,
+		Committee: testCommittee{
+			current: []string{"connected", "discovered", "me"},
+			next:    []string{"discovered", "known"},
+		},
+	})
+	// dials return at once, only their bookkeeping is tested
+	connManager.stop = 1
+
+	// bootnode gave a signed address of one validator, the address of another
+	// is only known from a previous connection
+	connManager.validators.setAddress("discovered", "/ip4/10.0.0.1/tcp/9333/ipfs/discovered")
+	addrManager.Good(&peer.Peer{RawAddress: "/ip4/10.0.0.2/tcp/9333/ipfs/known", PublicKey: "known"})
+
+	connManager.connectValidators(map[string]bool{"me": true})
+	validators := make(map[string]ValidatorConn)
+	for _, validator := range connManager.ValidatorConns() {
+		validators[validator.PublicKey] = validator
+	}
+	assert.Equal(t, 3, len(validators))
+
+	assert.True(t, validators["connected"].Connected())
+	assert.True(t, validators["connected"].IsCurrent)
+	assert.Equal(t, 0, validators["connected"].Attempts)
+
+	assert.False(t, validators["discovered"].Connected())
+	assert.True(t, validators["discovered"].IsCurrent)
+	assert.True(t, validators["discovered"].IsNext)
+	assert.Equal(t, "/ip4/10.0.0.1/tcp/9333/ipfs/discovered", validators["discovered"].RawAddress)
+	assert.Equal(t, 1, validators["discovered"].Attempts)
+
+	assert.False(t, validators["known"].IsCurrent)
+	assert.Equal(t, "/ip4/10.0.0.2/tcp/9333/ipfs/known", validators["known"].RawAddress)
+	assert.Equal(t, 1, validators["known"].Attempts)
+
+	// unreachable validators are not redialed before ValidatorRetryInterval
+	connManager.connectValidators(map[string]bool{"me": true})
+	for _, validator := range connManager.ValidatorConns() {
+		if !validator.Connected() {
+			assert.Equal(t, 1, validator.Attempts)
+		}
+	}
+}
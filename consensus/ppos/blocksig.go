@@ -0,0 +1,268 @@
+package ppos
+
+import (
+	"time"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/common/base58"
+	"github.com/ninjadotorg/constant/privacy-protocol"
+	"github.com/ninjadotorg/constant/signer"
+	"github.com/ninjadotorg/constant/wire"
+)
+
+// signersBitmapSize is number of bytes of a bitmap over committee members
+const signersBitmapSize = (common.TotalValidators + 7) / 8
+
+func setSigner(bitmap []byte, idx int) {
+	bitmap[idx/8] |= 1 << uint(idx%8)
+}
+
+func isSigner(bitmap []byte, idx int) bool {
+	return idx/8 < len(bitmap) && bitmap[idx/8]&(1<<uint(idx%8)) != 0
+}
+
+/*
+signersFromBitmap - public keys and committee indexes of signers, in committee
+order which is the key order of the multi-signature
+*/
+func signersFromBitmap(committee []string, bitmap []byte) ([][]byte, []int, error) {
+	if len(bitmap) != signersBitmapSize {
+		return nil, nil, NewConsensusError(ErrInvalidSignersBitmap, nil)
+	}
+	pubKeys := make([][]byte, 0, len(committee))
+	signers := make([]int, 0, len(committee))
+	for idx := 0; idx < signersBitmapSize*8; idx++ {
+		if !isSigner(bitmap, idx) {
+			continue
+		}
+		if idx >= len(committee) {
+			return nil, nil, NewConsensusError(ErrInvalidSignersBitmap, nil)
+		}
+		pubKey, _, err := base58.Base58Check{}.Decode(committee[idx])
+		if err != nil {
+			return nil, nil, NewConsensusError(ErrInvalidSignersBitmap, err)
+		}
+		pubKeys = append(pubKeys, pubKey)
+		signers = append(signers, idx)
+	}
+	return pubKeys, signers, nil
+}
+
+/*
+multiSignBlock - collect the multi-signature of committee for block proposed
+in round, it returns false when not enough validators reply in time or the
+engine is stopped
+*/
+func (self *Engine) multiSignBlock(block *blockchain.Block, round int) (bool, error) {
+	committee := block.Header.Committee
+	blockHash := block.SigningHash()
+	producerSig, err := self.signData(signer.SignRequest{
+		Type:    signer.SignBlockProposal,
+		ChainID: block.Header.ChainID,
+		Height:  block.Header.Height,
+		Round:   round,
+		Data:    []byte(blockHash.String()),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	// a signer which gave its nonce but no partial signature fails round 2,
+	// both rounds are repeated for the same block without it. Validators which
+	// signed already may sign the same block again with a fresh nonce
+	myIdx := int(block.Header.ChainID)
+	failed := make(map[int]bool)
+	for attempt := 0; attempt < MaxBlockSigAttempts; attempt++ {
+		validators := make([]int, 0, len(committee))
+		for idx := range committee {
+			if idx != myIdx && !failed[idx] {
+				validators = append(validators, idx)
+			}
+		}
+		if len(validators) < common.MinBlockSigs-1 {
+			return false, nil
+		}
+		signed, noPartial, err := self.multiSignRounds(block, producerSig, validators)
+		if err != nil || signed {
+			return signed, err
+		}
+		if len(noPartial) == 0 {
+			// not enough nonces in round 1
+			return false, nil
+		}
+		for _, idx := range noPartial {
+			Logger.log.Info("Validator gave no partial block signature ", committee[idx])
+			failed[idx] = true
+		}
+	}
+	return false, nil
+}
+
+/*
+multiSignRounds - run both rounds of block multi-signature with validators at
+committee indexes, it returns signers which gave their nonce but no partial
+signature when round 2 fails
+*/
+func (self *Engine) multiSignRounds(block *blockchain.Block, producerSig string, validators []int) (bool, []int, error) {
+	committee := block.Header.Committee
+	blockHash := block.SigningHash()
+
+	// Round 1: collect public nonces of validators
+	session := signer.BlockSession{
+		ChainID:   block.Header.ChainID,
+		Height:    block.Header.Height,
+		BlockHash: *blockHash,
+	}
+	myIdx := int(block.Header.ChainID)
+	var err error
+	session.Round, err = self.checkSignedHeight(session.ChainID, session.Height, session.BlockHash)
+	if err != nil {
+		return false, nil, err
+	}
+	myNonce, err := self.config.ProducerSigner.BlockNonce(session)
+	if err != nil {
+		return false, nil, err
+	}
+	nonces := map[int][]byte{myIdx: myNonce}
+
+	nonceReq := &wire.MessageBlockSigReq{
+		Block:       *block,
+		ProducerSig: producerSig,
+	}
+	wantNonces := common.MinBlockSigs - 1
+	_, ok := self.requestBlockSigs(nonceReq, committee, validators, blockHash.String(), wantNonces, func(idx int, msg *wire.MessageBlockSig) bool {
+		nonce, _, err := base58.Base58Check{}.Decode(msg.Nonce)
+		if err != nil || len(nonce) != privacy.MultiSigPublicNonceSize {
+			return false
+		}
+		nonces[idx] = nonce
+		return true
+	})
+	if !ok {
+		return false, nil, nil
+	}
+
+	// Round 2: every signer signs with the aggregated nonce
+	signersBitmap := make([]byte, signersBitmapSize)
+	for idx := range nonces {
+		setSigner(signersBitmap, idx)
+	}
+	pubKeys, signers, err := signersFromBitmap(committee, signersBitmap)
+	if err != nil {
+		return false, nil, err
+	}
+	signerNonces := make([][]byte, len(signers))
+	signerPos := make(map[int]int)
+	otherSigners := make([]int, 0, len(signers))
+	for pos, idx := range signers {
+		signerNonces[pos] = nonces[idx]
+		signerPos[idx] = pos
+		if idx != myIdx {
+			otherSigners = append(otherSigners, idx)
+		}
+	}
+	aggNonce, err := privacy.AggregatePublicNonces(signerNonces)
+	if err != nil {
+		return false, nil, err
+	}
+
+	partials := make([][]byte, len(signers))
+	signReq := &wire.MessageBlockSigReq{
+		Block:         *block,
+		ProducerSig:   producerSig,
+		SignersBitmap: signersBitmap,
+		AggNonce:      base58.Base58Check{}.Encode(aggNonce, byte(0x00)),
+	}
+	received, ok := self.requestBlockSigs(signReq, committee, otherSigners, blockHash.String(), len(otherSigners), func(idx int, msg *wire.MessageBlockSig) bool {
+		partial, _, err := base58.Base58Check{}.Decode(msg.BlockSig)
+		if err != nil {
+			return false
+		}
+		pos := signerPos[idx]
+		if !privacy.MultiSigVerifyPartial(blockHash[:], partial, nonces[idx], pubKeys, pos, aggNonce) {
+			Logger.log.Error("Invalid partial block signature from ", msg.Validator)
+			return false
+		}
+		partials[pos] = partial
+		return true
+	})
+	if !ok {
+		noPartial := make([]int, 0, len(otherSigners))
+		for _, idx := range otherSigners {
+			if !received[idx] {
+				noPartial = append(noPartial, idx)
+			}
+		}
+		return false, noPartial, nil
+	}
+	_, err = self.setSignedHeight(session.ChainID, session.Height, session.BlockHash)
+	if err != nil {
+		return false, nil, err
+	}
+	partials[signerPos[myIdx]], err = self.config.ProducerSigner.SignBlock(session, pubKeys, aggNonce)
+	if err != nil {
+		return false, nil, err
+	}
+
+	aggSig, err := privacy.MultiSigCombine(blockHash[:], partials, pubKeys, aggNonce)
+	if err != nil {
+		return false, nil, err
+	}
+	block.Header.AggregatedSig = base58.Base58Check{}.Encode(aggSig, byte(0x00))
+	block.Header.SignersBitmap = signersBitmap
+	return true, nil, nil
+}
+
+/*
+requestBlockSigs - send request to validators at committee indexes and wait
+until want replies for blockHash are accepted, it returns validators whose
+reply was accepted
+*/
+func (self *Engine) requestBlockSigs(req *wire.MessageBlockSigReq, committee []string, validators []int, blockHash string, want int, accept func(idx int, msg *wire.MessageBlockSig) bool) (map[int]bool, bool) {
+	for _, idx := range validators {
+		// every push sets sender of its message, so each gets a copy
+		go func(validator string, req wire.MessageBlockSigReq) {
+			peerIDs := self.config.Server.GetPeerIDsFromPublicKey(validator)
+			if len(peerIDs) != 0 {
+				Logger.log.Info("Request signature from "+peerIDs[0], validator)
+				self.config.Server.PushMessageToPeer(&req, peerIDs[0])
+			} else {
+				Logger.log.Error("Validator's peer not found!", validator)
+			}
+		}(committee[idx], *req)
+	}
+
+	expected := make(map[int]bool)
+	for _, idx := range validators {
+		expected[idx] = true
+	}
+	received := make(map[int]bool)
+	timeout := self.config.Clock.After(common.MaxBlockSigWaitTime * time.Second)
+	for len(received) < want {
+		select {
+		case <-self.cQuit:
+			return received, false
+		case <-self.cQuitProducer:
+			return received, false
+		case <-timeout:
+			return received, false
+		case msg, ok := <-self.cBlockSig:
+			if !ok {
+				return received, false
+			}
+			if msg.BlockHash != blockHash {
+				continue
+			}
+			idx := common.IndexOfStr(msg.Validator, committee)
+			if !expected[idx] || received[idx] {
+				continue
+			}
+			if accept(idx, msg) {
+				received[idx] = true
+				Logger.log.Info("Validator's signature received", len(received))
+			}
+		}
+	}
+	return received, true
+}
@@ -0,0 +1,142 @@
+package ppos
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	peer2 "github.com/libp2p/go-libp2p-peer"
+	"github.com/ninjadotorg/constant/cashec"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/common/base58"
+	"github.com/ninjadotorg/constant/signer"
+	"github.com/ninjadotorg/constant/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+testSigServer answers block signature requests of engine for validators of the
+test committee, a validator of noPartial gives its nonce but never its partial
+signature, validators of late reply only once a validator of noPartial was
+asked for its partial signature and validators of silent never reply
+*/
+type testSigServer struct {
+	engine    *Engine
+	signers   map[string]*signer.LocalSigner
+	noPartial map[string]bool
+	late      map[string]bool
+	silent    map[string]bool
+
+	mtx         sync.Mutex
+	partialReqs map[string]int
+}
+
+func newTestSigServer(engine *Engine, committee []string) *testSigServer {
+	server := &testSigServer{
+		engine:      engine,
+		signers:     make(map[string]*signer.LocalSigner),
+		noPartial:   make(map[string]bool),
+		late:        make(map[string]bool),
+		silent:      make(map[string]bool),
+		partialReqs: make(map[string]int),
+	}
+	for idx, pubKey := range committee {
+		keySet := cashec.KeySet{}
+		keySet.GenerateKey([]byte(fmt.Sprintf("rotation-%d", idx)))
+		protection, _ := signer.NewProtection(common.EmptyString)
+		server.signers[pubKey] = signer.NewLocalSigner(keySet, protection)
+	}
+	return server
+}
+
+func (self *testSigServer) GetPeerIDsFromPublicKey(pubKey string) []peer2.ID {
+	return []peer2.ID{peer2.ID(pubKey)}
+}
+
+func (self *testSigServer) PushMessageToAll(msg wire.Message) error {
+	return nil
+}
+
+func (self *testSigServer) PushMessageGetChainState() error {
+	return nil
+}
+
+func (self *testSigServer) PushMessageToPeer(msg wire.Message, peerID peer2.ID) error {
+	req := msg.(*wire.MessageBlockSigReq)
+	validator := string(peerID)
+	blockHash := req.Block.SigningHash()
+	session := signer.BlockSession{
+		ChainID:   req.Block.Header.ChainID,
+		Height:    req.Block.Header.Height,
+		BlockHash: *blockHash,
+	}
+	reply := &wire.MessageBlockSig{Validator: validator, BlockHash: blockHash.String()}
+
+	self.mtx.Lock()
+	late := self.late[validator] && len(self.partialReqs) == 0
+	dropped := !req.IsNonceRequest() && self.noPartial[validator]
+	if dropped {
+		self.partialReqs[validator]++
+	}
+	self.mtx.Unlock()
+	if self.silent[validator] || late || dropped {
+		return nil
+	}
+
+	if req.IsNonceRequest() {
+		nonce, err := self.signers[validator].BlockNonce(session)
+		if err != nil {
+			return err
+		}
+		reply.Nonce = base58.Base58Check{}.Encode(nonce, byte(0x00))
+	} else {
+		pubKeys, _, err := signersFromBitmap(req.Block.Header.Committee, req.SignersBitmap)
+		if err != nil {
+			return err
+		}
+		aggNonce, _, err := base58.Base58Check{}.Decode(req.AggNonce)
+		if err != nil {
+			return err
+		}
+		partial, err := self.signers[validator].SignBlock(session, pubKeys, aggNonce)
+		if err != nil {
+			return err
+		}
+		reply.BlockSig = base58.Base58Check{}.Encode(partial, byte(0x00))
+	}
+	self.engine.cBlockSig <- reply
+	return nil
+}
+
+func TestMultiSignBlock_NoPartial(t *testing.T) {
+	engine, cleanup := newTestEngine(t)
+	defer cleanup()
+	committee := engine.GetCommittee()
+	server := newTestSigServer(engine, committee)
+	engine.config.Server = server
+	engine.config.ProducerSigner = server.signers[committee[0]]
+	engine.cBlockSig = make(chan *wire.MessageBlockSig, 2*common.TotalValidators*MaxBlockSigAttempts)
+
+	// validators 1 and 2 are signers of round 1 with 3-10, they drop out
+	// before round 2 and 11, 12 take their place in a new attempt
+	server.noPartial[committee[1]] = true
+	server.noPartial[committee[2]] = true
+	server.late[committee[11]] = true
+	server.late[committee[12]] = true
+	for idx := 13; idx < common.TotalValidators; idx++ {
+		server.silent[committee[idx]] = true
+	}
+
+	block := newTestBlock(engine.config.BlockChain.BestState[0].BestBlock, committee)
+	signed, err := engine.multiSignBlock(block, 1)
+	assert.Nil(t, err)
+	if !assert.True(t, signed) {
+		return
+	}
+	assert.Equal(t, 1, server.partialReqs[committee[1]])
+	assert.Equal(t, 1, server.partialReqs[committee[2]])
+	_, signers, err := signersFromBitmap(committee, block.Header.SignersBitmap)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, signers)
+	assert.Nil(t, engine.ValidateCommitteeSigs(block.SigningHash()[:], committee, block.Header.AggregatedSig, block.Header.SignersBitmap))
+}
@@ -2,13 +2,11 @@ package ppos
 
 import (
 	"errors"
-	"fmt"
 	"time"
 
-	"encoding/binary"
-
 	"github.com/ninjadotorg/constant/common"
 	"github.com/ninjadotorg/constant/common/base58"
+	"github.com/ninjadotorg/constant/signer"
 )
 
 func (self *Engine) GetCommittee() []string {
@@ -19,17 +17,22 @@ func (self *Engine) GetCommittee() []string {
 	return committee
 }
 
-func (self *Engine) CheckCandidate(candidate string) error {
-	return nil
+// GetNextCommittee returns the best candidates, which are the members of next
+// committee
+func (self *Engine) GetNextCommittee() []string {
+	candidates := self.config.BlockChain.GetCommitteeCandidateList()
+	if len(candidates) > common.TotalValidators {
+		candidates = candidates[:common.TotalValidators]
+	}
+	return candidates
 }
 
-func (self *Engine) CheckCommittee(committee []string, blockHeight int, chainID byte) bool {
-
-	return true
+func (self *Engine) CheckCandidate(candidate string) error {
+	return nil
 }
 
-func (self *Engine) signData(data []byte) (string, error) {
-	signatureByte, err := self.config.ProducerKeySet.Sign(data)
+func (self *Engine) signData(req signer.SignRequest) (string, error) {
+	signatureByte, err := self.config.ProducerSigner.Sign(req)
 	if err != nil {
 		return common.EmptyString, errors.New("Can't sign data. " + err.Error())
 	}
@@ -38,8 +41,7 @@ func (self *Engine) signData(data []byte) (string, error) {
 
 // getMyChain validator chainID and committee of that chainID
 func (self *Engine) getMyChain() byte {
-	pbk := base58.Base58Check{}.Encode(self.config.ProducerKeySet.PaymentAddress.Pk, byte(0x00))
-	return self.getChainIdByPbk(pbk)
+	return self.getChainIdByPbk(self.config.ProducerSigner.PublicKey())
 }
 
 func (self *Engine) getChainIdByPbk(pbk string) byte {
@@ -47,55 +49,71 @@ func (self *Engine) getChainIdByPbk(pbk string) byte {
 	return byte(common.IndexOfStr(pbk, committee))
 }
 
-func (committee *committeeStruct) UpdateCommitteePoint(chainLeader string, validatorSig []string) {
+/*
+UpdateCommitteePoint - count a block of chainLeader and give reliability points
+to its signers, every known validator loses SigPointMin. It returns the points
+given by the block.
+*/
+func (committee *committeeStruct) UpdateCommitteePoint(chainLeader string, signersBitmap []byte) map[string]int {
 	committee.Lock()
 	defer committee.Unlock()
-	committee.ValidatorBlkNum[chainLeader]++
-	committee.ValidatorReliablePts[chainLeader] += BlkPointAdd
-	for idx, sig := range validatorSig {
-		if sig != common.EmptyString {
-			committee.ValidatorReliablePts[committee.CurrentCommittee[idx]] += SigPointAdd
+	points := make(map[string]int)
+	points[chainLeader] += BlkPointAdd
+	for idx, validator := range committee.CurrentCommittee {
+		if isSigner(signersBitmap, idx) {
+			points[validator] += SigPointAdd
 		}
 	}
 	for validator := range committee.ValidatorReliablePts {
-		committee.ValidatorReliablePts[validator] += SigPointMin
+		if _, ok := points[validator]; !ok {
+			points[validator] = 0
+		}
+	}
+	for validator := range points {
+		points[validator] += SigPointMin
+		committee.ValidatorReliablePts[validator] += points[validator]
 	}
+	committee.ValidatorBlkNum[chainLeader]++
+	return points
 }
 
+/*
+StartCommitteeWatcher - every MaxBlockTime look for chain leaders which are
+swappable and request their swap
+*/
 func (self *Engine) StartCommitteeWatcher() {
+	self.committee.Lock()
 	if self.committee.cmWatcherStarted {
+		self.committee.Unlock()
 		Logger.log.Error("Producer already started")
 		return
 	}
 	self.committee.cmWatcherStarted = true
+	// a watcher stops for good, every start gets its own quit channel
+	cQuit := make(chan struct{})
+	self.cQuitCommitteeWatcher = cQuit
+	cNewBlock := self.cNewBlock
+	self.committee.Unlock()
 	Logger.log.Info("Committee watcher started")
 	for {
 		select {
-		case <-self.cQuitCommitteeWatcher:
+		case <-cQuit:
 			Logger.log.Info("Committee watcher stopped")
 			return
-		case _ = <-self.cNewBlock:
-
-		case <-time.After(common.MaxBlockTime * time.Second):
-			self.committee.Lock()
-			myPubKey := base58.Base58Check{}.Encode(self.config.ProducerKeySet.PaymentAddress.Pk, byte(0x00))
-			fmt.Println(myPubKey, common.IndexOfStr(myPubKey, self.committee.CurrentCommittee))
-			if common.IndexOfStr(myPubKey, self.committee.CurrentCommittee) != -1 {
-				for idx := 0; idx < common.TotalValidators && self.committee.CurrentCommittee[idx] != myPubKey; idx++ {
-					blkTime := time.Since(time.Unix(self.config.BlockChain.BestState[idx].BestBlock.Header.Timestamp, 0))
-					fmt.Println(blkTime)
-					if blkTime > common.MaxBlockTime*time.Second {
+		case _ = <-cNewBlock:
 
-					}
-				}
+		case <-self.config.Clock.After(common.MaxBlockTime * time.Second):
+			myChainID := common.IndexOfStr(self.config.ProducerSigner.PublicKey(), self.GetCommittee())
+			if myChainID != -1 {
+				self.checkOfflineLeaders(byte(myChainID))
 			}
-
-			self.committee.Unlock()
 		}
 	}
 }
 
 func (self *Engine) StopCommitteeWatcher() {
+	self.committee.Lock()
+	defer self.committee.Unlock()
 	if self.committee.cmWatcherStarted {
 		Logger.log.Info("Stopping Committee watcher...")
 		close(self.cQuitCommitteeWatcher)
@@ -117,23 +135,8 @@ func (self *Engine) updateCommittee(producerPbk string, chanId byte) error {
 	currentCommittee := make([]string, common.TotalValidators)
 	currentCommittee = append(committee[:chanId], producerPbk)
 	currentCommittee = append(currentCommittee, committee[chanId+1:]...)
+	// producerPbk stays a candidate, swaps skip members of committee and
+	// its swap is verified again from the candidate list by other nodes
 	self.committee.CurrentCommittee = currentCommittee
-	//remove producerPbk from candidate list
-	for chainId, bestState := range self.config.BlockChain.BestState {
-		bestState.RemoveCandidate(producerPbk)
-		self.config.BlockChain.StoreBestState(byte(chainId))
-	}
-
 	return nil
 }
-
-func (self *Engine) getRawBytesForSwap(lockTime int64, requesterPbk string, chainId byte, producerPbk string) []byte {
-	rawBytes := []byte{}
-	bTime := make([]byte, 8)
-	binary.LittleEndian.PutUint64(bTime, uint64(lockTime))
-	rawBytes = append(rawBytes, bTime...)
-	rawBytes = append(rawBytes, []byte(requesterPbk)...)
-	rawBytes = append(rawBytes, chainId)
-	rawBytes = append(rawBytes, []byte(producerPbk)...)
-	return rawBytes
-}
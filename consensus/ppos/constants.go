@@ -1,8 +1,25 @@
 package ppos
 
+import "github.com/ninjadotorg/constant/common"
+
 const (
 	SigPointAdd = 1
 	SigPointMin = -1
 	BlkPointAdd = 5
 	BlkPointMin = -5
+
+	DoubleSignPointMin   = -100 // reliability points lost by a double signing validator
+	MaxEvidencesPerBlock = 10
+
+	RotationWindow       = 10 // blocks of every chain counted for reliability of a swap
+	RotationBeaconBlocks = 5  // block hashes of anchor chain mixed into the swap beacon
+	MinSwapPoints        = 0  // a committee member below this is swapped out
+	MaxAppliedSwaps      = 16 // applied swaps kept to be recorded in blocks, it is also the limit per block
+
+	MaxMissedSlots = 10 // slots a chain leader may miss in a row before it is swapped out
+
+	GetBlocksInterval = 1 // seconds between requests of a producer for blocks of chains which are behind
+
+	MaxBlockSigAttempts = 3                              // times a block is multi-signed again without signers which gave no partial signature
+	SignedHeightTimeout = 4 * common.MaxBlockSigWaitTime // seconds after a partial signature until another block of the height may be signed
 )
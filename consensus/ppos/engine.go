@@ -6,10 +6,10 @@ import (
 	"sync"
 	"time"
 
-	"github.com/ninjadotorg/constant/cashec"
 	"github.com/ninjadotorg/constant/common"
-	"github.com/ninjadotorg/constant/common/base58"
+	"github.com/ninjadotorg/constant/database"
 	"github.com/ninjadotorg/constant/mempool"
+	"github.com/ninjadotorg/constant/signer"
 
 	peer2 "github.com/libp2p/go-libp2p-peer"
 	"github.com/ninjadotorg/constant/blockchain"
@@ -23,12 +23,15 @@ type Engine struct {
 	sync.Mutex
 	started         bool
 	producerStarted bool
-	committeeMutex  sync.Mutex
+	// producerMtx guards producerStarted and channels of the producer, message
+	// handlers read them while the producer starts and stops
+	producerMtx    sync.Mutex
+	committeeMutex sync.Mutex
 
 	// channel
 	cQuit                 chan struct{}
 	cQuitProducer         chan struct{}
-	cBlockSig             chan blockSig
+	cBlockSig             chan *wire.MessageBlockSig
 	cQuitSwap             chan struct{}
 	cSwapChain            chan byte
 	cSwapSig              chan swapSig
@@ -40,12 +43,22 @@ type Engine struct {
 	validatedChainsHeight chainsHeight
 
 	committee committeeStruct
+
+	evidences        evidencePool
+	signedHeights    map[byte]signedHeight
+	signedHeightsMtx sync.Mutex
+
+	// last block proposal of our chain, see nextProposalRound
+	proposal proposalRound
+
+	liveness livenessStruct
 }
 
 type committeeStruct struct {
 	ValidatorBlkNum      map[string]int //track the number of block created by each validator
 	ValidatorReliablePts map[string]int //track how reliable is the validator node
 	CurrentCommittee     []string
+	AppliedSwaps         []blockchain.CommitteeSwap
 	cmWatcherStarted     bool
 	sync.Mutex
 	LastUpdate           int64
@@ -62,6 +75,21 @@ type chainsHeight struct {
 	sync.Mutex
 }
 
+// get returns a copy of heights of chains
+func (self *chainsHeight) get() []int {
+	self.Lock()
+	defer self.Unlock()
+	heights := make([]int, len(self.Heights))
+	copy(heights, self.Heights)
+	return heights
+}
+
+type proposalRound struct {
+	Height int32
+	Round  int
+	sync.Mutex
+}
+
 type EngineConfig struct {
 	BlockChain  *blockchain.BlockChain
 	ConnManager *connmanager.ConnManager
@@ -69,8 +97,9 @@ type EngineConfig struct {
 	ChainParams    *blockchain.Params
 	BlockGen       *blockchain.BlkTmplGenerator
 	MemPool        *mempool.TxPool
-	ProducerKeySet cashec.KeySet
-	Server interface {
+	// ProducerSigner holds producer key, it is set by StartProducer
+	ProducerSigner signer.Signer
+	Server         interface {
 		// list functions callback which are assigned from Server struct
 		GetPeerIDsFromPublicKey(string) []peer2.ID
 		PushMessageToAll(wire.Message) error
@@ -78,11 +107,9 @@ type EngineConfig struct {
 		PushMessageGetChainState() error
 	}
 	FeeEstimator map[byte]*mempool.FeeEstimator
-}
-
-type blockSig struct {
-	Validator string
-	BlockSig  string
+	DataBase     database.DatabaseInterface
+	// Clock is the time of rounds, waits and timeouts, the wall clock when nil
+	Clock common.Clock
 }
 
 type swapSig struct {
@@ -92,9 +119,15 @@ type swapSig struct {
 
 //Init apply configuration to consensus engine
 func (self Engine) Init(cfg *EngineConfig) (*Engine, error) {
+	if cfg.Clock == nil {
+		cfg.Clock = common.SystemClock{}
+	}
 	return &Engine{
 		committeeMutex: sync.Mutex{},
 		config:         *cfg,
+		evidences:      newEvidencePool(),
+		signedHeights:  make(map[byte]signedHeight),
+		liveness:       newLiveness(),
 	}, nil
 }
 
@@ -112,9 +145,10 @@ func (self *Engine) Start() error {
 	self.committee.ValidatorBlkNum = make(map[string]int)
 	self.committee.ValidatorReliablePts = make(map[string]int)
 	self.committee.CurrentCommittee = make([]string, common.TotalValidators)
+	self.committee.AppliedSwaps = make([]blockchain.CommitteeSwap, 0)
 
 	for chainID := 0; chainID < common.TotalValidators; chainID++ {
-		self.knownChainsHeight.Heights[chainID] = int(self.config.BlockChain.BestState[chainID].Height)
+		self.knownChainsHeight.Heights[chainID] = int(self.config.BlockChain.BestSnapshot(byte(chainID)).Height)
 		self.validatedChainsHeight.Heights[chainID] = 1
 	}
 
@@ -123,17 +157,25 @@ func (self *Engine) Start() error {
 
 	if _, ok := self.config.FeeEstimator[0]; !ok {
 		// happen when FastMode = false
+		err := self.config.DataBase.CleanValidatorLiveness()
+		if err != nil {
+			return err
+		}
+		err = self.config.DataBase.CleanEvidences()
+		if err != nil {
+			return err
+		}
 		validatedChainsHeight := make([]int, common.TotalValidators)
 		var wg sync.WaitGroup
-		errCh := make(chan error)
+		// every chain sends at most one error, they are read after wg.Wait
+		errCh := make(chan error, common.TotalValidators)
 		for chainID := byte(0); chainID < common.TotalValidators; chainID++ {
 			//Don't validate genesis block (blockHeight = 1)
 			validatedChainsHeight[chainID] = 1
 			self.config.FeeEstimator[chainID] = mempool.NewFeeEstimator(
-				mempool.DefaultEstimateFeeMaxRollback,
 				mempool.DefaultEstimateFeeMinRegisteredBlocks)
+			wg.Add(1)
 			go func(chainID byte) {
-				wg.Add(1)
 				var err error
 				defer func() {
 					wg.Done()
@@ -161,19 +203,18 @@ func (self *Engine) Start() error {
 						Logger.log.Error(err)
 						return
 					}
-					err = self.config.FeeEstimator[block.Header.ChainID].RegisterBlock(block)
-					if err != nil {
-						Logger.log.Error(err)
-						return
-					}
+					self.config.FeeEstimator[block.Header.ChainID].RegisterBlock(block)
 					self.validatedChainsHeight.Lock()
 					self.validatedChainsHeight.Heights[chainID] = blockHeight
 					self.validatedChainsHeight.Unlock()
-					self.committee.UpdateCommitteePoint(block.BlockProducer, block.Header.BlockCommitteeSigs)
+					points := self.committee.UpdateCommitteePoint(block.BlockProducer, block.Header.SignersBitmap)
+					self.updateLiveness(block, points)
+					self.applyEvidences(block)
+					self.applyBlockSwaps(block)
 				}
+				err = self.storeLiveness(chainID)
 			}(chainID)
 		}
-		time.Sleep(1000 * time.Millisecond)
 		wg.Wait()
 		select {
 		case err := <-errCh:
@@ -183,15 +224,21 @@ func (self *Engine) Start() error {
 		}
 	} else {
 		copy(self.validatedChainsHeight.Heights, self.knownChainsHeight.Heights)
+		self.loadLiveness()
 	}
 
 	self.started = true
 	self.cQuit = make(chan struct{})
 
+	cQuit := self.cQuit
 	go func() {
 		for {
 			self.config.Server.PushMessageGetChainState()
-			time.Sleep(common.GetChainStateInterval * time.Second)
+			select {
+			case <-cQuit:
+				return
+			case <-self.config.Clock.After(common.GetChainStateInterval * time.Second):
+			}
 		}
 	}()
 
@@ -212,42 +259,52 @@ func (self *Engine) Stop() error {
 		close(self.cQuitSwap)
 	}
 	close(self.cQuit)
+	for chainID := byte(0); chainID < common.TotalValidators; chainID++ {
+		err := self.storeLiveness(chainID)
+		if err != nil {
+			Logger.log.Errorf("Can't save validator liveness on chain #%d: %v", chainID, err)
+		}
+	}
 	self.started = false
 	Logger.log.Info("Consensus engine stopped")
 	return nil
 }
 
 //StartProducer start producing block
-func (self *Engine) StartProducer(producerKeySet cashec.KeySet) {
+func (self *Engine) StartProducer(producerSigner signer.Signer) {
+	self.producerMtx.Lock()
+	defer self.producerMtx.Unlock()
 	if self.producerStarted {
 		Logger.log.Error("Producer already started")
 		return
 	}
 
-	self.config.ProducerKeySet = producerKeySet
+	self.config.ProducerSigner = producerSigner
 
 	self.cQuitProducer = make(chan struct{})
-	self.cQuitCommitteeWatcher = make(chan struct{})
-	self.cBlockSig = make(chan blockSig)
+	self.cBlockSig = make(chan *wire.MessageBlockSig)
 	self.cNewBlock = make(chan blockchain.Block)
 
 	self.producerStarted = true
-	Logger.log.Info("Starting producer with public key: " + base58.Base58Check{}.Encode(self.config.ProducerKeySet.PaymentAddress.Pk, byte(0x00)))
+	Logger.log.Info("Starting producer with public key: " + self.config.ProducerSigner.PublicKey())
 
+	cQuitProducer := self.cQuitProducer
 	go func() {
 		for {
 			select {
-			case <-self.cQuitProducer:
+			case <-cQuitProducer:
 				return
 			default:
-				if self.started {
-					if common.IntArrayEquals(self.knownChainsHeight.Heights, self.validatedChainsHeight.Heights) {
+				if self.isStarted() {
+					knownChainsHeight := self.knownChainsHeight.get()
+					validatedChainsHeight := self.validatedChainsHeight.get()
+					if common.IntArrayEquals(knownChainsHeight, validatedChainsHeight) {
 						chainID := self.getMyChain()
 						if chainID >= 0 && chainID < common.TotalValidators {
 							go self.StartCommitteeWatcher()
 							Logger.log.Info("(๑•̀ㅂ•́)و Yay!! It's my turn")
 							Logger.log.Info("Current chainsHeight")
-							Logger.log.Info(self.validatedChainsHeight.Heights)
+							Logger.log.Info(validatedChainsHeight)
 							Logger.log.Info("My chainID: ", chainID)
 
 							newBlock, err := self.createBlock()
@@ -258,14 +315,14 @@ func (self *Engine) StartProducer(producerKeySet cashec.KeySet) {
 							err = self.Finalize(newBlock)
 							if err != nil {
 								Logger.log.Critical(err)
-								continue
 							}
+							continue
 						}
 					} else {
 						self.StopCommitteeWatcher()
-						for i, v := range self.knownChainsHeight.Heights {
-							if v > self.validatedChainsHeight.Heights[i] {
-								lastBlockHash := self.config.BlockChain.BestState[i].BestBlockHash.String()
+						for i, v := range knownChainsHeight {
+							if v > validatedChainsHeight[i] {
+								lastBlockHash := self.config.BlockChain.BestSnapshot(byte(i)).BestBlockHash.String()
 								getBlkMsg := &wire.MessageGetBlocks{
 									LastBlockHash: lastBlockHash,
 								}
@@ -274,6 +331,12 @@ func (self *Engine) StartProducer(producerKeySet cashec.KeySet) {
 						}
 					}
 				}
+				// it is not our turn, blocks of other chains come in meanwhile
+				select {
+				case <-cQuitProducer:
+					return
+				case <-self.config.Clock.After(GetBlocksInterval * time.Second):
+				}
 			}
 		}
 	}()
@@ -281,135 +344,110 @@ func (self *Engine) StartProducer(producerKeySet cashec.KeySet) {
 
 // StopProducer stop producer
 func (self *Engine) StopProducer() {
+	self.producerMtx.Lock()
+	defer self.producerMtx.Unlock()
 	if self.producerStarted {
 		Logger.log.Info("Stopping Producer...")
 		close(self.cQuitProducer)
-		close(self.cBlockSig)
 		self.StopCommitteeWatcher()
 		self.producerStarted = false
 	}
 }
 
+// isStarted returns whether the engine is running
+func (self *Engine) isStarted() bool {
+	self.Lock()
+	defer self.Unlock()
+	return self.started
+}
+
+// isProducerStarted returns whether the producer is running
+func (self *Engine) isProducerStarted() bool {
+	self.producerMtx.Lock()
+	defer self.producerMtx.Unlock()
+	return self.producerStarted
+}
+
 func (self *Engine) createBlock() (*blockchain.Block, error) {
 	Logger.log.Info("Start creating block...")
 	myChainID := self.getMyChain()
-	paymentAddress, err := self.config.ProducerKeySet.GetPaymentAddress()
-	newblock, err := self.config.BlockGen.NewBlockTemplate(paymentAddress, myChainID)
+	newblock, err := self.config.BlockGen.NewBlockTemplate(self.config.ProducerSigner.PaymentAddress(), myChainID)
 	if err != nil {
 		return &blockchain.Block{}, err
 	}
 	newblock.Header.ChainsHeight = make([]int, common.TotalValidators)
-	copy(newblock.Header.ChainsHeight, self.validatedChainsHeight.Heights)
+	copy(newblock.Header.ChainsHeight, self.validatedChainsHeight.get())
 	newblock.Header.ChainID = myChainID
-	newblock.BlockProducer = base58.Base58Check{}.Encode(self.config.ProducerKeySet.PaymentAddress.Pk, byte(0x00))
+	newblock.BlockProducer = self.config.ProducerSigner.PublicKey()
+	newblock.Evidences = self.pendingEvidences(MaxEvidencesPerBlock)
 
 	return newblock, nil
 }
 
+/*
+nextProposalRound - round of a new proposal of our block at height, so the
+signer accepts a proposal which replaces an unsigned one. Rounds are seconds
+of proposals, they keep growing when the node restarts while the height of
+our chain doesn't move.
+*/
+func (self *Engine) nextProposalRound(height int32) int {
+	self.proposal.Lock()
+	defer self.proposal.Unlock()
+	round := int(self.config.Clock.Now().Unix())
+	if self.proposal.Height == height && round <= self.proposal.Round {
+		round = self.proposal.Round + 1
+	}
+	self.proposal.Height = height
+	self.proposal.Round = round
+	return round
+}
+
 // Finalize after successfully create a block we will send this block to other validators to get their signatures
 func (self *Engine) Finalize(finalBlock *blockchain.Block) error {
 	Logger.log.Info("Start finalizing block...")
-	allSigReceived := make(chan struct{})
 	retryTime := 0
-	cancel := make(chan struct{})
-	defer func() {
-		close(cancel)
-		close(allSigReceived)
-	}()
 finalizing:
-	finalBlock.Header.BlockCommitteeSigs = make([]string, common.TotalValidators)
 	finalBlock.Header.Committee = make([]string, common.TotalValidators)
-
 	copy(finalBlock.Header.Committee, self.GetCommittee())
-	sig, err := self.signData([]byte(finalBlock.Hash().String()))
+	prevBlock, err := self.config.BlockChain.GetBlockByBlockHash(&finalBlock.Header.PrevBlockHash)
+	if err != nil {
+		return err
+	}
+	finalBlock.Header.CommitteeSwaps, err = self.committeeSwaps(prevBlock.Header.Committee, finalBlock.Header.Committee)
 	if err != nil {
 		return err
 	}
-	finalBlock.Header.BlockCommitteeSigs[finalBlock.Header.ChainID] = sig
-
-	committee := finalBlock.Header.Committee
-
-	// Collect signatures of other validators
-	go func(blockHash string) {
-		sigsReceived := 0
-		for {
-			select {
-			case <-self.cQuit:
-				return
-			case <-cancel:
-				return
-			case blocksig := <-self.cBlockSig:
-
-				if idx := common.IndexOfStr(blocksig.Validator, committee); idx != -1 {
-					if finalBlock.Header.BlockCommitteeSigs[idx] == common.EmptyString {
-						err := cashec.ValidateDataB58(blocksig.Validator, blocksig.BlockSig, []byte(blockHash))
-
-						if err != nil {
-							Logger.log.Error("ValidateTransaction sig error:", err)
-							continue
-						} else {
-							sigsReceived++
-							finalBlock.Header.BlockCommitteeSigs[idx] = blocksig.BlockSig
-							Logger.log.Info("Validator's signature received", sigsReceived)
-						}
-					} else {
-						Logger.log.Error("Already received this validator blocksig")
-					}
-				}
 
-				if sigsReceived == (common.MinBlockSigs - 1) {
-					allSigReceived <- struct{}{}
-					return
-				}
-			}
-		}
-	}(finalBlock.Hash().String())
-
-	//Request for signatures of other validators
-	go func(block blockchain.Block) {
-		//TODO Uncomment this segment to create block with only 1 node (validator)
-		allSigReceived <- struct{}{}
-		// end TODO
-
-		reqSigMsg, _ := wire.MakeEmptyMessage(wire.CmdBlockSigReq)
-		reqSigMsg.(*wire.MessageBlockSigReq).Block = block
-		for idx := 0; idx < common.TotalValidators; idx++ {
-			//@TODO: retry on failed validators
-			if committee[idx] != finalBlock.BlockProducer {
-				go func(validator string) {
-					peerIDs := self.config.Server.GetPeerIDsFromPublicKey(validator)
-					if len(peerIDs) != 0 {
-						Logger.log.Info("Request signature from "+peerIDs[0], validator)
-						self.config.Server.PushMessageToPeer(reqSigMsg, peerIDs[0])
-					} else {
-						Logger.log.Error("Validator's peer not found!", validator)
-					}
-				}(committee[idx])
-			}
+	round := self.nextProposalRound(finalBlock.Header.Height)
+	signed, err := self.multiSignBlock(finalBlock, round)
+	if err != nil {
+		return err
+	}
+	if !signed {
+		select {
+		case <-self.cQuit:
+			return nil
+		default:
 		}
-	}(*finalBlock)
-	// Wait for signatures of other validators
-	select {
-	case <-self.cQuit:
-		cancel <- struct{}{}
-		return nil
-	case <-allSigReceived:
-		Logger.log.Info("Validator sigs: ", finalBlock.Header.BlockCommitteeSigs)
-	case <-time.After(common.MaxBlockSigWaitTime * time.Second):
 		//blocksig wait time exceeded -> get a new committee list and retry
 		Logger.log.Error(ErrExceedSigWaitTime)
 		if retryTime == 5 {
-			cancel <- struct{}{}
 			return NewConsensusError(ErrExceedBlockRetry, nil)
 		}
 		retryTime++
 		Logger.log.Infof("Start finalizing block... %d time", retryTime)
-		cancel <- struct{}{}
 		goto finalizing
 	}
+	Logger.log.Info("Validator sigs: ", finalBlock.Header.AggregatedSig)
 
 	headerBytes, _ := json.Marshal(finalBlock.Header)
-	sig, err = self.signData(headerBytes)
+	sig, err := self.signData(signer.SignRequest{
+		Type:    signer.SignBlockHeader,
+		ChainID: finalBlock.Header.ChainID,
+		Height:  finalBlock.Header.Height,
+		Round:   round,
+		Data:    headerBytes,
+	})
 	if err != nil {
 		return err
 	}
@@ -427,24 +465,28 @@ func (self *Engine) UpdateChain(block *blockchain.Block) {
 		return
 	}
 	// save block into fee estimator
-	err = self.config.FeeEstimator[block.Header.ChainID].RegisterBlock(block)
-	if err != nil {
-		Logger.log.Error(err)
-		return
+	if feeEstimator, ok := self.config.FeeEstimator[block.Header.ChainID]; ok && feeEstimator != nil {
+		feeEstimator.RegisterBlock(block)
 	}
 
 	// update tx pool
-	for _, tx := range block.Transactions {
-		self.config.MemPool.RemoveTx(tx)
-	}
+	self.config.MemPool.RemoveBlockTxs(block)
 
 	// update candidate list
-	err = self.config.BlockChain.BestState[block.Header.ChainID].Update(block)
+	err = self.config.BlockChain.UpdateBestState(block)
 	if err != nil {
 		Logger.log.Errorf("Can not update merkle tree for block: %+v", err)
 		return
 	}
-	self.config.BlockChain.StoreBestState(block.Header.ChainID)
+
+	// expire old txs and drop txs which are invalid with the new best state,
+	// orphans accepted after their parents in block are relayed
+	for _, txDesc := range self.config.MemPool.OnBlockConnected(block) {
+		err := self.config.Server.PushMessageToAll(&wire.MessageTx{Transaction: txDesc.Desc.Tx})
+		if err != nil {
+			Logger.log.Error(err)
+		}
+	}
 
 	self.knownChainsHeight.Lock()
 	if self.knownChainsHeight.Heights[block.Header.ChainID] < int(block.Header.Height) {
@@ -456,5 +498,12 @@ func (self *Engine) UpdateChain(block *blockchain.Block) {
 	self.validatedChainsHeight.Heights[block.Header.ChainID] = int(block.Header.Height)
 	self.validatedChainsHeight.Unlock()
 
-	self.committee.UpdateCommitteePoint(block.BlockProducer, block.Header.BlockCommitteeSigs)
+	points := self.committee.UpdateCommitteePoint(block.BlockProducer, block.Header.SignersBitmap)
+	self.updateLiveness(block, points)
+	self.applyEvidences(block)
+	self.applyBlockSwaps(block)
+	err = self.storeLiveness(block.Header.ChainID)
+	if err != nil {
+		Logger.log.Error(err)
+	}
 }
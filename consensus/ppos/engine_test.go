@@ -0,0 +1,39 @@
+package ppos
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ninjadotorg/constant/mempool"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+TestStartMissingBlocks - errors of chains are returned by Start, no chain is
+left blocked on sending its error
+*/
+func TestStartMissingBlocks(t *testing.T) {
+	engine, cleanup := newTestEngine(t)
+	defer cleanup()
+	engine.config.FeeEstimator = make(map[byte]*mempool.FeeEstimator)
+	// blocks of these chains are not stored
+	for chainID := 1; chainID <= 3; chainID++ {
+		engine.config.BlockChain.BestState[chainID].Height = 3
+	}
+
+	goroutines := runtime.NumGoroutine()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- engine.Start()
+	}()
+	select {
+	case err := <-errCh:
+		assert.NotNil(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Start is blocked")
+	}
+	assert.False(t, engine.started)
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, runtime.NumGoroutine() <= goroutines, "chains are blocked after Start")
+}
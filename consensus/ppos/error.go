@@ -14,6 +14,13 @@ const (
 	ErrMerkleRootCommitments
 	ErrNotEnoughSigs
 	ErrExceedBlockRetry
+	ErrInvalidSignersBitmap
+	ErrInvalidEvidence
+	ErrConflictingBlockSig
+	ErrSwapNotAllowed
+	ErrInvalidSwapProof
+	ErrInvalidCommittee
+	ErrInvalidFeeSchedule
 )
 
 var ErrCodeMessage = map[int]struct {
@@ -31,6 +38,13 @@ var ErrCodeMessage = map[int]struct {
 	ErrMerkleRootCommitments: {-9, "MerkleRootCommitments is wrong"},
 	ErrNotEnoughSigs:         {-10, "not enough signatures"},
 	ErrExceedBlockRetry:      {-11, "exceed block retry"},
+	ErrInvalidSignersBitmap:  {-12, "signers bitmap is invalid"},
+	ErrInvalidEvidence:       {-13, "double signing evidence is invalid"},
+	ErrConflictingBlockSig:   {-14, "another block is already signed at this height"},
+	ErrSwapNotAllowed:        {-15, "committee member can't be swapped"},
+	ErrInvalidSwapProof:      {-16, "swap does not match its anchor"},
+	ErrInvalidCommittee:      {-17, "committee is not changed by verified swaps"},
+	ErrInvalidFeeSchedule:    {-18, "fee schedule is not changed by an accepted GOV proposal"},
 }
 
 type ConsensusError struct {
@@ -0,0 +1,261 @@
+package ppos
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/wire"
+)
+
+// evidencePool keeps double signing evidences until a block includes them,
+// evidences of connected blocks are stored in db
+type evidencePool struct {
+	sync.Mutex
+	pending map[common.Hash]blockchain.DoubleSignEvidence
+}
+
+func newEvidencePool() evidencePool {
+	return evidencePool{
+		pending: make(map[common.Hash]blockchain.DoubleSignEvidence),
+	}
+}
+
+// signedHeight is the last block a validator gave its partial signature for,
+// Signed is the unix time of its last partial signature
+type signedHeight struct {
+	Height    int32
+	Round     int
+	BlockHash common.Hash
+	Signed    int64
+}
+
+/*
+checkSignedHeight - a validator never signs a block below its last signed
+block or a different block at the same height of a chain, so it can't be
+slashed because of a faulty producer. A partial signature alone is no
+evidence, so once it is SignedHeightTimeout old and no block of the height is
+connected another block is signed in the next round. It returns the round of
+block session
+*/
+func (self *Engine) checkSignedHeight(chainID byte, height int32, blockHash common.Hash) (int, error) {
+	self.signedHeightsMtx.Lock()
+	defer self.signedHeightsMtx.Unlock()
+	return self.canSignHeight(chainID, height, blockHash)
+}
+
+/*
+setSignedHeight - save block as last signed block of chain before signing it,
+it returns the round of block session
+*/
+func (self *Engine) setSignedHeight(chainID byte, height int32, blockHash common.Hash) (int, error) {
+	self.signedHeightsMtx.Lock()
+	defer self.signedHeightsMtx.Unlock()
+	round, err := self.canSignHeight(chainID, height, blockHash)
+	if err != nil {
+		return 0, err
+	}
+	last := signedHeight{height, round, blockHash, self.config.Clock.Now().Unix()}
+	data, err := json.Marshal(last)
+	if err != nil {
+		return 0, err
+	}
+	err = self.config.DataBase.StoreSignedHeight(data, chainID)
+	if err != nil {
+		return 0, err
+	}
+	self.signedHeights[chainID] = last
+	return round, nil
+}
+
+func (self *Engine) canSignHeight(chainID byte, height int32, blockHash common.Hash) (int, error) {
+	last, ok := self.signedHeights[chainID]
+	if !ok {
+		data, err := self.config.DataBase.GetSignedHeight(chainID)
+		if err != nil {
+			// never signed a block of this chain
+			return 0, nil
+		}
+		err = json.Unmarshal(data, &last)
+		if err != nil {
+			return 0, err
+		}
+		self.signedHeights[chainID] = last
+	}
+	switch {
+	case height > last.Height:
+		return 0, nil
+	case height < last.Height:
+		return 0, NewConsensusError(ErrConflictingBlockSig, nil)
+	case blockHash == last.BlockHash:
+		return last.Round, nil
+	case self.config.Clock.Now().Unix()-last.Signed >= SignedHeightTimeout && self.config.BlockChain.BestSnapshot(chainID).Height < height:
+		// the round of the last block timed out without aggregated signature
+		return last.Round + 1, nil
+	}
+	return 0, NewConsensusError(ErrConflictingBlockSig, nil)
+}
+
+/*
+isEvidenceCommitted - evidence is already in a connected block
+*/
+func (self *Engine) isEvidenceCommitted(evidenceHash *common.Hash) bool {
+	committed, err := self.config.DataBase.HasEvidence(evidenceHash)
+	if err != nil {
+		Logger.log.Error(err)
+	}
+	return committed
+}
+
+/*
+checkDoubleSign - record evidence when block conflicts with the block we have
+at its height
+*/
+func (self *Engine) checkDoubleSign(block *blockchain.Block) {
+	existing, err := self.config.BlockChain.GetBlockByBlockHeight(block.Header.Height, block.Header.ChainID)
+	if err != nil || *existing.SigningHash() == *block.SigningHash() {
+		return
+	}
+	evidence := blockchain.DoubleSignEvidence{
+		BlockA: blockchain.NewSignedBlockHeader(existing),
+		BlockB: blockchain.NewSignedBlockHeader(block),
+	}
+	err = self.addEvidence(evidence)
+	if err != nil {
+		Logger.log.Debug("Conflicting block is not an evidence ", err)
+	}
+}
+
+/*
+verifyEvidence - check evidence and return offenders who are current
+committee members or candidates, only they can be punished
+*/
+func (self *Engine) verifyEvidence(evidence blockchain.DoubleSignEvidence) ([]string, error) {
+	offenders, err := evidence.Verify()
+	if err != nil {
+		return nil, NewConsensusError(ErrInvalidEvidence, err)
+	}
+	committee := self.GetCommittee()
+	result := make([]string, 0, len(offenders))
+	for _, offender := range offenders {
+		if common.IndexOfStr(offender, committee) != -1 || self.config.BlockChain.GetCommitteCandidate(offender) != nil {
+			result = append(result, offender)
+		}
+	}
+	if len(result) == 0 {
+		return nil, NewConsensusError(ErrInvalidEvidence, nil)
+	}
+	return result, nil
+}
+
+/*
+addEvidence - keep a new evidence for next block and relay it to other nodes
+*/
+func (self *Engine) addEvidence(evidence blockchain.DoubleSignEvidence) error {
+	evidenceHash := *evidence.Hash()
+	self.evidences.Lock()
+	_, pending := self.evidences.pending[evidenceHash]
+	self.evidences.Unlock()
+	if pending || self.isEvidenceCommitted(&evidenceHash) {
+		return nil
+	}
+
+	offenders, err := self.verifyEvidence(evidence)
+	if err != nil {
+		return err
+	}
+	Logger.log.Warn("Double signing evidence against ", offenders)
+
+	self.evidences.Lock()
+	self.evidences.pending[evidenceHash] = evidence
+	self.evidences.Unlock()
+	return self.config.Server.PushMessageToAll(&wire.MessageEvidence{Evidence: evidence})
+}
+
+/*
+pendingEvidences - evidences which are not in any block yet
+*/
+func (self *Engine) pendingEvidences(max int) []blockchain.DoubleSignEvidence {
+	self.evidences.Lock()
+	defer self.evidences.Unlock()
+	result := make([]blockchain.DoubleSignEvidence, 0)
+	for _, evidence := range self.evidences.pending {
+		if len(result) == max {
+			break
+		}
+		result = append(result, evidence)
+	}
+	return result
+}
+
+/*
+ValidateEvidences - evidences of a block must be valid and not punished before
+*/
+func (self *Engine) ValidateEvidences(block *blockchain.Block) error {
+	if len(block.Evidences) > MaxEvidencesPerBlock {
+		return NewConsensusError(ErrInvalidEvidence, nil)
+	}
+	seen := make(map[common.Hash]bool)
+	for _, evidence := range block.Evidences {
+		evidenceHash := *evidence.Hash()
+		if seen[evidenceHash] || self.isEvidenceCommitted(&evidenceHash) {
+			return NewConsensusError(ErrInvalidEvidence, nil)
+		}
+		seen[evidenceHash] = true
+		_, err := self.verifyEvidence(evidence)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+applyEvidences - punish offenders of evidences in a connected block, they lose
+reliability points and are removed from committee candidates
+*/
+func (self *Engine) applyEvidences(block *blockchain.Block) {
+	for _, evidence := range block.Evidences {
+		evidenceHash := *evidence.Hash()
+		self.evidences.Lock()
+		delete(self.evidences.pending, evidenceHash)
+		committed := self.isEvidenceCommitted(&evidenceHash)
+		if !committed {
+			err := self.config.DataBase.StoreEvidence(&evidenceHash)
+			if err != nil {
+				Logger.log.Error(err)
+			}
+		}
+		self.evidences.Unlock()
+		if committed {
+			continue
+		}
+
+		offenders, err := evidence.Verify()
+		if err != nil {
+			Logger.log.Error(err)
+			continue
+		}
+		self.committee.Lock()
+		for _, offender := range offenders {
+			self.committee.ValidatorReliablePts[offender] += DoubleSignPointMin
+		}
+		self.committee.Unlock()
+		for _, offender := range offenders {
+			self.addLivenessPoints(block.Header.ChainID, offender, DoubleSignPointMin)
+			Logger.log.Warn("Slash double signing validator ", offender)
+			err := self.config.BlockChain.RemoveCommitteeCandidate(offender)
+			if err != nil {
+				Logger.log.Error(err)
+			}
+		}
+	}
+}
+
+func (self *Engine) OnEvidenceReceived(evidence *blockchain.DoubleSignEvidence) {
+	err := self.addEvidence(*evidence)
+	if err != nil {
+		Logger.log.Error(err)
+	}
+}
@@ -0,0 +1,57 @@
+package ppos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// expireSignedHeight - the partial signature of chain is SignedHeightTimeout old
+func expireSignedHeight(engine *Engine, chainID byte) {
+	last := engine.signedHeights[chainID]
+	last.Signed = time.Now().Unix() - SignedHeightTimeout
+	engine.signedHeights[chainID] = last
+}
+
+func TestSignedHeight(t *testing.T) {
+	engine, cleanup := newTestEngine(t)
+	defer cleanup()
+	blockA := common.HashH([]byte("a"))
+	blockB := common.HashH([]byte("b"))
+
+	round, err := engine.setSignedHeight(0, 5, blockA)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, round)
+	round, err = engine.checkSignedHeight(0, 5, blockA)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, round)
+	_, err = engine.checkSignedHeight(0, 5, blockB)
+	assert.NotNil(t, err)
+	_, err = engine.checkSignedHeight(0, 4, blockB)
+	assert.NotNil(t, err)
+	round, err = engine.checkSignedHeight(0, 6, blockB)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, round)
+
+	// a block without aggregated signature in time is replaced in next round
+	expireSignedHeight(engine, 0)
+	round, err = engine.setSignedHeight(0, 5, blockB)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, round)
+	_, err = engine.checkSignedHeight(0, 5, blockA)
+	assert.NotNil(t, err)
+
+	// the lock survives restarts with its round
+	engine.signedHeights = make(map[byte]signedHeight)
+	round, err = engine.checkSignedHeight(0, 5, blockB)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, round)
+
+	// a connected block of the height keeps the lock
+	expireSignedHeight(engine, 0)
+	engine.config.BlockChain.BestState[0].Height = 5
+	_, err = engine.checkSignedHeight(0, 5, blockA)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,249 @@
+package ppos
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
+)
+
+// ValidatorLiveness is the uptime record of a validator on one chain
+type ValidatorLiveness struct {
+	PublicKey      string
+	ChainID        byte
+	ProducedBlocks int
+	MissedSlots    int
+	ReliablePts    int
+	LastBlockTime  int64
+	// slots missed in a row until now, only set for the chain leader
+	CurrentMissedSlots int `json:"-"`
+}
+
+/*
+Uptime - ratio of slots where the validator produced a block of the chain
+*/
+func (self ValidatorLiveness) Uptime() float64 {
+	slots := self.ProducedBlocks + self.MissedSlots + self.CurrentMissedSlots
+	if slots == 0 {
+		return 1
+	}
+	return float64(self.ProducedBlocks) / float64(slots)
+}
+
+// chainLiveness is the liveness state of a chain, it is stored in db by chain
+type chainLiveness struct {
+	LastProducer  string
+	LastBlockTime int64
+	Validators    map[string]*ValidatorLiveness
+}
+
+type livenessStruct struct {
+	sync.Mutex
+	chains map[byte]*chainLiveness
+	// last time a swap was requested for an offline chain leader
+	lastAutoSwap map[byte]int64
+}
+
+func newLiveness() livenessStruct {
+	return livenessStruct{
+		chains:       make(map[byte]*chainLiveness),
+		lastAutoSwap: make(map[byte]int64),
+	}
+}
+
+func (self *livenessStruct) getChain(chainID byte) *chainLiveness {
+	chain, ok := self.chains[chainID]
+	if !ok {
+		chain = &chainLiveness{
+			Validators: make(map[string]*ValidatorLiveness),
+		}
+		self.chains[chainID] = chain
+	}
+	return chain
+}
+
+func (self *chainLiveness) getValidator(pubKey string, chainID byte) *ValidatorLiveness {
+	validator, ok := self.Validators[pubKey]
+	if !ok {
+		validator = &ValidatorLiveness{
+			PublicKey: pubKey,
+			ChainID:   chainID,
+		}
+		self.Validators[pubKey] = validator
+	}
+	return validator
+}
+
+/*
+missedSlots - number of MaxBlockTime slots passed between two block times of a
+chain without a block
+*/
+func missedSlots(fromTime int64, toTime int64) int {
+	if fromTime <= 0 || toTime <= fromTime {
+		return 0
+	}
+	return int((toTime - fromTime) / common.MaxBlockTime)
+}
+
+/*
+updateLiveness - count the block for its producer and the slots missed before
+it for the previous producer of the chain, points are reliability points the
+block gave to validators
+*/
+func (self *Engine) updateLiveness(block *blockchain.Block, points map[string]int) {
+	chainID := block.Header.ChainID
+	self.liveness.Lock()
+	defer self.liveness.Unlock()
+	chain := self.liveness.getChain(chainID)
+	if chain.LastProducer != common.EmptyString {
+		chain.getValidator(chain.LastProducer, chainID).MissedSlots += missedSlots(chain.LastBlockTime, block.Header.Timestamp)
+	}
+	producer := chain.getValidator(block.BlockProducer, chainID)
+	producer.ProducedBlocks++
+	producer.LastBlockTime = block.Header.Timestamp
+	for validator, pts := range points {
+		chain.getValidator(validator, chainID).ReliablePts += pts
+	}
+	chain.LastProducer = block.BlockProducer
+	chain.LastBlockTime = block.Header.Timestamp
+}
+
+/*
+addLivenessPoints - keep reliability points which are not given by a block
+itself, like slashing, with the chain of the block
+*/
+func (self *Engine) addLivenessPoints(chainID byte, validator string, pts int) {
+	self.liveness.Lock()
+	defer self.liveness.Unlock()
+	self.liveness.getChain(chainID).getValidator(validator, chainID).ReliablePts += pts
+}
+
+/*
+storeLiveness - save liveness state of a chain into db
+*/
+func (self *Engine) storeLiveness(chainID byte) error {
+	self.liveness.Lock()
+	data, err := json.Marshal(self.liveness.getChain(chainID))
+	self.liveness.Unlock()
+	if err != nil {
+		return err
+	}
+	return self.config.DataBase.StoreValidatorLiveness(data, chainID)
+}
+
+/*
+loadLiveness - restore liveness state of every chain from db and rebuild block
+numbers and reliability points of committee from it. A chain without stored
+state starts counting from its best block.
+*/
+func (self *Engine) loadLiveness() {
+	self.liveness.Lock()
+	defer self.liveness.Unlock()
+	self.committee.Lock()
+	defer self.committee.Unlock()
+	for chainID := byte(0); chainID < common.TotalValidators; chainID++ {
+		chain := &chainLiveness{}
+		data, err := self.config.DataBase.GetValidatorLiveness(chainID)
+		if err == nil {
+			err = json.Unmarshal(data, chain)
+		}
+		if err != nil || chain.Validators == nil {
+			bestBlock := self.config.BlockChain.BestSnapshot(chainID).BestBlock
+			chain = &chainLiveness{
+				LastProducer:  bestBlock.BlockProducer,
+				LastBlockTime: bestBlock.Header.Timestamp,
+				Validators:    make(map[string]*ValidatorLiveness),
+			}
+		}
+		self.liveness.chains[chainID] = chain
+		for pubKey, validator := range chain.Validators {
+			self.committee.ValidatorBlkNum[pubKey] += validator.ProducedBlocks
+			self.committee.ValidatorReliablePts[pubKey] += validator.ReliablePts
+		}
+	}
+}
+
+/*
+currentMissedSlots - slots missed in a row by leader of a chain until now
+*/
+func (self *Engine) currentMissedSlots(chainID byte) int {
+	bestBlock := self.config.BlockChain.BestSnapshot(chainID).BestBlock
+	return missedSlots(bestBlock.Header.Timestamp, self.config.Clock.Now().Unix())
+}
+
+/*
+GetValidatorsLiveness - liveness of validators on every chain, sorted by chain
+and public key
+*/
+func (self *Engine) GetValidatorsLiveness() []ValidatorLiveness {
+	committee := self.GetCommittee()
+	self.liveness.Lock()
+	result := make([]ValidatorLiveness, 0)
+	for chainID, chain := range self.liveness.chains {
+		for _, validator := range chain.Validators {
+			result = append(result, *validator)
+		}
+		if int(chainID) < len(committee) && chain.Validators[committee[chainID]] == nil {
+			result = append(result, ValidatorLiveness{
+				PublicKey: committee[chainID],
+				ChainID:   chainID,
+			})
+		}
+	}
+	self.liveness.Unlock()
+
+	for i := range result {
+		if int(result[i].ChainID) < len(committee) && committee[result[i].ChainID] == result[i].PublicKey {
+			result[i].CurrentMissedSlots = self.currentMissedSlots(result[i].ChainID)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ChainID != result[j].ChainID {
+			return result[i].ChainID < result[j].ChainID
+		}
+		return result[i].PublicKey < result[j].PublicKey
+	})
+	return result
+}
+
+/*
+checkOfflineLeaders - request a swap of every chain leader which is swappable at
+our best block, the block swapChain anchors its swap to, at most once per
+MaxMissedSlots slots of a chain
+*/
+func (self *Engine) checkOfflineLeaders(myChainID byte) {
+	anchor := self.config.BlockChain.BestSnapshot(myChainID).BestBlock
+	committee := self.GetCommittee()
+	points, err := self.windowReliability(anchor)
+	if err != nil {
+		Logger.log.Error(err)
+		return
+	}
+	now := self.config.Clock.Now().Unix()
+	for chainID := byte(0); chainID < common.TotalValidators; chainID++ {
+		if chainID == myChainID {
+			continue
+		}
+		swappable, err := self.isSwappable(chainID, committee, anchor, points)
+		if err != nil || !swappable {
+			continue
+		}
+		self.liveness.Lock()
+		lastAutoSwap := self.liveness.lastAutoSwap[chainID]
+		if now-lastAutoSwap < MaxMissedSlots*common.MaxBlockTime {
+			self.liveness.Unlock()
+			continue
+		}
+		self.liveness.lastAutoSwap[chainID] = now
+		self.liveness.Unlock()
+
+		Logger.log.Warnf("Leader of chain %d is offline, request swap", chainID)
+		select {
+		case self.cSwapChain <- chainID:
+		default:
+			Logger.log.Info("Swap is busy or not started, chain ", chainID)
+		}
+	}
+}
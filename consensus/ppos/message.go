@@ -1,13 +1,12 @@
 package ppos
 
 import (
-	"time"
-
 	peer2 "github.com/libp2p/go-libp2p-peer"
 	"github.com/ninjadotorg/constant/blockchain"
 	"github.com/ninjadotorg/constant/cashec"
 	"github.com/ninjadotorg/constant/common"
 	"github.com/ninjadotorg/constant/common/base58"
+	"github.com/ninjadotorg/constant/signer"
 	"github.com/ninjadotorg/constant/wire"
 )
 
@@ -22,45 +21,87 @@ func (self *Engine) sendBlockMsg(block *blockchain.Block) {
 }
 
 func (self *Engine) OnRequestSign(msgBlock *wire.MessageBlockSigReq) {
+	if !self.isProducerStarted() {
+		return
+	}
 	block := &msgBlock.Block
-	err := self.validatePreSignBlockSanity(block)
-	if err != nil {
-		invalidBlockMsg := &wire.MessageInvalidBlock{
-			Reason:    err.Error(),
-			BlockHash: block.Hash().String(),
-			ChainID:   block.Header.ChainID,
-			Validator: base58.Base58Check{}.Encode(self.config.ProducerKeySet.PaymentAddress.Pk, byte(0x00)),
+	blockHash := block.SigningHash()
+	blockSigMsg := wire.MessageBlockSig{
+		Validator: self.config.ProducerSigner.PublicKey(),
+		BlockHash: blockHash.String(),
+	}
+	session := signer.BlockSession{
+		ChainID:   block.Header.ChainID,
+		Height:    block.Header.Height,
+		BlockHash: *blockHash,
+	}
+
+	if msgBlock.IsNonceRequest() {
+		var err error
+		session.Round, err = self.checkSignedHeight(block.Header.ChainID, block.Header.Height, *blockHash)
+		if err != nil {
+			Logger.log.Error("Refuse to sign block ", blockHash.String(), err)
+			return
 		}
-		dataByte, _ := invalidBlockMsg.JsonSerialize()
-		invalidBlockMsg.ValidatorSig, err = self.signData(dataByte)
+		err = self.validatePreSignBlockSanity(block, msgBlock.ProducerSig)
+		if err != nil {
+			invalidBlockMsg := &wire.MessageInvalidBlock{
+				Reason:    err.Error(),
+				BlockHash: blockHash.String(),
+				ChainID:   block.Header.ChainID,
+				Validator: blockSigMsg.Validator,
+			}
+			dataByte, _ := invalidBlockMsg.JsonSerialize()
+			invalidBlockMsg.ValidatorSig, err = self.signData(signer.SignRequest{Type: signer.SignMessage, Data: dataByte})
+			if err != nil {
+				Logger.log.Error(err)
+				return
+			}
+			Logger.log.Critical("Invalid block msg", invalidBlockMsg)
+			err = self.config.Server.PushMessageToAll(invalidBlockMsg)
+			if err != nil {
+				Logger.log.Error(err)
+				return
+			}
+			return
+		}
+		nonce, err := self.config.ProducerSigner.BlockNonce(session)
+		if err != nil {
+			Logger.log.Error("Refuse to sign block ", blockHash.String(), err)
+			return
+		}
+		blockSigMsg.Nonce = base58.Base58Check{}.Encode(nonce, byte(0x00))
+	} else {
+		// the block was validated in round 1, signer only signs with the
+		// nonce of that session, so a block is never signed twice with one nonce
+		pubKeys, _, err := signersFromBitmap(block.Header.Committee, msgBlock.SignersBitmap)
 		if err != nil {
 			Logger.log.Error(err)
 			return
 		}
-		Logger.log.Critical("Invalid block msg", invalidBlockMsg)
-		err = self.config.Server.PushMessageToAll(invalidBlockMsg)
+		aggNonce, _, err := base58.Base58Check{}.Decode(msgBlock.AggNonce)
 		if err != nil {
 			Logger.log.Error(err)
 			return
 		}
-		return
+		session.Round, err = self.setSignedHeight(block.Header.ChainID, block.Header.Height, *blockHash)
+		if err != nil {
+			Logger.log.Error("Refuse to sign block ", blockHash.String(), err)
+			return
+		}
+		partial, err := self.config.ProducerSigner.SignBlock(session, pubKeys, aggNonce)
+		if err != nil {
+			Logger.log.Error("Can't sign block ", blockHash.String(), err)
+			return
+		}
+		blockSigMsg.BlockSig = base58.Base58Check{}.Encode(partial, byte(0x00))
 	}
 
-	sig, err := self.signData([]byte(block.Hash().String()))
-	if err != nil {
-		Logger.log.Error("Can't sign block ", err)
-		// TODO something went terribly wrong
-		return
-	}
-	blockSigMsg := wire.MessageBlockSig{
-		Validator: base58.Base58Check{}.Encode(self.config.ProducerKeySet.PaymentAddress.Pk, byte(0x00)),
-		BlockSig:  sig,
-	}
 	peerID, err := peer2.IDB58Decode(msgBlock.SenderID)
 	if err != nil {
 		Logger.log.Error("ERROR", msgBlock.SenderID, peerID, err)
 	}
-	Logger.log.Info(block.Hash().String(), blockSigMsg)
+	Logger.log.Info(blockHash.String(), blockSigMsg)
 	err = self.config.Server.PushMessageToPeer(&blockSigMsg, peerID)
 	if err != nil {
 		Logger.log.Error(err)
@@ -69,7 +110,7 @@ func (self *Engine) OnRequestSign(msgBlock *wire.MessageBlockSigReq) {
 }
 
 func (self *Engine) OnBlockReceived(block *blockchain.Block) {
-	if self.config.BlockChain.BestState[block.Header.ChainID].Height < block.Header.Height {
+	if self.config.BlockChain.BestSnapshot(block.Header.ChainID).Height < block.Header.Height {
 		exists, err := self.config.BlockChain.BlockExists(block.Hash())
 		if err != nil {
 			Logger.log.Error(err)
@@ -84,15 +125,27 @@ func (self *Engine) OnBlockReceived(block *blockchain.Block) {
 				self.UpdateChain(block)
 			}
 		}
+	} else {
+		// a block we already have at this height may be double signed
+		self.checkDoubleSign(block)
 	}
 	return
 }
 
-func (self *Engine) OnBlockSigReceived(validator string, sig string) {
+func (self *Engine) OnBlockSigReceived(msg *wire.MessageBlockSig) {
 	Logger.log.Info("Received a block signature")
-	self.cBlockSig <- blockSig{
-		Validator: validator,
-		BlockSig:  sig,
+	self.producerMtx.Lock()
+	started := self.producerStarted
+	cBlockSig := self.cBlockSig
+	cQuitProducer := self.cQuitProducer
+	self.producerMtx.Unlock()
+	if !started {
+		return
+	}
+	// don't block message handling when producer stops
+	select {
+	case cBlockSig <- msg:
+	case <-cQuitProducer:
 	}
 	return
 }
@@ -105,11 +158,13 @@ func (self *Engine) OnInvalidBlockReceived(blockHash string, chainID byte, reaso
 
 func (self *Engine) OnChainStateReceived(msg *wire.MessageChainState) {
 	chainInfo := msg.ChainInfo.(map[string]interface{})
-	for i, v := range self.validatedChainsHeight.Heights {
+	for i, v := range self.validatedChainsHeight.get() {
 		if chainInfo["ChainsHeight"] != nil {
 			if v < int(chainInfo["ChainsHeight"].([]interface{})[i].(float64)) {
+				self.knownChainsHeight.Lock()
 				self.knownChainsHeight.Heights[i] = int(chainInfo["ChainsHeight"].([]interface{})[i].(float64))
-				lastBlockHash := self.config.BlockChain.BestState[i].BestBlockHash.String()
+				self.knownChainsHeight.Unlock()
+				lastBlockHash := self.config.BlockChain.BestSnapshot(byte(i)).BestBlockHash.String()
 				getBlkMsg := &wire.MessageGetBlocks{
 					LastBlockHash: lastBlockHash,
 				}
@@ -136,7 +191,7 @@ func (self *Engine) OnGetChainState(msg *wire.MessageGetChainState) {
 	newMsg.(*wire.MessageChainState).ChainInfo = ChainInfo{
 		CurrentCommittee:        self.GetCommittee(),
 		CandidateListMerkleHash: common.EmptyString,
-		ChainsHeight:            self.validatedChainsHeight.Heights,
+		ChainsHeight:            self.validatedChainsHeight.get(),
 	}
 	peerID, _ := peer2.IDB58Decode(msg.SenderID)
 	self.config.Server.PushMessageToPeer(newMsg, peerID)
@@ -146,7 +201,11 @@ func (self *Engine) OnGetChainState(msg *wire.MessageGetChainState) {
 func (self *Engine) OnSwapRequest(msg *wire.MessageSwapRequest) {
 	Logger.log.Info("Received a MessageSwapRequest")
 
-	if msg.LockTime > time.Now().Unix() {
+	if !self.isProducerStarted() {
+		return
+	}
+
+	if msg.LockTime > self.config.Clock.Now().Unix() {
 		return
 	}
 
@@ -157,26 +216,31 @@ func (self *Engine) OnSwapRequest(msg *wire.MessageSwapRequest) {
 		return
 	}
 
-	// rawBytes := self.getRawBytesForSwap(msg.LockTime, msg.Requester, msg.ChainID, msg.Candidate)
-	// // TODO check requester signature
-	// err := cashec.ValidateDataB58(msg.RequesterPbk, msg.RequesterSig, rawBytes)
-	// if err != nil {
-	// 	Logger.log.Info("Received a MessageSwapRequest validate error", err)
-	// 	return
-	// }
 	err := msg.Verify()
 	if err != nil {
 		Logger.log.Info("Received a MessageSwapRequest validate error", err)
 		return
 	}
-	// validate condition for swap
-
-	peerIDs := self.config.Server.GetPeerIDsFromPublicKey(msg.Candidate)
-	if len(peerIDs) == 0 {
+	// the swap is signed only when we compute the same one from its anchor
+	err = self.verifySwap(&blockchain.CommitteeSwap{
+		ChainID:       msg.ChainID,
+		Outgoing:      msg.Outgoing,
+		Candidate:     msg.Candidate,
+		AnchorChainID: msg.AnchorChainID,
+		AnchorHeight:  msg.AnchorHeight,
+		AnchorHash:    msg.AnchorHash,
+	}, committee)
+	if err != nil {
+		Logger.log.Error("ERROR OnSwapRequest", err)
 		return
 	}
 
-	sig, err := self.signData(msg.GetMsgByte())
+	sig, err := self.signData(signer.SignRequest{
+		Type:    signer.SignSwap,
+		ChainID: msg.AnchorChainID,
+		Height:  msg.AnchorHeight,
+		Data:    msg.GetMsgByte(),
+	})
 	if err != nil {
 		Logger.log.Error("Can't sign swap ", err)
 		return
@@ -185,11 +249,7 @@ func (self *Engine) OnSwapRequest(msg *wire.MessageSwapRequest) {
 	if err != nil {
 		return
 	}
-	// messageSigMsg.(*wire.MessageSwapSig).LockTime = msg.LockTime
-	// messageSigMsg.(*wire.MessageSwapSig).RequesterPbk = msg.RequesterPbk
-	// messageSigMsg.(*wire.MessageSwapSig).ChainID = msg.ChainID
-	// messageSigMsg.(*wire.MessageSwapSig).ProducerPbk = msg.ProducerPbk
-	messageSigMsg.(*wire.MessageSwapSig).Validator = base58.Base58Check{}.Encode(self.config.ProducerKeySet.PaymentAddress.Pk, byte(0x00))
+	messageSigMsg.(*wire.MessageSwapSig).Validator = self.config.ProducerSigner.PublicKey()
 	messageSigMsg.(*wire.MessageSwapSig).SwapSig = sig
 
 	peerID, err := peer2.IDB58Decode(msg.SenderID)
@@ -214,7 +274,7 @@ func (self *Engine) OnSwapSig(msg *wire.MessageSwapSig) {
 func (self *Engine) OnSwapUpdate(msg *wire.MessageSwapUpdate) {
 	Logger.log.Info("Received a MessageSwapUpdate")
 
-	if msg.LockTime > time.Now().Unix() {
+	if msg.LockTime > self.config.Clock.Now().Unix() {
 		return
 	}
 
@@ -226,11 +286,11 @@ func (self *Engine) OnSwapUpdate(msg *wire.MessageSwapUpdate) {
 	}
 
 	//verify signatures
-	rawBytes := self.getRawBytesForSwap(msg.LockTime, msg.Requester, msg.ChainID, msg.Candidate)
+	rawBytes := msg.GetMsgByte()
 	cLeader := 0
 	for leaderPbk, leaderSig := range msg.Signatures {
 		if common.IndexOfStr(leaderPbk, committee) >= 0 {
-			err := cashec.ValidateDataB58(leaderPbk, leaderSig, rawBytes)
+			err := cashec.ValidateDataB58(leaderPbk, leaderSig, signer.SignedData(signer.SignSwap, rawBytes))
 			if err != nil {
 				Logger.log.Error("ERROR OnSwapUpdate", leaderPbk, err)
 				continue
@@ -244,8 +304,23 @@ func (self *Engine) OnSwapUpdate(msg *wire.MessageSwapUpdate) {
 		Logger.log.Error("ERROR OnSwapUpdate not enough signatures")
 		return
 	}
-	//TODO update committee list
-	self.updateCommittee(msg.Candidate, msg.ChainID)
 
+	proof := &blockchain.CommitteeSwap{
+		ChainID:       msg.ChainID,
+		Outgoing:      msg.Outgoing,
+		Candidate:     msg.Candidate,
+		AnchorChainID: msg.AnchorChainID,
+		AnchorHeight:  msg.AnchorHeight,
+		AnchorHash:    msg.AnchorHash,
+	}
+	err := self.verifySwap(proof, committee)
+	if err != nil {
+		Logger.log.Error("ERROR OnSwapUpdate", err)
+		return
+	}
+	err = self.applySwap(proof)
+	if err != nil {
+		Logger.log.Error("ERROR OnSwapUpdate", err)
+	}
 	return
 }
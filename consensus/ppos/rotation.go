@@ -0,0 +1,266 @@
+package ppos
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
+)
+
+/*
+windowReliability - reliability points of validators over the last
+RotationWindow blocks of every chain before the anchor: producing a block gives
+BlkPointAdd, signing it SigPointAdd and missing its signature SigPointMin
+*/
+func (self *Engine) windowReliability(anchor *blockchain.Block) (map[string]int, error) {
+	points := make(map[string]int)
+	for chainID, height := range anchor.Header.ChainsHeight {
+		for h := height; h > height-RotationWindow && h > 1; h-- {
+			block, err := self.config.BlockChain.GetBlockByBlockHeight(int32(h), byte(chainID))
+			if err != nil {
+				return nil, NewConsensusError(ErrNotEnoughChainData, err)
+			}
+			points[block.BlockProducer] += BlkPointAdd
+			for idx, validator := range block.Header.Committee {
+				if isSigner(block.Header.SignersBitmap, idx) {
+					points[validator] += SigPointAdd
+				} else {
+					points[validator] += SigPointMin
+				}
+			}
+		}
+	}
+	return points, nil
+}
+
+/*
+rotationBeacon - random number from hashes of the last RotationBeaconBlocks
+blocks of anchor chain, producers can't choose it without dropping their blocks
+*/
+func (self *Engine) rotationBeacon(anchor *blockchain.Block, chainID byte) (*big.Int, error) {
+	record := []byte{chainID}
+	for h := anchor.Header.Height; h > anchor.Header.Height-RotationBeaconBlocks && h > 0; h-- {
+		block, err := self.config.BlockChain.GetBlockByBlockHeight(h, anchor.Header.ChainID)
+		if err != nil {
+			return nil, NewConsensusError(ErrNotEnoughChainData, err)
+		}
+		record = append(record, block.Hash()[:]...)
+	}
+	beacon := common.DoubleHashH(record)
+	return new(big.Int).SetBytes(beacon[:]), nil
+}
+
+/*
+isSwappable - member of committee seat chainID is swapped out at anchor when
+its reliability points in the window are below MinSwapPoints or its chain got
+no block for MaxMissedSlots slots before the anchor
+*/
+func (self *Engine) isSwappable(chainID byte, committee []string, anchor *blockchain.Block, points map[string]int) (bool, error) {
+	if int(chainID) >= len(committee) || int(chainID) >= len(anchor.Header.ChainsHeight) {
+		return false, NewConsensusError(ErrSwapNotAllowed, nil)
+	}
+	if points[committee[chainID]] < MinSwapPoints {
+		return true, nil
+	}
+	lastBlock := anchor
+	if chainID != anchor.Header.ChainID {
+		var err error
+		lastBlock, err = self.config.BlockChain.GetBlockByBlockHeight(int32(anchor.Header.ChainsHeight[chainID]), chainID)
+		if err != nil {
+			return false, NewConsensusError(ErrNotEnoughChainData, err)
+		}
+	}
+	return missedSlots(lastBlock.Header.Timestamp, anchor.Header.Timestamp) >= MaxMissedSlots, nil
+}
+
+/*
+computeSwap - decide who replaces the member of committee seat chainID at
+anchor. The member is swapped only when isSwappable, the replacement is drawn
+by the beacon among reliable candidates out of committee, weighted by their
+stake.
+*/
+func (self *Engine) computeSwap(chainID byte, committee []string, anchor *blockchain.Block) (*blockchain.CommitteeSwap, error) {
+	points, err := self.windowReliability(anchor)
+	if err != nil {
+		return nil, err
+	}
+	swappable, err := self.isSwappable(chainID, committee, anchor, points)
+	if err != nil {
+		return nil, err
+	}
+	if !swappable {
+		return nil, NewConsensusError(ErrSwapNotAllowed, nil)
+	}
+	outgoing := committee[chainID]
+
+	candidates := make([]string, 0)
+	for _, candidate := range self.config.BlockChain.GetCommitteeCandidateList() {
+		if common.IndexOfStr(candidate, committee) == -1 && points[candidate] >= 0 {
+			candidates = append(candidates, candidate)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, NewConsensusError(ErrSwapNotAllowed, nil)
+	}
+	sort.Strings(candidates)
+
+	weights := make([]*big.Int, len(candidates))
+	totalWeight := new(big.Int)
+	for i, candidate := range candidates {
+		// every candidate has a chance even without stake
+		weights[i] = new(big.Int).SetUint64(self.config.BlockChain.GetCommitteeCandidateInfo(candidate).Value + 1)
+		totalWeight.Add(totalWeight, weights[i])
+	}
+	beacon, err := self.rotationBeacon(anchor, chainID)
+	if err != nil {
+		return nil, err
+	}
+	pick := new(big.Int).Mod(beacon, totalWeight)
+	candidate := candidates[len(candidates)-1]
+	for i, weight := range weights {
+		if pick.Cmp(weight) < 0 {
+			candidate = candidates[i]
+			break
+		}
+		pick.Sub(pick, weight)
+	}
+
+	return &blockchain.CommitteeSwap{
+		ChainID:       chainID,
+		Outgoing:      outgoing,
+		Candidate:     candidate,
+		AnchorChainID: anchor.Header.ChainID,
+		AnchorHeight:  anchor.Header.Height,
+		AnchorHash:    anchor.Hash().String(),
+	}, nil
+}
+
+/*
+verifySwap - recompute a swap of committee from its anchor and check it matches
+the proof
+*/
+func (self *Engine) verifySwap(proof *blockchain.CommitteeSwap, committee []string) error {
+	anchor, err := self.config.BlockChain.GetBlockByBlockHeight(proof.AnchorHeight, proof.AnchorChainID)
+	if err != nil {
+		return NewConsensusError(ErrNotEnoughChainData, err)
+	}
+	if anchor.Hash().String() != proof.AnchorHash {
+		return NewConsensusError(ErrInvalidSwapProof, nil)
+	}
+	expected, err := self.computeSwap(proof.ChainID, committee, anchor)
+	if err != nil {
+		return err
+	}
+	if *expected != *proof {
+		return NewConsensusError(ErrInvalidSwapProof, nil)
+	}
+	return nil
+}
+
+/*
+CheckCommittee - committee of a block must be the committee of its previous
+block changed by the swaps recorded in the block, each of them is verified from
+its anchor with the committee it was applied to
+*/
+func (self *Engine) CheckCommittee(block *blockchain.Block) error {
+	if len(block.Header.Committee) != common.TotalValidators || len(block.Header.CommitteeSwaps) > MaxAppliedSwaps {
+		return NewConsensusError(ErrInvalidCommittee, nil)
+	}
+	prevBlock, err := self.config.BlockChain.GetBlockByBlockHash(&block.Header.PrevBlockHash)
+	if err != nil {
+		return NewConsensusError(ErrNotEnoughChainData, err)
+	}
+	committee := make([]string, len(prevBlock.Header.Committee))
+	copy(committee, prevBlock.Header.Committee)
+	for _, swap := range block.Header.CommitteeSwaps {
+		// anchor must be a block the block producer has seen
+		if int(swap.AnchorChainID) >= len(block.Header.ChainsHeight) || int(swap.AnchorHeight) > block.Header.ChainsHeight[swap.AnchorChainID] {
+			return NewConsensusError(ErrInvalidSwapProof, nil)
+		}
+		if int(swap.ChainID) >= len(committee) || committee[swap.ChainID] != swap.Outgoing {
+			return NewConsensusError(ErrInvalidSwapProof, nil)
+		}
+		err := self.verifySwap(&swap, committee)
+		if err != nil {
+			return err
+		}
+		committee[swap.ChainID] = swap.Candidate
+	}
+	if !common.StringArrayEquals(committee, block.Header.Committee) {
+		return NewConsensusError(ErrInvalidCommittee, nil)
+	}
+	return nil
+}
+
+// swapCommittee - committee after swaps, false when a swap doesn't match its seat
+func swapCommittee(committee []string, swaps []blockchain.CommitteeSwap) ([]string, bool) {
+	result := make([]string, len(committee))
+	copy(result, committee)
+	for _, swap := range swaps {
+		if int(swap.ChainID) >= len(result) || result[swap.ChainID] != swap.Outgoing {
+			return nil, false
+		}
+		result[swap.ChainID] = swap.Candidate
+	}
+	return result, true
+}
+
+/*
+committeeSwaps - the last applied swaps which turn committee of the previous
+block into committee, the producer records them in its block
+*/
+func (self *Engine) committeeSwaps(prevCommittee []string, committee []string) ([]blockchain.CommitteeSwap, error) {
+	self.committee.Lock()
+	applied := make([]blockchain.CommitteeSwap, len(self.committee.AppliedSwaps))
+	copy(applied, self.committee.AppliedSwaps)
+	self.committee.Unlock()
+
+	for start := len(applied); start >= 0; start-- {
+		swapped, ok := swapCommittee(prevCommittee, applied[start:])
+		if !ok || !common.StringArrayEquals(swapped, committee) {
+			continue
+		}
+		if start == len(applied) {
+			return nil, nil
+		}
+		return applied[start:], nil
+	}
+	return nil, NewConsensusError(ErrInvalidCommittee, nil)
+}
+
+/*
+applySwap - put candidate in committee seat and remember the proof so it can be
+recorded in the next block
+*/
+func (self *Engine) applySwap(proof *blockchain.CommitteeSwap) error {
+	err := self.updateCommittee(proof.Candidate, proof.ChainID)
+	if err != nil {
+		return err
+	}
+	self.committee.Lock()
+	swaps := append(self.committee.AppliedSwaps, *proof)
+	if len(swaps) > MaxAppliedSwaps {
+		swaps = swaps[len(swaps)-MaxAppliedSwaps:]
+	}
+	self.committee.AppliedSwaps = swaps
+	self.committee.Unlock()
+	return nil
+}
+
+/*
+applyBlockSwaps - apply swaps recorded in a connected block which didn't reach
+us as swap updates
+*/
+func (self *Engine) applyBlockSwaps(block *blockchain.Block) {
+	for _, swap := range block.Header.CommitteeSwaps {
+		committee := self.GetCommittee()
+		if int(swap.ChainID) >= len(committee) || committee[swap.ChainID] != swap.Outgoing {
+			continue
+		}
+		err := self.applySwap(&swap)
+		if err != nil {
+			Logger.log.Error(err)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package ppos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/cashec"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/common/base58"
+	"github.com/ninjadotorg/constant/database"
+	_ "github.com/ninjadotorg/constant/database/lvdb"
+	"github.com/stretchr/testify/assert"
+)
+
+const testOfflineSeat = 5
+
+func testPubKey(name string) string {
+	keySet := cashec.KeySet{}
+	keySet.GenerateKey([]byte(name))
+	return base58.Base58Check{}.Encode(keySet.PaymentAddress.Pk, byte(0x00))
+}
+
+/*
+newTestEngine - engine on a chain of genesis committee, chain 0 has blocks up
+to height 4 which every member signed except the one of testOfflineSeat
+*/
+func newTestEngine(t *testing.T) (*Engine, func()) {
+	backendLog := common.NewBackend(ioutil.Discard)
+	blockchain.Logger.Init(backendLog.Logger("blockChain log"))
+	database.Logger.Init(backendLog.Logger("Database Log"))
+	Logger.Init(backendLog.Logger("Consensus log"))
+
+	dir, err := ioutil.TempDir(common.EmptyString, "rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.Open("leveldb", dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	cleanup := func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+
+	committee := make([]string, common.TotalValidators)
+	for idx := range committee {
+		committee[idx] = testPubKey(fmt.Sprintf("rotation-%d", idx))
+	}
+	params := blockchain.TestNetParams
+	params.GenesisBlock = blockchain.GenesisBlockGenerator{}.CreateGenesisBlockPoSParallel(1, committee, blockchain.IcoParams{
+		InitialPaymentAddress: blockchain.TestnetGenesisBlockPaymentAddress,
+	}, 1000, 1000)
+	chain := &blockchain.BlockChain{}
+	err = chain.Init(&blockchain.Config{
+		ChainParams: &params,
+		DataBase:    db,
+	})
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	signersBitmap := make([]byte, (common.TotalValidators+7)/8)
+	for idx := range committee {
+		if idx != testOfflineSeat {
+			signersBitmap[idx/8] |= 1 << uint(idx%8)
+		}
+	}
+	prevBlock := chain.BestState[0].BestBlock
+	for height := int32(2); height <= 4; height++ {
+		block := newTestBlock(prevBlock, committee)
+		block.BlockProducer = committee[0]
+		block.Header.SignersBitmap = signersBitmap
+		err = chain.StoreBlock(block)
+		if err == nil {
+			err = chain.StoreBlockIndex(block)
+		}
+		if err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+		prevBlock = block
+	}
+
+	engine, err := Engine{}.Init(&EngineConfig{
+		BlockChain:  chain,
+		ChainParams: &params,
+		DataBase:    db,
+	})
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	engine.committee.CurrentCommittee = committee
+	return engine, cleanup
+}
+
+// newTestBlock - next block of chain 0 after prevBlock
+func newTestBlock(prevBlock *blockchain.Block, committee []string) *blockchain.Block {
+	chainsHeight := make([]int, common.TotalValidators)
+	for chainID := range chainsHeight {
+		chainsHeight[chainID] = 1
+	}
+	chainsHeight[0] = int(prevBlock.Header.Height)
+	block := &blockchain.Block{}
+	block.Header = blockchain.BlockHeader{
+		Height:        prevBlock.Header.Height + 1,
+		PrevBlockHash: *prevBlock.Hash(),
+		Timestamp:     prevBlock.Header.Timestamp + common.MaxBlockTime,
+		ChainID:       0,
+		ChainsHeight:  chainsHeight,
+		Committee:     make([]string, len(committee)),
+	}
+	copy(block.Header.Committee, committee)
+	return block
+}
+
+func addTestCandidates(engine *Engine, candidates ...string) {
+	for _, candidate := range candidates {
+		engine.config.BlockChain.BestState[0].Candidates[candidate] = blockchain.CommitteeCandidateInfo{Value: 10}
+	}
+}
+
+func TestComputeSwap(t *testing.T) {
+	engine, cleanup := newTestEngine(t)
+	defer cleanup()
+	committee := engine.GetCommittee()
+	anchor, err := engine.config.BlockChain.GetBlockByBlockHeight(4, 0)
+	assert.Nil(t, err)
+
+	// nobody to swap in
+	_, err = engine.computeSwap(testOfflineSeat, committee, anchor)
+	assert.NotNil(t, err)
+
+	candidate := testPubKey("rotation-candidate")
+	addTestCandidates(engine, candidate, committee[1])
+	swap, err := engine.computeSwap(testOfflineSeat, committee, anchor)
+	assert.Nil(t, err)
+	assert.Equal(t, committee[testOfflineSeat], swap.Outgoing)
+	// members of committee are not candidates
+	assert.Equal(t, candidate, swap.Candidate)
+	assert.Equal(t, anchor.Hash().String(), swap.AnchorHash)
+
+	// a member who signed the blocks is not swapped
+	_, err = engine.computeSwap(1, committee, anchor)
+	assert.NotNil(t, err)
+
+	// the same swap is computed again from its anchor
+	assert.Nil(t, engine.verifySwap(swap, committee))
+	wrongAnchor := *swap
+	wrongAnchor.AnchorHash = common.HashH([]byte("anchor")).String()
+	assert.NotNil(t, engine.verifySwap(&wrongAnchor, committee))
+}
+
+func TestIsSwappable(t *testing.T) {
+	engine, cleanup := newTestEngine(t)
+	defer cleanup()
+	committee := engine.GetCommittee()
+	anchor, err := engine.config.BlockChain.GetBlockByBlockHeight(4, 0)
+	assert.Nil(t, err)
+	points, err := engine.windowReliability(anchor)
+	assert.Nil(t, err)
+
+	// missed signatures
+	swappable, err := engine.isSwappable(testOfflineSeat, committee, anchor, points)
+	assert.Nil(t, err)
+	assert.True(t, swappable)
+	swappable, err = engine.isSwappable(1, committee, anchor, points)
+	assert.Nil(t, err)
+	assert.False(t, swappable)
+
+	// other chains got no block for MaxMissedSlots slots before a late anchor
+	late := newTestBlock(anchor, committee)
+	late.Header.Timestamp = anchor.Header.Timestamp + MaxMissedSlots*common.MaxBlockTime
+	swappable, err = engine.isSwappable(1, committee, late, points)
+	assert.Nil(t, err)
+	assert.True(t, swappable)
+	swappable, err = engine.isSwappable(0, committee, late, points)
+	assert.Nil(t, err)
+	assert.False(t, swappable)
+}
+
+/*
+TestCheckOfflineLeaders - the watcher requests swaps of the seats computeSwap
+swaps at the same anchor, no more
+*/
+func TestCheckOfflineLeaders(t *testing.T) {
+	engine, cleanup := newTestEngine(t)
+	defer cleanup()
+	anchor, err := engine.config.BlockChain.GetBlockByBlockHeight(4, 0)
+	assert.Nil(t, err)
+	engine.config.BlockChain.BestState[0].BestBlock = anchor
+	addTestCandidates(engine, testPubKey("rotation-candidate"))
+	engine.cSwapChain = make(chan byte, common.TotalValidators)
+
+	engine.checkOfflineLeaders(0)
+	close(engine.cSwapChain)
+	requested := make([]byte, 0)
+	for chainID := range engine.cSwapChain {
+		requested = append(requested, chainID)
+	}
+	assert.Equal(t, []byte{testOfflineSeat}, requested)
+
+	for chainID := byte(1); chainID < common.TotalValidators; chainID++ {
+		_, err := engine.computeSwap(chainID, engine.GetCommittee(), anchor)
+		assert.Equal(t, chainID == testOfflineSeat, err == nil, "seat %d", chainID)
+	}
+
+	// a swap is requested once per MaxMissedSlots slots
+	engine.cSwapChain = make(chan byte, common.TotalValidators)
+	engine.checkOfflineLeaders(0)
+	assert.Equal(t, 0, len(engine.cSwapChain))
+}
+
+func TestCheckCommittee(t *testing.T) {
+	engine, cleanup := newTestEngine(t)
+	defer cleanup()
+	committee := engine.GetCommittee()
+	prevBlock, err := engine.config.BlockChain.GetBlockByBlockHeight(4, 0)
+	assert.Nil(t, err)
+	candidate := testPubKey("rotation-candidate")
+	other := testPubKey("rotation-other")
+	addTestCandidates(engine, candidate)
+	swap, err := engine.computeSwap(testOfflineSeat, committee, prevBlock)
+	assert.Nil(t, err)
+
+	block := newTestBlock(prevBlock, committee)
+	assert.Nil(t, engine.CheckCommittee(block))
+
+	// committee is changed without swap
+	tampered := newTestBlock(prevBlock, committee)
+	tampered.Header.Committee[3] = candidate
+	assert.NotNil(t, engine.CheckCommittee(tampered))
+
+	swapped := newTestBlock(prevBlock, committee)
+	swapped.Header.Committee[testOfflineSeat] = candidate
+	assert.NotNil(t, engine.CheckCommittee(swapped))
+	swapped.Header.CommitteeSwaps = []blockchain.CommitteeSwap{*swap}
+	assert.Nil(t, engine.CheckCommittee(swapped))
+
+	// swap doesn't match committee of the block
+	swapped.Header.Committee[testOfflineSeat] = other
+	assert.NotNil(t, engine.CheckCommittee(swapped))
+
+	// swap to someone else than the computed candidate
+	addTestCandidates(engine, other)
+	expected, err := engine.computeSwap(testOfflineSeat, committee, prevBlock)
+	assert.Nil(t, err)
+	forged := *expected
+	forged.Candidate = other
+	if expected.Candidate == other {
+		forged.Candidate = candidate
+	}
+	swapped.Header.Committee[testOfflineSeat] = forged.Candidate
+	swapped.Header.CommitteeSwaps = []blockchain.CommitteeSwap{forged}
+	assert.NotNil(t, engine.CheckCommittee(swapped))
+
+	// swap of a reliable member
+	forged = *swap
+	forged.ChainID = 1
+	forged.Outgoing = committee[1]
+	swapped = newTestBlock(prevBlock, committee)
+	swapped.Header.Committee[1] = swap.Candidate
+	swapped.Header.CommitteeSwaps = []blockchain.CommitteeSwap{forged}
+	assert.NotNil(t, engine.CheckCommittee(swapped))
+
+	// anchor is not seen by the block producer
+	swapped = newTestBlock(prevBlock, committee)
+	swapped.Header.ChainsHeight[0] = 3
+	swapped.Header.Committee[testOfflineSeat] = swap.Candidate
+	swapped.Header.CommitteeSwaps = []blockchain.CommitteeSwap{*swap}
+	assert.NotNil(t, engine.CheckCommittee(swapped))
+}
+
+func TestCommitteeSwaps(t *testing.T) {
+	engine, cleanup := newTestEngine(t)
+	defer cleanup()
+	prevCommittee := engine.GetCommittee()
+	prevBlock, err := engine.config.BlockChain.GetBlockByBlockHeight(4, 0)
+	assert.Nil(t, err)
+	candidate := testPubKey("rotation-candidate")
+	addTestCandidates(engine, candidate)
+	swap, err := engine.computeSwap(testOfflineSeat, prevCommittee, prevBlock)
+	assert.Nil(t, err)
+
+	swaps, err := engine.committeeSwaps(prevCommittee, engine.GetCommittee())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(swaps))
+
+	assert.Nil(t, engine.applySwap(swap))
+	committee := engine.GetCommittee()
+	assert.Equal(t, candidate, committee[testOfflineSeat])
+	swaps, err = engine.committeeSwaps(prevCommittee, committee)
+	assert.Nil(t, err)
+	assert.Equal(t, []blockchain.CommitteeSwap{*swap}, swaps)
+
+	// the producer records the swap and its block passes the check
+	block := newTestBlock(prevBlock, committee)
+	block.Header.CommitteeSwaps = swaps
+	assert.Nil(t, engine.CheckCommittee(block))
+
+	// committee of the previous block is already swapped
+	swaps, err = engine.committeeSwaps(committee, committee)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(swaps))
+
+	// committee was changed by no applied swap
+	tampered := engine.GetCommittee()
+	tampered[2] = testPubKey("rotation-other")
+	_, err = engine.committeeSwaps(prevCommittee, tampered)
+	assert.NotNil(t, err)
+}
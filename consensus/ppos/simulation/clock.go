@@ -0,0 +1,108 @@
+package simulation
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+Clock is the virtual time of a network. Nodes wait on it for transactions,
+signatures and chain state and links deliver messages by it, it moves only
+when the network advances it so timeouts of consensus don't pass in wall
+clock.
+*/
+type Clock struct {
+	mtx    sync.Mutex
+	now    time.Time
+	timers []*clockTimer // sorted by time they fire at
+	// stopped clock fires every timer at once
+	stopped bool
+}
+
+type clockTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func newClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the virtual time
+func (self *Clock) Now() time.Time {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return self.now
+}
+
+/*
+After - channel which gets the virtual time once d passed, a timer nobody
+waits on any more just fires into its buffer
+*/
+func (self *Clock) After(d time.Duration) <-chan time.Time {
+	c := make(chan time.Time, 1)
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	if d <= 0 || self.stopped {
+		c <- self.now
+		return c
+	}
+	timer := &clockTimer{at: self.now.Add(d), c: c}
+	idx := sort.Search(len(self.timers), func(i int) bool {
+		return self.timers[i].at.After(timer.at)
+	})
+	self.timers = append(self.timers, nil)
+	copy(self.timers[idx+1:], self.timers[idx:])
+	self.timers[idx] = timer
+	return c
+}
+
+// next returns time of the earliest timer, false when there is none
+func (self *Clock) next() (time.Time, bool) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	if len(self.timers) == 0 {
+		return time.Time{}, false
+	}
+	return self.timers[0].at, true
+}
+
+/*
+Advance - move virtual time forward by d and fire every timer which is due
+*/
+func (self *Clock) Advance(d time.Duration) {
+	self.AdvanceTo(self.Now().Add(d))
+}
+
+/*
+AdvanceTo - move virtual time forward to t and fire every timer which is due,
+the clock never goes back
+*/
+func (self *Clock) AdvanceTo(t time.Time) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	if t.After(self.now) {
+		self.now = t
+	}
+	fired := 0
+	for _, timer := range self.timers {
+		if timer.at.After(self.now) {
+			break
+		}
+		timer.c <- self.now
+		fired++
+	}
+	self.timers = self.timers[fired:]
+}
+
+// stop fires every timer without moving virtual time, later ones fire at once
+func (self *Clock) stop() {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.stopped = true
+	for _, timer := range self.timers {
+		timer.c <- self.now
+	}
+	self.timers = nil
+}
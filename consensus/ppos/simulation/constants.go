@@ -0,0 +1,8 @@
+package simulation
+
+import "time"
+
+const (
+	MaxLinkQueue = 1024            // messages waiting on a link, new ones are dropped when it is full
+	CloseTime    = 2 * time.Second // time of the bubble for goroutines of stopped nodes to end
+)
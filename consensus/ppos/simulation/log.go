@@ -0,0 +1,46 @@
+package simulation
+
+import (
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/consensus/ppos"
+	"github.com/ninjadotorg/constant/database"
+	"github.com/ninjadotorg/constant/mempool"
+	"github.com/ninjadotorg/constant/netsync"
+)
+
+type SimulationLogger struct {
+	log common.Logger
+}
+
+func (self *SimulationLogger) Init(inst common.Logger) {
+	self.log = inst
+}
+
+// Global instant to use
+var Logger = SimulationLogger{}
+
+var initLogOnce sync.Once
+
+/*
+initLog - loggers of packages are global, so every node of every network logs
+into the writer of the first network
+*/
+func initLog(w io.Writer) {
+	initLogOnce.Do(func() {
+		if w == nil {
+			w = ioutil.Discard
+		}
+		backendLog := common.NewBackend(w)
+		Logger.Init(backendLog.Logger("Simulation log"))
+		netsync.Logger.Init(backendLog.Logger("Netsync Log"))
+		database.Logger.Init(backendLog.Logger("Database Log"))
+		blockchain.Logger.Init(backendLog.Logger("blockChain log"))
+		ppos.Logger.Init(backendLog.Logger("Consensus log"))
+		mempool.Logger.Init(backendLog.Logger("Mempool log"))
+	})
+}
@@ -0,0 +1,429 @@
+package simulation
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing/synctest"
+	"time"
+
+	peer2 "github.com/libp2p/go-libp2p-peer"
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/cashec"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/common/base58"
+	"github.com/ninjadotorg/constant/wire"
+)
+
+// Config of a simulated network
+type Config struct {
+	// Nodes is the number of nodes, the first common.TotalValidators of them
+	// are the genesis committee. It is common.TotalValidators when 0.
+	Nodes int
+	// DataDir keeps databases of nodes, a temporary dir is used when empty
+	DataDir string
+	// Seed derives keys of nodes and randomness of link rules
+	Seed int64
+	// LogWriter receives logs of every node, logs are dropped when nil
+	LogWriter io.Writer
+}
+
+/*
+LinkRule is how messages travel from one node to another. A message waits
+Delay plus a random part of Jitter and is lost with probability DropRate,
+messages of a link are still delivered in order like over a connection.
+*/
+type LinkRule struct {
+	Delay    time.Duration
+	Jitter   time.Duration
+	DropRate float64
+}
+
+type link struct {
+	from int
+	to   int
+}
+
+// delivery is a message on its way, it is encoded like on the wire
+type delivery struct {
+	to      *Node
+	toEpoch int
+	cmd     string
+	data    []byte
+	at      time.Time
+}
+
+/*
+Network runs nodes in one process, they talk through an in-memory transport
+which replaces libp2p. Tests script partitions, delays and drops of messages
+and crash and restart nodes. Nodes and links run on the virtual time of
+network, a network must run in a bubble of synctest so it knows when every
+node waits, see WaitFor.
+*/
+type Network struct {
+	sync.Mutex
+	config   Config
+	params   *blockchain.Params
+	nodes    []*Node
+	byPeerID map[peer2.ID]*Node
+	tmpDir   bool
+
+	clock       *Clock
+	rand        *rand.Rand
+	defaultRule LinkRule
+	rules       map[link]LinkRule
+	groups      map[int]int
+	queues      map[link]chan *delivery
+	lastAt      map[link]time.Time
+	delivered   map[link]int
+	dropped     map[link]int
+	cQuit       chan struct{}
+	stopped     bool
+}
+
+/*
+NewNetwork - create keys and data dirs of nodes and a genesis block whose
+committee are the first common.TotalValidators nodes
+*/
+func NewNetwork(cfg Config) (*Network, error) {
+	if cfg.Nodes == 0 {
+		cfg.Nodes = common.TotalValidators
+	}
+	if cfg.Nodes < common.TotalValidators {
+		return nil, fmt.Errorf("network needs at least %d nodes for the committee", common.TotalValidators)
+	}
+	initLog(cfg.LogWriter)
+
+	self := &Network{
+		config:    cfg,
+		byPeerID:  make(map[peer2.ID]*Node),
+		rand:      rand.New(rand.NewSource(cfg.Seed)),
+		rules:     make(map[link]LinkRule),
+		groups:    make(map[int]int),
+		queues:    make(map[link]chan *delivery),
+		lastAt:    make(map[link]time.Time),
+		delivered: make(map[link]int),
+		dropped:   make(map[link]int),
+		cQuit:     make(chan struct{}),
+	}
+	if self.config.DataDir == common.EmptyString {
+		dir, err := ioutil.TempDir(common.EmptyString, "simulation")
+		if err != nil {
+			return nil, err
+		}
+		self.config.DataDir = dir
+		self.tmpDir = true
+	}
+
+	committee := make([]string, 0, common.TotalValidators)
+	for idx := 0; idx < cfg.Nodes; idx++ {
+		node, err := newNode(self, idx)
+		if err != nil {
+			return nil, err
+		}
+		self.nodes = append(self.nodes, node)
+		self.byPeerID[node.PeerID] = node
+		if idx < common.TotalValidators {
+			committee = append(committee, node.PublicKey)
+		}
+	}
+
+	params := blockchain.TestNetParams
+	params.Name = "simulation"
+	params.GenesisBlock = blockchain.GenesisBlockGenerator{}.CreateGenesisBlockPoSParallel(1, committee, blockchain.IcoParams{
+		InitialPaymentAddress: blockchain.TestnetGenesisBlockPaymentAddress,
+		InitFundSalary:        blockchain.TestnetInitFundSalary,
+		InitialBondToken:      blockchain.TestnetInitBondToken,
+		InitialCMBToken:       blockchain.TestnetInitCmBToken,
+		InitialDCBToken:       blockchain.TestnetInitDCBToken,
+		InitialGOVToken:       blockchain.TestnetInitGovToken,
+	}, 1000, 1000)
+	self.params = &params
+	// the network starts fresh at its genesis block
+	self.clock = newClock(time.Unix(params.GenesisBlock.Header.Timestamp, 0))
+	return self, nil
+}
+
+/*
+nodeKeySet - key set of a node is derived from seed of network so a test gets
+the same committee every run
+*/
+func nodeKeySet(seed int64, idx int) cashec.KeySet {
+	keySet := cashec.KeySet{}
+	keySet.GenerateKey([]byte(fmt.Sprintf("simulation-%d-%d", seed, idx)))
+	return keySet
+}
+
+/*
+nodePeerID - libp2p id of a node, a sha256 multihash of its name
+*/
+func nodePeerID(seed int64, idx int) (peer2.ID, error) {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("simulation-peer-%d-%d", seed, idx)))
+	multihash := append([]byte{0x12, 0x20}, hash[:]...)
+	return peer2.IDB58Decode(base58.Base58{}.Encode(multihash))
+}
+
+// Params returns chain params of the network, with its own genesis block
+func (self *Network) Params() *blockchain.Params {
+	return self.params
+}
+
+// Clock returns virtual time of the network
+func (self *Network) Clock() *Clock {
+	return self.clock
+}
+
+// Node returns node at idx
+func (self *Network) Node(idx int) *Node {
+	return self.nodes[idx]
+}
+
+// Nodes returns all nodes, the committee first
+func (self *Network) Nodes() []*Node {
+	return self.nodes
+}
+
+// Start starts every node which is not running
+func (self *Network) Start() error {
+	for _, node := range self.nodes {
+		if node.IsUp() {
+			continue
+		}
+		err := node.Start()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Stop crashes every node and stops delivering messages, waits of crashed nodes
+on the clock end at once so their goroutines leave the bubble
+*/
+func (self *Network) Stop() {
+	for _, node := range self.nodes {
+		node.Crash()
+	}
+	self.Lock()
+	if !self.stopped {
+		self.stopped = true
+		close(self.cQuit)
+	}
+	self.Unlock()
+	self.clock.stop()
+	// closed databases drain their pools on the time of the bubble
+	time.Sleep(CloseTime)
+	if self.tmpDir {
+		os.RemoveAll(self.config.DataDir)
+	}
+}
+
+func (self *Network) nodeDataDir(idx int) string {
+	return filepath.Join(self.config.DataDir, fmt.Sprintf("node-%d", idx))
+}
+
+/*
+SetDefaultRule - rule of every link without its own rule
+*/
+func (self *Network) SetDefaultRule(rule LinkRule) {
+	self.Lock()
+	defer self.Unlock()
+	self.defaultRule = rule
+}
+
+/*
+SetLinkRule - rule of messages sent by node from to node to
+*/
+func (self *Network) SetLinkRule(from int, to int, rule LinkRule) {
+	self.Lock()
+	defer self.Unlock()
+	self.rules[link{from, to}] = rule
+}
+
+/*
+Partition - split network, nodes of a group reach only each other and nodes
+which are in no group reach only those which are in no group either
+*/
+func (self *Network) Partition(groups ...[]int) {
+	self.Lock()
+	defer self.Unlock()
+	self.groups = make(map[int]int)
+	for groupIdx, group := range groups {
+		for _, idx := range group {
+			self.groups[idx] = groupIdx + 1
+		}
+	}
+}
+
+// Heal removes partitions
+func (self *Network) Heal() {
+	self.Partition()
+}
+
+/*
+Reachable - whether messages of node from get to node to, it ignores drop rate
+*/
+func (self *Network) Reachable(from int, to int) bool {
+	self.Lock()
+	defer self.Unlock()
+	return self.groups[from] == self.groups[to]
+}
+
+/*
+Delivered - number of messages from a node which were handed to another node
+*/
+func (self *Network) Delivered(from int, to int) int {
+	self.Lock()
+	defer self.Unlock()
+	return self.delivered[link{from, to}]
+}
+
+/*
+Dropped - number of messages from a node to another node which were lost by a
+partition, drop rate, a crash or a full link
+*/
+func (self *Network) Dropped(from int, to int) int {
+	self.Lock()
+	defer self.Unlock()
+	return self.dropped[link{from, to}]
+}
+
+/*
+WaitFor - run network until cond holds or timeout of virtual time passes.
+Whenever every node waits, cond is checked and virtual time moves to the next
+timer of a node, so waits and timeouts of nodes take no wall clock and happen
+in the same order every run.
+*/
+func (self *Network) WaitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := self.clock.Now().Add(timeout)
+	for {
+		synctest.Wait()
+		if cond() {
+			return true
+		}
+		next, ok := self.clock.next()
+		if !ok || next.After(deadline) {
+			return false
+		}
+		self.clock.AdvanceTo(next)
+	}
+}
+
+/*
+peerIDs - ids of running nodes with public key which sender can reach
+*/
+func (self *Network) peerIDs(sender *Node, pubKey string) []peer2.ID {
+	result := []peer2.ID{}
+	for _, node := range self.nodes {
+		if node != sender && node.PublicKey == pubKey && node.IsUp() && self.Reachable(sender.Index, node.Index) {
+			result = append(result, node.PeerID)
+		}
+	}
+	return result
+}
+
+/*
+send - encode msg from sender and put it on the links to receivers, nil
+receiver means every other node
+*/
+func (self *Network) send(sender *endpoint, receiver *Node, msg wire.Message) error {
+	if !sender.node.isEpoch(sender.epoch) {
+		// messages of a crashed node are lost
+		return nil
+	}
+	err := msg.SetSenderID(sender.node.PeerID)
+	if err != nil {
+		return err
+	}
+	data, err := msg.JsonSerialize()
+	if err != nil {
+		return err
+	}
+
+	receivers := self.nodes
+	if receiver != nil {
+		receivers = []*Node{receiver}
+	}
+	for _, node := range receivers {
+		if node == sender.node {
+			continue
+		}
+		self.enqueue(sender.node.Index, node, msg.MessageType(), data)
+	}
+	return nil
+}
+
+func (self *Network) enqueue(from int, to *Node, cmd string, data []byte) {
+	epoch, up := to.currentEpoch()
+
+	self.Lock()
+	defer self.Unlock()
+	key := link{from, to.Index}
+	rule, ok := self.rules[key]
+	if !ok {
+		rule = self.defaultRule
+	}
+	if self.stopped || !up || self.groups[from] != self.groups[to.Index] || (rule.DropRate > 0 && self.rand.Float64() < rule.DropRate) {
+		self.dropped[key]++
+		return
+	}
+
+	at := self.clock.Now().Add(rule.Delay)
+	if rule.Jitter > 0 {
+		at = at.Add(time.Duration(self.rand.Int63n(int64(rule.Jitter))))
+	}
+	// keep order of messages on a link
+	if at.Before(self.lastAt[key]) {
+		at = self.lastAt[key]
+	}
+	self.lastAt[key] = at
+
+	queue, ok := self.queues[key]
+	if !ok {
+		queue = make(chan *delivery, MaxLinkQueue)
+		self.queues[key] = queue
+		go self.deliverLink(key, queue)
+	}
+	select {
+	case queue <- &delivery{to: to, toEpoch: epoch, cmd: cmd, data: data, at: at}:
+	default:
+		self.dropped[key]++
+	}
+}
+
+/*
+deliverLink - hand messages of a link to its receiver one by one at their time
+*/
+func (self *Network) deliverLink(key link, queue chan *delivery) {
+	for {
+		select {
+		case <-self.cQuit:
+			return
+		case d := <-queue:
+			if wait := d.at.Sub(self.clock.Now()); wait > 0 {
+				select {
+				case <-self.cQuit:
+					return
+				case <-self.clock.After(wait):
+				}
+			}
+			err := d.to.receive(d)
+			self.Lock()
+			if err != nil {
+				self.dropped[key]++
+			} else {
+				self.delivered[key]++
+			}
+			self.Unlock()
+		}
+	}
+}
+
+var errNodeDown = errors.New("node is down")
@@ -0,0 +1,342 @@
+package simulation
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	peer2 "github.com/libp2p/go-libp2p-peer"
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/cashec"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/common/base58"
+	"github.com/ninjadotorg/constant/consensus/ppos"
+	"github.com/ninjadotorg/constant/database"
+	_ "github.com/ninjadotorg/constant/database/lvdb"
+	"github.com/ninjadotorg/constant/mempool"
+	"github.com/ninjadotorg/constant/netsync"
+	"github.com/ninjadotorg/constant/rewardagent"
+	"github.com/ninjadotorg/constant/signer"
+	"github.com/ninjadotorg/constant/transaction"
+	"github.com/ninjadotorg/constant/wire"
+)
+
+/*
+Node is a simulated node, it runs the same consensus engine and sync manager
+as a real node. Its database stays in its data dir so it can crash and restart
+from it.
+*/
+type Node struct {
+	Index     int
+	KeySet    cashec.KeySet
+	PublicKey string
+	PeerID    peer2.ID
+	// Producer nodes produce and sign blocks, by default every node does
+	Producer bool
+
+	network *Network
+	dataDir string
+	// double sign protection of producer key, it survives crashes like the
+	// state of a signer process
+	protection *signer.Protection
+
+	mtx        sync.Mutex
+	up         bool
+	epoch      int
+	db         database.DatabaseInterface
+	blockChain *blockchain.BlockChain
+	engine     *ppos.Engine
+	netSync    *netsync.NetSync
+}
+
+func newNode(network *Network, idx int) (*Node, error) {
+	peerID, err := nodePeerID(network.config.Seed, idx)
+	if err != nil {
+		return nil, err
+	}
+	keySet := nodeKeySet(network.config.Seed, idx)
+	protection, err := signer.NewProtection(common.EmptyString)
+	if err != nil {
+		return nil, err
+	}
+	return &Node{
+		Index:      idx,
+		KeySet:     keySet,
+		PublicKey:  base58.Base58Check{}.Encode(keySet.PaymentAddress.Pk, byte(0x00)),
+		PeerID:     peerID,
+		Producer:   true,
+		network:    network,
+		dataDir:    network.nodeDataDir(idx),
+		protection: protection,
+	}, nil
+}
+
+/*
+Start - open database of node and start its engine and sync manager like a
+node without FastMode, chain dependencies are rebuilt from stored blocks
+*/
+func (self *Node) Start() error {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	if self.up {
+		return errors.New("node is already running")
+	}
+
+	db, err := database.Open("leveldb", self.dataDir)
+	if err != nil {
+		return err
+	}
+	err = self.start(db)
+	if err != nil {
+		db.Close()
+		return err
+	}
+	return nil
+}
+
+func (self *Node) start(db database.DatabaseInterface) error {
+	err := db.CleanCommitments()
+	if err != nil {
+		return err
+	}
+	err = db.CleanNullifiers()
+	if err != nil {
+		return err
+	}
+	err = db.CleanFeeEstimator()
+	if err != nil {
+		return err
+	}
+
+	chain := &blockchain.BlockChain{}
+	err = chain.Init(&blockchain.Config{
+		ChainParams: self.network.params,
+		DataBase:    db,
+		Clock:       self.network.clock,
+	})
+	if err != nil {
+		return err
+	}
+	feeEstimator := make(map[byte]*mempool.FeeEstimator)
+	memPool := &mempool.TxPool{}
+	memPool.Init(&mempool.Config{
+		Policy: mempool.Policy{
+			MaxTxVersion: transaction.TxVersion + 1,
+			BlockChain:   chain,
+		},
+		BlockChain:   chain,
+		DataBase:     db,
+		ChainParams:  self.network.params,
+		FeeEstimator: feeEstimator,
+	})
+	rewardAgent, err := rewardagent.RewardAgent{}.Init(&rewardagent.RewardAgentConfig{
+		BlockChain: chain,
+	})
+	if err != nil {
+		return err
+	}
+	blockGen, err := blockchain.BlkTmplGenerator{}.Init(memPool, chain, rewardAgent)
+	if err != nil {
+		return err
+	}
+
+	// messages of an older run of the node are lost from now on
+	self.epoch++
+	transport := &endpoint{node: self, epoch: self.epoch}
+	engine, err := ppos.Engine{}.Init(&ppos.EngineConfig{
+		ChainParams:  self.network.params,
+		BlockChain:   chain,
+		MemPool:      memPool,
+		Server:       transport,
+		FeeEstimator: feeEstimator,
+		BlockGen:     blockGen,
+		DataBase:     db,
+		Clock:        self.network.clock,
+	})
+	if err != nil {
+		return err
+	}
+	netSync := netsync.NetSync{}.New(&netsync.NetSyncConfig{
+		BlockChain:   chain,
+		ChainParam:   self.network.params,
+		MemTxPool:    memPool,
+		Server:       transport,
+		Consensus:    engine,
+		FeeEstimator: feeEstimator,
+	})
+
+	self.db = db
+	self.blockChain = chain
+	self.engine = engine
+	self.netSync = netSync
+	self.up = true
+
+	netSync.Start()
+	err = engine.Start()
+	if err != nil {
+		self.stop()
+		return err
+	}
+	if self.Producer {
+		engine.StartProducer(signer.NewLocalSigner(self.KeySet, self.protection))
+		go engine.StartSwap()
+	}
+	return nil
+}
+
+/*
+Crash - stop node at once, messages on the way to or from it are lost. Its
+database is kept for Start.
+*/
+func (self *Node) Crash() {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	if !self.up {
+		return
+	}
+	self.stop()
+}
+
+func (self *Node) stop() {
+	self.up = false
+	self.epoch++
+	self.netSync.Stop()
+	err := self.engine.Stop()
+	if err != nil {
+		Logger.log.Error(err)
+	}
+	err = self.db.Close()
+	if err != nil {
+		Logger.log.Error(err)
+	}
+}
+
+// Restart crashes node and starts it again from its database
+func (self *Node) Restart() error {
+	self.Crash()
+	return self.Start()
+}
+
+// IsUp returns whether node is running
+func (self *Node) IsUp() bool {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return self.up
+}
+
+// Engine returns consensus engine of the current run of node
+func (self *Node) Engine() *ppos.Engine {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return self.engine
+}
+
+// BlockChain returns chain of the current run of node
+func (self *Node) BlockChain() *blockchain.BlockChain {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return self.blockChain
+}
+
+/*
+ChainHeight - best height of a chain seen by node, 0 when it is down
+*/
+func (self *Node) ChainHeight(chainID byte) int32 {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	if !self.up {
+		return 0
+	}
+	return self.blockChain.BestSnapshot(chainID).Height
+}
+
+func (self *Node) currentEpoch() (int, bool) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return self.epoch, self.up
+}
+
+func (self *Node) isEpoch(epoch int) bool {
+	current, up := self.currentEpoch()
+	return up && current == epoch
+}
+
+/*
+receive - decode a delivered message and queue it into sync manager like the
+server does for messages of peers
+*/
+func (self *Node) receive(d *delivery) error {
+	self.mtx.Lock()
+	if !self.up || self.epoch != d.toEpoch {
+		self.mtx.Unlock()
+		return errNodeDown
+	}
+	netSync := self.netSync
+	self.mtx.Unlock()
+
+	msg, err := wire.MakeEmptyMessage(d.cmd)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(d.data, &msg)
+	if err != nil {
+		return err
+	}
+
+	switch msg := msg.(type) {
+	case *wire.MessageBlock:
+		netSync.QueueBlock(nil, msg, nil)
+	case *wire.MessageGetBlocks:
+		netSync.QueueGetBlock(nil, msg, nil)
+	case *wire.MessageTx:
+		netSync.QueueTx(nil, msg, nil)
+	case *wire.MessageInvalidBlock:
+		err = msg.Verify()
+		if err != nil {
+			return err
+		}
+		netSync.QueueMessage(nil, msg, nil)
+	case *wire.MessageEvidence:
+		_, err = msg.Evidence.Verify()
+		if err != nil {
+			return err
+		}
+		netSync.QueueMessage(nil, msg, nil)
+	default:
+		netSync.QueueMessage(nil, msg, nil)
+	}
+	return nil
+}
+
+/*
+endpoint is the transport of one run of a node, it replaces the server for
+engine and sync manager
+*/
+type endpoint struct {
+	node  *Node
+	epoch int
+}
+
+func (self *endpoint) GetPeerIDsFromPublicKey(pubKey string) []peer2.ID {
+	return self.node.network.peerIDs(self.node, pubKey)
+}
+
+func (self *endpoint) PushMessageToAll(msg wire.Message) error {
+	return self.node.network.send(self, nil, msg)
+}
+
+func (self *endpoint) PushMessageToPeer(msg wire.Message, peerID peer2.ID) error {
+	receiver, ok := self.node.network.byPeerID[peerID]
+	if !ok {
+		return errors.New("peer is not in network")
+	}
+	return self.node.network.send(self, receiver, msg)
+}
+
+func (self *endpoint) PushMessageGetChainState() error {
+	msg, err := wire.MakeEmptyMessage(wire.CmdGetChainState)
+	if err != nil {
+		return err
+	}
+	return self.PushMessageToAll(msg)
+}
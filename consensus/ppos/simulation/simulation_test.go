@@ -0,0 +1,139 @@
+package simulation
+
+import (
+	"net"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/ninjadotorg/constant/common"
+)
+
+/*
+runNetwork - start every node of a network in a bubble of synctest and run
+test on it, network stops when test returns
+*/
+func runNetwork(t *testing.T, test func(t *testing.T, network *Network)) {
+	if testing.Short() {
+		t.Skip("simulation starts every node of the committee")
+	}
+	// producers dial the prover of salary transactions, the resolver of net
+	// keeps its state across bubbles so it is set up outside of them
+	net.LookupHost("localhost")
+	synctest.Test(t, func(t *testing.T) {
+		network, err := NewNetwork(Config{Seed: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer network.Stop()
+		err = network.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		test(t, network)
+	})
+}
+
+// blockTimeout - virtual time for every chain to get its next block
+const blockTimeout = 2 * (common.MaxBlockWaitTime + common.MaxBlockSigWaitTime) * time.Second
+
+// waitForHeight waits until every running node has chain at height
+func waitForHeight(network *Network, chainID byte, height int32) bool {
+	return network.WaitFor(blockTimeout, func() bool {
+		for _, node := range network.Nodes() {
+			if node.IsUp() && node.ChainHeight(chainID) < height {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestProduceBlocks(t *testing.T) {
+	runNetwork(t, testProduceBlocks)
+}
+
+func testProduceBlocks(t *testing.T, network *Network) {
+	if !waitForHeight(network, 0, 2) {
+		t.Fatal("no block is produced")
+	}
+	// every node has the same block
+	hash := common.EmptyString
+	for _, node := range network.Nodes() {
+		block, err := node.BlockChain().GetBlockByBlockHeight(2, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hash != common.EmptyString && block.Hash().String() != hash {
+			t.Fatalf("node %d has another block at height 2", node.Index)
+		}
+		hash = block.Hash().String()
+		err = node.Engine().ValidateCommitteeSigs(block.SigningHash()[:], block.Header.Committee, block.Header.AggregatedSig, block.Header.SignersBitmap)
+		if err != nil {
+			t.Fatalf("block of node %d is not signed by committee: %v", node.Index, err)
+		}
+	}
+}
+
+func TestPartition(t *testing.T) {
+	runNetwork(t, testPartition)
+}
+
+func testPartition(t *testing.T, network *Network) {
+	network.Partition([]int{0})
+	if network.Reachable(0, 1) || network.Reachable(1, 0) || !network.Reachable(1, 2) {
+		t.Fatal("node 0 must be cut from the others")
+	}
+	delivered := network.Delivered(0, 1)
+	synced := network.Delivered(1, 2)
+	cut := network.WaitFor((common.GetChainStateInterval+5)*time.Second, func() bool {
+		return network.Delivered(1, 2) > synced && network.Dropped(0, 1) > 0
+	})
+	if !cut {
+		t.Fatal("nodes did not exchange chain state")
+	}
+	if network.Delivered(0, 1) != delivered {
+		t.Fatal("messages crossed the partition")
+	}
+
+	network.Heal()
+	healed := network.WaitFor((common.GetChainStateInterval+5)*time.Second, func() bool {
+		return network.Delivered(0, 1) > delivered
+	})
+	if !healed {
+		t.Fatal("node 0 is still cut after heal")
+	}
+}
+
+func TestCrashRestart(t *testing.T) {
+	runNetwork(t, testCrashRestart)
+}
+
+func testCrashRestart(t *testing.T, network *Network) {
+	node := network.Node(1)
+	height := node.ChainHeight(0)
+	node.Crash()
+	if node.IsUp() {
+		t.Fatal("node is up after crash")
+	}
+	dropped := network.Dropped(0, 1)
+	if !waitForHeight(network, 0, height+1) {
+		t.Fatal("no block is produced while node is down")
+	}
+	if network.Dropped(0, 1) == dropped {
+		t.Fatal("messages to a crashed node must be lost")
+	}
+
+	err := node.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.ChainHeight(0) < height {
+		t.Fatalf("chain height is %d after restart, want at least %d", node.ChainHeight(0), height)
+	}
+	// restarted node catches up with blocks produced while it was down
+	target := network.Node(0).ChainHeight(0)
+	if !waitForHeight(network, 0, target) {
+		t.Fatalf("chain height of restarted node is %d, want %d", node.ChainHeight(0), target)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/ninjadotorg/constant/cashec"
 	"github.com/ninjadotorg/constant/common"
 	"github.com/ninjadotorg/constant/common/base58"
+	"github.com/ninjadotorg/constant/signer"
 	"github.com/ninjadotorg/constant/transaction"
 	"github.com/ninjadotorg/constant/wire"
 	"github.com/ninjadotorg/constant/privacy-protocol"
@@ -43,44 +44,28 @@ func (self *Engine) ValidateSpecTxByItSelf(tx transaction.Transaction) bool {
 	return self.config.MemPool.ValidateTxByItSelf(tx)
 }
 
-func (self *Engine) ValidateCommitteeSigs(blockHash []byte, committee []string, sigs []string) error {
-	validatedSigs := 0
-	for idx, validator := range committee {
-		decPubkey, _, err := base58.Base58Check{}.Decode(validator)
-		if err != nil {
-			Logger.log.Error(err)
-			continue
-		}
-		k := cashec.KeySet{
-			PaymentAddress: privacy.PaymentAddress{
-				Pk: decPubkey,
-			},
-		}
-		decSig, _, err := base58.Base58Check{}.Decode(sigs[idx])
-		if err != nil {
-			Logger.log.Error(err)
-			continue
-		}
-		isValidSignature, err := k.Verify(blockHash, decSig)
-		if err != nil {
-			Logger.log.Error(err)
-			continue
-		}
-		if isValidSignature == false {
-			continue
-		}
-		validatedSigs++
+func (self *Engine) ValidateCommitteeSigs(blockHash []byte, committee []string, aggSig string, signersBitmap []byte) error {
+	pubKeys, _, err := signersFromBitmap(committee, signersBitmap)
+	if err != nil {
+		return err
 	}
-
-	if validatedSigs < 10 {
+	if len(pubKeys) < common.MinBlockSigs {
 		return NewConsensusError(ErrNotEnoughSigs, nil)
 	}
+	decSig, _, err := base58.Base58Check{}.Decode(aggSig)
+	if err != nil {
+		return NewConsensusError(ErrSigWrongOrNotExits, err)
+	}
+	if !privacy.MultiSigVerify(decSig, blockHash, pubKeys) {
+		return NewConsensusError(ErrSigWrongOrNotExits, nil)
+	}
 	return nil
 }
 
 func (self *Engine) ValidateMerkleRootCommitments(block *blockchain.Block) error {
-	rtOld := self.config.BlockChain.BestState[block.Header.ChainID].BestBlock.Header.MerkleRootCommitments.CloneBytes()
-	newTree := self.config.BlockChain.BestState[block.Header.ChainID].CmTree.MakeCopy()
+	bestState := self.config.BlockChain.BestSnapshot(block.Header.ChainID)
+	rtOld := bestState.BestBlock.Header.MerkleRootCommitments.CloneBytes()
+	newTree := bestState.CmTree.MakeCopy()
 	Logger.log.Infof("[validateblock] old tree rt: %x\n", newTree.GetRoot(common.IncMerkleTreeHeight))
 	err := blockchain.UpdateMerkleTreeForBlock(newTree, block)
 	if err != nil {
@@ -120,14 +105,31 @@ func (self *Engine) CheckBlockSize(block *blockchain.Block) error {
 	return nil
 }
 
+/*
+CheckFeeSchedule - fee schedule of GOV params of block is the one of its
+previous block or the one voted by a GOV proposal accepted in block
+*/
+func (self *Engine) CheckFeeSchedule(block *blockchain.Block) error {
+	prevBlock, err := self.config.BlockChain.GetBlockByBlockHash(&block.Header.PrevBlockHash)
+	if err != nil {
+		return NewConsensusError(ErrNotEnoughChainData, err)
+	}
+	err = self.config.BlockChain.ValidateFeeSchedule(block, prevBlock)
+	if err != nil {
+		return NewConsensusError(ErrInvalidFeeSchedule, err)
+	}
+	return nil
+}
+
 func (self *Engine) IsEnoughData(block *blockchain.Block) error {
-	if self.validatedChainsHeight.Heights[block.Header.ChainID] == (int(block.Header.Height) - 1) {
+	validatedChainsHeight := self.validatedChainsHeight.get()
+	if validatedChainsHeight[block.Header.ChainID] == (int(block.Header.Height) - 1) {
 		notFullySync := false
 		for i := 0; i < common.TotalValidators; i++ {
-			if self.validatedChainsHeight.Heights[i] < (block.Header.ChainsHeight[i]) && (i != int(block.Header.ChainID)) {
+			if validatedChainsHeight[i] < (block.Header.ChainsHeight[i]) && (i != int(block.Header.ChainID)) {
 				notFullySync = true
 				getBlkMsg := &wire.MessageGetBlocks{
-					LastBlockHash: self.config.BlockChain.BestState[i].BestBlockHash.String(),
+					LastBlockHash: self.config.BlockChain.BestSnapshot(byte(i)).BestBlockHash.String(),
 				}
 				go func(chainLeader string) {
 					peerIDs := self.config.Server.GetPeerIDsFromPublicKey(chainLeader)
@@ -141,10 +143,10 @@ func (self *Engine) IsEnoughData(block *blockchain.Block) error {
 			}
 		}
 		if notFullySync {
-			timer := time.NewTimer(common.MaxSyncChainTime * time.Second)
-			<-timer.C
+			<-self.config.Clock.After(common.MaxSyncChainTime * time.Second)
+			validatedChainsHeight = self.validatedChainsHeight.get()
 			for i := 0; i < common.TotalValidators; i++ {
-				if self.validatedChainsHeight.Heights[i] < (block.Header.ChainsHeight[i]) && (i != int(block.Header.ChainID)) {
+				if validatedChainsHeight[i] < (block.Header.ChainsHeight[i]) && (i != int(block.Header.ChainID)) {
 					return NewConsensusError(ErrChainNotFullySynced, nil)
 				}
 			}
@@ -170,29 +172,47 @@ func (self *Engine) validateBlockSanity(block *blockchain.Block) error {
 
 	// 3. Check signature of the block leader for block header
 	headerBytes, _ := json.Marshal(block.Header)
-	err = cashec.ValidateDataB58(block.BlockProducer, block.BlockProducerSig, headerBytes)
+	err = cashec.ValidateDataB58(block.BlockProducer, block.BlockProducerSig, signer.SignedData(signer.SignBlockHeader, headerBytes))
 	if err != nil {
 		return err
 	}
 
-	// 4. ValidateTransaction committee member signatures
-	err = self.ValidateCommitteeSigs([]byte(block.Hash().String()), block.Header.Committee, block.Header.BlockCommitteeSigs)
+	// 4. Check committee only changed by verified swaps
+	err = self.CheckCommittee(block)
 	if err != nil {
 		return err
 	}
 
-	// 5. ValidateTransaction MerkleRootCommitments
+	// 5. Check fee schedule only changed by an accepted GOV proposal
+	err = self.CheckFeeSchedule(block)
+	if err != nil {
+		return err
+	}
+
+	// 6. ValidateTransaction committee member signatures
+	err = self.ValidateCommitteeSigs(block.SigningHash()[:], block.Header.Committee, block.Header.AggregatedSig, block.Header.SignersBitmap)
+	if err != nil {
+		return err
+	}
+
+	// 7. ValidateTransaction MerkleRootCommitments
 	err = self.ValidateMerkleRootCommitments(block)
 	if err != nil {
 		return err
 	}
 
-	// 6. Validate transactions
+	// 8. Validate double signing evidences
+	err = self.ValidateEvidences(block)
+	if err != nil {
+		return err
+	}
+
+	// 9. Validate transactions
 	return self.ValidateTxList(block.Transactions)
 
 }
 
-func (self *Engine) validatePreSignBlockSanity(block *blockchain.Block) error {
+func (self *Engine) validatePreSignBlockSanity(block *blockchain.Block, producerSig string) error {
 	// 1. Check whether we acquire enough data to validate this block
 	err := self.IsEnoughData(block)
 	if err != nil {
@@ -206,18 +226,36 @@ func (self *Engine) validatePreSignBlockSanity(block *blockchain.Block) error {
 	}
 
 	// 3. Check signature of the block leader for block hash
-	err = cashec.ValidateDataB58(block.BlockProducer, block.Header.BlockCommitteeSigs[block.Header.ChainID], []byte(block.Hash().String()))
+	err = cashec.ValidateDataB58(block.BlockProducer, producerSig, signer.SignedData(signer.SignBlockProposal, []byte(block.Hash().String())))
+	if err != nil {
+		return err
+	}
+
+	// 4. Check committee only changed by verified swaps
+	err = self.CheckCommittee(block)
 	if err != nil {
 		return err
 	}
 
-	// 4. ValidateTransaction MerkleRootCommitments
+	// 5. Check fee schedule only changed by an accepted GOV proposal
+	err = self.CheckFeeSchedule(block)
+	if err != nil {
+		return err
+	}
+
+	// 6. ValidateTransaction MerkleRootCommitments
 	err = self.ValidateMerkleRootCommitments(block)
 	if err != nil {
 		return err
 	}
 
-	// 5. ValidateTransaction transactions
+	// 7. Validate double signing evidences
+	err = self.ValidateEvidences(block)
+	if err != nil {
+		return err
+	}
+
+	// 8. ValidateTransaction transactions
 	return self.ValidateTxList(block.Transactions)
 }
 
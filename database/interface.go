@@ -51,6 +51,20 @@ type DatabaseInterface interface {
 	GetFeeEstimator(byte) ([]byte, error)
 	CleanFeeEstimator() error
 
+	// Validator liveness
+	StoreValidatorLiveness([]byte, byte) error
+	GetValidatorLiveness(byte) ([]byte, error)
+	CleanValidatorLiveness() error
+
+	// Double signing evidence
+	StoreEvidence(*common.Hash) error
+	HasEvidence(*common.Hash) (bool, error)
+	CleanEvidences() error
+
+	// Last block signed by validator
+	StoreSignedHeight([]byte, byte) error
+	GetSignedHeight(byte) ([]byte, error)
+
 	// Custom token
 	StoreCustomToken(*common.Hash, []byte) error                       // param: tokenID, txInitToken-id, data tx
 	StoreCustomTokenTx(*common.Hash, byte, int32, int32, []byte) error // param: tokenID, chainID, block height, tx-id, data tx
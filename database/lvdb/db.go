@@ -27,6 +27,9 @@ var (
 	commitmentsPrefix         = []byte("commitments-")
 	bestBlockKey              = []byte("bestBlock")
 	feeEstimator              = []byte("feeEstimator")
+	validatorLiveness         = []byte("validatorLiveness")
+	evidencePrefix            = []byte("evidence-")
+	signedHeight              = []byte("signedHeight")
 	splitter                  = []byte("-[-]-")
 	tokenPrefix               = []byte("token-")
 	tokenPaymentAddressPrefix = []byte("token-paymentaddress-")
@@ -187,6 +187,77 @@ func (db *db) CleanFeeEstimator() error {
 	return nil
 }
 
+func (db *db) StoreValidatorLiveness(val []byte, chainId byte) error {
+	if err := db.put(append(validatorLiveness, chainId), val); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.put"))
+	}
+	return nil
+}
+
+func (db *db) GetValidatorLiveness(chainId byte) ([]byte, error) {
+	b, err := db.lvdb.Get(append(validatorLiveness, chainId), nil)
+	if err != nil {
+		return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
+	}
+	return b, err
+}
+
+func (db *db) CleanValidatorLiveness() error {
+	iter := db.lvdb.NewIterator(util.BytesPrefix(validatorLiveness), nil)
+	for iter.Next() {
+		err := db.lvdb.Delete(iter.Key(), nil)
+		if err != nil {
+			return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "iter.Error"))
+	}
+	return nil
+}
+
+func (db *db) StoreEvidence(evidenceHash *common.Hash) error {
+	if err := db.put(append(evidencePrefix, evidenceHash[:]...), []byte{}); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.put"))
+	}
+	return nil
+}
+
+func (db *db) HasEvidence(evidenceHash *common.Hash) (bool, error) {
+	return db.hasValue(append(evidencePrefix, evidenceHash[:]...))
+}
+
+func (db *db) CleanEvidences() error {
+	iter := db.lvdb.NewIterator(util.BytesPrefix(evidencePrefix), nil)
+	for iter.Next() {
+		err := db.lvdb.Delete(iter.Key(), nil)
+		if err != nil {
+			return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Delete"))
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "iter.Error"))
+	}
+	return nil
+}
+
+func (db *db) StoreSignedHeight(val []byte, chainId byte) error {
+	if err := db.put(append(signedHeight, chainId), val); err != nil {
+		return database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.put"))
+	}
+	return nil
+}
+
+func (db *db) GetSignedHeight(chainId byte) ([]byte, error) {
+	b, err := db.lvdb.Get(append(signedHeight, chainId), nil)
+	if err != nil {
+		return nil, database.NewDatabaseError(database.UnexpectedError, errors.Wrap(err, "db.lvdb.Get"))
+	}
+	return b, err
+}
+
 /*
 	StoreTransactionIndex
 	Store tx detail location
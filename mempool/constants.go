@@ -1,8 +1,33 @@
 package mempool
 
+import "time"
+
 const (
 	// UnminedHeight is the height used for the "block" height field of the
 	// contextual transaction information provided in a transaction store
 	// when it has not yet been mined into a block.
 	UnminedHeight = 0x7fffffff
+
+	// IncrementalRelayFee is added to fee rate of an evicted tx to get the
+	// minimum fee rate of new txs, in coins per KB
+	IncrementalRelayFee = 1
+
+	// RollingFeeHalfLife is how fast the minimum fee rate raised by evictions
+	// decays, in seconds. It decays faster when the pool is far from full.
+	RollingFeeHalfLife = 12 * 60 * 60
+
+	// MaxReplacementEvictions is the max number of txs in pool replaced by a
+	// new tx
+	MaxReplacementEvictions = 100
+
+	// DumpFileName is the file in data dir which keeps txs of pool between
+	// restarts of node
+	DumpFileName = "mempool.json"
+
+	// DefaultTxSubscriptionBuffer is the number of unread events a
+	// subscription of pool keeps when no buffer size is given
+	DefaultTxSubscriptionBuffer = 1000
+
+	// OrphanTxTTL is how long an orphan tx waits for its parents
+	OrphanTxTTL = 15 * time.Minute
 )
@@ -0,0 +1,182 @@
+package mempool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/transaction"
+	"golang.org/x/crypto/sha3"
+)
+
+/*
+// missingParents returns keys of parents of tx (see blockchain.TxParentKeys)
+// which are neither in pool nor in blockchain, a tx with missing parents is
+// an orphan
+//
+// This function MUST be called with the mempool lock held (for reads).
+*/
+func (tp *TxPool) missingParents(tx transaction.Transaction) []string {
+	missing := []string{}
+	for _, key := range blockchain.TxParentKeys(tx) {
+		if _, ok := tp.poolDependencies[key]; ok {
+			continue
+		}
+		if tp.config.BlockChain.GetTxDependency(key) == nil {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+/*
+// checkTxDependencies rejects tx when another tx in pool already has one of
+// its dependency keys, e.g. a second request or response of the same loan.
+// Txs replaced by tx are left out
+//
+// This function MUST be called with the mempool lock held (for reads).
+*/
+func (tp *TxPool) checkTxDependencies(tx transaction.Transaction, replaced map[common.Hash]*TxDesc) error {
+	for _, key := range blockchain.TxDependencyKeys(tx) {
+		hash, ok := tp.poolDependencies[key]
+		if !ok {
+			continue
+		}
+		if _, ok := replaced[hash]; ok {
+			continue
+		}
+		err := MempoolTxError{}
+		err.Init(RejectDuplicateTx, fmt.Errorf("%+v has the same %s as tx %+v in mempool", tx.Hash().String(), key, hash.String()))
+		return err
+	}
+	return nil
+}
+
+/*
+// validateTxWithPoolParents validates a loan tx whose request or response is
+// still in pool, instead of looking for it in blockchain. It returns false
+// when tx has no such parent in pool
+//
+// This function MUST be called with the mempool lock held (for reads).
+*/
+func (tp *TxPool) validateTxWithPoolParents(tx transaction.Transaction) (bool, error) {
+	switch tx := tx.(type) {
+	case *transaction.TxLoanResponse:
+		// a request in pool has no response in blockchain
+		_, ok := tp.poolDependencies[blockchain.LoanRequestDependency(tx.LoanID)]
+		return ok, nil
+	case *transaction.TxLoanPayment:
+		_, ok := tp.poolDependencies[blockchain.LoanResponseDependency(tx.LoanID)]
+		return ok, nil
+	case *transaction.TxLoanWithdraw:
+		hash, ok := tp.poolDependencies[blockchain.LoanResponseDependency(tx.LoanID)]
+		if !ok {
+			return false, nil
+		}
+		txResponse, ok := tp.pool[hash].Desc.Tx.(*transaction.TxLoanResponse)
+		if !ok || txResponse.Response != transaction.Accept {
+			return true, errors.New("Loan of withdraw is not accepted")
+		}
+		var txRequest transaction.Transaction
+		requestKey := blockchain.LoanRequestDependency(tx.LoanID)
+		if hash, ok := tp.poolDependencies[requestKey]; ok {
+			txRequest = tp.pool[hash].Desc.Tx
+		} else {
+			txRequest = tp.config.BlockChain.GetTxDependency(requestKey)
+		}
+		txLoanRequest, ok := txRequest.(*transaction.TxLoanRequest)
+		if !ok {
+			return true, errors.New("Corresponding loan request not found")
+		}
+		h := make([]byte, 32)
+		sha3.ShakeSum256(h, tx.Key)
+		if !bytes.Equal(h, txLoanRequest.KeyDigest) {
+			return true, errors.New("Wrong key of loan withdraw")
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+/*
+// addTxDependencies links tx with its parents in pool and lets other txs
+// refer to it
+//
+// This function MUST be called with the mempool lock held (for writes).
+*/
+func (tp *TxPool) addTxDependencies(tx transaction.Transaction) {
+	txHash := *tx.Hash()
+	for _, key := range blockchain.TxDependencyKeys(tx) {
+		tp.poolDependencies[key] = txHash
+	}
+	for _, key := range blockchain.TxParentKeys(tx) {
+		parentHash, ok := tp.poolDependencies[key]
+		if !ok || tp.poolChildren[parentHash][txHash] {
+			continue
+		}
+		if _, ok := tp.poolChildren[parentHash]; !ok {
+			tp.poolChildren[parentHash] = make(map[common.Hash]bool)
+		}
+		tp.poolChildren[parentHash][txHash] = true
+		tp.poolParents[txHash] = append(tp.poolParents[txHash], parentHash)
+	}
+}
+
+/*
+// removeTxDependencies unlinks tx from its parents and children in pool, and
+// returns its children
+//
+// This function MUST be called with the mempool lock held (for writes).
+*/
+func (tp *TxPool) removeTxDependencies(tx transaction.Transaction) []common.Hash {
+	txHash := *tx.Hash()
+	for _, key := range blockchain.TxDependencyKeys(tx) {
+		if tp.poolDependencies[key] == txHash {
+			delete(tp.poolDependencies, key)
+		}
+	}
+	for _, parentHash := range tp.poolParents[txHash] {
+		delete(tp.poolChildren[parentHash], txHash)
+		if len(tp.poolChildren[parentHash]) == 0 {
+			delete(tp.poolChildren, parentHash)
+		}
+	}
+	delete(tp.poolParents, txHash)
+
+	children := []common.Hash{}
+	for childHash := range tp.poolChildren[txHash] {
+		children = append(children, childHash)
+		parents := tp.poolParents[childHash]
+		for i, parentHash := range parents {
+			if parentHash == txHash {
+				tp.poolParents[childHash] = append(parents[:i], parents[i+1:]...)
+				break
+			}
+		}
+		if len(tp.poolParents[childHash]) == 0 {
+			delete(tp.poolParents, childHash)
+		}
+	}
+	delete(tp.poolChildren, txHash)
+	return children
+}
+
+/*
+// validateTxWithParents validates tx with data of blockchain like
+// ValidateTxWithBlockChain, a loan tx whose request or response is still in
+// pool is validated with it instead
+//
+// This function MUST be called with the mempool lock held (for reads).
+*/
+func (tp *TxPool) validateTxWithParents(tx transaction.Transaction, chainID byte) error {
+	inPool, err := tp.validateTxWithPoolParents(tx)
+	if !inPool {
+		return tp.ValidateTxWithBlockChain(tx, chainID)
+	}
+	if err != nil {
+		return err
+	}
+	return tp.config.BlockChain.ValidateDoubleSpend(tx, chainID)
+}
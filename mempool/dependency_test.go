@@ -0,0 +1,165 @@
+package mempool
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/privacy-protocol"
+	"github.com/ninjadotorg/constant/transaction"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/sha3"
+)
+
+// newTestLoanTxs returns a request, an accepted response and a payment of
+// loanID, the request is withdrawn with key
+func newTestLoanTxs(loanID []byte, key []byte) (*transaction.TxLoanRequest, *transaction.TxLoanResponse, *transaction.TxLoanPayment) {
+	keyDigest := make([]byte, transaction.LoanKeyDigestLen)
+	sha3.ShakeSum256(keyDigest, key)
+	request := &transaction.TxLoanRequest{
+		Tx: newTestNormalTx(common.TxLoanRequest),
+		LoanRequest: &transaction.LoanRequest{
+			LoanID:           loanID,
+			LoanAmount:       1,
+			CollateralAmount: big.NewInt(1),
+			ReceiveAddress:   &privacy.PaymentAddress{Pk: make([]byte, 33), Tk: make([]byte, 33)},
+			KeyDigest:        keyDigest,
+		},
+	}
+	response := &transaction.TxLoanResponse{
+		Tx:           newTestNormalTx(common.TxLoanResponse),
+		LoanResponse: &transaction.LoanResponse{LoanID: loanID, Response: transaction.Accept},
+	}
+	payment := &transaction.TxLoanPayment{
+		Tx:          newTestNormalTx(common.TxLoanPayment),
+		LoanPayment: &transaction.LoanPayment{LoanID: loanID},
+	}
+	return request, response, payment
+}
+
+func TestTxDependencies(t *testing.T) {
+	Logger.Init(common.Disabled)
+	mempool := &TxPool{}
+	mempool.Init(&Config{})
+	subscription := mempool.Subscribe(10)
+	request, response, payment := newTestLoanTxs([]byte{1}, []byte{2})
+	mempool.addTx(request, 0, 1, 10)
+	mempool.addTx(response, 0, 1, 10)
+	mempool.addTx(payment, 0, 1, 10)
+	assert.True(t, mempool.poolChildren[*request.Hash()][*response.Hash()])
+	assert.True(t, mempool.poolChildren[*response.Hash()][*payment.Hash()])
+	assert.Equal(t, []common.Hash{*response.Hash()}, mempool.poolParents[*payment.Hash()])
+
+	// a loan has only one response
+	_, otherResponse, _ := newTestLoanTxs([]byte{1}, []byte{2})
+	otherResponse.Response = transaction.Reject
+	assert.NotNil(t, mempool.checkTxDependencies(otherResponse, nil))
+	assert.Nil(t, mempool.checkTxDependencies(otherResponse, map[common.Hash]*TxDesc{*response.Hash(): nil}))
+
+	// withdraw is validated with request and response in pool
+	withdraw := &transaction.TxLoanWithdraw{
+		Tx:           newTestNormalTx(common.TxLoanWithdraw),
+		LoanWithdraw: &transaction.LoanWithdraw{LoanID: []byte{1}, Key: []byte{2}},
+	}
+	inPool, err := mempool.validateTxWithPoolParents(withdraw)
+	assert.True(t, inPool)
+	assert.Nil(t, err)
+	withdraw.Key = []byte{3}
+	inPool, err = mempool.validateTxWithPoolParents(withdraw)
+	assert.True(t, inPool)
+	assert.NotNil(t, err)
+
+	// descendants of a removed tx are removed too
+	mempool.RemoveTx(request)
+	assert.Equal(t, 0, len(mempool.pool))
+	assert.Equal(t, 0, len(mempool.poolDependencies))
+	assert.Equal(t, 0, len(mempool.poolParents))
+	assert.Equal(t, 0, len(mempool.poolChildren))
+	reasons := []string{}
+	for len(subscription.Events()) > 0 {
+		event := <-subscription.Events()
+		if event.Type == TxRemoved {
+			reasons = append(reasons, event.Reason)
+		}
+	}
+	assert.Equal(t, []string{TxRemovedByCaller, TxRemovedAncestor, TxRemovedAncestor}, reasons)
+
+	// descendants of a mined tx are kept
+	mempool.addTx(request, 0, 1, 10)
+	mempool.addTx(response, 0, 1, 10)
+	var minedTx transaction.Transaction = request
+	mempool.removeTx(&minedTx, TxRemovedMined)
+	assert.True(t, mempool.isTxInPool(response.Hash()))
+	assert.Equal(t, 0, len(mempool.poolParents))
+	assert.Equal(t, 0, len(mempool.poolChildren))
+}
+
+func TestAddOrphan(t *testing.T) {
+	Logger.Init(common.Disabled)
+	mempool := &TxPool{}
+	mempool.Init(&Config{})
+	_, response, payment := newTestLoanTxs([]byte{1}, []byte{2})
+	missing := blockchain.TxParentKeys(response)
+	err := mempool.addOrphan(response, missing)
+	assert.Equal(t, ErrCodeMessage[RejectOrphanTx].code, err.(MempoolTxError).code)
+	assert.Equal(t, 0, len(mempool.orphans))
+
+	mempool.config.MaxOrphanTxs = 1
+	mempool.addOrphan(response, missing)
+	assert.Equal(t, 1, mempool.OrphanCount())
+	err = mempool.addOrphan(response, missing)
+	assert.Equal(t, ErrCodeMessage[RejectDuplicateTx].code, err.(MempoolTxError).code)
+
+	// the oldest orphan is evicted from full orphan pool
+	mempool.addOrphan(payment, blockchain.TxParentKeys(payment))
+	assert.Equal(t, 1, mempool.OrphanCount())
+	assert.NotNil(t, mempool.orphans[*payment.Hash()])
+	assert.Nil(t, mempool.orphansByParent[missing[0]])
+
+	assert.Equal(t, 0, mempool.expireOrphans(time.Now()))
+	assert.Equal(t, 1, mempool.expireOrphans(time.Now().Add(OrphanTxTTL+time.Second)))
+	assert.Equal(t, 0, len(mempool.orphans))
+	assert.Equal(t, 0, len(mempool.orphansByParent))
+}
+
+func TestProcessTransaction_Orphans(t *testing.T) {
+	chain, db, cleanup := newTestBlockChain(t)
+	defer cleanup()
+	mempool := newTestDumpPool(chain, db, common.EmptyString)
+	mempool.config.MaxOrphanTxs = 10
+	request, response, payment := newTestLoanTxs([]byte{1}, []byte{2})
+	request.Params = chain.BestState[0].BestBlock.Header.LoanParams
+	request.CollateralType = "ETH"
+	request.Fee, response.Fee, payment.Fee = 1000000, 1000000, 1000000
+
+	// children coming before their parents wait in orphan pool
+	_, err := mempool.ProcessTransaction(payment)
+	assert.Equal(t, ErrCodeMessage[RejectOrphanTx].code, err.(MempoolTxError).code)
+	_, err = mempool.ProcessTransaction(response)
+	assert.Equal(t, ErrCodeMessage[RejectOrphanTx].code, err.(MempoolTxError).code)
+	assert.Equal(t, 2, mempool.OrphanCount())
+	assert.Equal(t, 0, mempool.Count())
+
+	// the request brings every orphan in, parents first, to be relayed
+	txDescs, err := mempool.ProcessTransaction(request)
+	assert.Nil(t, err)
+	txs := []transaction.Transaction{}
+	for _, txDesc := range txDescs {
+		txs = append(txs, txDesc.Desc.Tx)
+	}
+	assert.Equal(t, []transaction.Transaction{request, response, payment}, txs)
+	assert.Equal(t, 0, mempool.OrphanCount())
+	assert.Equal(t, 3, mempool.Count())
+
+	// MaybeAcceptTransaction accepts orphans too
+	mempool = newTestDumpPool(chain, db, common.EmptyString)
+	mempool.config.MaxOrphanTxs = 10
+	_, _, err = mempool.MaybeAcceptTransaction(response)
+	assert.Equal(t, ErrCodeMessage[RejectOrphanTx].code, err.(MempoolTxError).code)
+	_, _, err = mempool.MaybeAcceptTransaction(request)
+	assert.Nil(t, err)
+	assert.True(t, mempool.isTxInPool(response.Hash()))
+	assert.Equal(t, 0, mempool.OrphanCount())
+}
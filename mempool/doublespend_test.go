@@ -0,0 +1,26 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDoubleSpendTxWithCurrentMempool(t *testing.T) {
+	mempool := &TxPool{
+		poolNullifiers: map[common.Hash][][]byte{
+			common.Hash{1}: {{1}, {2}},
+		},
+	}
+	spending := func(nullifiers ...[]byte) transaction.Tx {
+		return transaction.Tx{Descs: []*transaction.JoinSplitDesc{{Nullifiers: nullifiers}}}
+	}
+	// a nullifier spent by a tx in pool is a double spend
+	assert.NotNil(t, mempool.ValidateDoubleSpendTxWithCurrentMempool(spending([]byte{2})))
+	assert.NotNil(t, mempool.ValidateDoubleSpendTxWithCurrentMempool(spending([]byte{3}, []byte{1})))
+	// nullifiers unknown to pool are not
+	assert.Nil(t, mempool.ValidateDoubleSpendTxWithCurrentMempool(spending([]byte{3})))
+	assert.Nil(t, mempool.ValidateDoubleSpendTxWithCurrentMempool(spending()))
+}
@@ -8,6 +8,10 @@ const (
 	RejectVersion
 	RejectInvalidFee
 	CanNotCheckDoubleSpend
+	RejectLowFeeRate
+	RejectPoolFull
+	RejectReplacement
+	RejectOrphanTx
 )
 
 var ErrCodeMessage = map[int]struct {
@@ -21,6 +25,10 @@ var ErrCodeMessage = map[int]struct {
 	RejectInvalidFee:       {-1004, "Reject invalid fee"},
 	RejectVersion:          {-1005, "Reject invalid version"},
 	CanNotCheckDoubleSpend: {-1006, "Can not check double spend"},
+	RejectLowFeeRate:       {-1007, "Reject fee rate under minimum fee rate of mempool"},
+	RejectPoolFull:         {-1008, "Reject tx, mempool is full of txs with higher fee rate"},
+	RejectReplacement:      {-1009, "Reject tx replacing txs in mempool against replacement rules"},
+	RejectOrphanTx:         {-1010, "Orphan tx, its parent tx is neither in mempool nor in blockchain"},
 }
 
 type MempoolTxError struct {
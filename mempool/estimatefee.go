@@ -10,39 +10,46 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"math"
-	"math/rand"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ninjadotorg/constant/blockchain"
 	"github.com/ninjadotorg/constant/common"
-	"github.com/ninjadotorg/constant/transaction"
 )
 
 const (
-	// estimateFeeDepth is the maximum number of blocks before a transaction
-	// is confirmed that we want to track.
-	estimateFeeDepth = 25
+	// estimateFeeMaxSamples is the number of latest included txs which
+	// estimations of a chain are made from.
+	estimateFeeMaxSamples = 2000
 
-	// estimateFeeBinSize is the number of txs stored in each bin.
-	estimateFeeBinSize = 100
+	// estimateFeeMinSamples is the minimum number of txs with a fee rate
+	// higher than an estimated one which must have been seen, so a few
+	// lucky txs do not make an estimation.
+	estimateFeeMinSamples = 10
 
-	// estimateFeeMaxReplacements is the max number of replacements that
-	// can be made by the txs found in a given block.
-	estimateFeeMaxReplacements = 10
+	// estimateFeeBlockWindow is the number of latest block intervals which
+	// block time of a chain is averaged over.
+	estimateFeeBlockWindow = 100
 
-	// DefaultEstimateFeeMaxRollback is the default number of rollbacks
-	// allowed by the fee estimator for orphaned blocks.
-	DefaultEstimateFeeMaxRollback = 2
+	// estimateFeeMaxWait is the number of seconds an observed tx is
+	// followed. A tx which is not included by then (it was expired,
+	// replaced or its fee is too low) is forgotten.
+	estimateFeeMaxWait = 6 * 60 * 60
 
 	// DefaultEstimateFeeMinRegisteredBlocks is the default minimum
 	// number of blocks which must be observed by the fee estimator before
 	// it will provide fee estimations.
 	DefaultEstimateFeeMinRegisteredBlocks = 3
+)
 
-	bytePerKb = 1000
+// Confidence of the bands of a fee estimation, the part of txs paying the
+// estimated fee rate which were included within the wanted time.
+const (
+	EstimateFeeLowConfidence    = 0.5
+	EstimateFeeMediumConfidence = 0.85
+	EstimateFeeHighConfidence   = 0.95
 )
 
 var (
@@ -60,74 +67,69 @@ func NewCoinPerKilobyte(fee uint64, size uint64) CoinPerKilobyte {
 	return CoinPerKilobyte(float64(fee) / float64(size))
 }
 
-// observedTransaction represents an observed transaction and some
-// additional data required for the fee estimation algorithm.
+// observedTransaction is a tx of mempool which is waiting to be included in
+// a block of its chain.
 type observedTransaction struct {
 	// A transaction hash.
 	hash common.Hash
 
-	// The fee per byte of the transaction in coins.
+	// The fee per kilobyte of the transaction in coins.
 	feeRate CoinPerKilobyte
 
-	// The block height when it was observed.
-	observed int32
-
-	// The height of the block in which it was mined.
-	// If the transaction has not yet been mined, it is zero.
-	mined int32
+	// The unix time when it entered mempool.
+	observed int64
 }
 
 func (o *observedTransaction) Serialize(w io.Writer) {
 	binary.Write(w, binary.BigEndian, o.hash)
 	binary.Write(w, binary.BigEndian, o.feeRate)
 	binary.Write(w, binary.BigEndian, o.observed)
-	binary.Write(w, binary.BigEndian, o.mined)
 }
 
 func deserializeObservedTransaction(r io.Reader) (*observedTransaction, error) {
 	ot := observedTransaction{}
-
-	// The first 32 bytes should be a hash.
-	binary.Read(r, binary.BigEndian, &ot.hash)
-
-	// The next 8 are CoinPerByte
-	binary.Read(r, binary.BigEndian, &ot.feeRate)
-
-	// And next there are two uint32's.
-	binary.Read(r, binary.BigEndian, &ot.observed)
-	binary.Read(r, binary.BigEndian, &ot.mined)
-
+	err := binary.Read(r, binary.BigEndian, &ot.hash)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Read(r, binary.BigEndian, &ot.feeRate)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Read(r, binary.BigEndian, &ot.observed)
+	if err != nil {
+		return nil, err
+	}
 	return &ot, nil
 }
 
-// registeredBlock has the hash of a block and the list of transactions
-// it mined which had been previously observed by the feeEstimator. It
-// is used if Rollback is called to reverse the effect of registering
-// a block.
-type registeredBlock struct {
-	hash         common.Hash
-	transactions []*observedTransaction
+// inclusionSample is an observed tx which was included in a block, with the
+// number of seconds it waited in mempool.
+type inclusionSample struct {
+	feeRate CoinPerKilobyte
+	wait    int64
 }
 
-func (rb *registeredBlock) serialize(w io.Writer, txs map[*observedTransaction]uint32) {
-	binary.Write(w, binary.BigEndian, rb.hash)
-
-	binary.Write(w, binary.BigEndian, uint32(len(rb.transactions)))
-	for _, o := range rb.transactions {
-		binary.Write(w, binary.BigEndian, txs[o])
-	}
+// FeeEstimate is a fee estimation of a chain: fee rates paid by txs which
+// were included within Wait, with low, medium and high confidence.
+type FeeEstimate struct {
+	Wait    time.Duration
+	Low     CoinPerKilobyte
+	Medium  CoinPerKilobyte
+	High    CoinPerKilobyte
+	Samples int
 }
 
-// feeEstimator manages the data necessary to create
-// fee estimations. It is safe for concurrent access.
+/*
+FeeEstimator estimates fee rates of a chain by the time txs take from entering
+mempool to being included in a block of the chain. Blocks of a chain are only
+produced at the turn of its producer and never faster than MinBlockWaitTime,
+so the estimator learns block time of the chain from the blocks it registers
+and turns a number of blocks into a time to wait. Fee rates are per KB of
+virtual size, so large proofs of shielded txs are paid for.
+It is safe for concurrent access.
+*/
 type FeeEstimator struct {
-	maxRollback uint32
-	binSize     int32
-
-	// The maximum number of replacements that can be made in a single
-	// bin per block. Default is estimateFeeMaxReplacements
-	maxReplacements int32
-
 	// The minimum number of blocks that can be registered with the fee
 	// estimator before it will provide answers.
 	minRegisteredBlocks uint32
@@ -138,30 +140,30 @@ type FeeEstimator struct {
 	// The number of blocks that have been registered.
 	numBlocksRegistered uint32
 
-	mtx      sync.RWMutex
-	observed map[common.Hash]*observedTransaction
-	bin      [estimateFeeDepth][]*observedTransaction
+	// The unix time of the last registered block.
+	lastBlockTime int64
+
+	// Seconds between latest registered blocks, the oldest first.
+	blockIntervals []int64
 
-	// The cached estimates.
-	cached []CoinPerKilobyte
+	// Latest included txs, the oldest first.
+	samples []inclusionSample
 
-	// Transactions that have been removed from the bins. This allows us to
-	// revert in case of an orphaned block.
-	dropped []*registeredBlock
+	// Txs of mempool which are not included yet.
+	observed map[common.Hash]*observedTransaction
+
+	mtx sync.RWMutex
 }
 
-// NewFeeEstimator creates a feeEstimator for which at most maxRollback blocks
-// can be unregistered and which returns an error unless minRegisteredBlocks
-// have been registered with it.
-func NewFeeEstimator(maxRollback, minRegisteredBlocks uint32) *FeeEstimator {
+// NewFeeEstimator creates a feeEstimator which returns an error unless
+// minRegisteredBlocks have been registered with it.
+func NewFeeEstimator(minRegisteredBlocks uint32) *FeeEstimator {
 	return &FeeEstimator{
-		maxRollback:         maxRollback,
 		minRegisteredBlocks: minRegisteredBlocks,
 		lastKnownHeight:     UnminedHeight,
-		binSize:             estimateFeeBinSize,
-		maxReplacements:     estimateFeeMaxReplacements,
+		blockIntervals:      make([]int64, 0, estimateFeeBlockWindow),
+		samples:             make([]inclusionSample, 0, estimateFeeMaxSamples),
 		observed:            make(map[common.Hash]*observedTransaction),
-		dropped:             make([]*registeredBlock, 0, maxRollback),
 	}
 }
 
@@ -170,406 +172,214 @@ func (ef *FeeEstimator) ObserveTransaction(t *TxDesc) {
 	ef.mtx.Lock()
 	defer ef.mtx.Unlock()
 
-	// If we haven't seen a block yet we don't know when this one arrived,
-	// so we ignore it.
-	if ef.lastKnownHeight == UnminedHeight {
+	hash := *t.Desc.Tx.Hash()
+	if _, ok := ef.observed[hash]; ok {
 		return
 	}
-
-	hash := *t.Desc.Tx.Hash()
-	if _, ok := ef.observed[hash]; !ok {
-		size := t.Desc.Tx.GetTxVirtualSize()
-		ef.observed[hash] = &observedTransaction{
-			hash:     hash,
-			feeRate:  NewCoinPerKilobyte(uint64(t.Desc.Fee), size),
-			observed: t.Desc.Height,
-			mined:    UnminedHeight,
-		}
+	size := t.Desc.Tx.GetTxVirtualSize()
+	if size == 0 {
+		size = 1
+	}
+	ef.observed[hash] = &observedTransaction{
+		hash:     hash,
+		feeRate:  NewCoinPerKilobyte(t.Desc.Fee, size),
+		observed: t.Desc.Added.Unix(),
 	}
 }
 
-// RegisterBlock informs the fee estimator of a new block to take into account.
-func (ef *FeeEstimator) RegisterBlock(block *blockchain.Block) error {
+// RemoveTransaction forgets an observed tx which left mempool without being
+// included, it was expired, evicted, replaced or found invalid. It would
+// count as a tx whose fee rate was too low to be included otherwise.
+func (ef *FeeEstimator) RemoveTransaction(hash *common.Hash) {
 	ef.mtx.Lock()
 	defer ef.mtx.Unlock()
 
-	// The previous sorted list is invalid, so delete it.
-	ef.cached = nil
+	delete(ef.observed, *hash)
+}
+
+// RegisterBlock informs the fee estimator of a new block of its chain, every
+// observed tx in block becomes a sample of the time it waited. A block which
+// is not newer than the last registered one is ignored.
+func (ef *FeeEstimator) RegisterBlock(block *blockchain.Block) {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
 
 	height := block.Header.Height
-	if height != ef.lastKnownHeight+1 && ef.lastKnownHeight != UnminedHeight {
-		return fmt.Errorf("intermediate block not recorded; current height is %d; new height is %d",
-			ef.lastKnownHeight, height)
+	if ef.lastKnownHeight != UnminedHeight && height <= ef.lastKnownHeight {
+		return
 	}
-
-	// Update the last known height.
 	ef.lastKnownHeight = height
 	ef.numBlocksRegistered++
 
-	// Randomly order txs in block.
-	transactions := make(map[*transaction.Tx]struct{})
-	for _, t := range block.Transactions {
-		switch t.GetType() {
-		case common.TxNormalType, common.TxSalaryType:
-			{
-				transactions[t.(*transaction.Tx)] = struct{}{}
-			}
+	blockTime := block.Header.Timestamp
+	if ef.lastBlockTime > 0 && blockTime > ef.lastBlockTime {
+		ef.blockIntervals = append(ef.blockIntervals, blockTime-ef.lastBlockTime)
+		if over := len(ef.blockIntervals) - estimateFeeBlockWindow; over > 0 {
+			ef.blockIntervals = ef.blockIntervals[over:]
 		}
 	}
-
-	// Count the number of replacements we make per bin so that we don't
-	// replace too many.
-	var replacementCounts [estimateFeeDepth]int
-
-	// Keep track of which txs were dropped in case of an orphan block.
-	dropped := &registeredBlock{
-		hash:         *block.Hash(),
-		transactions: make([]*observedTransaction, 0, 100),
+	if blockTime > ef.lastBlockTime {
+		ef.lastBlockTime = blockTime
 	}
 
-	// Go through the txs in the block.
-	for t := range transactions {
-		hash := *t.Hash()
-
-		// Have we observed this tx in the mempool?
+	for _, tx := range block.Transactions {
+		hash := *tx.Hash()
 		o, ok := ef.observed[hash]
 		if !ok {
 			continue
 		}
-
-		// Put the observed tx in the oppropriate bin.
-		blocksToConfirm := height - o.observed - 1
-
-		// This shouldn't happen if the fee estimator works correctly,
-		// but return an error if it does.
-		if o.mined != UnminedHeight {
-			Logger.log.Error("Estimate fee: transaction ", hash.String(), " has already been mined")
-			return errors.New("Transaction has already been mined")
-		}
-
-		// This shouldn't happen but check just in case to avoid
-		// an out-of-bounds array index later.
-		if blocksToConfirm >= estimateFeeDepth {
-			continue
-		}
-
-		// Make sure we do not replace too many transactions per min.
-		if replacementCounts[blocksToConfirm] == int(ef.maxReplacements) {
-			continue
-		}
-
-		o.mined = height
-
-		replacementCounts[blocksToConfirm]++
-
-		bin := ef.bin[blocksToConfirm]
-
-		// Remove a random element and replace it with this new tx.
-		if len(bin) == int(ef.binSize) {
-			// Don't drop transactions we have just added from this same block.
-			l := int(ef.binSize) - replacementCounts[blocksToConfirm]
-			drop := rand.Intn(l)
-			dropped.transactions = append(dropped.transactions, bin[drop])
-
-			bin[drop] = bin[l-1]
-			bin[l-1] = o
-		} else {
-			bin = append(bin, o)
+		wait := blockTime - o.observed
+		if wait < 0 {
+			wait = 0
 		}
-		ef.bin[blocksToConfirm] = bin
+		ef.samples = append(ef.samples, inclusionSample{
+			feeRate: o.feeRate,
+			wait:    wait,
+		})
+		delete(ef.observed, hash)
+	}
+	if over := len(ef.samples) - estimateFeeMaxSamples; over > 0 {
+		ef.samples = ef.samples[over:]
 	}
 
-	// Go through the mempool for txs that have been in too long.
+	// Forget txs which have waited too long.
 	for hash, o := range ef.observed {
-		if o.mined == UnminedHeight && height-o.observed >= estimateFeeDepth {
+		if blockTime-o.observed > estimateFeeMaxWait {
 			delete(ef.observed, hash)
 		}
 	}
-
-	// Add dropped list to history.
-	if ef.maxRollback == 0 {
-		return nil
-	}
-
-	if uint32(len(ef.dropped)) == ef.maxRollback {
-		ef.dropped = append(ef.dropped[1:], dropped)
-	} else {
-		ef.dropped = append(ef.dropped, dropped)
-	}
-
-	return nil
 }
 
 // LastKnownHeight returns the height of the last block which was registered.
 func (ef *FeeEstimator) LastKnownHeight() int32 {
-	ef.mtx.Lock()
-	defer ef.mtx.Unlock()
+	ef.mtx.RLock()
+	defer ef.mtx.RUnlock()
 
 	return ef.lastKnownHeight
 }
 
-// Rollback unregisters a recently registered block from the feeEstimator.
-// This can be used to reverse the effect of an orphaned block on the fee
-// estimator. The maximum number of rollbacks allowed is given by
-// maxRollbacks.
-//
-// Note: not everything can be rolled back because some transactions are
-// deleted if they have been observed too long ago. That means the result
-// of Rollback won't always be exactly the same as if the last block had not
-// happened, but it should be close enough.
-func (ef *FeeEstimator) Rollback(hash *common.Hash) error {
-	ef.mtx.Lock()
-	defer ef.mtx.Unlock()
-
-	// Find this block in the stack of recent registered blocks.
-	var n int
-	for n = 1; n <= len(ef.dropped); n++ {
-		if ef.dropped[len(ef.dropped)-n].hash.IsEqual(hash) {
-			break
-		}
-	}
-
-	if n > len(ef.dropped) {
-		return errors.New("no such block was recently registered")
-	}
-
-	for i := 0; i < n; i++ {
-		ef.rollback()
-	}
+// AverageBlockTime returns the time between blocks of the chain, averaged
+// over the latest registered blocks. It is never lower than MinBlockWaitTime.
+func (ef *FeeEstimator) AverageBlockTime() time.Duration {
+	ef.mtx.RLock()
+	defer ef.mtx.RUnlock()
 
-	return nil
+	return time.Duration(ef.averageBlockTime()) * time.Second
 }
 
-// rollback rolls back the effect of the last block in the stack
-// of registered blocks.
-func (ef *FeeEstimator) rollback() {
-	// The previous sorted list is invalid, so delete it.
-	ef.cached = nil
-
-	// pop the last list of dropped txs from the stack.
-	last := len(ef.dropped) - 1
-	if last == -1 {
-		// Cannot really happen because the exported calling function
-		// only rolls back a block already known to be in the list
-		// of dropped transactions.
-		return
+// averageBlockTime returns average block time in seconds, MaxBlockTime when
+// no interval is known yet.
+func (ef *FeeEstimator) averageBlockTime() int64 {
+	if len(ef.blockIntervals) == 0 {
+		return common.MaxBlockTime
 	}
-
-	dropped := ef.dropped[last]
-
-	// where we are in each bin as we replace txs?
-	var replacementCounters [estimateFeeDepth]int
-
-	// Go through the txs in the dropped block.
-	for _, o := range dropped.transactions {
-		// Which bin was this tx in?
-		blocksToConfirm := o.mined - o.observed - 1
-
-		bin := ef.bin[blocksToConfirm]
-
-		var counter = replacementCounters[blocksToConfirm]
-
-		// Continue to go through that bin where we left off.
-		for {
-			if counter >= len(bin) {
-				// Panic, as we have entered an unrecoverable invalid state.
-				panic(errors.New("illegal state: cannot rollback dropped transaction"))
-			}
-
-			prev := bin[counter]
-
-			if prev.mined == ef.lastKnownHeight {
-				prev.mined = UnminedHeight
-
-				bin[counter] = o
-
-				counter++
-				break
-			}
-
-			counter++
-		}
-
-		replacementCounters[blocksToConfirm] = counter
+	total := int64(0)
+	for _, interval := range ef.blockIntervals {
+		total += interval
 	}
-
-	// Continue going through bins to find other txs to remove
-	// which did not replace any other when they were entered.
-	for i, j := range replacementCounters {
-		for {
-			l := len(ef.bin[i])
-			if j >= l {
-				break
-			}
-
-			prev := ef.bin[i][j]
-
-			if prev.mined == ef.lastKnownHeight {
-				prev.mined = UnminedHeight
-
-				newBin := append(ef.bin[i][0:j], ef.bin[i][j+1:l]...)
-				// leak but it causes a panic when it is uncommented.
-				// ef.bin[i][j] = nil
-				ef.bin[i] = newBin
-
-				continue
-			}
-
-			j++
-		}
+	average := total / int64(len(ef.blockIntervals))
+	if average < common.MinBlockWaitTime {
+		return common.MinBlockWaitTime
 	}
-
-	ef.dropped = ef.dropped[0:last]
-
-	// The number of blocks the fee estimator has seen is decrimented.
-	ef.numBlocksRegistered--
-	ef.lastKnownHeight--
+	return average
 }
 
-// estimateFeeSet is a set of txs that can that is sorted
-// by the fee per kb rate.
-type estimateFeeSet struct {
-	feeRate []CoinPerKilobyte
-	bin     [estimateFeeDepth]uint32
+// waitResult is a fee rate with whether a tx paying it was included within
+// the wanted time.
+type waitResult struct {
+	feeRate  CoinPerKilobyte
+	included bool
 }
 
-func (b *estimateFeeSet) Len() int { return len(b.feeRate) }
-
-func (b *estimateFeeSet) Less(i, j int) bool {
-	return b.feeRate[i] > b.feeRate[j]
-}
-
-func (b *estimateFeeSet) Swap(i, j int) {
-	b.feeRate[i], b.feeRate[j] = b.feeRate[j], b.feeRate[i]
-}
-
-// estimateFee returns the estimated fee for a transaction
-// to confirm in confirmations blocks from now, given
-// the data set we have collected.
-func (b *estimateFeeSet) estimateFee(confirmations int) CoinPerKilobyte {
-	if confirmations <= 0 {
-		return CoinPerKilobyte(math.Inf(1))
-	}
-
-	if confirmations > estimateFeeDepth {
-		return 0
-	}
-
-	// We don't have any transactions!
-	if len(b.feeRate) == 0 {
-		return 0
-	}
-
-	var min, max int = 0, 0
-	for i := 0; i < confirmations-1; i++ {
-		min += int(b.bin[i])
-	}
-
-	max = min + int(b.bin[confirmations-1]) - 1
-	if max < min {
-		max = min
-	}
-	feeIndex := (min + max) / 2
-	if feeIndex >= len(b.feeRate) {
-		feeIndex = len(b.feeRate) - 1
+// waitResults returns samples by fee rate, the highest first. Txs still in
+// mempool which have waited longer than wait already count as not included.
+func (ef *FeeEstimator) waitResults(wait int64, now int64) []waitResult {
+	results := make([]waitResult, 0, len(ef.samples)+len(ef.observed))
+	for _, sample := range ef.samples {
+		results = append(results, waitResult{
+			feeRate:  sample.feeRate,
+			included: sample.wait <= wait,
+		})
+	}
+	for _, o := range ef.observed {
+		if now-o.observed > wait {
+			results = append(results, waitResult{feeRate: o.feeRate})
+		}
 	}
-
-	return b.feeRate[feeIndex]
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].feeRate > results[j].feeRate
+	})
+	return results
 }
 
-// newEstimateFeeSet creates a temporary data structure that
-// can be used to find all fee estimates.
-func (ef *FeeEstimator) newEstimateFeeSet() *estimateFeeSet {
-	set := &estimateFeeSet{}
-
-	capacity := 0
-	for i, b := range ef.bin {
-		l := len(b)
-		set.bin[i] = uint32(l)
-		capacity += l
+// estimateFee returns the lowest fee rate for which at least confidence of
+// txs paying it or more were included in time. The highest seen fee rate is
+// returned when no fee rate is good enough.
+func estimateFee(results []waitResult, confidence float64) CoinPerKilobyte {
+	if len(results) == 0 {
+		return 0
 	}
-
-	set.feeRate = make([]CoinPerKilobyte, capacity)
-
-	i := 0
-	for _, b := range ef.bin {
-		for _, o := range b {
-			set.feeRate[i] = o.feeRate
-			i++
+	estimate := results[0].feeRate
+	total, included := 0, 0
+	for i, result := range results {
+		total++
+		if result.included {
+			included++
+		}
+		// only check at the end of txs of the same fee rate
+		if i+1 < len(results) && results[i+1].feeRate == result.feeRate {
+			continue
+		}
+		if total >= estimateFeeMinSamples && float64(included) >= confidence*float64(total) {
+			estimate = result.feeRate
 		}
 	}
-
-	sort.Sort(set)
-
-	return set
-}
-
-// estimates returns the set of all fee estimates from 1 to estimateFeeDepth
-// confirmations from now.
-func (ef *FeeEstimator) estimates() []CoinPerKilobyte {
-	set := ef.newEstimateFeeSet()
-
-	estimates := make([]CoinPerKilobyte, estimateFeeDepth)
-	for i := 0; i < estimateFeeDepth; i++ {
-		estimates[i] = set.estimateFee(i + 1)
-	}
-
-	return estimates
+	return estimate
 }
 
-// EstimateFee estimates the fee per byte to have a tx confirmed a given
-// number of blocks from now.
-func (ef *FeeEstimator) EstimateFee(numBlocks uint32) (CoinPerKilobyte, error) {
-	ef.mtx.Lock()
-	defer ef.mtx.Unlock()
+// EstimateFeeBands estimates the fee per kilobyte to have a tx included in a
+// block of the chain within a given number of blocks from now, with low,
+// medium and high confidence.
+func (ef *FeeEstimator) EstimateFeeBands(numBlocks uint32) (FeeEstimate, error) {
+	ef.mtx.RLock()
+	defer ef.mtx.RUnlock()
 
-	// If the number of registered blocks is below the minimum, return
-	// an error.
 	if ef.numBlocksRegistered < ef.minRegisteredBlocks {
-		return 0, errors.New("not enough blocks have been observed")
+		return FeeEstimate{}, errors.New("not enough blocks have been observed")
 	}
-
 	if numBlocks == 0 {
-		return 0, errors.New("cannot confirm transaction in zero blocks")
+		return FeeEstimate{}, errors.New("cannot confirm transaction in zero blocks")
 	}
-
-	if numBlocks > estimateFeeDepth {
-		return 0, fmt.Errorf(
-			"can only estimate fees for up to %d blocks from now",
-			estimateFeeBinSize)
+	if len(ef.samples) < estimateFeeMinSamples {
+		return FeeEstimate{}, fmt.Errorf("not enough transactions have been included, %d of %d", len(ef.samples), estimateFeeMinSamples)
 	}
 
-	// If there are no cached results, generate them.
-	if ef.cached == nil {
-		ef.cached = ef.estimates()
-	}
+	wait := int64(numBlocks) * ef.averageBlockTime()
+	results := ef.waitResults(wait, time.Now().Unix())
+	return FeeEstimate{
+		Wait:    time.Duration(wait) * time.Second,
+		Low:     estimateFee(results, EstimateFeeLowConfidence),
+		Medium:  estimateFee(results, EstimateFeeMediumConfidence),
+		High:    estimateFee(results, EstimateFeeHighConfidence),
+		Samples: len(results),
+	}, nil
+}
 
-	return ef.cached[int(numBlocks)-1], nil
+// EstimateFee estimates the fee per kilobyte to have a tx included a given
+// number of blocks from now, with medium confidence.
+func (ef *FeeEstimator) EstimateFee(numBlocks uint32) (CoinPerKilobyte, error) {
+	estimate, err := ef.EstimateFeeBands(numBlocks)
+	if err != nil {
+		return 0, err
+	}
+	return estimate.Medium, nil
 }
 
 // In case the format for the serialized version of the feeEstimator changes,
 // we use a version number. If the version number changes, it does not make
 // sense to try to upgrade a previous version to a new version. Instead, just
 // start fee estimation over.
-const estimateFeeSaveVersion = 1
-
-func deserializeRegisteredBlock(r io.Reader, txs map[uint32]*observedTransaction) (*registeredBlock, error) {
-	var lenTransactions uint32
-
-	rb := &registeredBlock{}
-	binary.Read(r, binary.BigEndian, &rb.hash)
-	binary.Read(r, binary.BigEndian, &lenTransactions)
-
-	rb.transactions = make([]*observedTransaction, lenTransactions)
-
-	for i := uint32(0); i < lenTransactions; i++ {
-		var index uint32
-		binary.Read(r, binary.BigEndian, &index)
-		rb.transactions[i] = txs[index]
-	}
-
-	return rb, nil
-}
+const estimateFeeSaveVersion = 2
 
 // FeeEstimatorState represents a saved feeEstimator that can be
 // restored with data from an earlier session of the program.
@@ -593,57 +403,39 @@ func (q observedTxSet) Swap(i, j int) {
 // Save records the current state of the feeEstimator to a []byte that
 // can be restored later.
 func (ef *FeeEstimator) Save() FeeEstimatorState {
-	ef.mtx.Lock()
-	defer ef.mtx.Unlock()
+	ef.mtx.RLock()
+	defer ef.mtx.RUnlock()
 
 	w := bytes.NewBuffer(make([]byte, 0))
 
 	binary.Write(w, binary.BigEndian, uint32(estimateFeeSaveVersion))
 
 	// Insert basic parameters.
-	binary.Write(w, binary.BigEndian, &ef.maxRollback)
-	binary.Write(w, binary.BigEndian, &ef.binSize)
-	binary.Write(w, binary.BigEndian, &ef.maxReplacements)
-	binary.Write(w, binary.BigEndian, &ef.minRegisteredBlocks)
-	binary.Write(w, binary.BigEndian, &ef.lastKnownHeight)
-	binary.Write(w, binary.BigEndian, &ef.numBlocksRegistered)
+	binary.Write(w, binary.BigEndian, ef.minRegisteredBlocks)
+	binary.Write(w, binary.BigEndian, ef.lastKnownHeight)
+	binary.Write(w, binary.BigEndian, ef.numBlocksRegistered)
+	binary.Write(w, binary.BigEndian, ef.lastBlockTime)
 
-	// Put all the observed transactions in a sorted list.
-	var txCount uint32
-	ots := make([]*observedTransaction, len(ef.observed))
-	for hash := range ef.observed {
-		ots[txCount] = ef.observed[hash]
-		txCount++
+	// Block intervals and samples, the oldest first.
+	binary.Write(w, binary.BigEndian, uint32(len(ef.blockIntervals)))
+	binary.Write(w, binary.BigEndian, ef.blockIntervals)
+	binary.Write(w, binary.BigEndian, uint32(len(ef.samples)))
+	for _, sample := range ef.samples {
+		binary.Write(w, binary.BigEndian, sample.feeRate)
+		binary.Write(w, binary.BigEndian, sample.wait)
 	}
 
+	// Put all the observed transactions in a sorted list.
+	ots := make([]*observedTransaction, 0, len(ef.observed))
+	for _, ot := range ef.observed {
+		ots = append(ots, ot)
+	}
 	sort.Sort(observedTxSet(ots))
-
-	txCount = 0
-	observed := make(map[*observedTransaction]uint32)
-	binary.Write(w, binary.BigEndian, uint32(len(ef.observed)))
+	binary.Write(w, binary.BigEndian, uint32(len(ots)))
 	for _, ot := range ots {
 		ot.Serialize(w)
-		observed[ot] = txCount
-		txCount++
-	}
-
-	// Save all the right bins.
-	for _, list := range ef.bin {
-
-		binary.Write(w, binary.BigEndian, uint32(len(list)))
-
-		for _, o := range list {
-			binary.Write(w, binary.BigEndian, observed[o])
-		}
 	}
 
-	// Dropped transactions.
-	binary.Write(w, binary.BigEndian, uint32(len(ef.dropped)))
-	for _, registered := range ef.dropped {
-		registered.serialize(w, observed)
-	}
-
-	// Commit the tx and return.
 	return FeeEstimatorState(w.Bytes())
 }
 
@@ -662,59 +454,65 @@ func RestoreFeeEstimator(data FeeEstimatorState) (*FeeEstimator, error) {
 		return nil, fmt.Errorf("Incorrect version: expected %d found %d", estimateFeeSaveVersion, version)
 	}
 
-	ef := &FeeEstimator{
-		observed: make(map[common.Hash]*observedTransaction),
-	}
+	ef := NewFeeEstimator(0)
 
 	// Read basic parameters.
-	binary.Read(r, binary.BigEndian, &ef.maxRollback)
-	binary.Read(r, binary.BigEndian, &ef.binSize)
-	binary.Read(r, binary.BigEndian, &ef.maxReplacements)
-	binary.Read(r, binary.BigEndian, &ef.minRegisteredBlocks)
-	binary.Read(r, binary.BigEndian, &ef.lastKnownHeight)
-	binary.Read(r, binary.BigEndian, &ef.numBlocksRegistered)
-
-	// Read transactions.
-	var numObserved uint32
-	observed := make(map[uint32]*observedTransaction)
-	binary.Read(r, binary.BigEndian, &numObserved)
-	for i := uint32(0); i < numObserved; i++ {
-		ot, err := deserializeObservedTransaction(r)
+	for _, value := range []interface{}{&ef.minRegisteredBlocks, &ef.lastKnownHeight, &ef.numBlocksRegistered, &ef.lastBlockTime} {
+		err = binary.Read(r, binary.BigEndian, value)
 		if err != nil {
 			return nil, err
 		}
-		observed[i] = ot
-		ef.observed[ot.hash] = ot
 	}
 
-	// Read bins.
-	for i := 0; i < estimateFeeDepth; i++ {
-		var numTransactions uint32
-		binary.Read(r, binary.BigEndian, &numTransactions)
-		bin := make([]*observedTransaction, numTransactions)
-		for j := uint32(0); j < numTransactions; j++ {
-			var index uint32
-			binary.Read(r, binary.BigEndian, &index)
-
-			var exists bool
-			bin[j], exists = observed[index]
-			if !exists {
-				return nil, fmt.Errorf("Invalid transaction reference %d", index)
-			}
+	// Read block intervals.
+	var numIntervals uint32
+	err = binary.Read(r, binary.BigEndian, &numIntervals)
+	if err != nil {
+		return nil, err
+	}
+	if numIntervals > estimateFeeBlockWindow {
+		return nil, fmt.Errorf("Invalid number of block intervals %d", numIntervals)
+	}
+	ef.blockIntervals = ef.blockIntervals[:numIntervals]
+	err = binary.Read(r, binary.BigEndian, ef.blockIntervals)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read samples.
+	var numSamples uint32
+	err = binary.Read(r, binary.BigEndian, &numSamples)
+	if err != nil {
+		return nil, err
+	}
+	if numSamples > estimateFeeMaxSamples {
+		return nil, fmt.Errorf("Invalid number of samples %d", numSamples)
+	}
+	for i := uint32(0); i < numSamples; i++ {
+		sample := inclusionSample{}
+		err = binary.Read(r, binary.BigEndian, &sample.feeRate)
+		if err != nil {
+			return nil, err
 		}
-		ef.bin[i] = bin
+		err = binary.Read(r, binary.BigEndian, &sample.wait)
+		if err != nil {
+			return nil, err
+		}
+		ef.samples = append(ef.samples, sample)
 	}
 
-	// Read dropped transactions.
-	var numDropped uint32
-	binary.Read(r, binary.BigEndian, &numDropped)
-	ef.dropped = make([]*registeredBlock, numDropped)
-	for i := uint32(0); i < numDropped; i++ {
-		var err error
-		ef.dropped[int(i)], err = deserializeRegisteredBlock(r, observed)
+	// Read transactions.
+	var numObserved uint32
+	err = binary.Read(r, binary.BigEndian, &numObserved)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < numObserved; i++ {
+		ot, err := deserializeObservedTransaction(r)
 		if err != nil {
 			return nil, err
 		}
+		ef.observed[ot.hash] = ot
 	}
 
 	return ef, nil
@@ -0,0 +1,144 @@
+package mempool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestWaitResults returns included and not included results of feeRate
+func newTestWaitResults(feeRate CoinPerKilobyte, included int, notIncluded int) []waitResult {
+	results := []waitResult{}
+	for i := 0; i < included+notIncluded; i++ {
+		results = append(results, waitResult{feeRate: feeRate, included: i < included})
+	}
+	return results
+}
+
+// newTestFeePool - pool with a fee estimator of chains 0 and 1, it returns
+// txs of feeRate added to every chain
+func newTestFeePool(count int, feeRates map[byte]uint64) (*TxPool, map[byte][]transaction.Transaction) {
+	Logger.Init(common.Disabled)
+	mempool := &TxPool{}
+	mempool.Init(&Config{FeeEstimator: map[byte]*FeeEstimator{
+		0: NewFeeEstimator(2),
+		1: NewFeeEstimator(2),
+	}})
+	txs := map[byte][]transaction.Transaction{}
+	for chainID, feeRate := range feeRates {
+		for i := 0; i < count; i++ {
+			tx := newTestSpendingTx(int64(chainID)*1000+int64(i), []byte{chainID, byte(i)})
+			mempool.addTx(tx, chainID, 1, feeRate*tx.GetTxVirtualSize())
+			txs[chainID] = append(txs[chainID], tx)
+		}
+	}
+	return mempool, txs
+}
+
+func TestEstimateFee(t *testing.T) {
+	assert.Equal(t, CoinPerKilobyte(0), estimateFee(nil, EstimateFeeLowConfidence))
+
+	// the highest fee rate when too few txs were seen
+	results := newTestWaitResults(100, estimateFeeMinSamples-1, 0)
+	results = append(results, newTestWaitResults(10, 0, 1)...)
+	assert.Equal(t, CoinPerKilobyte(100), estimateFee(results, EstimateFeeHighConfidence))
+
+	// the lowest fee rate for which enough txs paying it or more were
+	// included, every confidence has its own
+	results = newTestWaitResults(100, 19, 1)
+	results = append(results, newTestWaitResults(80, 10, 0)...)
+	results = append(results, newTestWaitResults(50, 5, 5)...)
+	results = append(results, newTestWaitResults(10, 1, 19)...)
+	assert.Equal(t, CoinPerKilobyte(10), estimateFee(results, EstimateFeeLowConfidence))
+	assert.Equal(t, CoinPerKilobyte(50), estimateFee(results, EstimateFeeMediumConfidence))
+	assert.Equal(t, CoinPerKilobyte(80), estimateFee(results, EstimateFeeHighConfidence))
+}
+
+func TestFeeEstimator_Chains(t *testing.T) {
+	now := time.Now().Unix()
+	mempool, txs := newTestFeePool(estimateFeeMinSamples, map[byte]uint64{0: 100, 1: 10})
+	chain0 := mempool.config.FeeEstimator[0]
+	chain1 := mempool.config.FeeEstimator[1]
+
+	chain0.RegisterBlock(&blockchain.Block{Header: blockchain.BlockHeader{Height: 1, Timestamp: now}})
+	_, err := chain0.EstimateFeeBands(1)
+	assert.NotNil(t, err)
+
+	// txs of chain 0 are included the next block, they aren't samples of
+	// chain 1 which never observed them
+	chain0.RegisterBlock(&blockchain.Block{Header: blockchain.BlockHeader{Height: 2, Timestamp: now + 10}, Transactions: txs[0]})
+	chain1.RegisterBlock(&blockchain.Block{Header: blockchain.BlockHeader{Height: 1, Timestamp: now}, Transactions: txs[0]})
+	chain1.RegisterBlock(&blockchain.Block{Header: blockchain.BlockHeader{Height: 2, Timestamp: now + 100}})
+	estimate, err := chain0.EstimateFeeBands(1)
+	assert.Nil(t, err)
+	assert.Equal(t, FeeEstimate{Wait: 10 * time.Second, Low: 100, Medium: 100, High: 100, Samples: estimateFeeMinSamples}, estimate)
+	_, err = chain0.EstimateFeeBands(0)
+	assert.NotNil(t, err)
+	_, err = chain1.EstimateFeeBands(1)
+	assert.NotNil(t, err)
+
+	// an older block is ignored
+	chain0.RegisterBlock(&blockchain.Block{Header: blockchain.BlockHeader{Height: 1, Timestamp: now + 20}})
+	assert.Equal(t, int32(2), chain0.LastKnownHeight())
+	assert.Equal(t, 10*time.Second, chain0.AverageBlockTime())
+
+	// chain 1 learns its own block time, its txs were included in 3 blocks
+	chain1.RegisterBlock(&blockchain.Block{Header: blockchain.BlockHeader{Height: 3, Timestamp: now + 200}})
+	chain1.RegisterBlock(&blockchain.Block{Header: blockchain.BlockHeader{Height: 4, Timestamp: now + 300}, Transactions: txs[1]})
+	assert.Equal(t, 100*time.Second, chain1.AverageBlockTime())
+	estimate, err = chain1.EstimateFeeBands(3)
+	assert.Nil(t, err)
+	assert.Equal(t, FeeEstimate{Wait: 300 * time.Second, Low: 10, Medium: 10, High: 10, Samples: estimateFeeMinSamples}, estimate)
+}
+
+func TestFeeEstimator_RemovedTxs(t *testing.T) {
+	mempool, txs := newTestFeePool(5, map[byte]uint64{0: 100})
+	feeEstimator := mempool.config.FeeEstimator[0]
+	assert.Equal(t, 5, len(feeEstimator.observed))
+
+	// txs which can't be included any more are forgotten
+	mempool.removeTx(&txs[0][0], TxRemovedReplaced)
+	mempool.removeTx(&txs[0][1], TxRemovedEvicted)
+	mempool.pool[*txs[0][2].Hash()].Desc.Added = time.Now().Add(-2 * time.Hour)
+	mempool.config.MaxTxAge = time.Hour
+	assert.Equal(t, 1, mempool.expireTxs(time.Now()))
+	assert.Equal(t, 2, len(feeEstimator.observed))
+	assert.Nil(t, feeEstimator.observed[*txs[0][2].Hash()])
+
+	// a mined tx is still followed until its block is registered
+	mempool.RemoveBlockTxs(&blockchain.Block{Transactions: txs[0][3:4]})
+	assert.Equal(t, 2, len(feeEstimator.observed))
+	assert.NotNil(t, feeEstimator.observed[*txs[0][3].Hash()])
+}
+
+func TestFeeEstimator_SaveRestore(t *testing.T) {
+	now := time.Now().Unix()
+	// half of txs are included, the others are still observed
+	mempool, txs := newTestFeePool(2*estimateFeeMinSamples, map[byte]uint64{0: 100})
+	feeEstimator := mempool.config.FeeEstimator[0]
+	feeEstimator.RegisterBlock(&blockchain.Block{Header: blockchain.BlockHeader{Height: 1, Timestamp: now}})
+	feeEstimator.RegisterBlock(&blockchain.Block{Header: blockchain.BlockHeader{Height: 2, Timestamp: now + 10}, Transactions: txs[0][:estimateFeeMinSamples]})
+
+	state := feeEstimator.Save()
+	restored, err := RestoreFeeEstimator(state)
+	assert.Nil(t, err)
+	assert.Equal(t, feeEstimator, restored)
+	assert.Equal(t, state, restored.Save())
+	expected, err := feeEstimator.EstimateFeeBands(1)
+	assert.Nil(t, err)
+	estimate, err := restored.EstimateFeeBands(1)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, estimate)
+
+	// a state of another version or a broken one isn't restored
+	state[3]++
+	_, err = RestoreFeeEstimator(state)
+	assert.NotNil(t, err)
+	state[3]--
+	_, err = RestoreFeeEstimator(state[:len(state)-1])
+	assert.NotNil(t, err)
+}
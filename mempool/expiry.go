@@ -0,0 +1,122 @@
+package mempool
+
+import (
+	"time"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
+)
+
+/*
+// expireTxs removes txs which are in pool longer than MaxTxAge, it returns
+// number of removed txs
+//
+// This function MUST be called with the mempool lock held (for writes).
+*/
+func (tp *TxPool) expireTxs(now time.Time) int {
+	if tp.config.MaxTxAge <= 0 {
+		return 0
+	}
+	expired := 0
+	for hash, txDesc := range tp.pool {
+		if now.Sub(txDesc.Desc.Added) <= tp.config.MaxTxAge {
+			continue
+		}
+		Logger.log.Infof("Expire tx %+v added at %+v", hash.String(), txDesc.Desc.Added)
+		tp.removeTx(&txDesc.Desc.Tx, TxRemovedExpired)
+		expired++
+	}
+	return expired
+}
+
+/*
+// blockAffectedTxs returns txs in pool which conflict with txs of block or
+// depend on them: they spend a nullifier or custom token output spent by
+// block, have a dependency key of a tx of block (e.g. another response of a
+// loan) or have a parent in block
+//
+// This function MUST be called with the mempool lock held (for reads).
+*/
+func (tp *TxPool) blockAffectedTxs(block *blockchain.Block) map[common.Hash]*TxDesc {
+	affected := make(map[common.Hash]*TxDesc)
+	blockKeys := make(map[string]bool)
+	for _, blockTx := range block.Transactions {
+		for hash, txDesc := range tp.conflictingTxs(blockTx) {
+			affected[hash] = txDesc
+		}
+		if blockToken := customTokenTx(blockTx); blockToken != nil {
+			for hash, txDesc := range tp.pool {
+				poolToken := customTokenTx(txDesc.Desc.Tx)
+				if poolToken != nil && tp.config.BlockChain.ValidateDoubleSpendCustomTokenOnTx(blockToken, poolToken) != nil {
+					affected[hash] = txDesc
+				}
+			}
+		}
+		for _, key := range blockchain.TxDependencyKeys(blockTx) {
+			blockKeys[key] = true
+			if hash, ok := tp.poolDependencies[key]; ok {
+				affected[hash] = tp.pool[hash]
+			}
+		}
+	}
+	for hash, txDesc := range tp.pool {
+		for _, key := range blockchain.TxParentKeys(txDesc.Desc.Tx) {
+			if blockKeys[key] {
+				affected[hash] = txDesc
+				break
+			}
+		}
+	}
+	return affected
+}
+
+/*
+// revalidateTxs validates txs in pool which block affects again with data of
+// blockchain, a tx which became invalid (e.g. its nullifier is spent by
+// block) is removed. It returns number of removed txs
+//
+// This function MUST be called with the mempool lock held (for writes).
+*/
+func (tp *TxPool) revalidateTxs(block *blockchain.Block) int {
+	invalid := 0
+	for hash, txDesc := range tp.blockAffectedTxs(block) {
+		// a descendant of an invalid tx is removed with it
+		if !tp.isTxInPool(&hash) {
+			continue
+		}
+		err := tp.validateTxWithParents(txDesc.Desc.Tx, txDesc.ChainID)
+		if err == nil {
+			continue
+		}
+		Logger.log.Infof("Remove tx %+v which is invalid now: %+v", hash.String(), err)
+		tp.removeTx(&txDesc.Desc.Tx, TxRemovedInvalid)
+		invalid++
+	}
+	return invalid
+}
+
+/*
+OnBlockConnected - expire old txs and validate txs which conflict with or
+depend on txs of block again after a block of any chain is connected and its
+best state is stored. Orphans waiting for txs of block are accepted and
+returned to be relayed, old orphans expire
+
+This function is safe for concurrent access.
+*/
+func (tp *TxPool) OnBlockConnected(block *blockchain.Block) []*TxDesc {
+	tp.mtx.Lock()
+	defer tp.mtx.Unlock()
+	expired := tp.expireTxs(time.Now())
+	invalid := tp.revalidateTxs(block)
+	if expired > 0 || invalid > 0 {
+		Logger.log.Infof("Block %+v of chain %d: %d txs expired, %d txs invalid, %d txs left in mempool", block.Hash().String(), block.Header.ChainID, expired, invalid, len(tp.pool))
+	}
+	acceptedOrphans := []*TxDesc{}
+	for _, tx := range block.Transactions {
+		acceptedOrphans = append(acceptedOrphans, tp.processOrphans(tx)...)
+	}
+	if expiredOrphans := tp.expireOrphans(time.Now()); expiredOrphans > 0 {
+		Logger.log.Infof("%d orphan txs expired, %d orphan txs left", expiredOrphans, len(tp.orphans))
+	}
+	return acceptedOrphans
+}
@@ -0,0 +1,165 @@
+package mempool
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/database"
+	_ "github.com/ninjadotorg/constant/database/lvdb"
+	"github.com/ninjadotorg/constant/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestBlockChain - blockchain of test net genesis in a temporary database
+func newTestBlockChain(t *testing.T) (*blockchain.BlockChain, database.DatabaseInterface, func()) {
+	backendLog := common.NewBackend(ioutil.Discard)
+	blockchain.Logger.Init(backendLog.Logger("blockChain log"))
+	database.Logger.Init(backendLog.Logger("Database Log"))
+	Logger.Init(common.Disabled)
+
+	dir, err := ioutil.TempDir(common.EmptyString, "mempool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.Open("leveldb", dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	cleanup := func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+	params := blockchain.TestNetParams
+	chain := &blockchain.BlockChain{}
+	err = chain.Init(&blockchain.Config{
+		ChainParams: &params,
+		DataBase:    db,
+	})
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return chain, db, cleanup
+}
+
+func TestExpireTxs(t *testing.T) {
+	Logger.Init(common.Disabled)
+	mempool := &TxPool{}
+	mempool.Init(&Config{})
+	subscription := mempool.Subscribe(10)
+	old := newTestSpendingTx(1, []byte{1})
+	mempool.addTx(old, 1, 1, 10).Desc.Added = time.Now().Add(-2 * time.Hour)
+	fresh := newTestSpendingTx(2, []byte{2})
+	mempool.addTx(fresh, 1, 1, 10)
+
+	// txs never expire without MaxTxAge
+	assert.Equal(t, 0, mempool.expireTxs(time.Now()))
+
+	mempool.config.MaxTxAge = time.Hour
+	assert.Equal(t, 1, mempool.expireTxs(time.Now()))
+	assert.False(t, mempool.isTxInPool(old.Hash()))
+	assert.True(t, mempool.isTxInPool(fresh.Hash()))
+	assert.Equal(t, 1, len(mempool.poolByChain[1]))
+	assert.Equal(t, 1, mempool.expireTxs(time.Now().Add(2*time.Hour)))
+	assert.Equal(t, 0, len(mempool.poolByChain[1]))
+
+	reasons := []string{}
+	for len(subscription.Events()) > 0 {
+		event := <-subscription.Events()
+		if event.Type == TxRemoved {
+			reasons = append(reasons, event.Reason)
+		}
+	}
+	assert.Equal(t, []string{TxRemovedExpired, TxRemovedExpired}, reasons)
+}
+
+func TestPoolByChain(t *testing.T) {
+	Logger.Init(common.Disabled)
+	mempool := &TxPool{}
+	mempool.Init(&Config{})
+	first := newTestSpendingTx(1, []byte{1})
+	second := newTestSpendingTx(2, []byte{2})
+	third := newTestSpendingTx(3, []byte{3})
+	mempool.addTx(first, 0, 1, 10)
+	mempool.addTx(second, 1, 1, 10)
+	mempool.addTx(third, 1, 1, 10)
+
+	assert.Equal(t, 3, len(mempool.MiningDescs()))
+	descs := mempool.ChainMiningDescs(1)
+	assert.Equal(t, 2, len(descs))
+	for _, desc := range descs {
+		assert.NotEqual(t, first.Hash(), desc.Tx.Hash())
+	}
+	assert.Equal(t, 0, len(mempool.ChainMiningDescs(2)))
+
+	mempool.RemoveTx(second)
+	assert.Equal(t, 1, len(mempool.ChainMiningDescs(1)))
+	assert.Equal(t, third, mempool.ChainMiningDescs(1)[0].Tx)
+	assert.Equal(t, 1, len(mempool.ChainMiningDescs(0)))
+}
+
+func TestBlockAffectedTxs(t *testing.T) {
+	Logger.Init(common.Disabled)
+	mempool := &TxPool{}
+	mempool.Init(&Config{})
+	conflicting := newTestSpendingTx(1, []byte{1})
+	unaffected := newTestSpendingTx(2, []byte{2})
+	request, response, payment := newTestLoanTxs([]byte{1}, []byte{2})
+	_, otherResponse, _ := newTestLoanTxs([]byte{1}, []byte{2})
+	otherResponse.Response = transaction.Reject
+	_, _, otherPayment := newTestLoanTxs([]byte{2}, []byte{2})
+	tokenVins := []transaction.TxTokenVin{{TxCustomTokenID: common.HashH([]byte("token")), VoutIndex: 1}}
+	poolToken := &transaction.TxCustomToken{Tx: newTestNormalTx(common.TxCustomTokenType)}
+	poolToken.TxTokenData.Vins = tokenVins
+	for _, tx := range []transaction.Transaction{conflicting, unaffected, otherResponse, payment, otherPayment, poolToken} {
+		mempool.addTx(tx, 0, 1, 10)
+	}
+
+	// the block spends a nullifier and a token output of txs in pool, has
+	// another response of a loan in pool and the response of a payment
+	blockToken := &transaction.TxCustomToken{Tx: newTestNormalTx(common.TxCustomTokenType)}
+	blockToken.LockTime = 1
+	blockToken.TxTokenData.Vins = tokenVins
+	block := &blockchain.Block{Transactions: []transaction.Transaction{
+		newTestSpendingTx(3, []byte{1}, []byte{4}),
+		blockToken,
+		request,
+		response,
+	}}
+	affected := mempool.blockAffectedTxs(block)
+	expected := []*common.Hash{conflicting.Hash(), poolToken.Hash(), otherResponse.Hash(), payment.Hash()}
+	assert.Equal(t, len(expected), len(affected))
+	for _, hash := range expected {
+		assert.NotNil(t, affected[*hash])
+	}
+	assert.Equal(t, 0, len(mempool.blockAffectedTxs(&blockchain.Block{})))
+}
+
+func TestOnBlockConnected(t *testing.T) {
+	chain, db, cleanup := newTestBlockChain(t)
+	defer cleanup()
+	mempool := &TxPool{}
+	mempool.Init(&Config{BlockChain: chain, DataBase: db, MaxTxAge: time.Hour})
+	conflicting := newTestSpendingTx(1, []byte{1})
+	mempool.addTx(conflicting, 0, 1, 10)
+	// a tx which the block doesn't touch isn't validated again
+	untouched := newTestSpendingTx(2, []byte{2})
+	mempool.addTx(untouched, 0, 1, 10)
+	old := newTestSpendingTx(3, []byte{3})
+	mempool.addTx(old, 0, 1, 10).Desc.Added = time.Now().Add(-2 * time.Hour)
+
+	// nullifiers of the block are stored when it is connected
+	blockTx := newTestSpendingTx(4, []byte{1})
+	assert.Nil(t, db.StoreNullifiers([]byte{1}, 0))
+	assert.Nil(t, db.StoreNullifiers([]byte{2}, 0))
+	mempool.OnBlockConnected(&blockchain.Block{Transactions: []transaction.Transaction{blockTx}})
+	assert.False(t, mempool.isTxInPool(conflicting.Hash()))
+	assert.False(t, mempool.isTxInPool(old.Hash()))
+	assert.True(t, mempool.isTxInPool(untouched.Hash()))
+	assert.Equal(t, 1, mempool.Count())
+}
@@ -0,0 +1,229 @@
+package mempool
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/transaction"
+)
+
+// feeRate returns fee per KB of tx in pool
+func (txDesc *TxDesc) feeRate() CoinPerKilobyte {
+	return NewCoinPerKilobyte(txDesc.Desc.Fee, txDesc.Desc.Tx.GetTxVirtualSize())
+}
+
+// isOverLimits returns true when pool has more txs than config allows
+func (tp *TxPool) isOverLimits() bool {
+	return tp.overLimits(tp.poolSize, len(tp.pool))
+}
+
+// overLimits returns true when txs of size and count don't fit into pool
+func (tp *TxPool) overLimits(size uint64, count int) bool {
+	if tp.config.MaxSize > 0 && size > tp.config.MaxSize {
+		return true
+	}
+	if tp.config.MaxTxs > 0 && count > tp.config.MaxTxs {
+		return true
+	}
+	return false
+}
+
+/*
+// minFeeRate returns the minimum fee rate of new txs, it is raised by
+// evictions and halves every RollingFeeHalfLife, faster when pool is far
+// from its size limit.
+//
+// This function MUST be called with the mempool lock held (for writes).
+*/
+func (tp *TxPool) minFeeRate() CoinPerKilobyte {
+	if tp.rollingMinFee == 0 {
+		return 0
+	}
+	now := time.Now().Unix()
+	elapsed := now - tp.lastRollingFeeUpdate
+	if elapsed <= 0 {
+		return CoinPerKilobyte(tp.rollingMinFee)
+	}
+
+	halfLife := float64(RollingFeeHalfLife)
+	if tp.config.MaxSize > 0 {
+		if tp.poolSize < tp.config.MaxSize/4 {
+			halfLife /= 4
+		} else if tp.poolSize < tp.config.MaxSize/2 {
+			halfLife /= 2
+		}
+	}
+	tp.rollingMinFee /= math.Pow(2, float64(elapsed)/halfLife)
+	tp.lastRollingFeeUpdate = now
+	if tp.rollingMinFee < IncrementalRelayFee/2.0 {
+		tp.rollingMinFee = 0
+	}
+	return CoinPerKilobyte(tp.rollingMinFee)
+}
+
+/*
+// checkMinFeeRate rejects tx when its fee rate is under the minimum fee rate
+// of pool
+//
+// This function MUST be called with the mempool lock held (for writes).
+*/
+func (tp *TxPool) checkMinFeeRate(tx transaction.Transaction, fee uint64) error {
+	minFeeRate := tp.minFeeRate()
+	if minFeeRate == 0 {
+		return nil
+	}
+	feeRate := NewCoinPerKilobyte(fee, tx.GetTxVirtualSize())
+	if feeRate < minFeeRate {
+		str := fmt.Sprintf("transaction %+v has fee rate %d which is under the minimum fee rate %d of mempool", tx.Hash().String(), feeRate, minFeeRate)
+		err := MempoolTxError{}
+		err.Init(RejectLowFeeRate, errors.New(str))
+		return err
+	}
+	return nil
+}
+
+/*
+// trimToLimits evicts txs with the lowest fee rate, the newest first among
+// equal ones, until pool is in its limits. The minimum fee rate is raised
+// above every evicted tx. It returns true when newTx was evicted.
+//
+// This function MUST be called with the mempool lock held (for writes).
+*/
+func (tp *TxPool) trimToLimits(newTx *common.Hash) bool {
+	newTxEvicted := false
+	for tp.isOverLimits() {
+		var lowest *TxDesc
+		for _, txDesc := range tp.pool {
+			if lowest == nil || txDesc.feeRate() < lowest.feeRate() ||
+				(txDesc.feeRate() == lowest.feeRate() && txDesc.Desc.Added.After(lowest.Desc.Added)) {
+				lowest = txDesc
+			}
+		}
+		if lowest == nil {
+			break
+		}
+
+		tp.raiseMinFeeRate(lowest.feeRate())
+		hash := lowest.Desc.Tx.Hash()
+		Logger.log.Infof("Evict tx %+v with fee rate %d from full mempool", hash.String(), lowest.feeRate())
+		if newTx != nil && *hash == *newTx {
+			newTxEvicted = true
+		}
+		tp.removeTx(&lowest.Desc.Tx, TxRemovedEvicted)
+	}
+	// newTx is evicted with its evicted parent too
+	return newTxEvicted || (newTx != nil && !tp.isTxInPool(newTx))
+}
+
+/*
+// raiseMinFeeRate raises the minimum fee rate above fee rate of an evicted tx
+//
+// This function MUST be called with the mempool lock held (for writes).
+*/
+func (tp *TxPool) raiseMinFeeRate(evicted CoinPerKilobyte) {
+	evictedRate := float64(evicted) + IncrementalRelayFee
+	if evictedRate > tp.rollingMinFee {
+		tp.rollingMinFee = evictedRate
+	}
+	tp.lastRollingFeeUpdate = time.Now().Unix()
+}
+
+/*
+// isEvictedOnAccept tells whether trimToLimits would evict tx with fee right
+// after it enters pool in place of replaced txs, without changing pool. Txs
+// with the lowest fee rate go first with their descendants, tx is the newest
+// of pool so it goes first among equal fee rates, and it goes with an evicted
+// ancestor.
+//
+// This function MUST be called with the mempool lock held (for reads).
+*/
+func (tp *TxPool) isEvictedOnAccept(tx transaction.Transaction, fee uint64, replaced map[common.Hash]*TxDesc) bool {
+	size := tp.poolSize + tx.GetTxVirtualSize()
+	count := len(tp.pool) + 1
+	if !tp.overLimits(size, count) {
+		return false
+	}
+	gone := make(map[common.Hash]bool)
+	var remove func(hash common.Hash)
+	remove = func(hash common.Hash) {
+		txDesc, ok := tp.pool[hash]
+		if !ok || gone[hash] {
+			return
+		}
+		gone[hash] = true
+		size -= txDesc.Desc.Tx.GetTxVirtualSize()
+		count--
+		for childHash := range tp.poolChildren[hash] {
+			remove(childHash)
+		}
+	}
+	for hash := range replaced {
+		remove(hash)
+	}
+
+	ancestors := tp.poolAncestors(tx)
+	feeRate := NewCoinPerKilobyte(fee, tx.GetTxVirtualSize())
+	for tp.overLimits(size, count) {
+		var lowest *TxDesc
+		for hash, txDesc := range tp.pool {
+			if gone[hash] {
+				continue
+			}
+			if lowest == nil || txDesc.feeRate() < lowest.feeRate() ||
+				(txDesc.feeRate() == lowest.feeRate() && txDesc.Desc.Added.After(lowest.Desc.Added)) {
+				lowest = txDesc
+			}
+		}
+		if lowest == nil || feeRate <= lowest.feeRate() {
+			return true
+		}
+		hash := *lowest.Desc.Tx.Hash()
+		if ancestors[hash] {
+			return true
+		}
+		remove(hash)
+	}
+	return false
+}
+
+/*
+// poolAncestors returns hashes of parents of tx in pool and their ancestors
+//
+// This function MUST be called with the mempool lock held (for reads).
+*/
+func (tp *TxPool) poolAncestors(tx transaction.Transaction) map[common.Hash]bool {
+	ancestors := make(map[common.Hash]bool)
+	queue := []common.Hash{}
+	for _, key := range blockchain.TxParentKeys(tx) {
+		if hash, ok := tp.poolDependencies[key]; ok {
+			queue = append(queue, hash)
+		}
+	}
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+		if ancestors[hash] {
+			continue
+		}
+		ancestors[hash] = true
+		queue = append(queue, tp.poolParents[hash]...)
+	}
+	return ancestors
+}
+
+// MinFeeRate returns the minimum fee rate in coins per KB of txs accepted by
+// pool, it is 0 until pool becomes full
+func (tp *TxPool) MinFeeRate() uint64 {
+	tp.mtx.Lock()
+	defer tp.mtx.Unlock()
+	return uint64(tp.minFeeRate())
+}
+
+// MaxSize returns the size limit of pool in KB, 0 when there is no limit
+func (tp *TxPool) MaxSize() uint64 {
+	return tp.config.MaxSize
+}
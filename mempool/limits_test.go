@@ -0,0 +1,102 @@
+package mempool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrimToLimits(t *testing.T) {
+	Logger.Init(common.Disabled)
+	mempool := &TxPool{}
+	mempool.Init(&Config{MaxTxs: 3})
+	subscription := mempool.Subscribe(10)
+	high := newTestSpendingTx(1, []byte{1})
+	size := high.GetTxVirtualSize()
+	mempool.addTx(high, 0, 1, 10*size)
+	older := newTestSpendingTx(2, []byte{2})
+	mempool.addTx(older, 0, 1, 5*size).Desc.Added = time.Now().Add(-time.Hour)
+	newer := newTestSpendingTx(3, []byte{3})
+	mempool.addTx(newer, 0, 1, 5*size)
+	highest := newTestSpendingTx(4, []byte{4})
+	mempool.addTx(highest, 0, 1, 20*size)
+
+	// the newest tx is evicted among txs with the lowest fee rate
+	assert.False(t, mempool.trimToLimits(highest.Hash()))
+	assert.Equal(t, 3, mempool.Count())
+	assert.False(t, mempool.isTxInPool(newer.Hash()))
+	assert.Equal(t, float64(5+IncrementalRelayFee), mempool.rollingMinFee)
+
+	// it tells when the new tx is evicted
+	mempool.config.MaxTxs = 2
+	assert.True(t, mempool.trimToLimits(older.Hash()))
+	assert.False(t, mempool.isTxInPool(older.Hash()))
+	assert.Equal(t, float64(5+IncrementalRelayFee), mempool.rollingMinFee)
+
+	// the size limit evicts the lowest fee rate too and raises the minimum
+	mempool.config.MaxTxs = 0
+	mempool.config.MaxSize = size
+	assert.False(t, mempool.trimToLimits(nil))
+	assert.Equal(t, 1, mempool.Count())
+	assert.True(t, mempool.isTxInPool(highest.Hash()))
+	assert.Equal(t, float64(10+IncrementalRelayFee), mempool.rollingMinFee)
+
+	evicted := []*common.Hash{}
+	for len(subscription.Events()) > 0 {
+		event := <-subscription.Events()
+		if event.Type == TxRemoved {
+			assert.Equal(t, TxRemovedEvicted, event.Reason)
+			evicted = append(evicted, event.TxDesc.Desc.Tx.Hash())
+		}
+	}
+	assert.Equal(t, []*common.Hash{newer.Hash(), older.Hash(), high.Hash()}, evicted)
+}
+
+func TestMinFeeRate(t *testing.T) {
+	mempool := &TxPool{}
+	mempool.Init(&Config{})
+	assert.Equal(t, CoinPerKilobyte(0), mempool.minFeeRate())
+
+	// the minimum fee rate halves every half life
+	mempool.rollingMinFee = 1000
+	mempool.lastRollingFeeUpdate = time.Now().Unix()
+	assert.Equal(t, CoinPerKilobyte(1000), mempool.minFeeRate())
+	mempool.lastRollingFeeUpdate = time.Now().Unix() - RollingFeeHalfLife
+	mempool.minFeeRate()
+	assert.InDelta(t, 500, mempool.rollingMinFee, 1)
+
+	// faster when pool is far from its size limit
+	mempool.config.MaxSize = 100
+	mempool.poolSize = 30
+	mempool.rollingMinFee = 1000
+	mempool.lastRollingFeeUpdate = time.Now().Unix() - RollingFeeHalfLife/2
+	mempool.minFeeRate()
+	assert.InDelta(t, 500, mempool.rollingMinFee, 1)
+	mempool.poolSize = 20
+	mempool.rollingMinFee = 1000
+	mempool.lastRollingFeeUpdate = time.Now().Unix() - RollingFeeHalfLife/4
+	mempool.minFeeRate()
+	assert.InDelta(t, 500, mempool.rollingMinFee, 1)
+
+	// it drops to 0 below half of the incremental relay fee
+	mempool.lastRollingFeeUpdate = time.Now().Unix() - 10*RollingFeeHalfLife
+	assert.Equal(t, CoinPerKilobyte(0), mempool.minFeeRate())
+	assert.Equal(t, float64(0), mempool.rollingMinFee)
+}
+
+func TestCheckMinFeeRate(t *testing.T) {
+	mempool := &TxPool{}
+	mempool.Init(&Config{})
+	tx := newTestSpendingTx(1, []byte{1})
+	size := tx.GetTxVirtualSize()
+	assert.Nil(t, mempool.checkMinFeeRate(tx, 0))
+
+	mempool.rollingMinFee = 10
+	mempool.lastRollingFeeUpdate = time.Now().Unix()
+	err := mempool.checkMinFeeRate(tx, 9*size)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrCodeMessage[RejectLowFeeRate].code, err.(MempoolTxError).code)
+	assert.Nil(t, mempool.checkMinFeeRate(tx, 10*size))
+}
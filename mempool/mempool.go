@@ -31,6 +31,27 @@ type Config struct {
 	// FeeEstimatator provides a feeEstimator. If it is not nil, the mempool
 	// records all new transactions it observes into the feeEstimator.
 	FeeEstimator map[byte]*FeeEstimator
+
+	// MaxSize is the max sum of virtual sizes of txs in pool, in KB like
+	// GetTxVirtualSize. MaxTxs is the max number of txs. Txs with the lowest
+	// fee rate are evicted over a limit, 0 means no limit.
+	MaxSize uint64
+	MaxTxs  int
+
+	// MaxTxAge is how long a tx is kept in pool before it expires, 0 means
+	// txs never expire
+	MaxTxAge time.Duration
+
+	// EnableReplacement lets a tx spending nullifiers of txs in pool replace
+	// them when it pays a higher fee and fee rate, see replacement rules
+	EnableReplacement bool
+
+	// DumpFile is where Save writes txs of pool and Load reads them
+	DumpFile string
+
+	// MaxOrphanTxs is the max number of txs held until their request or
+	// response comes, 0 means such txs are rejected
+	MaxOrphanTxs int
 }
 
 // TxDesc is transaction description in mempool
@@ -39,6 +60,9 @@ type TxDesc struct {
 	Desc transaction.TxDesc
 
 	StartingPriority int
+
+	// chain of sender of tx
+	ChainID byte
 }
 
 // TxPool is transaction pool
@@ -49,7 +73,27 @@ type TxPool struct {
 	mtx            sync.RWMutex
 	config         Config
 	pool           map[common.Hash]*TxDesc
+	poolByChain    map[byte]map[common.Hash]*TxDesc // txs indexed by sender chain
 	poolNullifiers map[common.Hash][][]byte
+	poolSize       uint64 // sum of virtual sizes of txs in pool
+
+	// minimum fee rate raised by evictions, it decays over time
+	rollingMinFee        float64
+	lastRollingFeeUpdate int64
+
+	// dependencies between txs in pool, see blockchain.TxDependencyKeys
+	poolDependencies map[string]common.Hash               // tx in pool by dependency key
+	poolParents      map[common.Hash][]common.Hash        // parents in pool of tx
+	poolChildren     map[common.Hash]map[common.Hash]bool // children in pool of tx
+
+	// txs waiting for their parents, by hash and by keys of missing parents
+	orphans         map[common.Hash]*orphanTx
+	orphansByParent map[string]map[common.Hash]*orphanTx
+
+	// subscriptions of txs accepted into and removed from pool
+	subsMtx            sync.Mutex
+	subscriptions      map[uint64]*TxSubscription
+	nextSubscriptionID uint64
 }
 
 /*
@@ -58,7 +102,14 @@ Init Txpool from config
 func (tp *TxPool) Init(cfg *Config) {
 	tp.config = *cfg
 	tp.pool = make(map[common.Hash]*TxDesc)
+	tp.poolByChain = make(map[byte]map[common.Hash]*TxDesc)
 	tp.poolNullifiers = make(map[common.Hash][][]byte)
+	tp.poolDependencies = make(map[string]common.Hash)
+	tp.poolParents = make(map[common.Hash][]common.Hash)
+	tp.poolChildren = make(map[common.Hash]map[common.Hash]bool)
+	tp.orphans = make(map[common.Hash]*orphanTx)
+	tp.orphansByParent = make(map[string]map[common.Hash]*orphanTx)
+	tp.subscriptions = make(map[uint64]*TxSubscription)
 }
 
 // check transaction in pool
@@ -72,7 +123,7 @@ func (tp *TxPool) isTxInPool(hash *common.Hash) bool {
 /*
 // add transaction into pool
 */
-func (tp *TxPool) addTx(tx transaction.Transaction, height int32, fee uint64) *TxDesc {
+func (tp *TxPool) addTx(tx transaction.Transaction, chainID byte, height int32, fee uint64) *TxDesc {
 	txD := &TxDesc{
 		Desc: transaction.TxDesc{
 			Tx:     tx,
@@ -81,24 +132,25 @@ func (tp *TxPool) addTx(tx transaction.Transaction, height int32, fee uint64) *T
 			Fee:    fee,
 		},
 		StartingPriority: 1, //@todo we will apply calc function for it.
+		ChainID:          chainID,
 	}
-	log.Printf(tx.Hash().String())
+	log.Print(tx.Hash().String())
 	tp.pool[*tx.Hash()] = txD
+	if _, ok := tp.poolByChain[chainID]; !ok {
+		tp.poolByChain[chainID] = make(map[common.Hash]*TxDesc)
+	}
+	tp.poolByChain[chainID][*tx.Hash()] = txD
 	tp.poolNullifiers[*tx.Hash()] = txD.Desc.Tx.ListNullifiers()
+	tp.addTxDependencies(tx)
+	tp.poolSize += tx.GetTxVirtualSize()
 	atomic.StoreInt64(&tp.lastUpdated, time.Now().Unix())
 
-	// Record this tx for fee estimation if enabled. only apply for normal tx
-	if tx.GetType() == common.TxNormalType {
-		if tp.config.FeeEstimator != nil {
-			chainId, err := common.GetTxSenderChain(tx.(*transaction.Tx).AddressLastByte)
-			if err == nil {
-				tp.config.FeeEstimator[chainId].ObserveTransaction(txD)
-			} else {
-				Logger.log.Error(err)
-			}
-		}
+	// Record this tx for fee estimation of its sender chain if enabled
+	if feeEstimator, ok := tp.config.FeeEstimator[chainID]; ok && feeEstimator != nil {
+		feeEstimator.ObserveTransaction(txD)
 	}
 
+	tp.notify(TxAccepted, txD, common.EmptyString)
 	return txD
 }
 
@@ -110,11 +162,15 @@ func (tp *TxPool) addTx(tx transaction.Transaction, height int32, fee uint64) *T
 // This function MUST be called with the mempool lock held (for writes).
 */
 func (tp *TxPool) maybeAcceptTransaction(tx transaction.Transaction) (*common.Hash, *TxDesc, error) {
+	// check struct and type of tx before any data of its type is read
+	err := tp.checkTxType(tx)
+	if err != nil {
+		return nil, nil, err
+	}
 	txHash := tx.Hash()
 
 	// that make sure transaction is accepted when passed any rules
 	var chainID byte
-	var err error
 
 	// get chainID of tx
 	chainID, err = common.GetTxSenderChain(tx.GetSenderAddrLastByte())
@@ -122,7 +178,7 @@ func (tp *TxPool) maybeAcceptTransaction(tx transaction.Transaction) (*common.Ha
 	if err != nil {
 		return nil, nil, err
 	}
-	bestHeight := tp.config.BlockChain.BestState[chainID].BestBlock.Header.Height
+	bestHeight := tp.config.BlockChain.BestSnapshot(chainID).BestBlock.Header.Height
 	// nextBlockHeight := bestHeight + 1
 	// Check tx with policy
 	// check version
@@ -138,25 +194,52 @@ func (tp *TxPool) maybeAcceptTransaction(tx transaction.Transaction) (*common.Ha
 	if err != nil {
 		return nil, nil, err
 	}
+	// check fee rate of tx with minimum fee rate of a full pool
+	err = tp.checkMinFeeRate(tx, txFee)
+	if err != nil {
+		return nil, nil, err
+	}
 	// end check with policy
 
-	// check tx with all txs in current mempool
-	err = tp.ValidateTxWithCurrentMempool(tx)
+	// Don't accept the transaction if it already exists in the pool.
+	if tp.isTxInPool(txHash) {
+		str := fmt.Sprintf("already have transaction %+v", txHash.String())
+		err := MempoolTxError{}
+		err.Init(RejectDuplicateTx, errors.New(str))
+		return nil, nil, err
+	}
+
+	// a tx whose request or response is neither in pool nor in blockchain
+	// waits for it in orphan pool
+	if missing := tp.missingParents(tx); len(missing) > 0 {
+		if validate, errS := tp.ValidateSanityData(tx); !validate {
+			err := MempoolTxError{}
+			err.Init(RejectSansityTx, errors.New(fmt.Sprintf("transaction's sansity %v is error %v", txHash.String(), errS.Error())))
+			return nil, nil, err
+		}
+		return nil, nil, tp.addOrphan(tx, missing)
+	}
+
+	// txs in pool spending the same nullifiers are replaced by tx when it
+	// follows replacement rules
+	replaced, err := tp.checkReplacement(tx, txFee)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// validate tx with data of blockchain
-	err = tp.ValidateTxWithBlockChain(tx, chainID)
+	// check tx with all txs in current mempool
+	err = tp.validateTxWithCurrentMempool(tx, replaced)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = tp.checkTxDependencies(tx, replaced)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// Don't accept the transaction if it already exists in the pool.
-	if tp.isTxInPool(txHash) {
-		str := fmt.Sprintf("already have transaction %+v", txHash.String())
-		err := MempoolTxError{}
-		err.Init(RejectDuplicateTx, errors.New(str))
+	// validate tx with data of blockchain, or with its parents in pool
+	err = tp.validateTxWithParents(tx, chainID)
+	if err != nil {
 		return nil, nil, err
 	}
 
@@ -182,16 +265,55 @@ func (tp *TxPool) maybeAcceptTransaction(tx transaction.Transaction) (*common.Ha
 		return nil, nil, err
 	}
 
-	txD := tp.addTx(tx, bestHeight, txFee)
+	// a tx which doesn't fit into pool is rejected before it replaces txs
+	if tp.isEvictedOnAccept(tx, txFee, replaced) {
+		tp.raiseMinFeeRate(NewCoinPerKilobyte(txFee, tx.GetTxVirtualSize()))
+		err := MempoolTxError{}
+		err.Init(RejectPoolFull, errors.New(fmt.Sprintf("%+v doesn't fit into full mempool", txHash.String())))
+		return nil, nil, err
+	}
+	for replacedHash, txDesc := range replaced {
+		Logger.log.Infof("Replace tx %+v with tx %+v", replacedHash.String(), txHash.String())
+		tp.removeTx(&txDesc.Desc.Tx, TxRemovedReplaced)
+	}
+	txD := tp.addTx(tx, chainID, bestHeight, txFee)
+
+	// keep pool in its limits, the new tx may have the lowest fee rate
+	if tp.trimToLimits(txHash) {
+		err := MempoolTxError{}
+		err.Init(RejectPoolFull, errors.New(fmt.Sprintf("%+v is evicted from full mempool", txHash.String())))
+		return nil, nil, err
+	}
+
 	return tx.Hash(), txD, nil
 }
 
-// remove transaction for pool
-func (tp *TxPool) removeTx(tx *transaction.Transaction) error {
+// remove transaction for pool, reason tells subscriptions why it is removed
+func (tp *TxPool) removeTx(tx *transaction.Transaction, reason string) error {
 	Logger.log.Infof((*tx).Hash().String())
-	if _, exists := tp.pool[*(*tx).Hash()]; exists {
+	if txDesc, exists := tp.pool[*(*tx).Hash()]; exists {
 		delete(tp.pool, *(*tx).Hash())
+		delete(tp.poolByChain[txDesc.ChainID], *(*tx).Hash())
+		delete(tp.poolNullifiers, *(*tx).Hash())
+		children := tp.removeTxDependencies(txDesc.Desc.Tx)
+		tp.poolSize -= txDesc.Desc.Tx.GetTxVirtualSize()
 		atomic.StoreInt64(&tp.lastUpdated, time.Now().Unix())
+		tp.notify(TxRemoved, txDesc, reason)
+
+		// children of a mined tx depend on blockchain now, children of a tx
+		// removed for any other reason can never be included, nor can the tx,
+		// so fee estimator forgets it
+		if reason != TxRemovedMined {
+			if feeEstimator, ok := tp.config.FeeEstimator[txDesc.ChainID]; ok && feeEstimator != nil {
+				feeEstimator.RemoveTransaction((*tx).Hash())
+			}
+			for _, childHash := range children {
+				if child, ok := tp.pool[childHash]; ok {
+					Logger.log.Infof("Remove tx %+v whose parent %+v is removed", childHash.String(), (*tx).Hash().String())
+					tp.removeTx(&child.Desc.Tx, TxRemovedAncestor)
+				}
+			}
+		}
 		return nil
 	} else {
 		return errors.New("Not exist tx in pool")
@@ -199,8 +321,9 @@ func (tp *TxPool) removeTx(tx *transaction.Transaction) error {
 	return nil
 }
 
-// ValidateTransaction sanity for normal tx data
-func (tp *TxPool) validateSanityNormalTxData(tx *transaction.Tx) (bool, error) {
+// ValidateTransaction sanity for normal tx data of a tx of txType, which is
+// created from a tx of baseType
+func (tp *TxPool) validateSanityNormalTxData(tx *transaction.Tx, txType string, baseType string) (bool, error) {
 	txN := tx
 	//check version
 	if txN.Version > transaction.TxVersion {
@@ -210,8 +333,8 @@ func (tp *TxPool) validateSanityNormalTxData(tx *transaction.Tx) (bool, error) {
 	if int64(txN.LockTime) > time.Now().Unix() {
 		return false, errors.New("Wrong tx locktime")
 	}
-	// check Type is normal or salary tx
-	if len(txN.Type) != 1 || (txN.Type != common.TxNormalType && txN.Type != common.TxSalaryType) { // only 1 byte
+	// check Type is the type of tx or the type of tx it is created from
+	if txN.Type != txType && txN.Type != baseType {
 		return false, errors.New("Wrong tx type")
 	}
 	// check length of JSPubKey
@@ -296,8 +419,8 @@ func (tp *TxPool) validateSanityNormalTxData(tx *transaction.Tx) (bool, error) {
 	return true, nil
 }
 
-func (tp *TxPool) validateSanityCustomTokenTxData(txCustomToken *transaction.TxCustomToken) (bool, error) {
-	ok, err := tp.validateSanityNormalTxData(&txCustomToken.Tx)
+func (tp *TxPool) validateSanityCustomTokenTxData(txCustomToken *transaction.TxCustomToken, txType string) (bool, error) {
+	ok, err := tp.validateSanityNormalTxData(&txCustomToken.Tx, txType, common.TxCustomTokenType)
 	if err != nil || !ok {
 		return ok, err
 	}
@@ -326,52 +449,63 @@ func (tp *TxPool) validateSanityCustomTokenTxData(txCustomToken *transaction.TxC
 	return true, nil
 }
 
-func (tp *TxPool) validateSanityVoteDCBBoardTx(voteDCBBoard *transaction.TxVoteDCBBoard) (bool, error) {
-	ok, err := tp.validateSanityCustomTokenTxData(&voteDCBBoard.TxCustomToken)
-	if err != nil || !ok {
-		return ok, err
-	}
-	if len(voteDCBBoard.VoteDCBBoardData.CandidatePubKey) != 33 {
-		return false, nil
-	}
-	return true, nil
-}
-
-func (tp *TxPool) validateSanityVoteGOVBoardTx(voteGOVBoard *transaction.TxVoteGOVBoard) (bool, error) {
-	ok, err := tp.validateSanityCustomTokenTxData(&voteGOVBoard.TxCustomToken)
-	if err != nil || !ok {
-		return ok, err
-	}
-	if len(voteGOVBoard.VoteGOVBoardData.CandidatePubKey) != 33 {
-		return false, nil
-	}
-	return true, nil
-}
-
 // MaybeAcceptTransaction is the main workhorse for handling insertion of new
 // free-standing transactions into a memory pool.  It includes functionality
 // such as rejecting duplicate transactions, ensuring transactions follow all
 // rules, detecting orphan transactions, and insertion into the memory pool.
 //
-// If the transaction is an orphan (missing parent transactions), the
-// transaction is NOT added to the orphan pool, but each unknown referenced
-// parent is returned.  Use ProcessTransaction instead if new orphans should
-// be added to the orphan pool.
+// If the transaction is an orphan (missing its request or response), it is
+// held in the orphan pool and RejectOrphanTx is returned. Orphans waiting for
+// an accepted tx are accepted too, use ProcessTransaction instead if they
+// should be relayed.
 //
 // This function is safe for concurrent access.
 func (tp *TxPool) MaybeAcceptTransaction(tx transaction.Transaction) (*common.Hash, *TxDesc, error) {
 	tp.mtx.Lock()
 	hash, txDesc, err := tp.maybeAcceptTransaction(tx)
+	if err == nil {
+		tp.processOrphans(tx)
+	}
 	tp.mtx.Unlock()
 	return hash, txDesc, err
 }
 
+/*
+ProcessTransaction - accept tx into pool like MaybeAcceptTransaction, it
+returns descs of tx and of every orphan accepted after it, parents first, so
+the caller relays them all
+
+This function is safe for concurrent access.
+*/
+func (tp *TxPool) ProcessTransaction(tx transaction.Transaction) ([]*TxDesc, error) {
+	tp.mtx.Lock()
+	defer tp.mtx.Unlock()
+	_, txDesc, err := tp.maybeAcceptTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	return append([]*TxDesc{txDesc}, tp.processOrphans(tx)...), nil
+}
+
 // ValidateDoubleSpendTxWithCurrentMempool - check double spend for new tx with all txs in mempool
 func (tp *TxPool) ValidateDoubleSpendTxWithCurrentMempool(txNormal transaction.Tx) error {
-	for _, temp1 := range tp.poolNullifiers {
+	return tp.validateDoubleSpendTxWithCurrentMempool(txNormal, nil)
+}
+
+// validateDoubleSpendTxWithCurrentMempool - check double spend for new tx with
+// txs in mempool except the ones it replaces
+func (tp *TxPool) validateDoubleSpendTxWithCurrentMempool(txNormal transaction.Tx, replaced map[common.Hash]*TxDesc) error {
+	for txHash, temp1 := range tp.poolNullifiers {
+		if _, ok := replaced[txHash]; ok {
+			continue
+		}
 		for _, desc := range txNormal.Descs {
 			for _, nullifier := range desc.Nullifiers {
-				if ok, err := common.SliceBytesExists(temp1, nullifier); !ok || err != nil {
+				ok, err := common.SliceBytesExists(temp1, nullifier)
+				if err != nil {
+					return err
+				}
+				if ok {
 					return errors.New("Double spend")
 				}
 			}
@@ -382,63 +516,42 @@ func (tp *TxPool) ValidateDoubleSpendTxWithCurrentMempool(txNormal transaction.T
 
 // ValidateTxWithCurrentMempool - check new tx with all txs in mempool
 func (tp *TxPool) ValidateTxWithCurrentMempool(tx transaction.Transaction) error {
-	switch tx.GetType() {
-	case common.TxNormalType:
-		{
-			txNormal := tx.(*transaction.Tx)
-			err := tp.ValidateDoubleSpendTxWithCurrentMempool(*txNormal)
-			if err != nil {
-				return err
-			}
-			return nil
-		}
-	case common.TxSalaryType:
-		{
-			return errors.New("Can not receive a salary tx from other node, this is a violation")
-		}
-	case common.TxCustomTokenType:
-		{
-			err := tp.validateTxCustomTokenInPool(tx)
-			return err
-		}
-	case common.TxVoteDCBBoard:
-		{
-			txVoteDCBBoard := tx.(*transaction.TxVoteDCBBoard)
-			txCustomToKen := txVoteDCBBoard.TxCustomToken
-			err := tp.validateTxCustomTokenInPool(&txCustomToKen)
-			if err != nil {
-				return err
-			}
-			return nil
-		}
-	case common.TxVoteGOVBoard:
-		{
-			txVoteGOVBoard := tx.(*transaction.TxVoteGOVBoard)
-			txCustomToKen := txVoteGOVBoard.TxCustomToken
-			err := tp.validateTxCustomTokenInPool(&txCustomToKen)
-			if err != nil {
-				return err
-			}
-			return nil
-		}
-	default:
-		{
-			return errors.New("Wrong tx type")
-		}
-	}
-	return errors.New("No check tx")
+	return tp.validateTxWithCurrentMempool(tx, nil)
 }
 
-func (tp *TxPool) validateTxCustomTokenInPool(tx transaction.Transaction) error {
-	txCustomToken := tx.(*transaction.TxCustomToken)
-	txNormal := txCustomToken.Tx
-	err := tp.ValidateDoubleSpendTxWithCurrentMempool(txNormal)
+// validateTxWithCurrentMempool - check new tx with txs in mempool except the
+// ones it replaces
+func (tp *TxPool) validateTxWithCurrentMempool(tx transaction.Transaction, replaced map[common.Hash]*TxDesc) error {
+	if tx.GetType() == common.TxSalaryType {
+		return errors.New("Can not receive a salary tx from other node, this is a violation")
+	}
+	txNormal := baseTx(tx)
+	if txNormal == nil {
+		return errors.New("Wrong tx type")
+	}
+	// check double spend for constant coin of tx of any type
+	err := tp.validateDoubleSpendTxWithCurrentMempool(*txNormal, replaced)
 	if err != nil {
 		return err
 	}
-	txsInMem := tp.pool
-	for _, txInMem := range txsInMem {
-		err := tp.config.BlockChain.ValidateDoubleSpendCustomTokenOnTx(txCustomToken, txInMem.Desc.Tx)
+	if txCustomToken := customTokenTx(tx); txCustomToken != nil {
+		return tp.validateTxCustomTokenInPool(txCustomToken, replaced)
+	}
+	return nil
+}
+
+// validateTxCustomTokenInPool - check double spend for custom token with txs in
+// mempool which move custom token
+func (tp *TxPool) validateTxCustomTokenInPool(txCustomToken *transaction.TxCustomToken, replaced map[common.Hash]*TxDesc) error {
+	for txHash, txInMem := range tp.pool {
+		if _, ok := replaced[txHash]; ok {
+			continue
+		}
+		txCustomTokenInMem := customTokenTx(txInMem.Desc.Tx)
+		if txCustomTokenInMem == nil {
+			continue
+		}
+		err := tp.config.BlockChain.ValidateDoubleSpendCustomTokenOnTx(txCustomToken, txCustomTokenInMem)
 		if err != nil {
 			return err
 		}
@@ -448,7 +561,11 @@ func (tp *TxPool) validateTxCustomTokenInPool(tx transaction.Transaction) error
 
 func (tp *TxPool) ValidateTxCustomTokenBlockChain(tx transaction.Transaction, chainID byte) error {
 	blockChain := tp.config.BlockChain
-	if !blockChain.VerifyCustomTokenSigns(tx) {
+	txCustomToken := customTokenTx(tx)
+	if txCustomToken == nil {
+		return errors.New("Tx moves no custom token")
+	}
+	if !blockChain.VerifyCustomTokenSigns(txCustomToken) {
 		return errors.New("Custom token signs validation is not passed.")
 	}
 
@@ -458,7 +575,7 @@ func (tp *TxPool) ValidateTxCustomTokenBlockChain(tx transaction.Transaction, ch
 		return err
 	}
 	// check double spend for custom token with blockchain data
-	err = blockChain.ValidateDoubleSpendCustomToken(tx.(*transaction.TxCustomToken))
+	err = blockChain.ValidateDoubleSpendCustomToken(txCustomToken)
 	if err != nil {
 		return err
 	}
@@ -466,17 +583,28 @@ func (tp *TxPool) ValidateTxCustomTokenBlockChain(tx transaction.Transaction, ch
 }
 
 // ValidateTxWithBlockChain - process validation of tx with old data in blockchain
-// - check double spend
+// - check double spend of every tx sent by users
+// - check data of tx by its type
 func (tp *TxPool) ValidateTxWithBlockChain(tx transaction.Transaction, chainID byte) error {
 	blockChain := tp.config.BlockChain
-	switch tx.GetType() {
-	case common.TxNormalType:
-		{
+	if !producerTxTypes[tx.GetType()] {
+		if customTokenTx(tx) != nil {
+			// check double spend for constant coin and custom token
+			err := tp.ValidateTxCustomTokenBlockChain(tx, chainID)
+			if err != nil {
+				return err
+			}
+		} else {
 			// check double spend
 			err := blockChain.ValidateDoubleSpend(tx, chainID)
 			if err != nil {
 				return err
 			}
+		}
+	}
+	switch tx.GetType() {
+	case common.TxNormalType, common.TxCustomTokenType, common.TxVoteDCBBoard, common.TxVoteGOVBoard, common.TxCrowdsale:
+		{
 			return nil
 		}
 	case common.TxSalaryType:
@@ -484,23 +612,6 @@ func (tp *TxPool) ValidateTxWithBlockChain(tx transaction.Transaction, chainID b
 			//return errors.New("Can not receive a salary tx from other node, this is a violation")
 			return nil
 		}
-	case common.TxCustomTokenType:
-		{
-			return tp.ValidateTxCustomTokenBlockChain(tx, chainID)
-			// verify custom token signs
-		}
-	case common.TxVoteDCBBoard:
-		{
-			txVoteDCBBoard := tx.(*transaction.TxVoteDCBBoard)
-			txCustomToken := txVoteDCBBoard.TxCustomToken
-			return tp.ValidateTxCustomTokenBlockChain(&txCustomToken, chainID)
-		}
-	case common.TxVoteGOVBoard:
-		{
-			txVoteGOVBoard := tx.(*transaction.TxVoteGOVBoard)
-			txCustomToken := txVoteGOVBoard.TxCustomToken
-			return tp.ValidateTxCustomTokenBlockChain(&txCustomToken, chainID)
-		}
 	case common.TxLoanRequest:
 		{
 			err := blockChain.ValidateTxLoanRequest(tx, chainID)
@@ -541,7 +652,7 @@ func (tp *TxPool) ValidateTxWithBlockChain(tx transaction.Transaction, chainID b
 			}
 			return nil
 		}
-	case common.TxBuySellDCBRequest:
+	case common.TxBuyFromGOVRequest, common.TxBuySellDCBRequest:
 		{
 			err := blockChain.ValidateTxBuyRequest(tx, chainID)
 			if err != nil {
@@ -549,6 +660,14 @@ func (tp *TxPool) ValidateTxWithBlockChain(tx transaction.Transaction, chainID b
 			}
 			return nil
 		}
+	case common.TxBuyBackRequest:
+		{
+			err := blockChain.ValidateTxBuyBackRequest(tx, chainID)
+			if err != nil {
+				return err
+			}
+			return nil
+		}
 	case common.TxSubmitDCBProposal:
 		{
 			return blockChain.ValidateTxSubmitDCBProposal(tx, chainID)
@@ -578,7 +697,6 @@ func (tp *TxPool) ValidateTxWithBlockChain(tx transaction.Transaction, chainID b
 			return errors.New("Wrong tx type")
 		}
 	}
-	return errors.New("No check Tx")
 }
 
 func (tp *TxPool) GetListUTXOForCustomToken(txCustomToken *transaction.TxCustomToken) bool {
@@ -601,51 +719,62 @@ func (tp *TxPool) GetListUTXOForCustomToken(txCustomToken *transaction.TxCustomT
 
 func (tp *TxPool) ValidateTxByItSelf(tx transaction.Transaction) bool {
 	switch tx.GetType() {
-	case common.TxCustomTokenType:
+	case common.TxCustomTokenType, common.TxVoteDCBBoard, common.TxVoteGOVBoard, common.TxCrowdsale:
 		{
 			// with custom token tx, we need to get utxo for custom token and for validation
-			txCustomToken := tx.(*transaction.TxCustomToken)
+			txCustomToken := customTokenTx(tx)
+			if txCustomToken == nil {
+				return false
+			}
 			ok := tp.GetListUTXOForCustomToken(txCustomToken)
 			if ok == false {
 				return false
 			}
-			return txCustomToken.ValidateTransaction()
-		}
-	case common.TxVoteDCBBoard:
-		{
-			txVoteDCBBoard := tx.(*transaction.TxVoteDCBBoard)
-			txCustomToken := txVoteDCBBoard.TxCustomToken
-			ok := tp.GetListUTXOForCustomToken(&txCustomToken)
-			if ok == false {
+			if !txCustomToken.ValidateTransaction() {
 				return false
 			}
-			return txCustomToken.ValidateTransaction() && txVoteDCBBoard.Validate()
+			switch tx := tx.(type) {
+			case *transaction.TxVoteDCBBoard:
+				return tx.Validate()
+			case *transaction.TxVoteGOVBoard:
+				return tx.Validate()
+			case *transaction.TxCrowdsale:
+				return tx.ValidateTransaction()
+			}
+			return true
 		}
-	case common.TxVoteGOVBoard:
+	case common.TxVoteDCBProposal:
 		{
-			txVoteGOVBoard := tx.(*transaction.TxVoteGOVBoard)
-			txCustomToken := txVoteGOVBoard.TxCustomToken
-			ok := tp.GetListUTXOForCustomToken(&txCustomToken)
-			if ok == false {
-				return false
-			}
-			return txCustomToken.ValidateTransaction() && txVoteGOVBoard.Validate()
+			txVoteDCBProposal, ok := tx.(*transaction.TxVoteDCBProposal)
+			return ok && txVoteDCBProposal.Validate()
+		}
+	case common.TxVoteGOVProposal:
+		{
+			txVoteGOVProposal, ok := tx.(*transaction.TxVoteGOVProposal)
+			return ok && txVoteGOVProposal.Validate()
 		}
-
 	default:
 		return tx.ValidateTransaction()
 	}
-	return false
 }
 
 // RemoveTx safe remove transaction for pool
 func (tp *TxPool) RemoveTx(tx transaction.Transaction) error {
 	tp.mtx.Lock()
-	err := tp.removeTx(&tx)
+	err := tp.removeTx(&tx, TxRemovedByCaller)
 	tp.mtx.Unlock()
 	return err
 }
 
+// RemoveBlockTxs safe remove txs of a block connected to blockchain from pool
+func (tp *TxPool) RemoveBlockTxs(block *blockchain.Block) {
+	tp.mtx.Lock()
+	defer tp.mtx.Unlock()
+	for _, tx := range block.Transactions {
+		tp.removeTx(&tx, TxRemovedMined)
+	}
+}
+
 // GetTx get transaction info by hash
 func (tp *TxPool) GetTx(txHash *common.Hash) (transaction.Transaction, error) {
 	tp.mtx.Lock()
@@ -672,6 +801,19 @@ func (tp *TxPool) MiningDescs() []*transaction.TxDesc {
 	return descs
 }
 
+// ChainMiningDescs returns a slice of mining descriptors for the transactions
+// sent from a chain, so a block template of the chain does not scan all pool
+func (tp *TxPool) ChainMiningDescs(chainID byte) []*transaction.TxDesc {
+	descs := []*transaction.TxDesc{}
+	tp.mtx.Lock()
+	for _, desc := range tp.poolByChain[chainID] {
+		descs = append(descs, &desc.Desc)
+	}
+	tp.mtx.Unlock()
+
+	return descs
+}
+
 // Count return len of transaction pool
 func (tp *TxPool) Count() int {
 	count := len(tp.pool)
@@ -683,10 +825,7 @@ Sum of all transactions sizes
 */
 func (tp *TxPool) Size() uint64 {
 	tp.mtx.RLock()
-	size := uint64(0)
-	for _, tx := range tp.pool {
-		size += tx.Desc.Tx.GetTxVirtualSize()
-	}
+	size := tp.poolSize
 	tp.mtx.RUnlock()
 
 	return size
@@ -728,7 +867,7 @@ func (tp *TxPool) HaveTransaction(hash *common.Hash) bool {
 }
 
 /*
-CheckTransactionFee - check fee of tx
+CheckTransactionFee - check fee of tx with fee schedule of its sender chain
 */
 func (tp *TxPool) CheckTransactionFee(tx transaction.Transaction) (uint64, error) {
 	// Salary transactions have no inputs.
@@ -736,65 +875,39 @@ func (tp *TxPool) CheckTransactionFee(tx transaction.Transaction) (uint64, error
 		return 0, nil
 	}
 
-	txType := tx.GetType()
-	switch txType {
-	case common.TxCustomTokenType:
-		{
-			{
-				tx := tx.(*transaction.TxCustomToken)
-				err := tp.config.Policy.CheckCustomTokenTransactionFee(tx)
-				return tx.Fee, err
-			}
-		}
-	case common.TxNormalType:
-		{
-			normalTx := tx.(*transaction.Tx)
-			err := tp.config.Policy.CheckTransactionFee(normalTx)
-			return normalTx.Fee, err
-		}
-	default:
-		{
-			return 0, errors.New("Wrong tx type")
-		}
+	chainID, err := common.GetTxSenderChain(tx.GetSenderAddrLastByte())
+	if err != nil {
+		return 0, err
 	}
-	return 0, errors.New("No check tx")
+	err = tp.config.Policy.CheckTransactionFee(tx, chainID)
+	return tx.GetTxFee(), err
 }
 
 /*
-ValidateSanityData - validate sansity data of tx
+ValidateSanityData - validate sansity data of tx of any type, its normal tx,
+its custom token and the data of its type
 */
 func (tp *TxPool) ValidateSanityData(tx transaction.Transaction) (bool, error) {
-	switch tx.GetType() {
-	case common.TxNormalType, common.TxSalaryType:
-		{
-			txA := tx.(*transaction.Tx)
-			ok, err := tp.validateSanityNormalTxData(txA)
-			return ok, err
-		}
-	case common.TxCustomTokenType:
-		{
-			txCustomToken := tx.(*transaction.TxCustomToken)
-			ok, err := tp.validateSanityCustomTokenTxData(txCustomToken)
-			return ok, err
-		}
-	case common.TxVoteDCBBoard:
-		{
-			txVoteDCBBoard := tx.(*transaction.TxVoteDCBBoard)
-			ok, err := tp.validateSanityVoteDCBBoardTx(txVoteDCBBoard)
+	err := checkTxStruct(tx)
+	if err != nil {
+		return false, err
+	}
+	if txCustomToken := customTokenTx(tx); txCustomToken != nil {
+		ok, err := tp.validateSanityCustomTokenTxData(txCustomToken, tx.GetType())
+		if !ok {
 			return ok, err
 		}
-	case common.TxVoteGOVBoard:
-		{
-			txVoteGOVBoard := tx.(*transaction.TxVoteGOVBoard)
-			ok, err := tp.validateSanityVoteGOVBoardTx(txVoteGOVBoard)
+	} else {
+		ok, err := tp.validateSanityNormalTxData(baseTx(tx), tx.GetType(), common.TxNormalType)
+		if !ok {
 			return ok, err
 		}
-	default:
-		{
-			return false, errors.New("Wrong tx type")
-		}
 	}
-	return false, errors.New("No check tx")
+	err = tp.validateSanityTxTypeData(tx)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 /*
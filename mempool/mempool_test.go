@@ -3,6 +3,7 @@ package mempool
 import (
 	"testing"
 
+	"github.com/ninjadotorg/constant/common"
 	"github.com/ninjadotorg/constant/transaction"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -13,7 +14,8 @@ type TxMockedObject struct {
 }
 
 func TestNew(t *testing.T) {
-	mempool := New(&Config{
+	mempool := &TxPool{}
+	mempool.Init(&Config{
 		Policy: Policy{},
 	})
 
@@ -28,27 +30,28 @@ func (m *TxMockedObject) GetTransaction() transaction.Tx {
 	//}
 	return transaction.Tx{
 		Version:  0,
-		TxIn:     nil,
-		TxOut:    nil,
 		LockTime: 0,
 	}
 
 }
 
-func TestTxPool_CanAcceptTransaction(t *testing.T) {
-	mempool := New(&Config{
+func TestTxPool_MaybeAcceptTransactionRejectsWrongStruct(t *testing.T) {
+	mempool := &TxPool{}
+	mempool.Init(&Config{
 		Policy: Policy{},
 	})
+	// a normal tx claiming to be a vote is rejected before any data of its
+	// type is read
 	tx := transaction.Tx{
 		Version:  0,
-		TxIn:     nil,
-		TxOut:    nil,
 		LockTime: 0,
+		Type:     common.TxVoteDCBBoard,
 	}
-	txHash, txDesc, txError := mempool.CanAcceptTransaction(&tx)
+	txHash, txDesc, txError := mempool.MaybeAcceptTransaction(&tx)
 
-	assert.Nil(t, txError)
-
-	assert.NotNil(t, txHash, "hash should not nil")
-	assert.NotEmpty(t, txDesc, "have txDesc")
+	assert.NotNil(t, txError)
+	assert.Equal(t, ErrCodeMessage[RejectInvalidTx].code, txError.(MempoolTxError).code)
+	assert.Nil(t, txHash, "hash should be nil")
+	assert.Nil(t, txDesc, "no txDesc")
+	assert.Equal(t, mempool.Count(), 0, "tx is not in mempool")
 }
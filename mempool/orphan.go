@@ -0,0 +1,155 @@
+package mempool
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/transaction"
+)
+
+// orphanTx is a tx waiting for its parents (see blockchain.TxParentKeys) to
+// come into pool or blockchain
+type orphanTx struct {
+	tx    transaction.Transaction
+	added time.Time
+
+	// keys of parents which are neither in pool nor in blockchain
+	missing []string
+}
+
+/*
+// addOrphan holds tx until its missing parents come, the oldest orphan is
+// evicted when orphan pool is full. Orphans are rejected when MaxOrphanTxs is
+// 0. It returns the error telling why tx is not accepted yet
+//
+// This function MUST be called with the mempool lock held (for writes).
+*/
+func (tp *TxPool) addOrphan(tx transaction.Transaction, missing []string) error {
+	txHash := *tx.Hash()
+	if tp.config.MaxOrphanTxs <= 0 {
+		err := MempoolTxError{}
+		err.Init(RejectOrphanTx, fmt.Errorf("%+v misses parents %v", txHash.String(), missing))
+		return err
+	}
+	if _, ok := tp.orphans[txHash]; ok {
+		err := MempoolTxError{}
+		err.Init(RejectDuplicateTx, fmt.Errorf("already have orphan transaction %+v", txHash.String()))
+		return err
+	}
+	for len(tp.orphans) >= tp.config.MaxOrphanTxs {
+		var oldest *orphanTx
+		for _, orphan := range tp.orphans {
+			if oldest == nil || orphan.added.Before(oldest.added) {
+				oldest = orphan
+			}
+		}
+		Logger.log.Infof("Evict orphan tx %+v from full orphan pool", oldest.tx.Hash().String())
+		tp.removeOrphan(oldest.tx)
+	}
+
+	orphan := &orphanTx{
+		tx:      tx,
+		added:   time.Now(),
+		missing: missing,
+	}
+	tp.orphans[txHash] = orphan
+	for _, key := range missing {
+		if _, ok := tp.orphansByParent[key]; !ok {
+			tp.orphansByParent[key] = make(map[common.Hash]*orphanTx)
+		}
+		tp.orphansByParent[key][txHash] = orphan
+	}
+	err := MempoolTxError{}
+	err.Init(RejectOrphanTx, fmt.Errorf("%+v is held until its parents %v come", txHash.String(), missing))
+	return err
+}
+
+/*
+// removeOrphan removes tx from orphan pool
+//
+// This function MUST be called with the mempool lock held (for writes).
+*/
+func (tp *TxPool) removeOrphan(tx transaction.Transaction) {
+	txHash := *tx.Hash()
+	orphan, ok := tp.orphans[txHash]
+	if !ok {
+		return
+	}
+	for _, key := range orphan.missing {
+		delete(tp.orphansByParent[key], txHash)
+		if len(tp.orphansByParent[key]) == 0 {
+			delete(tp.orphansByParent, key)
+		}
+	}
+	delete(tp.orphans, txHash)
+}
+
+/*
+// processOrphans tries again to accept orphans waiting for tx, which came
+// into pool or blockchain. An orphan still missing other parents is held
+// again. It returns descs of accepted orphans and of orphans accepted after
+// them in turn, parents first
+//
+// This function MUST be called with the mempool lock held (for writes).
+*/
+func (tp *TxPool) processOrphans(tx transaction.Transaction) []*TxDesc {
+	accepted := []*TxDesc{}
+	waiting := []*orphanTx{}
+	for _, key := range blockchain.TxDependencyKeys(tx) {
+		for _, orphan := range tp.orphansByParent[key] {
+			waiting = append(waiting, orphan)
+		}
+	}
+	for _, orphan := range waiting {
+		orphanHash := *orphan.tx.Hash()
+		if _, ok := tp.orphans[orphanHash]; !ok {
+			continue
+		}
+		tp.removeOrphan(orphan.tx)
+		_, txDesc, err := tp.maybeAcceptTransaction(orphan.tx)
+		if err != nil {
+			Logger.log.Debugf("Orphan tx %+v is not accepted: %+v", orphanHash.String(), err)
+			// keep age of an orphan held again
+			if heldAgain, ok := tp.orphans[orphanHash]; ok {
+				heldAgain.added = orphan.added
+			}
+			continue
+		}
+		Logger.log.Infof("Accept orphan tx %+v after its parent %+v", orphanHash.String(), tx.Hash().String())
+		accepted = append(accepted, txDesc)
+		accepted = append(accepted, tp.processOrphans(orphan.tx)...)
+	}
+	return accepted
+}
+
+/*
+// expireOrphans removes orphans held longer than OrphanTxTTL, it returns
+// number of expired orphans
+//
+// This function MUST be called with the mempool lock held (for writes).
+*/
+func (tp *TxPool) expireOrphans(now time.Time) int {
+	expired := 0
+	for hash, orphan := range tp.orphans {
+		if now.Sub(orphan.added) <= OrphanTxTTL {
+			continue
+		}
+		Logger.log.Infof("Expire orphan tx %+v added at %+v", hash.String(), orphan.added)
+		tp.removeOrphan(orphan.tx)
+		expired++
+	}
+	return expired
+}
+
+/*
+OrphanCount - number of orphan txs waiting for their parents
+
+This function is safe for concurrent access.
+*/
+func (tp *TxPool) OrphanCount() int {
+	tp.mtx.RLock()
+	defer tp.mtx.RUnlock()
+	return len(tp.orphans)
+}
@@ -0,0 +1,125 @@
+package mempool
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/transaction"
+)
+
+// txDescDump is a tx of pool saved in dump file
+type txDescDump struct {
+	Type   string
+	Tx     json.RawMessage
+	Added  time.Time
+	Height int32
+}
+
+/*
+Save - write all txs of pool into dump file with the time they were added and
+best height of their chain at that time, it returns number of saved txs
+
+This function is safe for concurrent access.
+*/
+func (tp *TxPool) Save() (int, error) {
+	if tp.config.DumpFile == common.EmptyString {
+		return 0, errors.New("Mempool has no dump file")
+	}
+	tp.mtx.RLock()
+	dumps := make([]txDescDump, 0, len(tp.pool))
+	for _, txDesc := range tp.pool {
+		txBytes, err := json.Marshal(txDesc.Desc.Tx)
+		if err != nil {
+			tp.mtx.RUnlock()
+			return 0, err
+		}
+		dumps = append(dumps, txDescDump{
+			Type:   txDesc.Desc.Tx.GetType(),
+			Tx:     txBytes,
+			Added:  txDesc.Desc.Added,
+			Height: txDesc.Desc.Height,
+		})
+	}
+	tp.mtx.RUnlock()
+
+	data, err := json.Marshal(dumps)
+	if err != nil {
+		return 0, err
+	}
+	// write a temp file first, so a crash never leaves a broken dump
+	tmpFile := tp.config.DumpFile + ".tmp"
+	err = ioutil.WriteFile(tmpFile, data, 0600)
+	if err != nil {
+		return 0, err
+	}
+	err = os.Rename(tmpFile, tp.config.DumpFile)
+	if err != nil {
+		return 0, err
+	}
+	return len(dumps), nil
+}
+
+/*
+Load - read txs from dump file and validate every one again as a new tx, txs
+keep the time they were first added so they expire as before. It returns
+number of accepted txs, txs which are not valid any more are dropped
+
+This function is safe for concurrent access.
+*/
+func (tp *TxPool) Load() (int, error) {
+	if tp.config.DumpFile == common.EmptyString {
+		return 0, errors.New("Mempool has no dump file")
+	}
+	data, err := ioutil.ReadFile(tp.config.DumpFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	dumps := []txDescDump{}
+	err = json.Unmarshal(data, &dumps)
+	if err != nil {
+		return 0, err
+	}
+	// older txs first, so requests are accepted before their responses
+	sort.Slice(dumps, func(i, j int) bool {
+		return dumps[i].Added.Before(dumps[j].Added)
+	})
+
+	now := time.Now()
+	accepted := 0
+	tp.mtx.Lock()
+	defer tp.mtx.Unlock()
+	for _, dump := range dumps {
+		if tp.config.MaxTxAge > 0 && now.Sub(dump.Added) > tp.config.MaxTxAge {
+			continue
+		}
+		tx, err := transaction.NewTxByType(dump.Type)
+		if err != nil {
+			Logger.log.Error(err)
+			continue
+		}
+		err = json.Unmarshal(dump.Tx, tx)
+		if err != nil {
+			Logger.log.Error(err)
+			continue
+		}
+		_, txDesc, err := tp.maybeAcceptTransaction(tx)
+		if err != nil {
+			Logger.log.Infof("Drop tx %+v of mempool dump: %+v", tx.Hash().String(), err)
+			continue
+		}
+		txDesc.Desc.Added = dump.Added
+		txDesc.Desc.Height = dump.Height
+		accepted++
+		// orphans of a dump come back with their parents
+		accepted += len(tp.processOrphans(tx))
+	}
+	return accepted, nil
+}
@@ -0,0 +1,134 @@
+package mempool
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/database"
+	"github.com/ninjadotorg/constant/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestDumpPool - pool validating txs with chain which saves into dumpFile
+func newTestDumpPool(chain *blockchain.BlockChain, db database.DatabaseInterface, dumpFile string) *TxPool {
+	mempool := &TxPool{}
+	mempool.Init(&Config{
+		BlockChain: chain,
+		DataBase:   db,
+		Policy:     Policy{BlockChain: chain, MaxTxVersion: transaction.TxVersion},
+		DumpFile:   dumpFile,
+		MaxTxAge:   time.Hour,
+	})
+	return mempool
+}
+
+// newTestFeeTx returns a normal tx without descs which pays fee
+func newTestFeeTx(lockTime int64, fee uint64) *transaction.Tx {
+	tx := newTestNormalTx(common.TxNormalType)
+	tx.LockTime = lockTime
+	tx.Fee = fee
+	return &tx
+}
+
+func TestSaveLoad(t *testing.T) {
+	chain, db, cleanup := newTestBlockChain(t)
+	defer cleanup()
+	dir, err := ioutil.TempDir(common.EmptyString, "mempool-dump")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	dumpFile := filepath.Join(dir, "mempool.dat")
+
+	mempool := newTestDumpPool(chain, db, dumpFile)
+	added := time.Now().Add(-time.Minute).Round(time.Second)
+	txs := []*transaction.Tx{newTestFeeTx(1, 1000000), newTestFeeTx(2, 2000000), newTestFeeTx(3, 3000000)}
+	for _, tx := range txs {
+		_, txDesc, err := mempool.MaybeAcceptTransaction(tx)
+		assert.Nil(t, err)
+		txDesc.Desc.Added = added
+	}
+	// a tx which expires before it is loaded again is dropped
+	mempool.pool[*txs[2].Hash()].Desc.Added = time.Now().Add(-2 * time.Hour)
+	saved, err := mempool.Save()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, saved)
+	_, err = os.Stat(dumpFile + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+
+	loaded := newTestDumpPool(chain, db, dumpFile)
+	accepted, err := loaded.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, accepted)
+	assert.False(t, loaded.isTxInPool(txs[2].Hash()))
+	for _, tx := range txs[:2] {
+		txDesc := loaded.pool[*tx.Hash()]
+		if !assert.NotNil(t, txDesc) {
+			continue
+		}
+		// txs keep the time they were first added and their fee
+		assert.True(t, added.Equal(txDesc.Desc.Added))
+		assert.Equal(t, mempool.pool[*tx.Hash()].Desc.Height, txDesc.Desc.Height)
+		assert.Equal(t, tx.Fee, txDesc.Desc.Fee)
+	}
+
+	// txs in pool already aren't accepted again
+	accepted, err = loaded.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, accepted)
+	assert.Equal(t, 2, loaded.Count())
+}
+
+func TestLoad_MissingOrCorruptFile(t *testing.T) {
+	chain, db, cleanup := newTestBlockChain(t)
+	defer cleanup()
+	dir, err := ioutil.TempDir(common.EmptyString, "mempool-dump")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	dumpFile := filepath.Join(dir, "mempool.dat")
+
+	// a pool without dump file can't be saved or loaded
+	mempool := newTestDumpPool(chain, db, common.EmptyString)
+	_, err = mempool.Save()
+	assert.NotNil(t, err)
+	_, err = mempool.Load()
+	assert.NotNil(t, err)
+
+	// a missing dump file is an empty pool
+	mempool = newTestDumpPool(chain, db, dumpFile)
+	accepted, err := mempool.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, accepted)
+
+	// a broken dump file is an error
+	assert.Nil(t, ioutil.WriteFile(dumpFile, []byte("[{\"Type\":"), 0600))
+	accepted, err = mempool.Load()
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, accepted)
+	assert.Equal(t, 0, mempool.Count())
+
+	// a tx of unknown type, a broken tx and an invalid tx are dropped,
+	// the others are loaded
+	valid, err := json.Marshal(newTestFeeTx(1, 1000000))
+	assert.Nil(t, err)
+	invalidTx := newTestFeeTx(2, 1000000)
+	invalidTx.JSSig = nil
+	invalid, err := json.Marshal(invalidTx)
+	assert.Nil(t, err)
+	dump, err := json.Marshal([]txDescDump{
+		{Type: "unknown", Tx: json.RawMessage("{}"), Added: time.Now()},
+		{Type: common.TxNormalType, Tx: json.RawMessage("\"broken\""), Added: time.Now()},
+		{Type: common.TxNormalType, Tx: invalid, Added: time.Now()},
+		{Type: common.TxNormalType, Tx: valid, Added: time.Now()},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(dumpFile, dump, 0600))
+	accepted, err = mempool.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, accepted)
+	assert.Equal(t, 1, mempool.Count())
+}
@@ -5,7 +5,6 @@ import (
 
 	"errors"
 	"github.com/ninjadotorg/constant/blockchain"
-	"github.com/ninjadotorg/constant/common"
 	"github.com/ninjadotorg/constant/transaction"
 )
 
@@ -20,57 +19,40 @@ type Policy struct {
 }
 
 /*
-
- */
+CheckTxVersion - check version of the normal tx inside tx of any type
+*/
 func (self *Policy) CheckTxVersion(tx *transaction.Transaction) bool {
-	txType := (*tx).GetType()
-	switch txType {
-	case common.TxSalaryType:
-		{
-			temp := (*tx).(*transaction.Tx)
-			if temp.Version > self.MaxTxVersion {
-				return false
-			}
-		}
-	case common.TxNormalType:
-		{
-			temp := (*tx).(*transaction.Tx)
-			if temp.Version > self.MaxTxVersion {
-				return false
-			}
-		}
+	txNormal := baseTx(*tx)
+	if txNormal == nil {
+		return false
 	}
-	return true
-}
-
-// return min transacton fee required for a transaction that we accepted into the memmory pool and replayed.
-func (self *Policy) calcMinFeeTxCustomTokenAccepted(tx *transaction.TxCustomToken) uint64 {
-	return self.BlockChain.BestState[0].BestBlock.Header.GOVConstitution.GOVParams.BasicSalary
+	return txNormal.Version <= self.MaxTxVersion
 }
 
 // return min transacton fee required for a transaction that we accepted into the memmory pool and replayed.
-func (self *Policy) calcMinFeeTxAccepted(tx *transaction.Tx) uint64 {
-	return self.BlockChain.BestState[0].BestBlock.Header.GOVConstitution.GOVParams.BasicSalary
+// The fee schedule of GOV params of the sender chain gives min fee per kb by tx type, so governance tunes
+// fees without a code change
+func (self *Policy) calcMinFeeTxAccepted(tx transaction.Transaction, chainID byte) (uint64, error) {
+	govParams := self.BlockChain.BestSnapshot(chainID).BestBlock.Header.GOVConstitution.GOVParams
+	minFee, ok := govParams.MinFee(tx.GetType(), tx.GetTxVirtualSize())
+	if !ok {
+		return 0, fmt.Errorf("transaction %+v has type %s which is not in fee schedule", tx.Hash().String(), tx.GetType())
+	}
+	return minFee, nil
 }
 
 /*
 
  */
-func (self *Policy) CheckTransactionFee(tx *transaction.Tx) error {
-	minFee := self.calcMinFeeTxAccepted(tx)
-	if tx.Fee < minFee {
-		str := fmt.Sprintf("transaction %+v has %d fees which is under the required amount of %d", tx.Hash().String(), tx.Fee, minFee)
-		err := MempoolTxError{}
-		err.Init(RejectInvalidFee, errors.New(str))
-		return err
+func (self *Policy) CheckTransactionFee(tx transaction.Transaction, chainID byte) error {
+	minFee, err := self.calcMinFeeTxAccepted(tx, chainID)
+	if err != nil {
+		mempoolErr := MempoolTxError{}
+		mempoolErr.Init(RejectInvalidFee, err)
+		return mempoolErr
 	}
-	return nil
-}
-
-func (self *Policy) CheckCustomTokenTransactionFee(tx *transaction.TxCustomToken) error {
-	minFee := self.calcMinFeeTxCustomTokenAccepted(tx)
-	if tx.Fee < minFee {
-		str := fmt.Sprintf("transaction %+v has %d fees which is under the required amount of %d", tx.Hash().String(), tx.Fee, minFee)
+	if tx.GetTxFee() < minFee {
+		str := fmt.Sprintf("transaction %+v has %d fees which is under the required amount of %d", tx.Hash().String(), tx.GetTxFee(), minFee)
 		err := MempoolTxError{}
 		err.Init(RejectInvalidFee, errors.New(str))
 		return err
@@ -0,0 +1,52 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_CheckTransactionFee(t *testing.T) {
+	chain, db, cleanup := newTestBlockChain(t)
+	defer cleanup()
+	mempool := newTestDumpPool(chain, db, common.EmptyString)
+	govParams := &chain.BestState[0].BestBlock.Header.GOVConstitution.GOVParams
+	tx := newTestFeeTx(1, govParams.BasicSalary)
+	size := tx.GetTxVirtualSize()
+
+	// genesis has no fee schedule, basic salary is min fee of every tx
+	fee, err := mempool.CheckTransactionFee(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, govParams.BasicSalary, fee)
+	tx.Fee--
+	_, err = mempool.CheckTransactionFee(tx)
+	assert.Equal(t, ErrCodeMessage[RejectInvalidFee].code, err.(MempoolTxError).code)
+
+	// min fee is fee per kb of the tx type in fee schedule
+	govParams.FeeSchedule = &blockchain.FeeSchedule{Normal: 10, Loan: 20}
+	tx.Fee = 10*size - 1
+	_, err = mempool.CheckTransactionFee(tx)
+	assert.Equal(t, ErrCodeMessage[RejectInvalidFee].code, err.(MempoolTxError).code)
+	tx.Fee = 10 * size
+	_, err = mempool.CheckTransactionFee(tx)
+	assert.Nil(t, err)
+	_, txDesc, err := mempool.MaybeAcceptTransaction(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, 10*size, txDesc.Desc.Fee)
+
+	request, _, _ := newTestLoanTxs([]byte{1}, []byte{2})
+	request.Fee = 10 * request.GetTxVirtualSize()
+	_, err = mempool.CheckTransactionFee(request)
+	assert.Equal(t, ErrCodeMessage[RejectInvalidFee].code, err.(MempoolTxError).code)
+	request.Fee = 20 * request.GetTxVirtualSize()
+	_, err = mempool.CheckTransactionFee(request)
+	assert.Nil(t, err)
+
+	// a tx type which isn't in fee schedule is never accepted
+	salary := newTestFeeTx(2, 100*size)
+	salary.Type = common.TxSalaryType
+	_, err = mempool.CheckTransactionFee(salary)
+	assert.Equal(t, ErrCodeMessage[RejectInvalidFee].code, err.(MempoolTxError).code)
+}
@@ -0,0 +1,109 @@
+package mempool
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/transaction"
+)
+
+/*
+TxFilter - conditions of txs returned by QueryTxs, a zero field matches every
+tx. Age is how long a tx is in pool
+*/
+type TxFilter struct {
+	Types    []string
+	ChainIDs []byte
+	MinFee   uint64
+	MaxFee   uint64
+	MinAge   time.Duration
+	MaxAge   time.Duration
+}
+
+func (self *TxFilter) match(txDesc *TxDesc, now time.Time) bool {
+	if len(self.Types) > 0 {
+		if ok, _ := common.SliceExists(self.Types, txDesc.Desc.Tx.GetType()); !ok {
+			return false
+		}
+	}
+	if len(self.ChainIDs) > 0 {
+		found := false
+		for _, chainID := range self.ChainIDs {
+			if chainID == txDesc.ChainID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if txDesc.Desc.Fee < self.MinFee || (self.MaxFee > 0 && txDesc.Desc.Fee > self.MaxFee) {
+		return false
+	}
+	age := now.Sub(txDesc.Desc.Added)
+	if age < self.MinAge || (self.MaxAge > 0 && age > self.MaxAge) {
+		return false
+	}
+	return true
+}
+
+/*
+QueryTxs - txs in pool matching filter, the oldest first
+
+This function is safe for concurrent access.
+*/
+func (tp *TxPool) QueryTxs(filter TxFilter) []*TxDesc {
+	tp.mtx.RLock()
+	defer tp.mtx.RUnlock()
+	now := time.Now()
+	txDescs := []*TxDesc{}
+	for _, txDesc := range tp.pool {
+		if filter.match(txDesc, now) {
+			txDescs = append(txDescs, txDesc)
+		}
+	}
+	sort.Slice(txDescs, func(i, j int) bool {
+		return txDescs[i].Desc.Added.Before(txDescs[j].Desc.Added)
+	})
+	return txDescs
+}
+
+// TxConflict is a tx in pool which spends some nullifiers of another tx
+type TxConflict struct {
+	TxDesc *TxDesc
+
+	// nullifiers spent by both txs
+	Nullifiers [][]byte
+}
+
+/*
+Conflicts - txs in pool which spend nullifiers of tx, tx itself is left out
+when it is in pool. Without replacement a tx with conflicts is rejected as
+double spend, with replacement it replaces them when it pays enough
+
+This function is safe for concurrent access.
+*/
+func (tp *TxPool) Conflicts(tx transaction.Transaction) []*TxConflict {
+	tp.mtx.RLock()
+	defer tp.mtx.RUnlock()
+	txHash := tx.Hash()
+	nullifiers := tx.ListNullifiers()
+	conflicts := []*TxConflict{}
+	for hash, poolNullifiers := range tp.poolNullifiers {
+		if hash == *txHash {
+			continue
+		}
+		conflict := &TxConflict{TxDesc: tp.pool[hash]}
+		for _, nullifier := range nullifiers {
+			if ok, _ := common.SliceBytesExists(poolNullifiers, nullifier); ok {
+				conflict.Nullifiers = append(conflict.Nullifiers, nullifier)
+			}
+		}
+		if len(conflict.Nullifiers) > 0 {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,80 @@
+package mempool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSpendingTx(lockTime int64, nullifiers ...[]byte) *transaction.Tx {
+	tx := newTestNormalTx(common.TxNormalType)
+	tx.LockTime = lockTime
+	tx.Descs = []*transaction.JoinSplitDesc{{Nullifiers: nullifiers}}
+	return &tx
+}
+
+func TestQueryTxs(t *testing.T) {
+	mempool := &TxPool{}
+	mempool.Init(&Config{})
+	txs := newTestTxs()
+	old := mempool.addTx(txs[common.TxNormalType], 0, 1, 10)
+	old.Desc.Added = time.Now().Add(-time.Hour)
+	mempool.addTx(txs[common.TxCustomTokenType], 1, 1, 20)
+	mempool.addTx(txs[common.TxVoteDCBBoard], 1, 1, 30)
+
+	assert.Equal(t, 3, len(mempool.QueryTxs(TxFilter{})))
+	result := mempool.QueryTxs(TxFilter{ChainIDs: []byte{1}, MinFee: 25})
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, common.TxVoteDCBBoard, result[0].Desc.Tx.GetType())
+	result = mempool.QueryTxs(TxFilter{Types: []string{common.TxNormalType, common.TxCustomTokenType}, MaxFee: 15})
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, old, result[0])
+	assert.Equal(t, []*TxDesc{old}, mempool.QueryTxs(TxFilter{MinAge: time.Minute}))
+	assert.Equal(t, 2, len(mempool.QueryTxs(TxFilter{MaxAge: time.Minute})))
+}
+
+func TestConflicts(t *testing.T) {
+	mempool := &TxPool{}
+	mempool.Init(&Config{})
+	first := newTestSpendingTx(1, []byte{1}, []byte{2})
+	second := newTestSpendingTx(2, []byte{3})
+	mempool.addTx(first, 0, 1, 10)
+	mempool.addTx(second, 0, 1, 10)
+
+	// a tx in pool does not conflict with itself
+	assert.Equal(t, 0, len(mempool.Conflicts(first)))
+	conflicts := mempool.Conflicts(newTestSpendingTx(3, []byte{2}, []byte{4}))
+	assert.Equal(t, 1, len(conflicts))
+	assert.Equal(t, first, conflicts[0].TxDesc.Desc.Tx)
+	assert.Equal(t, [][]byte{{2}}, conflicts[0].Nullifiers)
+	assert.Equal(t, 2, len(mempool.Conflicts(newTestSpendingTx(3, []byte{1}, []byte{3}))))
+}
+
+func TestSubscribe(t *testing.T) {
+	Logger.Init(common.Disabled)
+	mempool := &TxPool{}
+	mempool.Init(&Config{})
+	subscription := mempool.Subscribe(2)
+	tx := newTestSpendingTx(1, []byte{1})
+	txDesc := mempool.addTx(tx, 0, 1, 10)
+	mempool.RemoveTx(tx)
+	mempool.addTx(tx, 0, 1, 10)
+
+	event := <-subscription.Events()
+	assert.Equal(t, TxAccepted, event.Type)
+	assert.Equal(t, txDesc, event.TxDesc)
+	event = <-subscription.Events()
+	assert.Equal(t, TxRemoved, event.Type)
+	assert.Equal(t, TxRemovedByCaller, event.Reason)
+	// the second accept is dropped from the full buffer
+	assert.Equal(t, uint64(1), subscription.Dropped())
+
+	subscription.Unsubscribe()
+	_, ok := <-subscription.Events()
+	assert.False(t, ok)
+	subscription.Unsubscribe()
+	mempool.RemoveTx(tx)
+}
@@ -0,0 +1,117 @@
+package mempool
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/transaction"
+)
+
+/*
+// conflictingTxs returns txs in pool which spend any nullifier of tx
+//
+// This function MUST be called with the mempool lock held (for reads).
+*/
+func (tp *TxPool) conflictingTxs(tx transaction.Transaction) map[common.Hash]*TxDesc {
+	conflicts := make(map[common.Hash]*TxDesc)
+	nullifiers := tx.ListNullifiers()
+	for txHash, poolNullifiers := range tp.poolNullifiers {
+		for _, nullifier := range nullifiers {
+			if ok, _ := common.SliceBytesExists(poolNullifiers, nullifier); ok {
+				conflicts[txHash] = tp.pool[txHash]
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+/*
+// evictedByReplacement returns replaced txs and their descendants in pool,
+// the txs which leave pool when replaced txs are removed
+//
+// This function MUST be called with the mempool lock held (for reads).
+*/
+func (tp *TxPool) evictedByReplacement(replaced map[common.Hash]*TxDesc) map[common.Hash]*TxDesc {
+	evicted := make(map[common.Hash]*TxDesc)
+	var evict func(hash common.Hash)
+	evict = func(hash common.Hash) {
+		txDesc, ok := tp.pool[hash]
+		if !ok {
+			return
+		}
+		if _, ok := evicted[hash]; ok {
+			return
+		}
+		evicted[hash] = txDesc
+		for childHash := range tp.poolChildren[hash] {
+			evict(childHash)
+		}
+	}
+	for hash := range replaced {
+		evict(hash)
+	}
+	return evicted
+}
+
+/*
+// checkReplacement returns txs in pool which tx replaces. When replacement is
+// enabled a tx spending nullifiers of txs in pool replaces them if:
+//  - it evicts at most MaxReplacementEvictions txs, the replaced txs and
+//    their descendants which go with them
+//  - its fee rate is higher than fee rate of every replaced tx
+//  - its fee is higher than sum of fees of evicted txs, by at least
+//    IncrementalRelayFee per KB of it, so relaying it again is paid for
+// No tx is returned when replacement is disabled, the double spend check
+// rejects tx then.
+//
+// This function MUST be called with the mempool lock held (for reads).
+*/
+func (tp *TxPool) checkReplacement(tx transaction.Transaction, fee uint64) (map[common.Hash]*TxDesc, error) {
+	if !tp.config.EnableReplacement {
+		return nil, nil
+	}
+	conflicts := tp.conflictingTxs(tx)
+	if len(conflicts) == 0 {
+		return nil, nil
+	}
+	txHash := tx.Hash()
+	evicted := tp.evictedByReplacement(conflicts)
+	if len(evicted) > MaxReplacementEvictions {
+		str := fmt.Sprintf("transaction %+v evicts %d txs with descendants of replaced txs, more than %d", txHash.String(), len(evicted), MaxReplacementEvictions)
+		err := MempoolTxError{}
+		err.Init(RejectReplacement, errors.New(str))
+		return nil, err
+	}
+
+	size := tx.GetTxVirtualSize()
+	feeRate := NewCoinPerKilobyte(fee, size)
+	for conflictHash, conflict := range conflicts {
+		if feeRate <= conflict.feeRate() {
+			str := fmt.Sprintf("transaction %+v has fee rate %d which is not higher than fee rate %d of replaced tx %+v", txHash.String(), feeRate, conflict.feeRate(), conflictHash.String())
+			err := MempoolTxError{}
+			err.Init(RejectReplacement, errors.New(str))
+			return nil, err
+		}
+	}
+	replacedFees := uint64(0)
+	for _, txDesc := range evicted {
+		replacedFees += txDesc.Desc.Fee
+	}
+	minFee := replacedFees + uint64(math.Ceil(IncrementalRelayFee*float64(size)))
+	if fee < minFee {
+		str := fmt.Sprintf("transaction %+v has fee %d, evicting %d txs needs a fee of at least %d", txHash.String(), fee, len(evicted), minFee)
+		err := MempoolTxError{}
+		err.Init(RejectReplacement, errors.New(str))
+		return nil, err
+	}
+	return conflicts, nil
+}
+
+// IsReplacementEnabled returns true when txs in pool can be replaced by txs
+// with a higher fee
+func (tp *TxPool) IsReplacementEnabled() bool {
+	return tp.config.EnableReplacement
+}
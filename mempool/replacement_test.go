@@ -0,0 +1,128 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckReplacement(t *testing.T) {
+	Logger.Init(common.Disabled)
+	mempool := &TxPool{}
+	mempool.Init(&Config{})
+	first := newTestSpendingTx(1, []byte{1})
+	size := first.GetTxVirtualSize()
+	mempool.addTx(first, 0, 1, 10*size)
+	second := newTestSpendingTx(2, []byte{2})
+	mempool.addTx(second, 0, 1, 20*size)
+	replacing := newTestSpendingTx(3, []byte{1}, []byte{2})
+
+	// nothing is replaced when replacement is disabled
+	replaced, err := mempool.checkReplacement(replacing, 100*size)
+	assert.Nil(t, err)
+	assert.Nil(t, replaced)
+
+	mempool.config.EnableReplacement = true
+	replaced, err = mempool.checkReplacement(newTestSpendingTx(3, []byte{3}), 100*size)
+	assert.Nil(t, err)
+	assert.Nil(t, replaced)
+
+	// fee rate must be higher than fee rate of every replaced tx
+	_, err = mempool.checkReplacement(replacing, 20*size)
+	assert.Equal(t, ErrCodeMessage[RejectReplacement].code, err.(MempoolTxError).code)
+	// fee must pay replaced fees and relaying tx again
+	_, err = mempool.checkReplacement(replacing, 30*size)
+	assert.Equal(t, ErrCodeMessage[RejectReplacement].code, err.(MempoolTxError).code)
+	_, err = mempool.checkReplacement(replacing, 30*size+IncrementalRelayFee*size-1)
+	assert.NotNil(t, err)
+	replaced, err = mempool.checkReplacement(replacing, 30*size+IncrementalRelayFee*size)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(replaced))
+	assert.NotNil(t, replaced[*first.Hash()])
+	assert.NotNil(t, replaced[*second.Hash()])
+
+	// a tx replaces at most MaxReplacementEvictions txs
+	nullifiers := [][]byte{}
+	for i := 0; i <= MaxReplacementEvictions; i++ {
+		nullifier := []byte{byte(i), byte(i >> 8), 1}
+		mempool.addTx(newTestSpendingTx(int64(10+i), nullifier), 0, 1, size)
+		nullifiers = append(nullifiers, nullifier)
+	}
+	_, err = mempool.checkReplacement(newTestSpendingTx(1000, nullifiers...), 1000000*size)
+	assert.Equal(t, ErrCodeMessage[RejectReplacement].code, err.(MempoolTxError).code)
+}
+
+func TestCheckReplacementDescendants(t *testing.T) {
+	Logger.Init(common.Disabled)
+	mempool := &TxPool{}
+	mempool.Init(&Config{EnableReplacement: true})
+	request, response, payment := newTestLoanTxs([]byte{1}, []byte{2})
+	request.Descs = []*transaction.JoinSplitDesc{{Nullifiers: [][]byte{{9}}}}
+	replacing := newTestSpendingTx(1, []byte{9})
+	size := replacing.GetTxVirtualSize()
+	mempool.addTx(request, 0, 1, 10*size)
+	mempool.addTx(response, 0, 1, 10*size)
+	mempool.addTx(payment, 0, 1, 10*size)
+
+	// fees of response and payment are lost with request, tx pays them too
+	_, err := mempool.checkReplacement(replacing, 10*size+IncrementalRelayFee*size)
+	assert.Equal(t, ErrCodeMessage[RejectReplacement].code, err.(MempoolTxError).code)
+	_, err = mempool.checkReplacement(replacing, 30*size+IncrementalRelayFee*size-1)
+	assert.NotNil(t, err)
+	replaced, err := mempool.checkReplacement(replacing, 30*size+IncrementalRelayFee*size)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(replaced))
+	assert.NotNil(t, replaced[*request.Hash()])
+
+	// descendants count against MaxReplacementEvictions
+	nullifiers := [][]byte{{9}}
+	for i := 0; i < MaxReplacementEvictions-1; i++ {
+		nullifier := []byte{byte(i), byte(i >> 8), 1}
+		mempool.addTx(newTestSpendingTx(int64(10+i), nullifier), 0, 1, size)
+		nullifiers = append(nullifiers, nullifier)
+	}
+	replacing = newTestSpendingTx(1000, nullifiers...)
+	assert.Equal(t, MaxReplacementEvictions, len(mempool.conflictingTxs(replacing)))
+	_, err = mempool.checkReplacement(replacing, 1000000*size)
+	assert.Equal(t, ErrCodeMessage[RejectReplacement].code, err.(MempoolTxError).code)
+}
+
+func TestIsEvictedOnAccept(t *testing.T) {
+	Logger.Init(common.Disabled)
+	mempool := &TxPool{}
+	mempool.Init(&Config{MaxTxs: 2, EnableReplacement: true})
+	low := newTestSpendingTx(1, []byte{1})
+	size := low.GetTxVirtualSize()
+	mempool.addTx(low, 0, 1, 10*size)
+	high := newTestSpendingTx(2, []byte{2})
+	mempool.addTx(high, 0, 1, 50*size)
+
+	// a tx fits into a full pool when it isn't the lowest fee rate
+	assert.False(t, mempool.isEvictedOnAccept(newTestSpendingTx(3, []byte{3}), 20*size, nil))
+	assert.True(t, mempool.isEvictedOnAccept(newTestSpendingTx(3, []byte{3}), 10*size, nil))
+	assert.True(t, mempool.isEvictedOnAccept(newTestSpendingTx(3, []byte{3}), 5*size, nil))
+
+	// replaced txs leave room, the replacing tx stays even at the lowest rate
+	replacing := newTestSpendingTx(3, []byte{1})
+	replaced, err := mempool.checkReplacement(replacing, 12*size)
+	assert.Nil(t, err)
+	assert.False(t, mempool.isEvictedOnAccept(replacing, 12*size, replaced))
+
+	// a tx which doesn't fit keeps the txs it would replace: pool is full with
+	// a tx of a higher rate after a replaced one goes
+	mempool.config.MaxTxs = 1
+	replacing = newTestSpendingTx(4, []byte{1})
+	replaced, err = mempool.checkReplacement(replacing, 20*size)
+	assert.Nil(t, err)
+	assert.True(t, mempool.isEvictedOnAccept(replacing, 20*size, replaced))
+	assert.True(t, mempool.isTxInPool(low.Hash()))
+
+	// a tx goes with an evicted parent
+	mempool.config.MaxTxs = 3
+	request, response, _ := newTestLoanTxs([]byte{1}, []byte{2})
+	mempool.addTx(request, 0, 1, size)
+	assert.True(t, mempool.isEvictedOnAccept(response, 100*size, nil))
+	assert.False(t, mempool.isEvictedOnAccept(newTestSpendingTx(5, []byte{5}), 100*size, nil))
+}
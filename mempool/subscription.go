@@ -0,0 +1,122 @@
+package mempool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// TxEventType tells whether a tx came into pool or left it
+type TxEventType int
+
+const (
+	TxAccepted TxEventType = iota
+	TxRemoved
+)
+
+func (eventType TxEventType) String() string {
+	switch eventType {
+	case TxAccepted:
+		return "accepted"
+	case TxRemoved:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// reasons of TxRemoved events
+const (
+	TxRemovedMined    = "mined"
+	TxRemovedExpired  = "expired"
+	TxRemovedInvalid  = "invalid"
+	TxRemovedEvicted  = "evicted"
+	TxRemovedReplaced = "replaced"
+	TxRemovedByCaller = "removed"
+	TxRemovedAncestor = "ancestor" // a parent of tx was removed
+)
+
+// TxEvent is a tx accepted into pool or removed from it
+type TxEvent struct {
+	Type   TxEventType
+	TxDesc *TxDesc
+	Reason string // why tx was removed, empty for accepted txs
+	Time   time.Time
+}
+
+/*
+TxSubscription - events of txs accepted into and removed from pool since the
+subscription is made. Events are dropped when the subscriber does not read
+them fast enough, Dropped tells how many
+*/
+type TxSubscription struct {
+	ID      uint64
+	events  chan *TxEvent
+	dropped uint64 // number of dropped events, read and written atomically
+	pool    *TxPool
+}
+
+// Events returns the channel of events, it is closed by Unsubscribe
+func (self *TxSubscription) Events() <-chan *TxEvent {
+	return self.events
+}
+
+// Dropped returns number of events dropped because the channel was full
+func (self *TxSubscription) Dropped() uint64 {
+	return atomic.LoadUint64(&self.dropped)
+}
+
+// Unsubscribe stops events of subscription and closes its channel
+func (self *TxSubscription) Unsubscribe() {
+	self.pool.subsMtx.Lock()
+	defer self.pool.subsMtx.Unlock()
+	if _, ok := self.pool.subscriptions[self.ID]; !ok {
+		return
+	}
+	delete(self.pool.subscriptions, self.ID)
+	close(self.events)
+}
+
+/*
+Subscribe - make a subscription of tx events which keeps up to bufferSize
+unread events
+
+This function is safe for concurrent access.
+*/
+func (tp *TxPool) Subscribe(bufferSize int) *TxSubscription {
+	if bufferSize <= 0 {
+		bufferSize = DefaultTxSubscriptionBuffer
+	}
+	tp.subsMtx.Lock()
+	defer tp.subsMtx.Unlock()
+	tp.nextSubscriptionID++
+	subscription := &TxSubscription{
+		ID:     tp.nextSubscriptionID,
+		events: make(chan *TxEvent, bufferSize),
+		pool:   tp,
+	}
+	tp.subscriptions[subscription.ID] = subscription
+	return subscription
+}
+
+/*
+// notify sends an event of tx to every subscription, it never blocks
+*/
+func (tp *TxPool) notify(eventType TxEventType, txDesc *TxDesc, reason string) {
+	tp.subsMtx.Lock()
+	defer tp.subsMtx.Unlock()
+	if len(tp.subscriptions) == 0 {
+		return
+	}
+	event := &TxEvent{
+		Type:   eventType,
+		TxDesc: txDesc,
+		Reason: reason,
+		Time:   time.Now(),
+	}
+	for _, subscription := range tp.subscriptions {
+		select {
+		case subscription.events <- event:
+		default:
+			atomic.AddUint64(&subscription.dropped, 1)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package mempool
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/transaction"
+)
+
+// producerTxTypes are created by block producers into their blocks, users
+// never send them so mempool never accepts them
+var producerTxTypes = map[string]bool{
+	common.TxSalaryType:         true,
+	common.TxAcceptDCBProposal:  true,
+	common.TxAcceptGOVProposal:  true,
+	common.TxDividendPayout:     true,
+	common.TxBuyFromGOVResponse: true,
+	common.TxBuySellDCBResponse: true,
+	common.TxBuyBackResponse:    true,
+}
+
+/*
+// checkTxStruct checks that tx has every part its type needs and that its
+// struct is the struct of its type, so tx can be cast by its type later
+*/
+func checkTxStruct(tx transaction.Transaction) error {
+	missing := false
+	switch tx := tx.(type) {
+	case *transaction.TxLoanRequest:
+		missing = tx.LoanRequest == nil
+	case *transaction.TxLoanResponse:
+		missing = tx.LoanResponse == nil
+	case *transaction.TxLoanPayment:
+		missing = tx.LoanPayment == nil
+	case *transaction.TxLoanWithdraw:
+		missing = tx.LoanWithdraw == nil
+	case *transaction.TxDividendPayout:
+		missing = tx.Tx == nil
+	case *transaction.TxBuySellRequest:
+		missing = tx.Tx == nil || tx.RequestInfo == nil
+	case *transaction.TxBuyBackRequest:
+		missing = tx.Tx == nil || tx.BuyBackRequestInfo == nil
+	case *transaction.TxCrowdsale:
+		missing = tx.TxCustomToken == nil || tx.SaleData == nil
+	case *transaction.TxAcceptDCBProposal:
+		missing = tx.Tx == nil
+	case *transaction.TxAcceptGOVProposal:
+		missing = tx.Tx == nil
+	}
+	if missing {
+		return fmt.Errorf("Tx %T misses data of its type", tx)
+	}
+	emptyTx, err := transaction.NewTxByType(tx.GetType())
+	if err != nil {
+		return err
+	}
+	if reflect.TypeOf(emptyTx) != reflect.TypeOf(tx) {
+		return fmt.Errorf("Tx of type %s is %T instead of %T", tx.GetType(), tx, emptyTx)
+	}
+	return nil
+}
+
+/*
+// checkTxType rejects txs which are not of a type users send, or whose struct
+// does not match their type
+*/
+func (tp *TxPool) checkTxType(tx transaction.Transaction) error {
+	err := checkTxStruct(tx)
+	if err != nil {
+		mempoolErr := MempoolTxError{}
+		mempoolErr.Init(RejectInvalidTx, err)
+		return mempoolErr
+	}
+	txType := tx.GetType()
+	if txType == common.TxSalaryType {
+		mempoolErr := MempoolTxError{}
+		mempoolErr.Init(RejectSalaryTx, fmt.Errorf("%+v is salary tx", tx.Hash().String()))
+		return mempoolErr
+	}
+	if producerTxTypes[txType] {
+		mempoolErr := MempoolTxError{}
+		mempoolErr.Init(RejectInvalidTx, fmt.Errorf("Tx of type %s is created by block producers only", txType))
+		return mempoolErr
+	}
+	return nil
+}
+
+// baseTx returns the normal tx inside tx, which pays fee and spends
+// nullifiers of it
+func baseTx(tx transaction.Transaction) *transaction.Tx {
+	if txCustomToken := customTokenTx(tx); txCustomToken != nil {
+		return &txCustomToken.Tx
+	}
+	switch tx := tx.(type) {
+	case *transaction.Tx:
+		return tx
+	case *transaction.TxLoanRequest:
+		return &tx.Tx
+	case *transaction.TxLoanResponse:
+		return &tx.Tx
+	case *transaction.TxLoanPayment:
+		return &tx.Tx
+	case *transaction.TxLoanWithdraw:
+		return &tx.Tx
+	case *transaction.TxDividendPayout:
+		return tx.Tx
+	case *transaction.TxBuySellRequest:
+		return tx.Tx
+	case *transaction.TxBuyBackRequest:
+		return tx.Tx
+	case *transaction.TxSubmitDCBProposal:
+		return &tx.Tx
+	case *transaction.TxSubmitGOVProposal:
+		return &tx.Tx
+	case *transaction.TxVoteDCBProposal:
+		return &tx.Tx
+	case *transaction.TxVoteGOVProposal:
+		return &tx.Tx
+	case *transaction.TxAcceptDCBProposal:
+		return tx.Tx
+	case *transaction.TxAcceptGOVProposal:
+		return tx.Tx
+	}
+	return nil
+}
+
+// customTokenTx returns the custom token tx inside tx, nil when tx moves no
+// custom token
+func customTokenTx(tx transaction.Transaction) *transaction.TxCustomToken {
+	switch tx := tx.(type) {
+	case *transaction.TxCustomToken:
+		return tx
+	case *transaction.TxVoteDCBBoard:
+		return &tx.TxCustomToken
+	case *transaction.TxVoteGOVBoard:
+		return &tx.TxCustomToken
+	case *transaction.TxCrowdsale:
+		return tx.TxCustomToken
+	}
+	return nil
+}
+
+/*
+// validateSanityTxTypeData validates data which a tx has by its type, on top
+// of data of its normal and custom token txs
+*/
+func (tp *TxPool) validateSanityTxTypeData(tx transaction.Transaction) error {
+	switch tx := tx.(type) {
+	case *transaction.TxVoteDCBBoard:
+		if len(tx.VoteDCBBoardData.CandidatePubKey) != 33 {
+			return errors.New("Wrong tx candidate pubkey")
+		}
+	case *transaction.TxVoteGOVBoard:
+		if len(tx.VoteGOVBoardData.CandidatePubKey) != 33 {
+			return errors.New("Wrong tx candidate pubkey")
+		}
+	case *transaction.TxLoanRequest:
+		if len(tx.LoanID) == 0 {
+			return errors.New("Wrong tx loan id")
+		}
+		if len(tx.KeyDigest) != transaction.LoanKeyDigestLen {
+			return errors.New("Wrong tx key digest")
+		}
+		if tx.ReceiveAddress == nil || tx.LoanAmount == 0 {
+			return errors.New("Wrong tx loan amount")
+		}
+	case *transaction.TxLoanResponse:
+		if len(tx.LoanID) == 0 {
+			return errors.New("Wrong tx loan id")
+		}
+		if tx.Response != transaction.Accept && tx.Response != transaction.Reject {
+			return errors.New("Wrong tx loan response")
+		}
+	case *transaction.TxLoanPayment:
+		if len(tx.LoanID) == 0 {
+			return errors.New("Wrong tx loan id")
+		}
+	case *transaction.TxLoanWithdraw:
+		if len(tx.LoanID) == 0 {
+			return errors.New("Wrong tx loan id")
+		}
+		if len(tx.Key) == 0 {
+			return errors.New("Wrong tx loan key")
+		}
+	case *transaction.TxBuySellRequest:
+		if len(tx.PaymentAddress.Pk) == 0 {
+			return errors.New("Wrong tx payment address")
+		}
+		if tx.Amount == 0 || tx.BuyPrice == 0 {
+			return errors.New("Wrong tx buy amount")
+		}
+	case *transaction.TxBuyBackRequest:
+		if tx.BuyBackFromTxID == nil || tx.VoutIndex < 0 {
+			return errors.New("Wrong tx buy-back vout")
+		}
+	case *transaction.TxCrowdsale:
+		if len(tx.SaleID) == 0 {
+			return errors.New("Wrong tx sale id")
+		}
+		if tx.BaseAsset == common.EmptyString || tx.QuoteAsset == common.EmptyString || tx.Price == 0 {
+			return errors.New("Wrong tx sale data")
+		}
+	case *transaction.TxSubmitDCBProposal:
+		if tx.DCBProposalData.ExecuteDuration <= 0 {
+			return errors.New("Wrong tx proposal execute duration")
+		}
+	case *transaction.TxSubmitGOVProposal:
+		if tx.GOVProposalData.ExecuteDuration <= 0 {
+			return errors.New("Wrong tx proposal execute duration")
+		}
+	case *transaction.TxVoteDCBProposal:
+		if tx.VoteDCBProposalData.DCBProposalTXID == nil || tx.VoteDCBProposalData.AmountVoteToken == 0 {
+			return errors.New("Wrong tx proposal vote")
+		}
+	case *transaction.TxVoteGOVProposal:
+		if tx.VoteGOVProposalData.GOVProposalTXID == nil || tx.VoteGOVProposalData.AmountVoteToken == 0 {
+			return errors.New("Wrong tx proposal vote")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/privacy-protocol"
+	"github.com/ninjadotorg/constant/transaction"
+	"github.com/ninjadotorg/constant/voting"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestNormalTx(txType string) transaction.Tx {
+	return transaction.Tx{
+		Version:  transaction.TxVersion,
+		Type:     txType,
+		JSPubKey: make([]byte, 64),
+		JSSig:    make([]byte, 64),
+	}
+}
+
+// newTestTxs returns a tx with sane data of every type users send
+func newTestTxs() map[string]transaction.Transaction {
+	normalTx := newTestNormalTx(common.TxNormalType)
+	txs := map[string]transaction.Transaction{
+		common.TxNormalType:      &normalTx,
+		common.TxCustomTokenType: &transaction.TxCustomToken{Tx: newTestNormalTx(common.TxCustomTokenType)},
+		common.TxVoteDCBBoard: &transaction.TxVoteDCBBoard{
+			TxCustomToken:    transaction.TxCustomToken{Tx: newTestNormalTx(common.TxVoteDCBBoard)},
+			VoteDCBBoardData: transaction.VoteDCBBoardData{CandidatePubKey: string(make([]byte, 33))},
+		},
+		common.TxVoteGOVBoard: &transaction.TxVoteGOVBoard{
+			TxCustomToken:    transaction.TxCustomToken{Tx: newTestNormalTx(common.TxVoteGOVBoard)},
+			VoteGOVBoardData: transaction.VoteGOVBoardData{CandidatePubKey: string(make([]byte, 33))},
+		},
+		common.TxLoanRequest: &transaction.TxLoanRequest{
+			Tx: newTestNormalTx(common.TxNormalType),
+			LoanRequest: &transaction.LoanRequest{
+				LoanID:         []byte{1},
+				LoanAmount:     1,
+				ReceiveAddress: &privacy.PaymentAddress{},
+				KeyDigest:      make([]byte, transaction.LoanKeyDigestLen),
+			},
+		},
+		common.TxLoanResponse: &transaction.TxLoanResponse{
+			Tx:           newTestNormalTx(common.TxNormalType),
+			LoanResponse: &transaction.LoanResponse{LoanID: []byte{1}, Response: transaction.Accept},
+		},
+		common.TxLoanPayment: &transaction.TxLoanPayment{
+			Tx:          newTestNormalTx(common.TxNormalType),
+			LoanPayment: &transaction.LoanPayment{LoanID: []byte{1}},
+		},
+		common.TxLoanWithdraw: &transaction.TxLoanWithdraw{
+			Tx:           newTestNormalTx(common.TxNormalType),
+			LoanWithdraw: &transaction.LoanWithdraw{LoanID: []byte{1}, Key: []byte{1}},
+		},
+		common.TxCrowdsale: &transaction.TxCrowdsale{
+			TxCustomToken: &transaction.TxCustomToken{Tx: newTestNormalTx(common.TxCustomTokenType)},
+			SaleData:      &transaction.SaleData{SaleID: []byte{1}, BaseAsset: "base", QuoteAsset: "quote", Price: 1},
+		},
+		common.TxSubmitDCBProposal: &transaction.TxSubmitDCBProposal{
+			Tx:              newTestNormalTx(common.TxSubmitDCBProposal),
+			DCBProposalData: voting.DCBProposalData{ExecuteDuration: 1},
+		},
+		common.TxSubmitGOVProposal: &transaction.TxSubmitGOVProposal{
+			Tx:              newTestNormalTx(common.TxSubmitGOVProposal),
+			GOVProposalData: voting.GOVProposalData{ExecuteDuration: 1},
+		},
+		common.TxVoteDCBProposal: &transaction.TxVoteDCBProposal{
+			Tx:                  newTestNormalTx(common.TxVoteDCBProposal),
+			VoteDCBProposalData: transaction.VoteDCBProposalData{DCBProposalTXID: &common.Hash{}, AmountVoteToken: 1},
+		},
+		common.TxVoteGOVProposal: &transaction.TxVoteGOVProposal{
+			Tx:                  newTestNormalTx(common.TxVoteGOVProposal),
+			VoteGOVProposalData: transaction.VoteGOVProposalData{GOVProposalTXID: &common.Hash{}, AmountVoteToken: 1},
+		},
+	}
+	for _, txType := range []string{common.TxBuyFromGOVRequest, common.TxBuySellDCBRequest} {
+		tx := newTestNormalTx(txType)
+		txs[txType] = &transaction.TxBuySellRequest{
+			Tx:          &tx,
+			RequestInfo: &transaction.RequestInfo{PaymentAddress: privacy.PaymentAddress{Pk: []byte{1}}, Amount: 1, BuyPrice: 1},
+		}
+	}
+	buyBackTx := newTestNormalTx(common.TxBuyBackRequest)
+	txs[common.TxBuyBackRequest] = &transaction.TxBuyBackRequest{
+		Tx:                 &buyBackTx,
+		BuyBackRequestInfo: &transaction.BuyBackRequestInfo{BuyBackFromTxID: &common.Hash{}},
+	}
+	return txs
+}
+
+func TestValidateSanityDataEveryType(t *testing.T) {
+	mempool := &TxPool{}
+	mempool.Init(&Config{})
+	for txType, tx := range newTestTxs() {
+		assert.Equal(t, txType, tx.GetType())
+		ok, err := mempool.ValidateSanityData(tx)
+		assert.True(t, ok, "tx of type %s is sane", txType)
+		assert.Nil(t, err, "tx of type %s is sane", txType)
+
+		// normal tx inside can only be of the type of tx or of the tx it is
+		// created from
+		baseTx(tx).Type = "x"
+		ok, err = mempool.ValidateSanityData(tx)
+		assert.False(t, ok, "tx of type %s has a tx of unknown type inside", txType)
+		assert.NotNil(t, err, "tx of type %s has a tx of unknown type inside", txType)
+	}
+}
+
+func TestValidateSanityDataOfType(t *testing.T) {
+	mempool := &TxPool{}
+	mempool.Init(&Config{})
+	txs := newTestTxs()
+	txs[common.TxVoteDCBBoard].(*transaction.TxVoteDCBBoard).VoteDCBBoardData.CandidatePubKey = ""
+	txs[common.TxLoanRequest].(*transaction.TxLoanRequest).KeyDigest = nil
+	txs[common.TxLoanResponse].(*transaction.TxLoanResponse).Response = transaction.Reject + 1
+	txs[common.TxLoanWithdraw].(*transaction.TxLoanWithdraw).Key = nil
+	txs[common.TxBuyFromGOVRequest].(*transaction.TxBuySellRequest).Amount = 0
+	txs[common.TxBuyBackRequest].(*transaction.TxBuyBackRequest).BuyBackFromTxID = nil
+	txs[common.TxCrowdsale].(*transaction.TxCrowdsale).Price = 0
+	txs[common.TxSubmitGOVProposal].(*transaction.TxSubmitGOVProposal).GOVProposalData.ExecuteDuration = 0
+	txs[common.TxVoteDCBProposal].(*transaction.TxVoteDCBProposal).VoteDCBProposalData.AmountVoteToken = 0
+	for _, txType := range []string{common.TxVoteDCBBoard, common.TxLoanRequest, common.TxLoanResponse, common.TxLoanWithdraw, common.TxBuyFromGOVRequest, common.TxBuyBackRequest, common.TxCrowdsale, common.TxSubmitGOVProposal, common.TxVoteDCBProposal} {
+		ok, err := mempool.ValidateSanityData(txs[txType])
+		assert.False(t, ok, "tx of type %s has wrong data", txType)
+		assert.NotNil(t, err, "tx of type %s has wrong data", txType)
+	}
+}
+
+func TestCheckTxStruct(t *testing.T) {
+	for txType, tx := range newTestTxs() {
+		assert.Nil(t, checkTxStruct(tx), "tx of type %s has its struct", txType)
+	}
+	// a normal tx claiming to be a vote would be cast to a vote
+	assert.NotNil(t, checkTxStruct(&transaction.Tx{Type: common.TxVoteDCBBoard}))
+	// a loan request without its data
+	assert.NotNil(t, checkTxStruct(&transaction.TxLoanRequest{}))
+	assert.NotNil(t, checkTxStruct(&transaction.TxBuyBackRequest{}))
+}
+
+func TestCheckTxTypeRejectsProducerTxs(t *testing.T) {
+	mempool := &TxPool{}
+	mempool.Init(&Config{})
+	for txType := range producerTxTypes {
+		tx, err := transaction.NewTxByType(txType)
+		assert.Nil(t, err)
+		baseTx(tx).Type = txType
+
+		err = mempool.checkTxType(tx)
+		assert.NotNil(t, err, "tx of type %s is rejected", txType)
+		expected := ErrCodeMessage[RejectInvalidTx].code
+		if txType == common.TxSalaryType {
+			expected = ErrCodeMessage[RejectSalaryTx].code
+		}
+		assert.Equal(t, expected, err.(MempoolTxError).code, "tx of type %s", txType)
+	}
+	for txType, tx := range newTestTxs() {
+		assert.Nil(t, mempool.checkTxType(tx), "tx of type %s is sent by users", txType)
+	}
+}
+
+func TestCheckTxVersionEveryType(t *testing.T) {
+	policy := Policy{MaxTxVersion: transaction.TxVersion}
+	for txType, tx := range newTestTxs() {
+		assert.True(t, policy.CheckTxVersion(&tx), "tx of type %s has supported version", txType)
+		baseTx(tx).Version = transaction.TxVersion + 1
+		assert.False(t, policy.CheckTxVersion(&tx), "tx of type %s has unsupported version", txType)
+	}
+}
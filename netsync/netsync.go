@@ -17,8 +17,9 @@ type NetSync struct {
 	shutdown  int32
 	waitgroup sync.WaitGroup
 
-	cMessage chan interface{}
-	cQuit    chan struct{}
+	cMessage          chan interface{}
+	cConsensusMessage chan interface{}
+	cQuit             chan struct{}
 
 	config *NetSyncConfig
 }
@@ -35,8 +36,9 @@ type NetSyncConfig struct {
 	Consensus interface {
 		OnBlockReceived(*blockchain.Block)
 		OnRequestSign(*wire.MessageBlockSigReq)
-		OnBlockSigReceived(string, string)
+		OnBlockSigReceived(*wire.MessageBlockSig)
 		OnInvalidBlockReceived(string, byte, string)
+		OnEvidenceReceived(*blockchain.DoubleSignEvidence)
 		OnGetChainState(*wire.MessageGetChainState)
 		OnChainStateReceived(*wire.MessageChainState)
 		OnSwapRequest(swap *wire.MessageSwapRequest)
@@ -50,6 +52,7 @@ func (self NetSync) New(cfg *NetSyncConfig) *NetSync {
 	self.config = cfg
 	self.cQuit = make(chan struct{})
 	self.cMessage = make(chan interface{})
+	self.cConsensusMessage = make(chan interface{})
 	return &self
 }
 
@@ -59,8 +62,9 @@ func (self *NetSync) Start() {
 		return
 	}
 	Logger.log.Info("Starting sync manager")
-	self.waitgroup.Add(1)
+	self.waitgroup.Add(2)
 	go self.messageHandler()
+	go self.consensusMessageHandler()
 }
 
 // Stop gracefully shuts down the sync manager by stopping all asynchronous
@@ -103,17 +107,13 @@ out:
 					{
 						self.HandleMessageGetBlocks(msg)
 					}
-				case *wire.MessageBlockSig:
-					{
-						self.HandleMessageBlockSig(msg)
-					}
 				case *wire.MessageInvalidBlock:
 					{
 						self.HandleMessageInvalidBlock(msg)
 					}
-				case *wire.MessageBlockSigReq:
+				case *wire.MessageEvidence:
 					{
-						self.HandleMessageRequestSign(msg)
+						self.HandleMessageEvidence(msg)
 					}
 				case *wire.MessageGetChainState:
 					{
@@ -123,6 +123,40 @@ out:
 					{
 						self.HandleMessageChainState(msg)
 					}
+				default:
+					Logger.log.Infof("Invalid message type in block "+"handler: %T", msg)
+				}
+			}
+		case msgChan := <-self.cQuit:
+			{
+				Logger.log.Warn(msgChan)
+				break out
+			}
+		}
+	}
+
+	self.waitgroup.Done()
+	Logger.log.Info("Block handler done")
+}
+
+// consensusMessageHandler handles block signing and swap messages apart from
+// messageHandler so that they never wait behind tx validation or block sync.
+// It must be run as a goroutine.
+func (self *NetSync) consensusMessageHandler() {
+out:
+	for {
+		select {
+		case msgChan := <-self.cConsensusMessage:
+			{
+				switch msg := msgChan.(type) {
+				case *wire.MessageBlockSigReq:
+					{
+						self.HandleMessageRequestSign(msg)
+					}
+				case *wire.MessageBlockSig:
+					{
+						self.HandleMessageBlockSig(msg)
+					}
 				case *wire.MessageSwapRequest:
 					{
 						self.HandleMessageSwapRequest(msg)
@@ -136,19 +170,16 @@ out:
 						self.HandleMessageSwapUpdate(msg)
 					}
 				default:
-					Logger.log.Infof("Invalid message type in block "+"handler: %T", msg)
+					Logger.log.Infof("Invalid message type in consensus "+"handler: %T", msg)
 				}
 			}
-		case msgChan := <-self.cQuit:
-			{
-				Logger.log.Warn(msgChan)
-				break out
-			}
+		case <-self.cQuit:
+			break out
 		}
 	}
 
 	self.waitgroup.Done()
-	Logger.log.Info("Block handler done")
+	Logger.log.Info("Consensus handler done")
 }
 
 // QueueTx adds the passed transaction message and peer to the block handling
@@ -164,27 +195,25 @@ out:
 }*/
 
 func (self *NetSync) QueueTx(peer *peer.Peer, msg *wire.MessageTx, done chan struct{}) {
-	// Don't accept more transactions if we're shutting down.
-	if atomic.LoadInt32(&self.shutdown) != 0 {
-		done <- struct{}{}
-		return
-	}
-	self.cMessage <- msg
+	self.queue(self.cMessage, msg, done)
 }
 
-// handleTxMsg handles transaction messages from all peers.
+// handleTxMsg handles transaction messages from all peers. The tx and
+// orphans accepted after it are relayed to the network
 func (self *NetSync) HandleMessageTx(msg *wire.MessageTx) {
 	Logger.log.Info("Handling new message tx")
-	hash, txDesc, err := self.config.MemTxPool.MaybeAcceptTransaction(msg.Transaction)
+	txDescs, err := self.config.MemTxPool.ProcessTransaction(msg.Transaction)
 
 	if err != nil {
 		Logger.log.Error(err)
-	} else {
-		Logger.log.Infof("there is hash of transaction %s", hash.String())
+		return
+	}
+	for _, txDesc := range txDescs {
+		Logger.log.Infof("there is hash of transaction %s", txDesc.Desc.Tx.Hash().String())
 		Logger.log.Infof("there is priority of transaction in pool: %d", txDesc.StartingPriority)
 
 		// Broadcast to network
-		err := self.config.Server.PushMessageToAll(msg)
+		err := self.config.Server.PushMessageToAll(&wire.MessageTx{Transaction: txDesc.Desc.Tx})
 		if err != nil {
 			Logger.log.Error(err)
 		}
@@ -214,30 +243,50 @@ func (self *NetSync) HandleMessageTx(msg *wire.MessageTx) {
 // queue. Responds to the done channel argument after the block message is
 // processed.
 func (self *NetSync) QueueBlock(_ *peer.Peer, msg *wire.MessageBlock, done chan struct{}) {
-	// Don't accept more transactions if we're shutting down.
-	if atomic.LoadInt32(&self.shutdown) != 0 {
-		done <- struct{}{}
+	self.queue(self.cMessage, msg, done)
+}
+
+func (self *NetSync) QueueGetBlock(peer *peer.Peer, msg *wire.MessageGetBlocks, done chan struct{}) {
+	self.queue(self.cMessage, msg, done)
+}
+
+// QueueMessage adds the passed message to the queue of its handler, block
+// signing and swap messages are queued for consensusMessageHandler
+func (self *NetSync) QueueMessage(peer *peer.Peer, msg wire.Message, done chan struct{}) {
+	if isConsensusMessage(msg) {
+		self.queue(self.cConsensusMessage, msg, done)
 		return
 	}
-	self.cMessage <- msg
+	self.queue(self.cMessage, msg, done)
 }
 
-func (self *NetSync) QueueGetBlock(peer *peer.Peer, msg *wire.MessageGetBlocks, done chan struct{}) {
-	// Don't accept more transactions if we're shutting down.
+// queue hands msg to a handler, a message which comes while the sync manager
+// shuts down is dropped and done is signalled when somebody waits on it
+func (self *NetSync) queue(handler chan interface{}, msg interface{}, done chan struct{}) {
+	// Don't accept more messages if we're shutting down.
 	if atomic.LoadInt32(&self.shutdown) != 0 {
-		done <- struct{}{}
+		signalDone(done)
 		return
 	}
-	self.cMessage <- msg
+	select {
+	case handler <- msg:
+	case <-self.cQuit:
+		signalDone(done)
+	}
 }
 
-func (self *NetSync) QueueMessage(peer *peer.Peer, msg wire.Message, done chan struct{}) {
-	// Don't accept more transactions if we're shutting down.
-	if atomic.LoadInt32(&self.shutdown) != 0 {
+func signalDone(done chan struct{}) {
+	if done != nil {
 		done <- struct{}{}
-		return
 	}
-	self.cMessage <- msg
+}
+
+func isConsensusMessage(msg wire.Message) bool {
+	switch msg.(type) {
+	case *wire.MessageBlockSigReq, *wire.MessageBlockSig, *wire.MessageSwapRequest, *wire.MessageSwapSig, *wire.MessageSwapUpdate:
+		return true
+	}
+	return false
 }
 
 func (self *NetSync) HandleMessageGetBlocks(msg *wire.MessageGetBlocks) {
@@ -298,13 +347,18 @@ func (self *NetSync) HandleMessageBlock(msg *wire.MessageBlock) {
 
 func (self *NetSync) HandleMessageBlockSig(msg *wire.MessageBlockSig) {
 	Logger.log.Info("Handling new message BlockSig")
-	self.config.Consensus.OnBlockSigReceived(msg.Validator, msg.BlockSig)
+	self.config.Consensus.OnBlockSigReceived(msg)
 }
 func (self *NetSync) HandleMessageInvalidBlock(msg *wire.MessageInvalidBlock) {
 	Logger.log.Info("Handling new message invalidblock")
 	self.config.Consensus.OnInvalidBlockReceived(msg.BlockHash, msg.ChainID, msg.Reason)
 }
 
+func (self *NetSync) HandleMessageEvidence(msg *wire.MessageEvidence) {
+	Logger.log.Info("Handling new message evidence")
+	self.config.Consensus.OnEvidenceReceived(&msg.Evidence)
+}
+
 func (self *NetSync) HandleMessageRequestSign(msg *wire.MessageBlockSigReq) {
 	Logger.log.Info("Handling new message requestsign")
 	self.config.Consensus.OnRequestSign(msg)
@@ -0,0 +1,55 @@
+package peer
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+/*
+BanScore - misbehavior score of a peer connection. It is the sum of a persistent
+part which never decays and a transient part which halves every BanScoreHalfLife,
+so occasional mistakes are forgiven while sustained spam adds up
+*/
+type BanScore struct {
+	mtx        sync.Mutex
+	persistent uint32
+	transient  float64
+	lastUpdate time.Time
+}
+
+// decayedTransient returns transient score at time t
+func (self *BanScore) decayedTransient(t time.Time) float64 {
+	dt := t.Sub(self.lastUpdate).Seconds()
+	if self.transient == 0 || dt <= 0 {
+		return self.transient
+	}
+	return self.transient * math.Pow(0.5, dt/BanScoreHalfLife.Seconds())
+}
+
+// Increase adds points to both parts of score and returns the resulting score
+func (self *BanScore) Increase(persistent uint32, transient uint32) uint32 {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	now := time.Now()
+	self.transient = self.decayedTransient(now) + float64(transient)
+	self.lastUpdate = now
+	self.persistent += persistent
+	return self.persistent + uint32(self.transient)
+}
+
+// Int returns current score
+func (self *BanScore) Int() uint32 {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return self.persistent + uint32(self.decayedTransient(time.Now()))
+}
+
+// Reset sets score back to zero
+func (self *BanScore) Reset() {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.persistent = 0
+	self.transient = 0
+	self.lastUpdate = time.Time{}
+}
@@ -10,6 +10,40 @@ const (
 	ProtocolId        = "/blockchain/1.0.0"
 	DelimMessageByte  = '\n'
 	DelimMessageStr   = "\n"
+
+	// BanThreshold is the ban score at which a misbehaving peer is disconnected and banned
+	BanThreshold = 100
+	// BanScoreHalfLife is the time transient ban score takes to decay by half
+	BanScoreHalfLife = time.Minute
+
+	// capacity of inbound and outbound queues of each lane
+	MaxConsensusQueue = 256
+	MaxTxQueue        = 1000
+	MaxMessageQueue   = 256
+
+	// inbound rate limits of a peer connection, byte burst must fit the largest message
+	MaxInBytesPerSecond = 2 * 1024 * 1024 // 2 Mb
+	MaxInBytesBurst     = 8 * 1024 * 1024 // 8 Mb
+	DefaultMessageRate  = 50
+	DefaultMessageBurst = 100
+
+	// keepalive, a peer which does not answer ping within PingTimeout is disconnected
+	PingInterval = 30 * time.Second
+	PingTimeout  = 2 * PingInterval
+
+	// ChallengeSize is the number of random bytes of handshake challenge
+	ChallengeSize = 32
+)
+
+// Ban score points of each offense, undecodable, invalidblock spam, rate limited and unauthenticated
+// consensus messages are transient and decay, oversized payload and bad signature are persistent
+const (
+	BanScoreUndecodableMessage       = 20
+	BanScoreOversizedMessage         = 50
+	BanScoreInvalidBlockMsg          = 5
+	BanScoreBadSignature             = 50
+	BanScoreRateLimited              = 1
+	BanScoreUnauthenticatedConsensus = 10
 )
 
 // ConnState can be either pending, established, disconnected or failed.  When
@@ -19,6 +19,7 @@ import (
 	"github.com/libp2p/go-libp2p-peer"
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/signer"
 	"github.com/ninjadotorg/constant/wire"
 )
 
@@ -44,6 +45,9 @@ type Peer struct {
 	RawAddress       string
 	ListeningAddress common.SimpleAddr
 	PublicKey        string
+	// Priority peers such as committee members are connected even when
+	// MaxOutbound is reached
+	Priority bool
 
 	Seed   int64
 	Config Config
@@ -54,6 +58,9 @@ type Peer struct {
 	HandleConnected    func(peerConn *PeerConn)
 	HandleDisconnected func(peerConn *PeerConn)
 	HandleFailed       func(peerConn *PeerConn)
+	HandleBanned       func(peerConn *PeerConn, reason string)
+	// CheckBanned reports whether connections with a peer id are refused
+	CheckBanned func(peerID peer.ID) bool
 }
 
 type NewPeerMsg struct {
@@ -69,9 +76,10 @@ type NewStreamMsg struct {
 // config is the struct to hold configuration options useful to RemotePeer.
 type Config struct {
 	MessageListeners MessageListeners
-	ProducerPrvKey   string
+	ProducerSigner   signer.Signer // nil when node runs without producer key
 	MaxOutbound      int
 	MaxInbound       int
+	Net              uint32 // network magic of framed wire messages
 }
 
 type WrappedStream struct {
@@ -103,6 +111,7 @@ type MessageListeners struct {
 	//PoS
 	OnRequestSign   func(p *PeerConn, msg *wire.MessageBlockSigReq)
 	OnInvalidBlock  func(p *PeerConn, msg *wire.MessageInvalidBlock)
+	OnEvidence      func(p *PeerConn, msg *wire.MessageEvidence)
 	OnBlockSig      func(p *PeerConn, msg *wire.MessageBlockSig)
 	OnGetChainState func(p *PeerConn, msg *wire.MessageGetChainState)
 	OnChainState    func(p *PeerConn, msg *wire.MessageChainState)
@@ -364,7 +373,16 @@ func (self *Peer) handleConn(peer *Peer, cConn chan *PeerConn) (*PeerConn, error
 		return nil, nil
 	}
 
-	if self.NumOutbound() >= self.Config.MaxOutbound && self.Config.MaxOutbound > 0 && !ok {
+	if self.isBanned(peer.PeerID) {
+		Logger.log.Infof("Checked Banned PEER Id - %s", peer.RawAddress)
+
+		if cConn != nil {
+			cConn <- nil
+		}
+		return nil, nil
+	}
+
+	if self.NumOutbound() >= self.Config.MaxOutbound && self.Config.MaxOutbound > 0 && !ok && !peer.Priority {
 		Logger.log.Infof("Checked Max Outbound Connection PEER Id - %s", peer.RawAddress)
 
 		//push to pending peers
@@ -392,21 +410,28 @@ func (self *Peer) handleConn(peer *Peer, cConn chan *PeerConn) (*PeerConn, error
 	rw := bufio.NewReadWriter(bufio.NewReader(stream), bufio.NewWriter(stream))
 
 	peerConn := PeerConn{
-		IsOutbound:         true,
-		RemotePeer:         peer,
-		RemotePeerID:       remotePeerID,
-		RemoteRawAddress:   peer.RawAddress,
-		ListenerPeer:       self,
-		Config:             self.Config,
-		ReaderWriterStream: rw,
-		cDisconnect:        make(chan struct{}),
-		cClose:             make(chan struct{}),
-		cRead:              make(chan struct{}),
-		cWrite:             make(chan struct{}),
-		sendMessageQueue:   make(chan outMsg),
-		HandleConnected:    self.handleConnected,
-		HandleDisconnected: self.handleDisconnected,
-		HandleFailed:       self.handleFailed,
+		IsOutbound:          true,
+		RemotePeer:          peer,
+		RemotePeerID:        remotePeerID,
+		RemoteRawAddress:    peer.RawAddress,
+		ListenerPeer:        self,
+		Config:              self.Config,
+		ReaderWriterStream:  rw,
+		cDisconnect:         make(chan struct{}),
+		cClose:              make(chan struct{}),
+		cRead:               make(chan struct{}),
+		cWrite:              make(chan struct{}),
+		sendMessageQueue:    make(chan outMsg, MaxMessageQueue),
+		sendConsensusQueue:  make(chan outMsg, MaxConsensusQueue),
+		sendTxQueue:         make(chan outMsg, MaxTxQueue),
+		cInMessage:          make(chan wire.Message, MaxMessageQueue),
+		cInConsensusMessage: make(chan wire.Message, MaxConsensusQueue),
+		cInTxMessage:        make(chan wire.Message, MaxTxQueue),
+		rateLimiter:         NewRateLimiter(),
+		HandleConnected:     self.handleConnected,
+		HandleDisconnected:  self.handleDisconnected,
+		HandleFailed:        self.handleFailed,
+		HandleBanned:        self.handleBanned,
 	}
 
 	self.SetPeerConn(&peerConn)
@@ -459,6 +484,14 @@ func (self *Peer) handleStream(stream net.Stream, cDone chan *PeerConn) {
 
 	remotePeerID := stream.Conn().RemotePeer()
 	Logger.log.Infof("PEER %s Received a new stream from OTHER PEER with Id %s", self.Host.ID().String(), remotePeerID.String())
+	if self.isBanned(remotePeerID) {
+		Logger.log.Infof("Received a new stream from banned PEER Id - %s", remotePeerID)
+
+		if cDone != nil {
+			close(cDone)
+		}
+		return
+	}
 	_, ok := self.PeerConns[remotePeerID.String()]
 	if ok {
 		Logger.log.Infof("Received a new stream existed PEER Id - %s", remotePeerID)
@@ -478,18 +511,28 @@ func (self *Peer) handleStream(stream net.Stream, cDone chan *PeerConn) {
 		RemotePeer: &Peer{
 			PeerID: remotePeerID,
 		},
-		Config:             self.Config,
-		RemotePeerID:       remotePeerID,
-		ReaderWriterStream: rw,
-		cDisconnect:        make(chan struct{}),
-		cClose:             make(chan struct{}),
-		cRead:              make(chan struct{}),
-		cWrite:             make(chan struct{}),
-		sendMessageQueue:   make(chan outMsg),
-		HandleConnected:    self.handleConnected,
-		HandleDisconnected: self.handleDisconnected,
-		HandleFailed:       self.handleFailed,
-	}
+		Config:              self.Config,
+		RemotePeerID:        remotePeerID,
+		ReaderWriterStream:  rw,
+		cDisconnect:         make(chan struct{}),
+		cClose:              make(chan struct{}),
+		cRead:               make(chan struct{}),
+		cWrite:              make(chan struct{}),
+		sendMessageQueue:    make(chan outMsg, MaxMessageQueue),
+		sendConsensusQueue:  make(chan outMsg, MaxConsensusQueue),
+		sendTxQueue:         make(chan outMsg, MaxTxQueue),
+		cInMessage:          make(chan wire.Message, MaxMessageQueue),
+		cInConsensusMessage: make(chan wire.Message, MaxConsensusQueue),
+		cInTxMessage:        make(chan wire.Message, MaxTxQueue),
+		rateLimiter:         NewRateLimiter(),
+		HandleConnected:     self.handleConnected,
+		HandleDisconnected:  self.handleDisconnected,
+		HandleFailed:        self.handleFailed,
+		HandleBanned:        self.handleBanned,
+	}
+
+	// announcements of inbound peers are bucketed by their observed address
+	peerConn.RemoteObservedAddress = stream.Conn().RemoteMultiaddr().String()
 
 	self.SetPeerConn(&peerConn)
 
@@ -594,12 +637,36 @@ func (self *Peer) handleFailed(peerConn *PeerConn) {
 	}
 }
 
+/*
+handleBanned - handle peer conn whose ban score reached threshold, disconnect it
+*/
+func (self *Peer) handleBanned(peerConn *PeerConn, reason string) {
+	Logger.log.Infof("handleBanned %s %s", peerConn.RemotePeerID.String(), reason)
+
+	if self.HandleBanned != nil {
+		self.HandleBanned(peerConn, reason)
+	}
+
+	if peerConn.IsConnected {
+		peerConn.Close()
+	}
+}
+
+func (self *Peer) isBanned(peerID peer.ID) bool {
+	return self.CheckBanned != nil && self.CheckBanned(peerID)
+}
+
 /*
 retryPeerConnection - retry to connect to peer when being disconnected
 */
 func (self *Peer) retryPeerConnection(peerConn *PeerConn) {
 	time.AfterFunc(RetryConnDuration, func() {
 		Logger.log.Infof("Retry New RemotePeer Connection %s", peerConn.RemoteRawAddress)
+		if self.isBanned(peerConn.RemotePeer.PeerID) {
+			Logger.log.Infof("Stop retrying banned PEER Id - %s", peerConn.RemotePeer.PeerID)
+			peerConn.updateConnState(ConnCanceled)
+			return
+		}
 		peerConn.RetryCount += 1
 
 		if peerConn.RetryCount < MaxRetryConn {
@@ -3,27 +3,69 @@ package peer
 import (
 	"bufio"
 	"bytes"
+	"crypto/rand"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"io"
 	"reflect"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/libp2p/go-libp2p-peer"
 	"github.com/ninjadotorg/constant/wire"
 )
 
+// lanes of messages, consensus messages are queued and processed apart
+// from others so that they never wait behind tx floods
+const (
+	laneMessage = iota
+	laneConsensus
+	laneTx
+)
+
+func messageLane(cmd string) int {
+	switch cmd {
+	case wire.CmdBlockSigReq, wire.CmdBlockSig, wire.CmdSwapRequest, wire.CmdSwapSig, wire.CmdSwapUpdate:
+		return laneConsensus
+	default:
+		if wire.IsTxCommand(cmd) {
+			return laneTx
+		}
+		return laneMessage
+	}
+}
+
 type PeerConn struct {
 	connected      int32
+	banned         int32
+	banScore       BanScore
 	connState      ConnState
 	stateMtx       sync.RWMutex
 	verAckReceived bool
 
+	// handshake, challenge is sent in our version message and remote peer
+	// proves its producer key by signing it in verack
+	authMtx       sync.Mutex
+	challenge     string
+	authPublicKey string
+
 	// channel
-	sendMessageQueue chan outMsg
-	cDisconnect      chan struct{}
-	cRead            chan struct{}
-	cWrite           chan struct{}
-	cClose           chan struct{}
+	sendMessageQueue    chan outMsg
+	sendConsensusQueue  chan outMsg
+	sendTxQueue         chan outMsg
+	cInMessage          chan wire.Message
+	cInConsensusMessage chan wire.Message
+	cInTxMessage        chan wire.Message
+	cDisconnect         chan struct{}
+	cRead               chan struct{}
+	cWrite              chan struct{}
+	cClose              chan struct{}
+	closeOnce           sync.Once
+
+	rateLimiter *RateLimiter
 
 	RetryCount int32
 
@@ -31,9 +73,18 @@ type PeerConn struct {
 	RemotePeer       *Peer
 	RemotePeerID     peer.ID
 	RemoteRawAddress string
-	IsOutbound       bool
+	// observed address of the connection, inbound peers have no
+	// RemoteRawAddress so their announcements are bucketed by it
+	RemoteObservedAddress string
+	IsOutbound            bool
 
 	ReaderWriterStream *bufio.ReadWriter
+	wireVersion        int // negotiated wire protocol version
+	wireVersionMtx     sync.RWMutex
+	pingMtx            sync.Mutex
+	lastPingNonce      uint64        // nonce of ping waiting for pong, 0 if none
+	lastPingTime       time.Time     // time of sending last ping
+	pingTime           time.Duration // round trip time of last answered ping
 	VerValid           bool
 	IsConnected        bool
 
@@ -44,6 +95,7 @@ type PeerConn struct {
 	HandleConnected    func(peerConn *PeerConn)
 	HandleDisconnected func(peerConn *PeerConn)
 	HandleFailed       func(peerConn *PeerConn)
+	HandleBanned       func(peerConn *PeerConn, reason string)
 }
 
 /*
@@ -51,9 +103,32 @@ Handle all in message
 */
 func (self *PeerConn) InMessageHandler(rw *bufio.ReadWriter) {
 	self.IsConnected = true
+	go self.inMessageWorker(self.cInConsensusMessage)
+	go self.inMessageWorker(self.cInTxMessage)
+	go self.inMessageWorker(self.cInMessage)
+	go self.pingHandler()
+
+	magic := wire.MagicBytes(self.Config.Net)
 	for {
 		Logger.log.Infof("PEER %s (address: %s) Reading stream", self.RemotePeer.PeerID.String(), self.RemotePeer.RawAddress)
-		str, err := rw.ReadString(DelimMessageByte)
+		// framed messages always start with network magic, legacy ones are hex string
+		prefix, err := rw.Reader.Peek(wire.MessageMagicSize)
+		if err == nil && bytes.Equal(prefix, magic) {
+			var message wire.Message
+			var size int
+			message, size, err = self.readFramedMessage(rw.Reader)
+			if err == nil {
+				if message != nil {
+					self.queueInMessage(message, size)
+				}
+				continue
+			}
+		}
+
+		var str string
+		if err == nil {
+			str, err = self.readLegacyLine(rw.Reader)
+		}
 		if err != nil {
 			self.IsConnected = false
 			Logger.log.Error("---------------------------------------------------------------------")
@@ -66,108 +141,253 @@ func (self *PeerConn) InMessageHandler(rw *bufio.ReadWriter) {
 		}
 
 		if str != DelimMessageStr {
-			go func(msgStr string) {
-				// Parse Message header from last 24 bytes header message
-				jsonDecodeString, _ := hex.DecodeString(msgStr)
-				Logger.log.Infof("In message content : %s", string(jsonDecodeString))
-				messageHeader := jsonDecodeString[len(jsonDecodeString)-wire.MessageHeaderSize:]
-
-				// get cmd type in header message
-				commandInHeader := messageHeader[:wire.MessageCmdTypeSize]
-				commandInHeader = bytes.Trim(messageHeader, "\x00")
-				commandType := string(messageHeader[:len(commandInHeader)])
-				// convert to particular message from message cmd type
-				var message, err = wire.MakeEmptyMessage(string(commandType))
-				if err != nil {
-					Logger.log.Error("Can not find particular message for message cmd type")
-					Logger.log.Error(err)
-					return
-				}
+			message := self.readLegacyMessage(str)
+			if message != nil {
+				self.queueInMessage(message, len(str))
+			}
+		}
+	}
+}
 
-				// Parse Message body
-				messageBody := jsonDecodeString[:len(jsonDecodeString)-wire.MessageHeaderSize]
-				err = json.Unmarshal(messageBody, &message)
-				if err != nil {
-					Logger.log.Error("Can not parse struct from json message")
-					Logger.log.Error(err)
-					return
-				}
-				realType := reflect.TypeOf(message)
-				Logger.log.Infof("Cmd message type of struct %s", realType.String())
-
-				// process message for each of message type
-				switch realType {
-				case reflect.TypeOf(&wire.MessageTx{}):
-					if self.Config.MessageListeners.OnTx != nil {
-						self.Config.MessageListeners.OnTx(self, message.(*wire.MessageTx))
-					}
-				case reflect.TypeOf(&wire.MessageBlock{}):
-					if self.Config.MessageListeners.OnBlock != nil {
-						self.Config.MessageListeners.OnBlock(self, message.(*wire.MessageBlock))
-					}
-				case reflect.TypeOf(&wire.MessageGetBlocks{}):
-					if self.Config.MessageListeners.OnGetBlocks != nil {
-						self.Config.MessageListeners.OnGetBlocks(self, message.(*wire.MessageGetBlocks))
-					}
-				case reflect.TypeOf(&wire.MessageVersion{}):
-					if self.Config.MessageListeners.OnVersion != nil {
-						versionMessage := message.(*wire.MessageVersion)
-						self.Config.MessageListeners.OnVersion(self, versionMessage)
-					}
-				case reflect.TypeOf(&wire.MessageVerAck{}):
-					self.verAckReceived = true
-					if self.Config.MessageListeners.OnVerAck != nil {
-						self.Config.MessageListeners.OnVerAck(self, message.(*wire.MessageVerAck))
-					}
-				case reflect.TypeOf(&wire.MessageGetAddr{}):
-					if self.Config.MessageListeners.OnGetAddr != nil {
-						self.Config.MessageListeners.OnGetAddr(self, message.(*wire.MessageGetAddr))
-					}
-				case reflect.TypeOf(&wire.MessageAddr{}):
-					if self.Config.MessageListeners.OnGetAddr != nil {
-						self.Config.MessageListeners.OnAddr(self, message.(*wire.MessageAddr))
-					}
-				case reflect.TypeOf(&wire.MessageBlockSigReq{}):
-					if self.Config.MessageListeners.OnRequestSign != nil {
-						self.Config.MessageListeners.OnRequestSign(self, message.(*wire.MessageBlockSigReq))
-					}
-				case reflect.TypeOf(&wire.MessageInvalidBlock{}):
-					if self.Config.MessageListeners.OnInvalidBlock != nil {
-						self.Config.MessageListeners.OnInvalidBlock(self, message.(*wire.MessageInvalidBlock))
-					}
-				case reflect.TypeOf(&wire.MessageBlockSig{}):
-					if self.Config.MessageListeners.OnBlockSig != nil {
-						self.Config.MessageListeners.OnBlockSig(self, message.(*wire.MessageBlockSig))
-					}
-				case reflect.TypeOf(&wire.MessageGetChainState{}):
-					if self.Config.MessageListeners.OnGetChainState != nil {
-						self.Config.MessageListeners.OnGetChainState(self, message.(*wire.MessageGetChainState))
-					}
-				case reflect.TypeOf(&wire.MessageChainState{}):
-					if self.Config.MessageListeners.OnChainState != nil {
-						self.Config.MessageListeners.OnChainState(self, message.(*wire.MessageChainState))
-					}
-					/*case reflect.TypeOf(&wire.MessageRegistration{}):
-					  if self.Config.MessageListeners.OnRegistration != nil {
-						  self.Config.MessageListeners.OnRegistration(self, message.(*wire.MessageRegistration))
-					  }*/
-				case reflect.TypeOf(&wire.MessageSwapRequest{}):
-					if self.Config.MessageListeners.OnSwapRequest != nil {
-						self.Config.MessageListeners.OnSwapRequest(self, message.(*wire.MessageSwapRequest))
-					}
-				case reflect.TypeOf(&wire.MessageSwapSig{}):
-					if self.Config.MessageListeners.OnSwapSig != nil {
-						self.Config.MessageListeners.OnSwapSig(self, message.(*wire.MessageSwapSig))
-					}
-				case reflect.TypeOf(&wire.MessageSwapUpdate{}):
-					if self.Config.MessageListeners.OnSwapUpdate != nil {
-						self.Config.MessageListeners.OnSwapUpdate(self, message.(*wire.MessageSwapUpdate))
-					}
-				default:
-					Logger.log.Warnf("InMessageHandler Received unhandled message of type % from %v", realType, self)
-				}
-			}(str)
+/*
+readFramedMessage - read one framed message. Message is nil when the frame was
+skipped, err is only returned when the stream can not be read any more
+*/
+func (self *PeerConn) readFramedMessage(r *bufio.Reader) (wire.Message, int, error) {
+	counter := &countingReader{reader: r}
+	message, err := wire.ReadMessage(counter, self.WireVersion(), self.Config.Net)
+	if err != nil {
+		msgErr, ok := err.(*wire.MessageError)
+		if !ok {
+			return nil, 0, err
+		}
+		if msgErr.IsCode(wire.MessagePayloadLengthErr) {
+			self.AddBanScore(BanScoreOversizedMessage, 0, err.Error())
+		} else {
+			self.AddBanScore(0, BanScoreUndecodableMessage, err.Error())
+		}
+		if !msgErr.Recoverable() {
+			return nil, 0, err
+		}
+		Logger.log.Error("Can not read framed message")
+		Logger.log.Error(err)
+		return nil, 0, nil
+	}
+	return message, counter.count, nil
+}
+
+// countingReader counts bytes read through it
+type countingReader struct {
+	reader io.Reader
+	count  int
+}
+
+func (self *countingReader) Read(p []byte) (int, error) {
+	n, err := self.reader.Read(p)
+	self.count += n
+	return n, err
+}
+
+/*
+readLegacyLine - read a delimited hex message, a line longer than largest
+possible message is an offense and stops reading
+*/
+func (self *PeerConn) readLegacyLine(r *bufio.Reader) (string, error) {
+	maxLength := 2*(wire.MaxMessagePayload+wire.MessageHeaderSize) + len(DelimMessageStr)
+	line := []byte{}
+	for {
+		chunk, err := r.ReadSlice(DelimMessageByte)
+		line = append(line, chunk...)
+		if len(line) > maxLength {
+			self.AddBanScore(BanScoreOversizedMessage, 0, "oversized legacy message")
+			return "", errors.New("legacy message exceeds max length")
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		return string(line), err
+	}
+}
+
+/*
+readLegacyMessage - decode hex json message with 24 bytes header at tail
+*/
+func (self *PeerConn) readLegacyMessage(msgStr string) wire.Message {
+	// Parse Message header from last 24 bytes header message
+	jsonDecodeString, _ := hex.DecodeString(msgStr)
+	Logger.log.Infof("In message content : %s", string(jsonDecodeString))
+	if len(jsonDecodeString) < wire.MessageHeaderSize {
+		Logger.log.Error("Message is shorter than header")
+		self.AddBanScore(0, BanScoreUndecodableMessage, "message is shorter than header")
+		return nil
+	}
+	messageHeader := jsonDecodeString[len(jsonDecodeString)-wire.MessageHeaderSize:]
+
+	// get cmd type in header message
+	commandInHeader := messageHeader[:wire.MessageCmdTypeSize]
+	commandInHeader = bytes.Trim(messageHeader, "\x00")
+	commandType := string(messageHeader[:len(commandInHeader)])
+	// convert to particular message from message cmd type
+	var message, err = wire.MakeEmptyMessage(string(commandType))
+	if err != nil {
+		Logger.log.Error("Can not find particular message for message cmd type")
+		Logger.log.Error(err)
+		self.AddBanScore(0, BanScoreUndecodableMessage, err.Error())
+		return nil
+	}
+
+	// Parse Message body
+	messageBody := jsonDecodeString[:len(jsonDecodeString)-wire.MessageHeaderSize]
+	if len(messageBody) > message.MaxPayloadLength(wire.ProtocolVersionLegacy) {
+		Logger.log.Errorf("Message %s exceeds max payload length", commandType)
+		self.AddBanScore(BanScoreOversizedMessage, 0, "oversized "+commandType+" message")
+		return nil
+	}
+	err = json.Unmarshal(messageBody, &message)
+	if err != nil {
+		Logger.log.Error("Can not parse struct from json message")
+		Logger.log.Error(err)
+		self.AddBanScore(0, BanScoreUndecodableMessage, err.Error())
+		return nil
+	}
+	return message
+}
+
+/*
+queueInMessage - check inbound rate limits then push message into its lane.
+Tx lane drops new messages when full, other lanes block reader
+so that remote is throttled by the stream
+*/
+func (self *PeerConn) queueInMessage(message wire.Message, size int) {
+	cmd := message.MessageType()
+	if !self.rateLimiter.Allow(cmd, size) {
+		Logger.log.Warnf("Rate limited %s message from PEER %s", cmd, self.RemotePeerID.Pretty())
+		self.AddBanScore(0, BanScoreRateLimited, "rate limited "+cmd+" message")
+		return
+	}
+	switch messageLane(cmd) {
+	case laneConsensus:
+		select {
+		case self.cInConsensusMessage <- message:
+		case <-self.cDisconnect:
+		}
+	case laneTx:
+		select {
+		case self.cInTxMessage <- message:
+		default:
+			Logger.log.Warnf("Inbound tx queue of PEER %s is full, drop message", self.RemotePeerID.Pretty())
+		}
+	default:
+		select {
+		case self.cInMessage <- message:
+		case <-self.cDisconnect:
+		}
+	}
+}
+
+/*
+inMessageWorker - process messages of a lane one by one until disconnected
+*/
+func (self *PeerConn) inMessageWorker(cMessage chan wire.Message) {
+	for {
+		select {
+		case message := <-cMessage:
+			self.handleMessage(message)
+		case <-self.cDisconnect:
+			return
+		}
+	}
+}
+
+/*
+handleMessage - dispatch a decoded message to its listener
+*/
+func (self *PeerConn) handleMessage(message wire.Message) {
+	realType := reflect.TypeOf(message)
+	Logger.log.Infof("Cmd message type of struct %s", realType.String())
+
+	// process message for each of message type
+	switch realType {
+	case reflect.TypeOf(&wire.MessagePing{}):
+		pong := &wire.MessagePong{
+			Nonce: message.(*wire.MessagePing).Nonce,
+		}
+		self.QueueMessageWithEncoding(pong, nil)
+	case reflect.TypeOf(&wire.MessagePong{}):
+		self.handlePong(message.(*wire.MessagePong))
+	case reflect.TypeOf(&wire.MessageTx{}):
+		if self.Config.MessageListeners.OnTx != nil {
+			self.Config.MessageListeners.OnTx(self, message.(*wire.MessageTx))
+		}
+	case reflect.TypeOf(&wire.MessageBlock{}):
+		if self.Config.MessageListeners.OnBlock != nil {
+			self.Config.MessageListeners.OnBlock(self, message.(*wire.MessageBlock))
+		}
+	case reflect.TypeOf(&wire.MessageGetBlocks{}):
+		if self.Config.MessageListeners.OnGetBlocks != nil {
+			self.Config.MessageListeners.OnGetBlocks(self, message.(*wire.MessageGetBlocks))
+		}
+	case reflect.TypeOf(&wire.MessageVersion{}):
+		versionMessage := message.(*wire.MessageVersion)
+		self.negotiateWireVersion(versionMessage.WireVersion)
+		if self.Config.MessageListeners.OnVersion != nil {
+			self.Config.MessageListeners.OnVersion(self, versionMessage)
+		}
+	case reflect.TypeOf(&wire.MessageVerAck{}):
+		self.verAckReceived = true
+		if self.Config.MessageListeners.OnVerAck != nil {
+			self.Config.MessageListeners.OnVerAck(self, message.(*wire.MessageVerAck))
+		}
+	case reflect.TypeOf(&wire.MessageGetAddr{}):
+		if self.Config.MessageListeners.OnGetAddr != nil {
+			self.Config.MessageListeners.OnGetAddr(self, message.(*wire.MessageGetAddr))
+		}
+	case reflect.TypeOf(&wire.MessageAddr{}):
+		if self.Config.MessageListeners.OnGetAddr != nil {
+			self.Config.MessageListeners.OnAddr(self, message.(*wire.MessageAddr))
+		}
+	case reflect.TypeOf(&wire.MessageBlockSigReq{}):
+		if self.Config.MessageListeners.OnRequestSign != nil {
+			self.Config.MessageListeners.OnRequestSign(self, message.(*wire.MessageBlockSigReq))
+		}
+	case reflect.TypeOf(&wire.MessageInvalidBlock{}):
+		if self.Config.MessageListeners.OnInvalidBlock != nil {
+			self.Config.MessageListeners.OnInvalidBlock(self, message.(*wire.MessageInvalidBlock))
+		}
+	case reflect.TypeOf(&wire.MessageEvidence{}):
+		if self.Config.MessageListeners.OnEvidence != nil {
+			self.Config.MessageListeners.OnEvidence(self, message.(*wire.MessageEvidence))
+		}
+	case reflect.TypeOf(&wire.MessageBlockSig{}):
+		if self.Config.MessageListeners.OnBlockSig != nil {
+			self.Config.MessageListeners.OnBlockSig(self, message.(*wire.MessageBlockSig))
+		}
+	case reflect.TypeOf(&wire.MessageGetChainState{}):
+		if self.Config.MessageListeners.OnGetChainState != nil {
+			self.Config.MessageListeners.OnGetChainState(self, message.(*wire.MessageGetChainState))
+		}
+	case reflect.TypeOf(&wire.MessageChainState{}):
+		if self.Config.MessageListeners.OnChainState != nil {
+			self.Config.MessageListeners.OnChainState(self, message.(*wire.MessageChainState))
+		}
+		/*case reflect.TypeOf(&wire.MessageRegistration{}):
+		  if self.Config.MessageListeners.OnRegistration != nil {
+			  self.Config.MessageListeners.OnRegistration(self, message.(*wire.MessageRegistration))
+		  }*/
+	case reflect.TypeOf(&wire.MessageSwapRequest{}):
+		if self.Config.MessageListeners.OnSwapRequest != nil {
+			self.Config.MessageListeners.OnSwapRequest(self, message.(*wire.MessageSwapRequest))
 		}
+	case reflect.TypeOf(&wire.MessageSwapSig{}):
+		if self.Config.MessageListeners.OnSwapSig != nil {
+			self.Config.MessageListeners.OnSwapSig(self, message.(*wire.MessageSwapSig))
+		}
+	case reflect.TypeOf(&wire.MessageSwapUpdate{}):
+		if self.Config.MessageListeners.OnSwapUpdate != nil {
+			self.Config.MessageListeners.OnSwapUpdate(self, message.(*wire.MessageSwapUpdate))
+		}
+	default:
+		Logger.log.Warnf("InMessageHandler Received unhandled message of type % from %v", realType, self)
 	}
 }
 
@@ -179,41 +399,21 @@ func (self *PeerConn) InMessageHandler(rw *bufio.ReadWriter) {
 */
 func (self *PeerConn) OutMessageHandler(rw *bufio.ReadWriter) {
 	for {
+		// consensus messages go first whenever there are some waiting
 		select {
-		case outMsg := <-self.sendMessageQueue:
-			{
-				// Create and send message
-				messageByte, err := outMsg.message.JsonSerialize()
-				if err != nil {
-					Logger.log.Error("Can not serialize json format for message:" + outMsg.message.MessageType())
-					Logger.log.Error(err)
-					continue
-				}
+		case outMsg := <-self.sendConsensusQueue:
+			self.writeMessage(rw, outMsg)
+			continue
+		default:
+		}
 
-				// add 24 bytes header into message
-				header := make([]byte, wire.MessageHeaderSize)
-				cmdType, _ := wire.GetCmdType(reflect.TypeOf(outMsg.message))
-				copy(header[:], []byte(cmdType))
-				messageByte = append(messageByte, header...)
-				Logger.log.Infof("Out message TYPE %s CONTENT %s", cmdType, string(messageByte))
-				message := hex.EncodeToString(messageByte)
-				//Logger.log.Infof("Content in hex encode: %s", string(message))
-				// add end character to message (delim '\n')
-				message += DelimMessageStr
-
-				// send on p2p stream
-				Logger.log.Infof("Send a message %s to %s", outMsg.message.MessageType(), self.RemotePeer.PeerID.String())
-				_, err = rw.Writer.WriteString(message)
-				if err != nil {
-					Logger.log.Critical("DM ERROR", err)
-					continue
-				}
-				err = rw.Writer.Flush()
-				if err != nil {
-					Logger.log.Critical("DM ERROR", err)
-					continue
-				}
-			}
+		select {
+		case outMsg := <-self.sendConsensusQueue:
+			self.writeMessage(rw, outMsg)
+		case outMsg := <-self.sendMessageQueue:
+			self.writeMessage(rw, outMsg)
+		case outMsg := <-self.sendTxQueue:
+			self.writeMessage(rw, outMsg)
 		case <-self.cWrite:
 			Logger.log.Infof("OutMessageHandler QUIT %s %s", self.RemotePeerID, self.RemotePeer.RawAddress)
 
@@ -230,6 +430,62 @@ func (self *PeerConn) OutMessageHandler(rw *bufio.ReadWriter) {
 	}
 }
 
+/*
+writeMessage - encode message with negotiated wire version and send on p2p stream
+*/
+func (self *PeerConn) writeMessage(rw *bufio.ReadWriter, outMsg outMsg) {
+	defer notifyDone(outMsg)
+	// a peer older than ProtocolVersionTxTypes can't decode txs of most types
+	if cmd := outMsg.message.MessageType(); wire.IsTxCommand(cmd) && !wire.IsTxCommandSupported(cmd, self.WireVersion()) {
+		Logger.log.Infof("Skip message %s to %s of wire version %d", cmd, self.RemotePeer.PeerID.String(), self.WireVersion())
+		return
+	}
+	if self.WireVersion() >= wire.ProtocolVersionFramed {
+		Logger.log.Infof("Send a message %s to %s", outMsg.message.MessageType(), self.RemotePeer.PeerID.String())
+		err := wire.WriteMessage(rw.Writer, outMsg.message, self.WireVersion(), self.Config.Net)
+		if err != nil {
+			Logger.log.Critical("DM ERROR", err)
+			return
+		}
+		err = rw.Writer.Flush()
+		if err != nil {
+			Logger.log.Critical("DM ERROR", err)
+		}
+		return
+	}
+
+	// Create and send message
+	messageByte, err := outMsg.message.JsonSerialize()
+	if err != nil {
+		Logger.log.Error("Can not serialize json format for message:" + outMsg.message.MessageType())
+		Logger.log.Error(err)
+		return
+	}
+
+	// add 24 bytes header into message
+	header := make([]byte, wire.MessageHeaderSize)
+	cmdType := outMsg.message.MessageType()
+	copy(header[:], []byte(cmdType))
+	messageByte = append(messageByte, header...)
+	Logger.log.Infof("Out message TYPE %s CONTENT %s", cmdType, string(messageByte))
+	message := hex.EncodeToString(messageByte)
+	//Logger.log.Infof("Content in hex encode: %s", string(message))
+	// add end character to message (delim '\n')
+	message += DelimMessageStr
+
+	// send on p2p stream
+	Logger.log.Infof("Send a message %s to %s", outMsg.message.MessageType(), self.RemotePeer.PeerID.String())
+	_, err = rw.Writer.WriteString(message)
+	if err != nil {
+		Logger.log.Critical("DM ERROR", err)
+		return
+	}
+	err = rw.Writer.Flush()
+	if err != nil {
+		Logger.log.Critical("DM ERROR", err)
+	}
+}
+
 // QueueMessageWithEncoding adds the passed Constant message to the peer send
 // queue. This function is identical to QueueMessage, however it allows the
 // caller to specify the wire encoding type that should be used when
@@ -237,15 +493,205 @@ func (self *PeerConn) OutMessageHandler(rw *bufio.ReadWriter) {
 //
 // This function is safe for concurrent access.
 func (self *PeerConn) QueueMessageWithEncoding(msg wire.Message, doneChan chan<- struct{}) {
-	if self.IsConnected {
-		self.sendMessageQueue <- outMsg{message: msg, doneChan: doneChan}
+	if !self.IsConnected {
+		notifyDone(outMsg{message: msg, doneChan: doneChan})
+		return
+	}
+	out := outMsg{message: msg, doneChan: doneChan}
+	switch messageLane(msg.MessageType()) {
+	case laneConsensus:
+		select {
+		case self.sendConsensusQueue <- out:
+		case <-self.cDisconnect:
+			notifyDone(out)
+		}
+	case laneTx:
+		// tx relay is best effort, drop when remote can not keep up
+		select {
+		case self.sendTxQueue <- out:
+		default:
+			Logger.log.Warnf("Outbound tx queue of PEER %s is full, drop message", self.RemotePeerID.Pretty())
+			notifyDone(out)
+		}
+	default:
+		select {
+		case self.sendMessageQueue <- out:
+		case <-self.cDisconnect:
+			notifyDone(out)
+		}
 	}
 }
 
+// notifyDone signals that message was sent or will not be sent
+func notifyDone(msg outMsg) {
+	if msg.doneChan != nil {
+		go func() {
+			msg.doneChan <- struct{}{}
+		}()
+	}
+}
+
+/*
+negotiateWireVersion - use the highest wire version both sides speak,
+remote which does not advertise any is a legacy peer
+*/
+func (self *PeerConn) negotiateWireVersion(remoteVersion int) {
+	version := wire.ProtocolVersion
+	if remoteVersion < version {
+		version = remoteVersion
+	}
+	if version < wire.ProtocolVersionLegacy {
+		version = wire.ProtocolVersionLegacy
+	}
+	self.wireVersionMtx.Lock()
+	self.wireVersion = version
+	self.wireVersionMtx.Unlock()
+}
+
+// WireVersion returns negotiated wire protocol version of connection
+func (self *PeerConn) WireVersion() int {
+	self.wireVersionMtx.RLock()
+	defer self.wireVersionMtx.RUnlock()
+	if self.wireVersion == 0 {
+		return wire.ProtocolVersionLegacy
+	}
+	return self.wireVersion
+}
+
+/*
+pingHandler - periodically ping remote peer and disconnect it when ping is not
+answered within PingTimeout. Peers older than ProtocolVersionPong do not answer
+ping so they are not pinged
+*/
+func (self *PeerConn) pingHandler() {
+	ticker := time.NewTicker(PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if self.WireVersion() < wire.ProtocolVersionPong {
+				continue
+			}
+			self.pingMtx.Lock()
+			pending := self.lastPingNonce != 0
+			expired := pending && time.Since(self.lastPingTime) > PingTimeout
+			if !pending {
+				self.lastPingNonce = randomNonce()
+				self.lastPingTime = time.Now()
+			}
+			nonce := self.lastPingNonce
+			self.pingMtx.Unlock()
+
+			if expired {
+				Logger.log.Warnf("PEER %s did not answer ping for %s, disconnect", self.RemotePeerID.Pretty(), PingTimeout)
+				if self.IsConnected {
+					self.Close()
+				}
+				return
+			}
+			if !pending {
+				self.QueueMessageWithEncoding(&wire.MessagePing{Nonce: nonce}, nil)
+			}
+		case <-self.cDisconnect:
+			return
+		}
+	}
+}
+
+// randomNonce returns a non zero random nonce, zero means no pending ping
+func randomNonce() uint64 {
+	buf := make([]byte, 8)
+	for {
+		rand.Read(buf)
+		nonce := binary.LittleEndian.Uint64(buf)
+		if nonce != 0 {
+			return nonce
+		}
+	}
+}
+
+// handlePong records round trip time when pong answers the pending ping
+func (self *PeerConn) handlePong(msg *wire.MessagePong) {
+	self.pingMtx.Lock()
+	defer self.pingMtx.Unlock()
+	if self.lastPingNonce == 0 || msg.Nonce != self.lastPingNonce {
+		return
+	}
+	self.pingTime = time.Since(self.lastPingTime)
+	self.lastPingNonce = 0
+}
+
+// SourceAddress returns the address which addresses announced by remote peer
+// are learnt from, the dialed address of outbound peers or the observed
+// address of inbound ones
+func (self *PeerConn) SourceAddress() string {
+	if self.RemoteRawAddress != "" {
+		return self.RemoteRawAddress
+	}
+	return self.RemoteObservedAddress
+}
+
+// PingTime returns round trip time of last answered ping, 0 when unknown
+func (self *PeerConn) PingTime() time.Duration {
+	self.pingMtx.Lock()
+	defer self.pingMtx.Unlock()
+	return self.pingTime
+}
+
+/*
+AddBanScore - increase ban score of remote peer for an offense. Once the score
+reaches BanThreshold, the remote peer is banned and disconnected
+*/
+func (self *PeerConn) AddBanScore(persistent uint32, transient uint32, reason string) {
+	score := self.banScore.Increase(persistent, transient)
+	Logger.log.Warnf("Misbehaving PEER %s: %s -- ban score increased to %d", self.RemotePeerID.Pretty(), reason, score)
+	if score < BanThreshold || !atomic.CompareAndSwapInt32(&self.banned, 0, 1) {
+		return
+	}
+	Logger.log.Warnf("Banning PEER %s: %s", self.RemotePeerID.Pretty(), reason)
+	if self.HandleBanned != nil {
+		go self.HandleBanned(self, reason)
+	}
+}
+
+// BanScore returns current ban score of remote peer
+func (self *PeerConn) BanScore() uint32 {
+	return self.banScore.Int()
+}
+
 func (p *PeerConn) VerAckReceived() bool {
 	return p.verAckReceived
 }
 
+// Challenge returns the random challenge of our version message, it is created
+// once per connection
+func (self *PeerConn) Challenge() string {
+	self.authMtx.Lock()
+	defer self.authMtx.Unlock()
+	if self.challenge == "" {
+		buf := make([]byte, ChallengeSize)
+		rand.Read(buf)
+		self.challenge = hex.EncodeToString(buf)
+	}
+	return self.challenge
+}
+
+// SetAuthPublicKey binds the producer public key proven in handshake to the
+// connection
+func (self *PeerConn) SetAuthPublicKey(publicKey string) {
+	self.authMtx.Lock()
+	defer self.authMtx.Unlock()
+	self.authPublicKey = publicKey
+}
+
+// AuthPublicKey returns producer public key proven in handshake, empty when
+// remote peer is not a validator or is not authenticated yet
+func (self *PeerConn) AuthPublicKey() string {
+	self.authMtx.Lock()
+	defer self.authMtx.Unlock()
+	return self.authPublicKey
+}
+
 // updateState updates the state of the connection request.
 func (p *PeerConn) updateConnState(connState ConnState) {
 	p.stateMtx.Lock()
@@ -262,5 +708,7 @@ func (p *PeerConn) ConnState() ConnState {
 }
 
 func (p *PeerConn) Close() {
-	close(p.cClose)
+	p.closeOnce.Do(func() {
+		close(p.cClose)
+	})
 }
@@ -0,0 +1,68 @@
+package peer
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/transaction"
+	"github.com/ninjadotorg/constant/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPingPong(t *testing.T) {
+	Logger.Init(common.Disabled)
+	peerConn := &PeerConn{
+		IsConnected:      true,
+		sendMessageQueue: make(chan outMsg, 1),
+	}
+
+	// ping is answered with pong of the same nonce
+	peerConn.handleMessage(&wire.MessagePing{Nonce: 7})
+	out := <-peerConn.sendMessageQueue
+	assert.Equal(t, &wire.MessagePong{Nonce: 7}, out.message)
+
+	// pong of an unknown nonce is ignored
+	nonce := randomNonce()
+	assert.NotEqual(t, uint64(0), nonce)
+	peerConn.lastPingNonce = nonce
+	peerConn.lastPingTime = time.Now().Add(-time.Second)
+	peerConn.handleMessage(&wire.MessagePong{Nonce: nonce + 1})
+	assert.Equal(t, time.Duration(0), peerConn.PingTime())
+	assert.Equal(t, nonce, peerConn.lastPingNonce)
+
+	// pong of pending ping records round trip time
+	peerConn.handleMessage(&wire.MessagePong{Nonce: nonce})
+	assert.True(t, peerConn.PingTime() >= time.Second)
+	assert.Equal(t, uint64(0), peerConn.lastPingNonce)
+
+	// a second pong of the same ping is ignored
+	pingTime := peerConn.PingTime()
+	peerConn.handleMessage(&wire.MessagePong{Nonce: nonce})
+	assert.Equal(t, pingTime, peerConn.PingTime())
+}
+
+func TestWriteMessage_TxTypes(t *testing.T) {
+	Logger.Init(common.Disabled)
+	buf := &bytes.Buffer{}
+	rw := bufio.NewReadWriter(bufio.NewReader(buf), bufio.NewWriter(buf))
+	peerConn := &PeerConn{RemotePeer: &Peer{}}
+	request := &wire.MessageTx{Transaction: &transaction.TxLoanRequest{Tx: transaction.Tx{Type: common.TxLoanRequest}}}
+	response := &wire.MessageTx{Transaction: &transaction.TxLoanResponse{Tx: transaction.Tx{Type: common.TxLoanResponse}}}
+
+	// a peer older than ProtocolVersionTxTypes only gets txs it decodes
+	peerConn.negotiateWireVersion(wire.ProtocolVersionAuth)
+	peerConn.writeMessage(rw, outMsg{message: response})
+	assert.Equal(t, 0, buf.Len())
+	peerConn.writeMessage(rw, outMsg{message: request})
+	assert.NotEqual(t, 0, buf.Len())
+
+	buf.Reset()
+	peerConn.negotiateWireVersion(wire.ProtocolVersionTxTypes)
+	peerConn.writeMessage(rw, outMsg{message: response})
+	msg, err := wire.ReadMessage(buf, wire.ProtocolVersionTxTypes, peerConn.Config.Net)
+	assert.Nil(t, err)
+	assert.Equal(t, common.TxLoanResponse, msg.(*wire.MessageTx).Transaction.GetType())
+}
@@ -0,0 +1,97 @@
+package peer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ninjadotorg/constant/wire"
+)
+
+// messageRate is the allowed rate (per second) and burst of one message type
+type messageRate struct {
+	rate  float64
+	burst float64
+}
+
+// messageRates overrides DefaultMessageRate for message types which are
+// cheap to send but expensive to serve, or which are relayed in bulk. Txs of
+// all types share the limit of CmdTx
+var messageRates = map[string]messageRate{
+	wire.CmdTx:            {100, 500},
+	wire.CmdGetBlocks:     {5, 20},
+	wire.CmdGetAddr:       {0.1, 5},
+	wire.CmdVersion:       {1, 5},
+	wire.CmdGetChainState: {1, 10},
+	wire.CmdInvalidBlock:  {1, 10},
+	wire.CmdEvidence:      {1, 10},
+}
+
+// tokenBucket refills rate tokens per second up to burst
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst float64) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+func (self *tokenBucket) allow(n float64, now time.Time) bool {
+	self.tokens += now.Sub(self.last).Seconds() * self.rate
+	if self.tokens > self.burst {
+		self.tokens = self.burst
+	}
+	self.last = now
+	if self.tokens < n {
+		return false
+	}
+	self.tokens -= n
+	return true
+}
+
+/*
+RateLimiter - inbound limits of a peer connection, by bytes per second over
+all messages and by number of messages per second of each type
+*/
+type RateLimiter struct {
+	mtx      sync.Mutex
+	bytes    *tokenBucket
+	messages map[string]*tokenBucket
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		bytes:    newTokenBucket(MaxInBytesPerSecond, MaxInBytesBurst),
+		messages: make(map[string]*tokenBucket),
+	}
+}
+
+// Allow reports whether a message of type cmd and size bytes is within limits
+func (self *RateLimiter) Allow(cmd string, size int) bool {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	now := time.Now()
+	if wire.IsTxCommand(cmd) {
+		cmd = wire.CmdTx
+	}
+	bucket, ok := self.messages[cmd]
+	if !ok {
+		limit, ok := messageRates[cmd]
+		if !ok {
+			limit = messageRate{DefaultMessageRate, DefaultMessageBurst}
+		}
+		bucket = newTokenBucket(limit.rate, limit.burst)
+		self.messages[cmd] = bucket
+	}
+	if !bucket.allow(1, now) {
+		return false
+	}
+	return self.bytes.allow(float64(size), now)
+}
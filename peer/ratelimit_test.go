@@ -0,0 +1,51 @@
+package peer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ninjadotorg/constant/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	bucket := newTokenBucket(2, 4)
+	bucket.last = now
+	for i := 0; i < 4; i++ {
+		assert.True(t, bucket.allow(1, now))
+	}
+	assert.False(t, bucket.allow(1, now))
+
+	// refills rate tokens per second
+	now = now.Add(time.Second)
+	assert.True(t, bucket.allow(2, now))
+	assert.False(t, bucket.allow(1, now))
+
+	// never refills over burst
+	now = now.Add(time.Hour)
+	assert.False(t, bucket.allow(5, now))
+	assert.True(t, bucket.allow(4, now))
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter()
+	limit := messageRates[wire.CmdGetAddr]
+	for i := 0; i < int(limit.burst); i++ {
+		assert.True(t, limiter.Allow(wire.CmdGetAddr, 10))
+	}
+	assert.False(t, limiter.Allow(wire.CmdGetAddr, 10))
+	// other types keep their own limits
+	assert.True(t, limiter.Allow(wire.CmdPing, 10))
+
+	// txs of all types share the limit of CmdTx
+	for i := 0; i < int(messageRates[wire.CmdTx].burst); i++ {
+		assert.True(t, limiter.Allow(wire.CmdTx, 10))
+	}
+	assert.False(t, limiter.Allow(wire.CmdCustomToken, 10))
+
+	// bytes are limited over all types
+	limiter = NewRateLimiter()
+	assert.True(t, limiter.Allow(wire.CmdBlock, MaxInBytesBurst))
+	assert.False(t, limiter.Allow(wire.CmdBlock, 1024))
+}
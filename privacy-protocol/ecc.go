@@ -181,13 +181,13 @@ func (eccPoint EllipticPoint) CompressPoint() []byte {
 // to a point on the given curve.
 func (eccPoint *EllipticPoint) DecompressPoint(compressPointBytes []byte) error {
 	format := compressPointBytes[0]
+	ybit := (format & 0x1) == 0x1
 	format &= ^byte(0x1)
 
 	if format != PointCompressed {
 		return fmt.Errorf("invalid magic in compressed "+
 			"compressPoint bytes: %d", compressPointBytes[0])
 	}
-	ybit := (format & 0x1) == 0x1
 	var err error
 	if eccPoint.X == nil {
 		eccPoint.X = new(big.Int).SetBytes(compressPointBytes[1:33])
@@ -0,0 +1,25 @@
+package privacy
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressDecompressPoint(t *testing.T) {
+	parities := make(map[bool]bool)
+	for k := int64(1); k <= 16; k++ {
+		point := new(EllipticPoint)
+		point.X, point.Y = Curve.ScalarBaseMult(big.NewInt(k).Bytes())
+		parities[isOdd(point.Y)] = true
+
+		decompressed := new(EllipticPoint)
+		err := decompressed.DecompressPoint(point.CompressPoint())
+		assert.Nil(t, err)
+		// the parity bit of the compressed point picks the right root
+		assert.Equal(t, point.X, decompressed.X)
+		assert.Equal(t, point.Y, decompressed.Y)
+	}
+	assert.Equal(t, 2, len(parities), "points of both y parities are tested")
+}
@@ -0,0 +1,247 @@
+package privacy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// Multi-signature of several spending keys over one message, it is a Schnorr
+// signature (R, s) which is verified by the aggregated public key of signers
+// like any single Schnorr signature. Signing takes two rounds:
+// 1. every signer creates a MultiSigNonce and sends its public nonce
+// 2. public nonces are aggregated, every signer sends a partial signature
+// which are combined into the multi-signature.
+// Public keys are weighted by a coefficient bound to the whole signer set so
+// that a signer can't cancel keys of others, and two nonces per signer keep
+// the two rounds secure under concurrent sessions (MuSig2).
+
+const (
+	MultiSigPublicNonceSize = 2 * PointBytesLenCompressed
+	MultiSigPartialSize     = 32
+	MultiSigSize            = PointBytesLenCompressed + 32
+)
+
+// MultiSigNonce is the secret nonce pair of a signer for one signing session,
+// it is cleared by MultiSigPartialSign and must never be reused
+type MultiSigNonce struct {
+	r1, r2 *big.Int
+}
+
+// hashToScalar hashes concatenation of data into a scalar of the curve
+func hashToScalar(data ...[]byte) *big.Int {
+	h := sha256.New()
+	for _, d := range data {
+		h.Write(d)
+	}
+	k := new(big.Int).SetBytes(h.Sum(nil))
+	return k.Mod(k, Curve.Params().N)
+}
+
+// NewMultiSigNonce creates a fresh secret nonce pair
+func NewMultiSigNonce() *MultiSigNonce {
+	return &MultiSigNonce{
+		r1: randScalar(),
+		r2: randScalar(),
+	}
+}
+
+// PublicNonce returns the public nonce pair which is sent to aggregator
+func (self *MultiSigNonce) PublicNonce() []byte {
+	if self.r1 == nil || self.r2 == nil {
+		return nil
+	}
+	publicNonce := basePointMult(self.r1).CompressPoint()
+	return append(publicNonce, basePointMult(self.r2).CompressPoint()...)
+}
+
+func decodePublicNonce(nonce []byte) (*EllipticPoint, *EllipticPoint, error) {
+	if len(nonce) != MultiSigPublicNonceSize {
+		return nil, nil, errors.New("invalid public nonce length")
+	}
+	r1, err := DecompressKey(nonce[:PointBytesLenCompressed])
+	if err != nil {
+		return nil, nil, err
+	}
+	r2, err := DecompressKey(nonce[PointBytesLenCompressed:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return r1, r2, nil
+}
+
+// AggregatePublicNonces sums public nonces of all signers
+func AggregatePublicNonces(nonces [][]byte) ([]byte, error) {
+	if len(nonces) == 0 {
+		return nil, errors.New("no public nonce to aggregate")
+	}
+	var aggR1, aggR2 *EllipticPoint
+	for _, nonce := range nonces {
+		r1, r2, err := decodePublicNonce(nonce)
+		if err != nil {
+			return nil, err
+		}
+		if aggR1 == nil {
+			aggR1, aggR2 = r1, r2
+			continue
+		}
+		aggR1 = pointAdd(aggR1, r1)
+		aggR2 = pointAdd(aggR2, r2)
+	}
+	aggNonce := aggR1.CompressPoint()
+	return append(aggNonce, aggR2.CompressPoint()...), nil
+}
+
+// aggregatePublicKeys returns sum of a_i*X_i and coefficients a_i which are
+// bound to the set of public keys
+func aggregatePublicKeys(pubKeys [][]byte) (*EllipticPoint, []*big.Int, error) {
+	if len(pubKeys) == 0 {
+		return nil, nil, errors.New("no public key to aggregate")
+	}
+	keySetHash := hashToScalar(pubKeys...).Bytes()
+
+	var aggKey *EllipticPoint
+	coefs := make([]*big.Int, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		point, err := DecompressKey(pubKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		coefs[i] = hashToScalar(keySetHash, pubKey)
+		weighted := pointMult(point, coefs[i])
+		if aggKey == nil {
+			aggKey = weighted
+		} else {
+			aggKey = pointAdd(aggKey, weighted)
+		}
+	}
+	return aggKey, coefs, nil
+}
+
+// multiSigChallenge returns R, nonce coefficient b and challenge e of a session
+func multiSigChallenge(aggKey *EllipticPoint, aggNonce []byte, hash []byte) (*EllipticPoint, *big.Int, *big.Int, error) {
+	aggR1, aggR2, err := decodePublicNonce(aggNonce)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	aggKeyBytes := aggKey.CompressPoint()
+	b := hashToScalar(aggKeyBytes, aggNonce, hash)
+	r := pointAdd(aggR1, pointMult(aggR2, b))
+	e := hashToScalar(r.CompressPoint(), aggKeyBytes, hash)
+	return r, b, e, nil
+}
+
+// MultiSigPartialSign signs hash by spending key, which is one of pubKeys, with
+// the session nonce. The nonce is cleared so it can't be used again.
+func MultiSigPartialSign(hash []byte, spendingKey []byte, nonce *MultiSigNonce, pubKeys [][]byte, aggNonce []byte) ([]byte, error) {
+	if nonce == nil || nonce.r1 == nil || nonce.r2 == nil {
+		return nil, errors.New("nonce is already used")
+	}
+	pubKey := GeneratePublicKey(spendingKey)
+	signerIdx := -1
+	for i := range pubKeys {
+		if bytes.Equal(pubKeys[i], pubKey) {
+			signerIdx = i
+			break
+		}
+	}
+	if signerIdx == -1 {
+		return nil, errors.New("spending key is not in signer set")
+	}
+
+	aggKey, coefs, err := aggregatePublicKeys(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	_, b, e, err := multiSigChallenge(aggKey, aggNonce, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// s_i = r1 + b*r2 + e*a_i*x_i
+	s := new(big.Int).Mul(e, coefs[signerIdx])
+	s.Mul(s, new(big.Int).SetBytes(spendingKey))
+	s.Add(s, new(big.Int).Mul(b, nonce.r2))
+	s.Add(s, nonce.r1)
+	s.Mod(s, Curve.Params().N)
+
+	nonce.r1, nonce.r2 = nil, nil
+	return scalarBytes(s), nil
+}
+
+// MultiSigVerifyPartial checks partial signature of signer at signerIdx of
+// pubKeys against its public nonce, so a bad signer is found before combining
+func MultiSigVerifyPartial(hash []byte, partial []byte, publicNonce []byte, pubKeys [][]byte, signerIdx int, aggNonce []byte) bool {
+	if len(partial) != MultiSigPartialSize || signerIdx < 0 || signerIdx >= len(pubKeys) {
+		return false
+	}
+	aggKey, coefs, err := aggregatePublicKeys(pubKeys)
+	if err != nil {
+		return false
+	}
+	_, b, e, err := multiSigChallenge(aggKey, aggNonce, hash)
+	if err != nil {
+		return false
+	}
+	r1, r2, err := decodePublicNonce(publicNonce)
+	if err != nil {
+		return false
+	}
+	pubKey, err := DecompressKey(pubKeys[signerIdx])
+	if err != nil {
+		return false
+	}
+
+	// s_i*G == R1_i + b*R2_i + e*a_i*X_i
+	lhs := basePointMult(new(big.Int).SetBytes(partial))
+	ea := new(big.Int).Mul(e, coefs[signerIdx])
+	ea.Mod(ea, Curve.Params().N)
+	rhs := pointAdd(pointAdd(r1, pointMult(r2, b)), pointMult(pubKey, ea))
+	return lhs.X.Cmp(rhs.X) == 0 && lhs.Y.Cmp(rhs.Y) == 0
+}
+
+// MultiSigCombine combines partial signatures of all pubKeys into R || s
+func MultiSigCombine(hash []byte, partials [][]byte, pubKeys [][]byte, aggNonce []byte) ([]byte, error) {
+	if len(partials) != len(pubKeys) {
+		return nil, errors.New("missing partial signatures")
+	}
+	aggKey, _, err := aggregatePublicKeys(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	r, _, _, err := multiSigChallenge(aggKey, aggNonce, hash)
+	if err != nil {
+		return nil, err
+	}
+	s := new(big.Int)
+	for _, partial := range partials {
+		if len(partial) != MultiSigPartialSize {
+			return nil, errors.New("invalid partial signature length")
+		}
+		s.Add(s, new(big.Int).SetBytes(partial))
+	}
+	s.Mod(s, Curve.Params().N)
+	return append(r.CompressPoint(), scalarBytes(s)...), nil
+}
+
+// MultiSigVerify checks multi-signature of pubKeys over hash
+func MultiSigVerify(sig []byte, hash []byte, pubKeys [][]byte) bool {
+	if len(sig) != MultiSigSize {
+		return false
+	}
+	r, err := DecompressKey(sig[:PointBytesLenCompressed])
+	if err != nil {
+		return false
+	}
+	aggKey, _, err := aggregatePublicKeys(pubKeys)
+	if err != nil {
+		return false
+	}
+	e := hashToScalar(sig[:PointBytesLenCompressed], aggKey.CompressPoint(), hash)
+
+	// s*G == R + e*X
+	lhs := basePointMult(new(big.Int).SetBytes(sig[PointBytesLenCompressed:]))
+	rhs := pointAdd(r, pointMult(aggKey, e))
+	return lhs.X.Cmp(rhs.X) == 0 && lhs.Y.Cmp(rhs.Y) == 0
+}
@@ -0,0 +1,47 @@
+package privacy
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiSig(t *testing.T) {
+	hash := common.HashB([]byte("block"))
+	n := 5
+	spendingKeys := make([][]byte, n)
+	pubKeys := make([][]byte, n)
+	nonces := make([]*MultiSigNonce, n)
+	publicNonces := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		spendingKeys[i] = GenerateSpendingKey(new(big.Int).SetInt64(int64(i + 1)).Bytes())
+		pubKeys[i] = GeneratePublicKey(spendingKeys[i])
+		nonces[i] = NewMultiSigNonce()
+		publicNonces[i] = nonces[i].PublicNonce()
+	}
+	aggNonce, err := AggregatePublicNonces(publicNonces)
+	assert.Nil(t, err)
+
+	partials := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		partials[i], err = MultiSigPartialSign(hash, spendingKeys[i], nonces[i], pubKeys, aggNonce)
+		assert.Nil(t, err)
+		assert.True(t, MultiSigVerifyPartial(hash, partials[i], publicNonces[i], pubKeys, i, aggNonce))
+	}
+
+	// nonce can't be reused
+	_, err = MultiSigPartialSign(hash, spendingKeys[0], nonces[0], pubKeys, aggNonce)
+	assert.NotNil(t, err)
+
+	sig, err := MultiSigCombine(hash, partials, pubKeys, aggNonce)
+	assert.Nil(t, err)
+	assert.Equal(t, MultiSigSize, len(sig))
+	assert.True(t, MultiSigVerify(sig, hash, pubKeys))
+	assert.False(t, MultiSigVerify(sig, common.HashB([]byte("other")), pubKeys))
+	assert.False(t, MultiSigVerify(sig, hash, pubKeys[1:]))
+
+	// a bad partial is detected
+	assert.False(t, MultiSigVerifyPartial(hash, partials[1], publicNonces[0], pubKeys, 0, aggNonce))
+}
@@ -36,103 +36,60 @@ type SignScheme interface {
 //KeyGen Generate PriKey and PubKey
 func (priKey *SchnPrivKey) KeyGen() {
 	if priKey == nil {
-		priKey = new(SchnPrivKey)
+		return
 	}
-	xBytes := RandBytes(32)
-	priKey.SK = new(big.Int).SetBytes(xBytes)
-	priKey.SK.Mod(priKey.SK, Curve.Params().N)
-
-	rBytes := RandBytes(32)
-	priKey.R = new(big.Int).SetBytes(rBytes)
-	priKey.R.Mod(priKey.R, Curve.Params().N)
-
-	priKey.PubKey = new(SchnPubKey)
-
-	genPoint := *new(EllipticPoint)
-	genPoint.X = Curve.Params().Gx
-	genPoint.Y = Curve.Params().Gy
-
-	priKey.PubKey.H = *new(EllipticPoint)
-	priKey.PubKey.H.X, priKey.PubKey.H.Y = Curve.ScalarBaseMult(RandBytes(32))
-	rH := new(EllipticPoint)
-	rH.X, rH.Y = Curve.ScalarMult(priKey.PubKey.H.X, priKey.PubKey.H.Y, priKey.R.Bytes())
-
-	priKey.PubKey.PK = *new(EllipticPoint)
-	priKey.PubKey.PK.X, priKey.PubKey.PK.Y = Curve.ScalarBaseMult(priKey.SK.Bytes())
-	priKey.PubKey.PK.X, priKey.PubKey.PK.Y = Curve.Add(priKey.PubKey.PK.X, priKey.PubKey.PK.Y, rH.X, rH.Y)
-
+	*priKey = *SchnGenPrivKey()
 }
 
 //Sign is function which using for sign on hash array by privatekey
 func (priKey SchnPrivKey) Sign(hash []byte) (*SchnSignature, error) {
-	if len(hash) != 32 {
-		return nil, errors.New("Hash length must be 32 bytes")
-	}
-
-	genPoint := *new(EllipticPoint)
-	genPoint.X = Curve.Params().Gx
-	genPoint.Y = Curve.Params().Gy
-
-	signature := new(SchnSignature)
-
-	k1Bytes := RandBytes(32)
-	k1 := new(big.Int).SetBytes(k1Bytes)
-	k1.Mod(k1, Curve.Params().N)
-
-	k2Bytes := RandBytes(32)
-	k2 := new(big.Int).SetBytes(k2Bytes)
-	k2.Mod(k2, Curve.Params().N)
-
-	t1 := new(EllipticPoint)
-	t1.X, t1.Y = Curve.ScalarMult(Curve.Params().Gx, Curve.Params().Gy, k1.Bytes())
-
-	t2 := new(EllipticPoint)
-	t2.X, t2.Y = Curve.ScalarMult(priKey.PubKey.H.X, priKey.PubKey.H.Y, k2.Bytes())
-
-	t := new(EllipticPoint)
-	t.X, t.Y = Curve.Add(t1.X, t1.Y, t2.X, t2.Y)
-
-	signature.E = Hash(*t, hash)
-
-	xe := new(big.Int)
-	xe.Mul(priKey.SK, signature.E)
-	signature.S1 = new(big.Int)
-	signature.S1.Sub(k1, xe)
-	signature.S1.Mod(signature.S1, Curve.Params().N)
-
-	re := new(big.Int)
-	re.Mul(priKey.R, signature.E)
-	signature.S2 = new(big.Int)
-	signature.S2.Sub(k2, re)
-	signature.S2.Mod(signature.S2, Curve.Params().N)
-
-	return signature, nil
+	return SchnSign(hash, priKey)
 }
 
 //Verify is function which using for verify that the given signature was signed by by privatekey of the public key
 func (pub SchnPubKey) Verify(signature *SchnSignature, hash []byte) bool {
-	if len(hash) != 32 {
-		return false
-	}
+	return SchnVerify(signature, hash, pub)
+}
 
-	if signature == nil {
-		return false
-	}
+//---------------------------------------------------------------------------------------------------------
 
-	rv := new(EllipticPoint)
-	rv.X, rv.Y = Curve.ScalarMult(Curve.Params().Gx, Curve.Params().Gy, signature.S1.Bytes())
-	tmp := new(EllipticPoint)
-	tmp.X, tmp.Y = Curve.ScalarMult(pub.H.X, pub.H.Y, signature.S2.Bytes())
-	rv.X, rv.Y = Curve.Add(rv.X, rv.Y, tmp.X, tmp.Y)
-	tmp.X, tmp.Y = Curve.ScalarMult(pub.PK.X, pub.PK.Y, signature.E.Bytes())
-	rv.X, rv.Y = Curve.Add(rv.X, rv.Y, tmp.X, tmp.Y)
+// Curve helpers of Schnorr signatures, they are shared by multi-signatures
 
-	ev := Hash(*rv, hash)
-	if ev.Cmp(signature.E) == 0 {
-		return true
+// randScalar returns a random non zero scalar of the curve
+func randScalar() *big.Int {
+	for {
+		k := new(big.Int).SetBytes(RandBytes(32))
+		k.Mod(k, Curve.Params().N)
+		if k.Sign() != 0 {
+			return k
+		}
 	}
+}
 
-	return false
+// scalarBytes returns 32 bytes big endian of a scalar
+func scalarBytes(k *big.Int) []byte {
+	b := make([]byte, 32)
+	kBytes := k.Bytes()
+	copy(b[32-len(kBytes):], kBytes)
+	return b
+}
+
+func basePointMult(k *big.Int) *EllipticPoint {
+	p := new(EllipticPoint)
+	p.X, p.Y = Curve.ScalarBaseMult(scalarBytes(k))
+	return p
+}
+
+func pointMult(p *EllipticPoint, k *big.Int) *EllipticPoint {
+	res := new(EllipticPoint)
+	res.X, res.Y = Curve.ScalarMult(p.X, p.Y, scalarBytes(k))
+	return res
+}
+
+func pointAdd(p1 *EllipticPoint, p2 *EllipticPoint) *EllipticPoint {
+	res := new(EllipticPoint)
+	res.X, res.Y = Curve.Add(p1.X, p1.Y, p2.X, p2.Y)
+	return res
 }
 
 //---------------------------------------------------------------------------------------------------------
@@ -140,13 +97,8 @@ func (pub SchnPubKey) Verify(signature *SchnSignature, hash []byte) bool {
 // SchnGenPrivKey generates Schnorr private key
 func SchnGenPrivKey() *SchnPrivKey {
 	priv := new(SchnPrivKey)
-	xBytes := RandBytes(32)
-	priv.SK = new(big.Int).SetBytes(xBytes)
-	priv.SK.Mod(priv.SK, Curve.Params().N)
-
-	rBytes := RandBytes(32)
-	priv.R = new(big.Int).SetBytes(rBytes)
-	priv.R.Mod(priv.R, Curve.Params().N)
+	priv.SK = randScalar()
+	priv.R = randScalar()
 	priv.PubKey = SchnGenPubKey(*priv)
 
 	return priv
@@ -154,19 +106,8 @@ func SchnGenPrivKey() *SchnPrivKey {
 
 func SchnGenPubKey(priv SchnPrivKey) *SchnPubKey {
 	pub := new(SchnPubKey)
-
-	genPoint := *new(EllipticPoint)
-	genPoint.X = Curve.Params().Gx
-	genPoint.Y = Curve.Params().Gy
-
-	pub.H = *new(EllipticPoint)
-	pub.H.X, pub.H.Y = Curve.ScalarBaseMult(RandBytes(32))
-	rH := new(EllipticPoint)
-	rH.X, rH.Y = Curve.ScalarMult(pub.H.X, pub.H.Y, priv.R.Bytes())
-
-	pub.PK = *new(EllipticPoint)
-	pub.PK.X, pub.PK.Y = Curve.ScalarBaseMult(priv.SK.Bytes())
-	pub.PK.X, pub.PK.Y = Curve.Add(pub.PK.X, pub.PK.Y, rH.X, rH.Y)
+	pub.H = *basePointMult(randScalar())
+	pub.PK = *pointAdd(basePointMult(priv.SK), pointMult(&pub.H, priv.R))
 
 	return pub
 }
@@ -176,28 +117,11 @@ func SchnSign(hash []byte, priv SchnPrivKey) (*SchnSignature, error) {
 		return nil, errors.New("Hash length must be 32 bytes")
 	}
 
-	genPoint := *new(EllipticPoint)
-	genPoint.X = Curve.Params().Gx
-	genPoint.Y = Curve.Params().Gy
-
 	signature := new(SchnSignature)
 
-	k1Bytes := RandBytes(32)
-	k1 := new(big.Int).SetBytes(k1Bytes)
-	k1.Mod(k1, Curve.Params().N)
-
-	k2Bytes := RandBytes(32)
-	k2 := new(big.Int).SetBytes(k2Bytes)
-	k2.Mod(k2, Curve.Params().N)
-
-	t1 := new(EllipticPoint)
-	t1.X, t1.Y = Curve.ScalarMult(Curve.Params().Gx, Curve.Params().Gy, k1.Bytes())
-
-	t2 := new(EllipticPoint)
-	t2.X, t2.Y = Curve.ScalarMult(priv.PubKey.H.X, priv.PubKey.H.Y, k2.Bytes())
-
-	t := new(EllipticPoint)
-	t.X, t.Y = Curve.Add(t1.X, t1.Y, t2.X, t2.Y)
+	k1 := randScalar()
+	k2 := randScalar()
+	t := pointAdd(basePointMult(k1), pointMult(&priv.PubKey.H, k2))
 
 	signature.E = Hash(*t, hash)
 
@@ -225,13 +149,8 @@ func SchnVerify(signature *SchnSignature, hash []byte, pub SchnPubKey) bool {
 		return false
 	}
 
-	rv := new(EllipticPoint)
-	rv.X, rv.Y = Curve.ScalarMult(Curve.Params().Gx, Curve.Params().Gy, signature.S1.Bytes())
-	tmp := new(EllipticPoint)
-	tmp.X, tmp.Y = Curve.ScalarMult(pub.H.X, pub.H.Y, signature.S2.Bytes())
-	rv.X, rv.Y = Curve.Add(rv.X, rv.Y, tmp.X, tmp.Y)
-	tmp.X, tmp.Y = Curve.ScalarMult(pub.PK.X, pub.PK.Y, signature.E.Bytes())
-	rv.X, rv.Y = Curve.Add(rv.X, rv.Y, tmp.X, tmp.Y)
+	rv := pointAdd(basePointMult(signature.S1), pointMult(&pub.H, signature.S2))
+	rv = pointAdd(rv, pointMult(&pub.PK, signature.E))
 
 	ev := Hash(*rv, hash)
 	if ev.Cmp(signature.E) == 0 {
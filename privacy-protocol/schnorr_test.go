@@ -0,0 +1,20 @@
+package privacy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchnSign(t *testing.T) {
+	priv := SchnGenPrivKey()
+	hash := RandBytes(32)
+	signature, err := priv.Sign(hash)
+	assert.Nil(t, err)
+	assert.True(t, priv.PubKey.Verify(signature, hash))
+	assert.False(t, priv.PubKey.Verify(signature, RandBytes(32)))
+
+	other := new(SchnPrivKey)
+	other.KeyGen()
+	assert.False(t, other.PubKey.Verify(signature, hash))
+}
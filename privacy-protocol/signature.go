@@ -27,14 +27,18 @@ func Sign(hash []byte, spendingKey []byte) (signature []byte, err error) {
 
 // Verify checks the signature that is signed by secret key corresponding with public key
 func Verify(signature []byte, hash []byte, address []byte) bool {
+	if len(signature) != 64 {
+		return false
+	}
 	r, s := FromByteArrayToSig(signature)
 
 	verKey := new(ecdsa.PublicKey)
 	verKey.Curve = Curve
 
-	point := new(EllipticPoint)
-
-	point, _ = DecompressKey(address)
+	point, err := DecompressKey(address)
+	if err != nil {
+		return false
+	}
 	verKey.X = point.X
 	verKey.Y = point.Y
 
@@ -47,8 +51,12 @@ func Verify(signature []byte, hash []byte, address []byte) bool {
 
 // SigToByteArray converts signature to byte array
 func SigToByteArray(r, s *big.Int) (sig []byte) {
-	sig = append(sig, r.Bytes()...)
-	sig = append(sig, s.Bytes()...)
+	// r and s are padded to 32 bytes each, FromByteArrayToSig splits at 32
+	sig = make([]byte, 64)
+	rBytes := r.Bytes()
+	sBytes := s.Bytes()
+	copy(sig[32-len(rBytes):32], rBytes)
+	copy(sig[64-len(sBytes):], sBytes)
 	return
 }
 
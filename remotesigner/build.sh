@@ -0,0 +1,14 @@
+echo "Start build remotesigner"
+
+echo "go get"
+go get -d
+
+APP_NAME="constant-signer"
+
+echo "go build -o $APP_NAME"
+go build -o $APP_NAME
+
+echo "cp ./$APP_NAME $GOPATH/bin/$APP_NAME"
+mv ./$APP_NAME $GOPATH/bin/$APP_NAME
+
+echo "Build remotesigner success!"
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/jessevdk/go-flags"
+)
+
+// See loadConfig for details on the configuration load process.
+type config struct {
+	ProducerSpendingKey string `long:"producerspendingkey" description:"Producer key which is kept by the signer"`
+	Listen              string `long:"listen" description:"Unix socket which nodes connect with --producersigner"`
+	DataDir             string `long:"datadir" description:"Directory to store double sign protection state"`
+}
+
+// newConfigParser returns a new command line flags parser.
+func newConfigParser(cfg *config, options flags.Options) *flags.Parser {
+	parser := flags.NewParser(cfg, options)
+	return parser
+}
+
+func loadConfig() (*config, error) {
+	cfg := config{
+		Listen:  DefaultListen,
+		DataDir: DefaultDataDir,
+	}
+
+	parser := newConfigParser(&cfg, flags.Default)
+	_, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
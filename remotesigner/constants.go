@@ -0,0 +1,8 @@
+package main
+
+const (
+	Version        = "1.0.0"
+	DefaultListen  = "signer.sock"
+	DefaultDataDir = "signer"
+	ProtectionFile = "protection.json"
+)
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/ninjadotorg/constant/cashec"
+	"github.com/ninjadotorg/constant/signer"
+	"github.com/ninjadotorg/constant/wallet"
+)
+
+var (
+	cfg *config
+)
+
+func main() {
+	// Show version at startup.
+	log.Printf("Version %s\n", Version)
+
+	// load config
+	tcfg, err := loadConfig()
+	if err != nil {
+		log.Println("Parse config error", err.Error())
+		return
+	}
+	cfg = tcfg
+
+	localSigner, err := newLocalSigner()
+	if err != nil {
+		log.Println("Init signer error", err.Error())
+		return
+	}
+
+	// a socket file is left behind when signer was killed
+	os.Remove(cfg.Listen)
+	listener, err := net.Listen("unix", cfg.Listen)
+	if err != nil {
+		log.Println("Listen error", err.Error())
+		return
+	}
+	// only the user of signer and node may talk to it
+	err = os.Chmod(cfg.Listen, 0600)
+	if err != nil {
+		log.Println("Listen error", err.Error())
+		listener.Close()
+		return
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		listener.Close()
+	}()
+
+	log.Printf("Signer of %s listens on %s\n", localSigner.PublicKey(), cfg.Listen)
+	err = signer.Serve(listener, localSigner)
+	if err != nil {
+		log.Println("Serve error", err.Error())
+	}
+}
+
+/*
+newLocalSigner - signer of producer key with double sign protection state of
+data dir
+*/
+func newLocalSigner() (*signer.LocalSigner, error) {
+	if cfg.ProducerSpendingKey == "" {
+		return nil, errors.New("producerspendingkey is required")
+	}
+	key, err := wallet.Base58CheckDeserialize(cfg.ProducerSpendingKey)
+	if err != nil {
+		return nil, err
+	}
+	keySet := cashec.KeySet{}
+	keySet.ImportFromPrivateKey(&key.KeySet.PrivateKey)
+
+	err = os.MkdirAll(cfg.DataDir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	protection, err := signer.NewProtection(filepath.Join(cfg.DataDir, ProtectionFile))
+	if err != nil {
+		return nil, err
+	}
+	return signer.NewLocalSigner(keySet, protection), nil
+}
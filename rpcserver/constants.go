@@ -2,14 +2,26 @@ package rpcserver
 
 // rpc cmd method
 const (
-	GetNetworkInfo     = "getnetworkinfo"
-	GetConnectionCount = "getconnectioncount"
-	GetAllPeers        = "getallpeers"
-	GetRawMempool      = "getrawmempool"
-	GetMempoolEntry    = "getmempoolentry"
-	EstimateFee        = "estimatefee"
-	GetGenerate        = "getgenerate"
-	GetMiningInfo      = "getmininginfo"
+	GetNetworkInfo      = "getnetworkinfo"
+	GetConnectionCount  = "getconnectioncount"
+	GetAllPeers         = "getallpeers"
+	GetRawMempool       = "getrawmempool"
+	GetMempoolEntry     = "getmempoolentry"
+	EstimateFee         = "estimatefee"
+	GetGenerate         = "getgenerate"
+	GetMiningInfo       = "getmininginfo"
+	ListBanned          = "listbanned"
+	SetBan              = "setban"
+	ClearBanned         = "clearbanned"
+	SaveMempool         = "savemempool"
+	LoadMempool         = "loadmempool"
+	GetMempoolTxs       = "getmempooltxs"
+	GetMempoolConflicts = "getmempoolconflicts"
+	SubscribeMempool    = "subscribemempool"
+	PollMempoolEvents   = "pollmempoolevents"
+	UnsubscribeMempool  = "unsubscribemempool"
+	GetValidatorConns   = "getvalidatorconns"
+	GetValidatorUptime  = "getvalidatoruptime"
 
 	GetBestBlock      = "getbestblock"
 	GetBestBlockHash  = "getbestblockhash"
@@ -56,6 +68,7 @@ const (
 	GetBalanceByPrivatekey = "getbalancebyprivatekey"
 	GetReceivedByAccount   = "getreceivedbyaccount"
 	SetTxFee               = "settxfee"
+	BumpFee                = "bumpfee"
 	EncryptData            = "encryptdata"
 
 	// multisig for board spending
@@ -1,5 +1,17 @@
 package jsonresult
 
 type EstimateFeeResult struct {
-	FeeRate map[string]uint64 `json:"FeeRate"`
+	FeeRate   map[string]uint64           `json:"FeeRate"`
+	Estimates map[string]ChainFeeEstimate `json:"Estimates"`
+}
+
+// ChainFeeEstimate is fee per kb for a tx to be included by a chain within
+// WaitSeconds, with low, medium and high confidence
+type ChainFeeEstimate struct {
+	WaitSeconds uint64 `json:"WaitSeconds"`
+	BlockTime   uint64 `json:"BlockTime"`
+	Low         uint64 `json:"Low"`
+	Medium      uint64 `json:"Medium"`
+	High        uint64 `json:"High"`
+	Samples     int    `json:"Samples"`
 }
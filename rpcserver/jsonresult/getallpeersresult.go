@@ -1,5 +1,15 @@
 package jsonresult
 
 type GetAllPeersResult struct {
-	Peers []string `json:"Peers"`
+	Peers     []string         `json:"Peers"`
+	PeersInfo []PeerInfoResult `json:"PeersInfo"`
+}
+
+type PeerInfoResult struct {
+	PeerID     string `json:"PeerID"`
+	RawAddress string `json:"RawAddress"`
+	PublicKey  string `json:"PublicKey"`
+	IsOutbound bool   `json:"IsOutbound"`
+	PingTime   int64  `json:"PingTime"` // round trip time in milliseconds, 0 if unknown
+	BanScore   uint32 `json:"BanScore"`
 }
@@ -8,4 +8,5 @@ type GetMempoolInfo struct {
 	MempoolMinFee uint64   `json:"MempoolMinFee"`
 	MempoolMaxFee uint64   `json:"MempoolMaxFee"`
 	ListTxs       []string `json:"ListTxs"`
+	Orphans       int      `json:"Orphans"`
 }
@@ -0,0 +1,46 @@
+package jsonresult
+
+// MempoolTxResult is a tx in mempool, Added is a unix time and Age is in
+// seconds
+type MempoolTxResult struct {
+	TxID    string `json:"TxID"`
+	Type    string `json:"Type"`
+	ChainID byte   `json:"ChainID"`
+	Fee     uint64 `json:"Fee"`
+	FeeRate uint64 `json:"FeeRate"`
+	Size    uint64 `json:"Size"`
+	Added   int64  `json:"Added"`
+	Age     int64  `json:"Age"`
+	Height  int32  `json:"Height"`
+}
+
+type GetMempoolTxsResult struct {
+	Txs []MempoolTxResult `json:"Txs"`
+}
+
+// MempoolConflictResult is a tx in mempool spending Nullifiers (hex) of the
+// tx asked for
+type MempoolConflictResult struct {
+	Tx         MempoolTxResult `json:"Tx"`
+	Nullifiers []string        `json:"Nullifiers"`
+}
+
+type GetMempoolConflictsResult struct {
+	TxID      string                  `json:"TxID"`
+	Conflicts []MempoolConflictResult `json:"Conflicts"`
+}
+
+// MempoolEventResult is a tx accepted into or removed from mempool, Time is
+// a unix time
+type MempoolEventResult struct {
+	Event  string          `json:"Event"`
+	Reason string          `json:"Reason"`
+	Time   int64           `json:"Time"`
+	Tx     MempoolTxResult `json:"Tx"`
+}
+
+type PollMempoolEventsResult struct {
+	SubscriptionID uint64               `json:"SubscriptionID"`
+	Events         []MempoolEventResult `json:"Events"`
+	Dropped        uint64               `json:"Dropped"`
+}
@@ -0,0 +1,18 @@
+package jsonresult
+
+type ValidatorConnResult struct {
+	PublicKey     string   `json:"PublicKey"`
+	IsCurrent     bool     `json:"IsCurrent"`
+	IsNext        bool     `json:"IsNext"`
+	Connected     bool     `json:"Connected"`
+	RawAddress    string   `json:"RawAddress"`
+	PeerIDs       []string `json:"PeerIDs"`
+	Attempts      int      `json:"Attempts"`
+	LastAttempt   int64    `json:"LastAttempt"`
+	LastConnected int64    `json:"LastConnected"`
+}
+
+type GetValidatorConnsResult struct {
+	Validators  []ValidatorConnResult `json:"Validators"`
+	Unreachable []string              `json:"Unreachable"`
+}
@@ -0,0 +1,16 @@
+package jsonresult
+
+type ValidatorUptimeResult struct {
+	PublicKey          string  `json:"PublicKey"`
+	ChainID            byte    `json:"ChainID"`
+	ProducedBlocks     int     `json:"ProducedBlocks"`
+	MissedSlots        int     `json:"MissedSlots"`
+	CurrentMissedSlots int     `json:"CurrentMissedSlots"`
+	ReliablePts        int     `json:"ReliablePts"`
+	LastBlockTime      int64   `json:"LastBlockTime"`
+	Uptime             float64 `json:"Uptime"`
+}
+
+type GetValidatorUptimeResult struct {
+	Validators []ValidatorUptimeResult `json:"Validators"`
+}
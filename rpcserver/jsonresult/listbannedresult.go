@@ -0,0 +1,12 @@
+package jsonresult
+
+type BannedPeerResult struct {
+	PeerID  string `json:"PeerID"`
+	Reason  string `json:"Reason"`
+	BanTime int64  `json:"BanTime"`
+	Until   int64  `json:"Until"`
+}
+
+type ListBannedResult struct {
+	Banned []BannedPeerResult `json:"Banned"`
+}
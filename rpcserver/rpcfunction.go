@@ -8,13 +8,16 @@ import (
 	"github.com/ninjadotorg/constant/cashec"
 	"github.com/ninjadotorg/constant/common"
 	"github.com/ninjadotorg/constant/common/base58"
+	"github.com/ninjadotorg/constant/mempool"
 	"github.com/ninjadotorg/constant/privacy-protocol"
 	"github.com/ninjadotorg/constant/rpcserver/jsonresult"
 	"github.com/ninjadotorg/constant/transaction"
 	"github.com/ninjadotorg/constant/wallet"
 	"github.com/ninjadotorg/constant/wire"
+	"math"
 	"net"
 	"strconv"
+	"time"
 )
 
 type commandHandler func(RpcServer, interface{}, <-chan struct{}) (interface{}, error)
@@ -22,14 +25,22 @@ type commandHandler func(RpcServer, interface{}, <-chan struct{}) (interface{},
 // Commands valid for normal user
 var RpcHandler = map[string]commandHandler{
 	// node
-	GetNetworkInfo:     RpcServer.handleGetNetWorkInfo,
-	GetConnectionCount: RpcServer.handleGetConnectionCount,
-	GetAllPeers:        RpcServer.handleGetAllPeers,
-	GetRawMempool:      RpcServer.handleGetRawMempool,
-	GetMempoolEntry:    RpcServer.handleMempoolEntry,
-	EstimateFee:        RpcServer.handleEstimateFee,
-	GetGenerate:        RpcServer.handleGetGenerate,
-	GetMiningInfo:      RpcServer.handleGetMiningInfo,
+	GetNetworkInfo:      RpcServer.handleGetNetWorkInfo,
+	GetConnectionCount:  RpcServer.handleGetConnectionCount,
+	GetAllPeers:         RpcServer.handleGetAllPeers,
+	GetRawMempool:       RpcServer.handleGetRawMempool,
+	GetMempoolEntry:     RpcServer.handleMempoolEntry,
+	GetMempoolTxs:       RpcServer.handleGetMempoolTxs,
+	GetMempoolConflicts: RpcServer.handleGetMempoolConflicts,
+	SubscribeMempool:    RpcServer.handleSubscribeMempool,
+	PollMempoolEvents:   RpcServer.handlePollMempoolEvents,
+	UnsubscribeMempool:  RpcServer.handleUnsubscribeMempool,
+	EstimateFee:         RpcServer.handleEstimateFee,
+	GetGenerate:         RpcServer.handleGetGenerate,
+	GetMiningInfo:       RpcServer.handleGetMiningInfo,
+	ListBanned:          RpcServer.handleListBanned,
+	GetValidatorConns:   RpcServer.handleGetValidatorConns,
+	GetValidatorUptime:  RpcServer.handleGetValidatorUptime,
 
 	// block
 	GetBestBlock:      RpcServer.handleGetBestBlock,
@@ -92,7 +103,14 @@ var RpcLimited = map[string]commandHandler{
 	GetBalanceByPrivatekey: RpcServer.handleGetBalanceByPrivatekey,
 	GetReceivedByAccount:   RpcServer.handleGetReceivedByAccount,
 	SetTxFee:               RpcServer.handleSetTxFee,
+	BumpFee:                RpcServer.handleBumpFee,
 	EncryptData:            RpcServer.handleEncryptDataByPaymentAddress,
+
+	// node
+	SetBan:      RpcServer.handleSetBan,
+	ClearBanned: RpcServer.handleClearBanned,
+	SaveMempool: RpcServer.handleSaveMempool,
+	LoadMempool: RpcServer.handleLoadMempool,
 }
 
 type RawVoteBoardDCBTransactionHelper struct{}
@@ -812,12 +830,20 @@ func (self RpcServer) buildRawCustomTokenTransaction(
 	// check real fee per Tx
 	var realFee uint64
 	if int64(estimateFeeCoinPerKb) == -1 {
-		temp, _ := self.config.FeeEstimator[chainIdSender].EstimateFee(numBlock)
-		estimateFeeCoinPerKb = int64(temp)
+		estimateFeeCoinPerKb = 0
+		if feeEstimator, ok := self.config.FeeEstimator[chainIdSender]; ok && feeEstimator != nil {
+			temp, _ := feeEstimator.EstimateFee(numBlock)
+			estimateFeeCoinPerKb = int64(temp)
+		}
 	}
 	estimateFeeCoinPerKb += int64(self.config.Wallet.Config.IncrementalFee)
 	estimateTxSizeInKb := transaction.EstimateTxSize(candidateTxs, nil)
 	realFee = uint64(estimateFeeCoinPerKb) * uint64(estimateTxSizeInKb)
+	// pay at least min fee in fee schedule of sender chain
+	govParams := self.config.BlockChain.BestState[chainIdSender].BestBlock.Header.GOVConstitution.GOVParams
+	if minFee, ok := govParams.MinFee(common.TxCustomTokenType, estimateTxSizeInKb); ok && realFee < minFee {
+		realFee = minFee
+	}
 
 	// list unspent tx for create tx
 	totalAmmount += int64(realFee)
@@ -1125,12 +1151,20 @@ func (self RpcServer) handleCreateRawTransaction(params interface{}, closeChan <
 	// check real fee per Tx
 	var realFee uint64
 	if int64(estimateFeeCoinPerKb) == -1 {
-		temp, _ := self.config.FeeEstimator[chainIdSender].EstimateFee(numBlock)
-		estimateFeeCoinPerKb = int64(temp)
+		estimateFeeCoinPerKb = 0
+		if feeEstimator, ok := self.config.FeeEstimator[chainIdSender]; ok && feeEstimator != nil {
+			temp, _ := feeEstimator.EstimateFee(numBlock)
+			estimateFeeCoinPerKb = int64(temp)
+		}
 	}
 	estimateFeeCoinPerKb += int64(self.config.Wallet.Config.IncrementalFee)
 	estimateTxSizeInKb := transaction.EstimateTxSize(candidateTxs, paymentInfos)
 	realFee = uint64(estimateFeeCoinPerKb) * uint64(estimateTxSizeInKb)
+	// pay at least min fee in fee schedule of sender chain
+	govParams := self.config.BlockChain.BestState[chainIdSender].BestBlock.Header.GOVConstitution.GOVParams
+	if minFee, ok := govParams.MinFee(common.TxNormalType, estimateTxSizeInKb); ok && realFee < minFee {
+		realFee = minFee
+	}
 
 	// list unspent tx for create tx
 	totalAmmount += int64(realFee)
@@ -1263,6 +1297,82 @@ func (self RpcServer) handlCreateAndSendTx(params interface{}, closeChan <-chan
 	return result, nil
 }
 
+/*
+handleBumpFee - RPC creates a tx again with a higher fee to replace a tx stuck
+in mempool, it spends the same nullifiers so nodes with replacement enabled
+drop the old tx
+Parameter #1—private key of sender
+Parameter #2—list receiver, the same as of the stuck tx
+Parameter #3—id of the stuck tx in mempool
+Parameter #4—fee coin per kb, -1 to pay the minimum needed by replacement rules
+Result—id of the new tx
+*/
+func (self RpcServer) handleBumpFee(params interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	Logger.log.Info(params)
+	if !self.config.TxMemPool.IsReplacementEnabled() {
+		return nil, NewRPCError(ErrUnexpected, errors.New("Replacement of txs in mempool is disabled on this node"))
+	}
+	arrayParams := common.InterfaceSlice(params)
+	if len(arrayParams) < 4 {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("Private key, receivers, tx id and fee coin per kb are needed"))
+	}
+
+	// param #3: id of the stuck tx
+	txID, err := common.Hash{}.NewHashFromStr(arrayParams[2].(string))
+	if err != nil {
+		return nil, NewRPCError(ErrRPCInvalidParams, err)
+	}
+	oldTx, err := self.config.TxMemPool.GetTx(txID)
+	if err != nil {
+		return nil, NewRPCError(ErrUnexpected, err)
+	}
+
+	// param #4: fee coin per kb, at least the minimum fee rate of replacement
+	oldFeeRate := mempool.NewCoinPerKilobyte(oldTx.GetTxFee(), oldTx.GetTxVirtualSize())
+	minFeeCoinPerKb := uint64(math.Ceil(float64(oldFeeRate))) + mempool.IncrementalRelayFee
+	feeCoinPerKb := int64(arrayParams[3].(float64))
+	if feeCoinPerKb < int64(minFeeCoinPerKb) {
+		feeCoinPerKb = int64(minFeeCoinPerKb)
+	}
+
+	createParams := []interface{}{arrayParams[0], arrayParams[1], float64(feeCoinPerKb), float64(0)}
+	data, err := self.handleCreateRawTransaction(createParams, closeChan)
+	if err != nil {
+		return nil, NewRPCError(ErrUnexpected, err)
+	}
+	hexStrOfTx := data.(jsonresult.CreateTransactionResult).HexData
+	rawTxBytes, err := hex.DecodeString(hexStrOfTx)
+	if err != nil {
+		return nil, NewRPCError(ErrUnexpected, err)
+	}
+	var newTx transaction.Tx
+	err = json.Unmarshal(rawTxBytes, &newTx)
+	if err != nil {
+		return nil, NewRPCError(ErrUnexpected, err)
+	}
+
+	// the new tx must spend a nullifier of the stuck tx to replace it
+	replaces := false
+	for _, nullifier := range newTx.ListNullifiers() {
+		if ok, _ := common.SliceBytesExists(oldTx.ListNullifiers(), nullifier); ok {
+			replaces = true
+			break
+		}
+	}
+	if !replaces {
+		return nil, NewRPCError(ErrUnexpected, errors.New("New tx does not spend the same notes as the stuck tx, it can not replace it"))
+	}
+
+	sendResult, err := self.handleSendRawTransaction([]interface{}{hexStrOfTx}, closeChan)
+	if err != nil {
+		return nil, NewRPCError(ErrUnexpected, err)
+	}
+	result := jsonresult.CreateTransactionResult{
+		TxID: sendResult.(jsonresult.CreateTransactionResult).TxID,
+	}
+	return result, nil
+}
+
 func assertEligibleAgentIDs(eligibleAgentIDs interface{}) []string {
 	assertedEligibleAgentIDs := eligibleAgentIDs.([]interface{})
 	results := []string{}
@@ -1345,7 +1455,7 @@ func (self RpcServer) handleGetAccountAddress(params interface{}, closeChan <-ch
 }
 
 /*
- dumpprivkey RPC returns the wallet-import-format (WIP) private key corresponding to an address. (But does not remove it from the wallet.)
+	dumpprivkey RPC returns the wallet-import-format (WIP) private key corresponding to an address. (But does not remove it from the wallet.)
 
 Parameter #1—the address corresponding to the private key to get
 Result—the private key
@@ -1402,6 +1512,20 @@ func (self RpcServer) handleGetAllPeers(params interface{}, closeChan <-chan str
 		}
 	}
 	result.Peers = peersMap
+
+	result.PeersInfo = []jsonresult.PeerInfoResult{}
+	for _, listener := range self.config.ConnMgr.ListeningPeers {
+		for _, peerConn := range listener.PeerConns {
+			result.PeersInfo = append(result.PeersInfo, jsonresult.PeerInfoResult{
+				PeerID:     peerConn.RemotePeerID.Pretty(),
+				RawAddress: peerConn.RemoteRawAddress,
+				PublicKey:  peerConn.RemotePeer.PublicKey,
+				IsOutbound: peerConn.IsOutbound,
+				PingTime:   int64(peerConn.PingTime() / time.Millisecond),
+				BanScore:   peerConn.BanScore(),
+			})
+		}
+	}
 	return result, nil
 }
 
@@ -1597,8 +1721,11 @@ func (self RpcServer) handleGetMempoolInfo(params interface{}, closeChan <-chan
 	result := jsonresult.GetMempoolInfo{}
 	result.Size = self.config.TxMemPool.Count()
 	result.Bytes = self.config.TxMemPool.Size()
+	result.MaxMempool = self.config.TxMemPool.MaxSize()
+	result.MempoolMinFee = self.config.TxMemPool.MinFeeRate()
 	result.MempoolMaxFee = self.config.TxMemPool.MaxFee()
 	result.ListTxs = self.config.TxMemPool.ListTxs()
+	result.Orphans = self.config.TxMemPool.OrphanCount()
 	return result, nil
 }
 
@@ -1649,24 +1776,85 @@ func (self RpcServer) handleMempoolEntry(params interface{}, closeChan <-chan st
 
 /*
 handleEstimateFee - RPC estimates the transaction fee per kilobyte that needs to be paid for a transaction to be included within a certain number of blocks.
+Every chain estimates by the time txs waited from mempool to its blocks, FeeRate has the estimate of medium confidence and
+Estimates has low, medium and high confidence bands. A chain which has not seen enough txs yet is left out.
+Parameter #1—how many blocks the transaction may wait before being included
+Parameter #2—(optional) id of the only chain to estimate for
 */
 func (self RpcServer) handleEstimateFee(params interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Param #1: —how many blocks the transaction may wait before being included
 	arrayParams := common.InterfaceSlice(params)
+	if len(arrayParams) < 1 {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("Missing number of blocks"))
+	}
 	numBlock := uint32(arrayParams[0].(float64))
 	result := jsonresult.EstimateFeeResult{
-		FeeRate: make(map[string]uint64),
+		FeeRate:   make(map[string]uint64),
+		Estimates: make(map[string]jsonresult.ChainFeeEstimate),
+	}
+	if len(arrayParams) > 1 {
+		chainID := byte(arrayParams[1].(float64))
+		feeEstimator, ok := self.config.FeeEstimator[chainID]
+		if !ok || feeEstimator == nil {
+			return nil, NewRPCError(ErrUnexpected, fmt.Errorf("No fee estimator for chain %d", chainID))
+		}
+		estimate, err := feeEstimator.EstimateFeeBands(numBlock)
+		if err != nil {
+			return nil, NewRPCError(ErrUnexpected, err)
+		}
+		addChainFeeEstimate(&result, chainID, estimate, feeEstimator.AverageBlockTime())
+		return result, nil
 	}
 	for chainID, feeEstimator := range self.config.FeeEstimator {
-		feeRate, err := feeEstimator.EstimateFee(numBlock)
-		result.FeeRate[strconv.Itoa(int(chainID))] = uint64(feeRate)
+		if feeEstimator == nil {
+			continue
+		}
+		estimate, err := feeEstimator.EstimateFeeBands(numBlock)
 		if err != nil {
-			return -1, NewRPCError(ErrUnexpected, err)
+			Logger.log.Debugf("No fee estimate for chain %d: %v", chainID, err)
+			continue
 		}
+		addChainFeeEstimate(&result, chainID, estimate, feeEstimator.AverageBlockTime())
 	}
 	return result, nil
 }
 
+func addChainFeeEstimate(result *jsonresult.EstimateFeeResult, chainID byte, estimate mempool.FeeEstimate, blockTime time.Duration) {
+	key := strconv.Itoa(int(chainID))
+	result.FeeRate[key] = uint64(estimate.Medium)
+	result.Estimates[key] = jsonresult.ChainFeeEstimate{
+		WaitSeconds: uint64(estimate.Wait / time.Second),
+		BlockTime:   uint64(blockTime / time.Second),
+		Low:         uint64(estimate.Low),
+		Medium:      uint64(estimate.Medium),
+		High:        uint64(estimate.High),
+		Samples:     estimate.Samples,
+	}
+}
+
+/*
+handleSaveMempool - RPC writes txs of mempool into dump file of data dir,
+result is number of saved txs
+*/
+func (self RpcServer) handleSaveMempool(params interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	saved, err := self.config.TxMemPool.Save()
+	if err != nil {
+		return nil, NewRPCError(ErrUnexpected, err)
+	}
+	return saved, nil
+}
+
+/*
+handleLoadMempool - RPC reads txs from dump file of data dir and validates
+them again into mempool, result is number of accepted txs
+*/
+func (self RpcServer) handleLoadMempool(params interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	loaded, err := self.config.TxMemPool.Load()
+	if err != nil {
+		return nil, NewRPCError(ErrUnexpected, err)
+	}
+	return loaded, nil
+}
+
 /*
 handleSetTxFee - RPC sets the transaction fee per kilobyte paid more by transactions created by this wallet. default is 1 coin per 1 kb
 */
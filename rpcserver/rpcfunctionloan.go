@@ -30,11 +30,8 @@ func (self RpcServer) handleCreateRawLoanRequest(params interface{}, closeChan <
 		return nil, NewRPCError(ErrUnexpected, err)
 	}
 
-	// param #2: Fee
+	// param #2: Fee, 0 to pay min fee of loan txs in fee schedule of sender chain
 	fee := uint64(arrayParams[1].(float64))
-	if fee == 0 {
-		fee = self.config.BlockChain.BestState[0].BestBlock.Header.GOVConstitution.GOVParams.BasicSalary
-	}
 	totalAmmount := fee
 
 	// param #3: loan params
@@ -66,6 +63,11 @@ func (self RpcServer) handleCreateRawLoanRequest(params interface{}, closeChan <
 		}
 	}
 
+	if fee == 0 {
+		govParams := self.config.BlockChain.BestState[chainIdSender].BestBlock.Header.GOVConstitution.GOVParams
+		fee, _ = govParams.MinFee(common.TxLoanRequest, transaction.EstimateTxSize(candidateTxs, nil))
+	}
+
 	// get merkleroot commitments, nullifers db, commitments db for every chain
 	nullifiersDb := make(map[byte]([][]byte))
 	commitmentsDb := make(map[byte]([][]byte))
@@ -0,0 +1,300 @@
+package rpcserver
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/mempool"
+	"github.com/ninjadotorg/constant/rpcserver/jsonresult"
+	"github.com/ninjadotorg/constant/transaction"
+)
+
+const (
+	// maxMempoolSubscriptions is the max number of mempool subscriptions
+	// made over rpc at the same time
+	maxMempoolSubscriptions = 100
+
+	// mempoolSubscriptionTimeout is how long a subscription is kept without
+	// being polled
+	mempoolSubscriptionTimeout = 10 * time.Minute
+
+	// maxMempoolPollWait is the max seconds a poll waits for events
+	maxMempoolPollWait = 30
+
+	// maxMempoolPollEvents is the max number of events returned by a poll
+	maxMempoolPollEvents = 1000
+)
+
+type mempoolSubscription struct {
+	subscription *mempool.TxSubscription
+	lastPoll     time.Time
+}
+
+// mempoolSubscriptions keeps subscriptions of mempool made by rpc clients,
+// which poll their events
+type mempoolSubscriptions struct {
+	mtx           sync.Mutex
+	subscriptions map[uint64]*mempoolSubscription
+}
+
+/*
+// expire unsubscribes subscriptions which are not polled for a while, it MUST
+// be called with the lock held
+*/
+func (self *mempoolSubscriptions) expire(now time.Time) {
+	for id, sub := range self.subscriptions {
+		if now.Sub(sub.lastPoll) > mempoolSubscriptionTimeout {
+			Logger.log.Infof("Mempool subscription %d is not polled since %+v, unsubscribe", id, sub.lastPoll)
+			sub.subscription.Unsubscribe()
+			delete(self.subscriptions, id)
+		}
+	}
+}
+
+func newMempoolTxResult(txDesc *mempool.TxDesc, now time.Time) jsonresult.MempoolTxResult {
+	tx := txDesc.Desc.Tx
+	return jsonresult.MempoolTxResult{
+		TxID:    tx.Hash().String(),
+		Type:    tx.GetType(),
+		ChainID: txDesc.ChainID,
+		Fee:     txDesc.Desc.Fee,
+		FeeRate: uint64(mempool.NewCoinPerKilobyte(txDesc.Desc.Fee, tx.GetTxVirtualSize())),
+		Size:    tx.GetTxVirtualSize(),
+		Added:   txDesc.Desc.Added.Unix(),
+		Age:     int64(now.Sub(txDesc.Desc.Added) / time.Second),
+		Height:  txDesc.Desc.Height,
+	}
+}
+
+/*
+handleGetMempoolTxs - RPC returns txs in mempool matching a filter, the oldest first
+Parameter #1—(optional) filter object, every key is optional:
+Types (list of tx types), ChainIDs (list of sender chains), MinFee, MaxFee,
+MinAge and MaxAge (seconds the tx is in mempool)
+*/
+func (self RpcServer) handleGetMempoolTxs(params interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	filter := mempool.TxFilter{}
+	arrayParams := common.InterfaceSlice(params)
+	if len(arrayParams) > 0 && arrayParams[0] != nil {
+		filterParam, ok := arrayParams[0].(map[string]interface{})
+		if !ok {
+			return nil, NewRPCError(ErrRPCInvalidParams, errors.New("Filter is not an object"))
+		}
+		if types, ok := filterParam["Types"]; ok {
+			for _, txType := range common.InterfaceSlice(types) {
+				filter.Types = append(filter.Types, fmt.Sprint(txType))
+			}
+		}
+		if chainIDs, ok := filterParam["ChainIDs"]; ok {
+			for _, chainID := range common.InterfaceSlice(chainIDs) {
+				value, ok := chainID.(float64)
+				if !ok {
+					return nil, NewRPCError(ErrRPCInvalidParams, fmt.Errorf("Wrong chain id %+v", chainID))
+				}
+				filter.ChainIDs = append(filter.ChainIDs, byte(value))
+			}
+		}
+		if value, ok := filterParam["MinFee"].(float64); ok {
+			filter.MinFee = uint64(value)
+		}
+		if value, ok := filterParam["MaxFee"].(float64); ok {
+			filter.MaxFee = uint64(value)
+		}
+		if value, ok := filterParam["MinAge"].(float64); ok {
+			filter.MinAge = time.Duration(value) * time.Second
+		}
+		if value, ok := filterParam["MaxAge"].(float64); ok {
+			filter.MaxAge = time.Duration(value) * time.Second
+		}
+	}
+
+	now := time.Now()
+	result := jsonresult.GetMempoolTxsResult{
+		Txs: []jsonresult.MempoolTxResult{},
+	}
+	for _, txDesc := range self.config.TxMemPool.QueryTxs(filter) {
+		result.Txs = append(result.Txs, newMempoolTxResult(txDesc, now))
+	}
+	return result, nil
+}
+
+/*
+handleGetMempoolConflicts - RPC returns txs in mempool which spend the same nullifiers as a tx,
+such a tx is rejected as double spend or replaces them when replacement is enabled
+Parameter #1—a serialized transaction
+Parameter #2—(optional) type of the transaction, normal tx by default
+*/
+func (self RpcServer) handleGetMempoolConflicts(params interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	arrayParams := common.InterfaceSlice(params)
+	if len(arrayParams) < 1 {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("Missing serialized transaction"))
+	}
+	hexRawTx, ok := arrayParams[0].(string)
+	if !ok {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("Serialized transaction is not a string"))
+	}
+	txType := common.TxNormalType
+	if len(arrayParams) > 1 {
+		txType, ok = arrayParams[1].(string)
+		if !ok {
+			return nil, NewRPCError(ErrRPCInvalidParams, errors.New("Transaction type is not a string"))
+		}
+	}
+	rawTxBytes, err := hex.DecodeString(hexRawTx)
+	if err != nil {
+		return nil, NewRPCError(ErrRPCInvalidParams, err)
+	}
+	tx, err := transaction.NewTxByType(txType)
+	if err != nil {
+		return nil, NewRPCError(ErrRPCInvalidParams, err)
+	}
+	err = json.Unmarshal(rawTxBytes, tx)
+	if err != nil {
+		return nil, NewRPCError(ErrRPCInvalidParams, err)
+	}
+
+	now := time.Now()
+	result := jsonresult.GetMempoolConflictsResult{
+		TxID:      tx.Hash().String(),
+		Conflicts: []jsonresult.MempoolConflictResult{},
+	}
+	for _, conflict := range self.config.TxMemPool.Conflicts(tx) {
+		conflictResult := jsonresult.MempoolConflictResult{
+			Tx: newMempoolTxResult(conflict.TxDesc, now),
+		}
+		for _, nullifier := range conflict.Nullifiers {
+			conflictResult.Nullifiers = append(conflictResult.Nullifiers, hex.EncodeToString(nullifier))
+		}
+		result.Conflicts = append(result.Conflicts, conflictResult)
+	}
+	return result, nil
+}
+
+/*
+handleSubscribeMempool - RPC subscribes to txs accepted into and removed from mempool,
+result is the subscription id to poll events with pollmempoolevents. A subscription
+which is not polled for 10 minutes is unsubscribed
+*/
+func (self RpcServer) handleSubscribeMempool(params interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	self.mempoolSubscriptions.mtx.Lock()
+	defer self.mempoolSubscriptions.mtx.Unlock()
+	now := time.Now()
+	self.mempoolSubscriptions.expire(now)
+	if len(self.mempoolSubscriptions.subscriptions) >= maxMempoolSubscriptions {
+		return nil, NewRPCError(ErrUnexpected, fmt.Errorf("Too many mempool subscriptions, max %d", maxMempoolSubscriptions))
+	}
+	subscription := self.config.TxMemPool.Subscribe(mempool.DefaultTxSubscriptionBuffer)
+	self.mempoolSubscriptions.subscriptions[subscription.ID] = &mempoolSubscription{
+		subscription: subscription,
+		lastPoll:     now,
+	}
+	return subscription.ID, nil
+}
+
+/*
+handlePollMempoolEvents - RPC returns events of a mempool subscription since the last poll,
+Dropped is the number of events lost because the subscription was not polled fast enough
+Parameter #1—subscription id
+Parameter #2—(optional) seconds to wait for an event when there is none, at most 30
+*/
+func (self RpcServer) handlePollMempoolEvents(params interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	arrayParams := common.InterfaceSlice(params)
+	if len(arrayParams) < 1 {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("Missing subscription id"))
+	}
+	id, ok := arrayParams[0].(float64)
+	if !ok {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("Subscription id is not a number"))
+	}
+	wait := 0
+	if len(arrayParams) > 1 {
+		value, ok := arrayParams[1].(float64)
+		if !ok {
+			return nil, NewRPCError(ErrRPCInvalidParams, errors.New("Wait time is not a number"))
+		}
+		wait = int(value)
+		if wait > maxMempoolPollWait {
+			wait = maxMempoolPollWait
+		}
+	}
+
+	self.mempoolSubscriptions.mtx.Lock()
+	sub, ok := self.mempoolSubscriptions.subscriptions[uint64(id)]
+	if ok {
+		sub.lastPoll = time.Now()
+	}
+	self.mempoolSubscriptions.mtx.Unlock()
+	if !ok {
+		return nil, NewRPCError(ErrRPCInvalidParams, fmt.Errorf("No mempool subscription %d", uint64(id)))
+	}
+
+	result := jsonresult.PollMempoolEventsResult{
+		SubscriptionID: sub.subscription.ID,
+		Events:         []jsonresult.MempoolEventResult{},
+	}
+	events := sub.subscription.Events()
+	if wait > 0 && len(events) == 0 {
+		timer := time.NewTimer(time.Duration(wait) * time.Second)
+		defer timer.Stop()
+		select {
+		case event, ok := <-events:
+			if ok {
+				result.Events = append(result.Events, newMempoolEventResult(event))
+			}
+		case <-timer.C:
+		case <-closeChan:
+		}
+	}
+drain:
+	for len(result.Events) < maxMempoolPollEvents {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				break drain
+			}
+			result.Events = append(result.Events, newMempoolEventResult(event))
+		default:
+			break drain
+		}
+	}
+	result.Dropped = sub.subscription.Dropped()
+	return result, nil
+}
+
+func newMempoolEventResult(event *mempool.TxEvent) jsonresult.MempoolEventResult {
+	return jsonresult.MempoolEventResult{
+		Event:  event.Type.String(),
+		Reason: event.Reason,
+		Time:   event.Time.Unix(),
+		Tx:     newMempoolTxResult(event.TxDesc, event.Time),
+	}
+}
+
+/*
+handleUnsubscribeMempool - RPC removes a mempool subscription
+Parameter #1—subscription id
+*/
+func (self RpcServer) handleUnsubscribeMempool(params interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	arrayParams := common.InterfaceSlice(params)
+	if len(arrayParams) < 1 {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("Missing subscription id"))
+	}
+	id, ok := arrayParams[0].(float64)
+	if !ok {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("Subscription id is not a number"))
+	}
+	self.mempoolSubscriptions.mtx.Lock()
+	defer self.mempoolSubscriptions.mtx.Unlock()
+	sub, ok := self.mempoolSubscriptions.subscriptions[uint64(id)]
+	if !ok {
+		return false, nil
+	}
+	sub.subscription.Unsubscribe()
+	delete(self.mempoolSubscriptions.subscriptions, uint64(id))
+	return true, nil
+}
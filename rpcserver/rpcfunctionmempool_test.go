@@ -0,0 +1,157 @@
+package rpcserver
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/database"
+	_ "github.com/ninjadotorg/constant/database/lvdb"
+	"github.com/ninjadotorg/constant/mempool"
+	"github.com/ninjadotorg/constant/rpcserver/jsonresult"
+	"github.com/ninjadotorg/constant/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestMempoolServer - rpc server of a mempool which validates txs with
+// test net genesis in a temporary database
+func newTestMempoolServer(t *testing.T) (*RpcServer, func()) {
+	backendLog := common.NewBackend(ioutil.Discard)
+	blockchain.Logger.Init(backendLog.Logger("blockChain log"))
+	database.Logger.Init(backendLog.Logger("Database Log"))
+	mempool.Logger.Init(common.Disabled)
+	Logger.Init(common.Disabled)
+
+	dir, err := ioutil.TempDir(common.EmptyString, "rpcserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.Open("leveldb", dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	cleanup := func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+	params := blockchain.TestNetParams
+	chain := &blockchain.BlockChain{}
+	err = chain.Init(&blockchain.Config{
+		ChainParams: &params,
+		DataBase:    db,
+	})
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	txPool := &mempool.TxPool{}
+	txPool.Init(&mempool.Config{
+		BlockChain: chain,
+		DataBase:   db,
+		Policy:     mempool.Policy{BlockChain: chain, MaxTxVersion: transaction.TxVersion},
+	})
+	server := &RpcServer{}
+	server.Init(&RpcServerConfig{TxMemPool: txPool})
+	return server, cleanup
+}
+
+// newTestMempoolTx returns a normal tx which the test mempool accepts
+func newTestMempoolTx(lockTime int64) *transaction.Tx {
+	return &transaction.Tx{
+		Version:  transaction.TxVersion,
+		Type:     common.TxNormalType,
+		LockTime: lockTime,
+		Fee:      1000000,
+		JSPubKey: make([]byte, 64),
+		JSSig:    make([]byte, 64),
+	}
+}
+
+func pollMempoolEvents(t *testing.T, server *RpcServer, params ...interface{}) jsonresult.PollMempoolEventsResult {
+	result, err := server.handlePollMempoolEvents(params, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.(jsonresult.PollMempoolEventsResult)
+}
+
+func TestMempoolSubscription(t *testing.T) {
+	server, cleanup := newTestMempoolServer(t)
+	defer cleanup()
+	result, err := server.handleSubscribeMempool(nil, nil)
+	assert.Nil(t, err)
+	id := float64(result.(uint64))
+	poll := pollMempoolEvents(t, server, id)
+	assert.Equal(t, uint64(id), poll.SubscriptionID)
+	assert.Equal(t, 0, len(poll.Events))
+
+	// events since the last poll, the oldest first
+	tx := newTestMempoolTx(1)
+	_, _, err = server.config.TxMemPool.MaybeAcceptTransaction(tx)
+	assert.Nil(t, err)
+	server.config.TxMemPool.RemoveTx(tx)
+	poll = pollMempoolEvents(t, server, id)
+	if assert.Equal(t, 2, len(poll.Events)) {
+		assert.Equal(t, "accepted", poll.Events[0].Event)
+		assert.Equal(t, tx.Hash().String(), poll.Events[0].Tx.TxID)
+		assert.Equal(t, tx.Fee, poll.Events[0].Tx.Fee)
+		assert.Equal(t, "removed", poll.Events[1].Event)
+		assert.Equal(t, mempool.TxRemovedByCaller, poll.Events[1].Reason)
+	}
+	assert.Equal(t, uint64(0), poll.Dropped)
+	assert.Equal(t, 0, len(pollMempoolEvents(t, server, id).Events))
+
+	// a poll waits for the next event
+	tx = newTestMempoolTx(2)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		server.config.TxMemPool.MaybeAcceptTransaction(tx)
+	}()
+	poll = pollMempoolEvents(t, server, id, float64(5))
+	if assert.Equal(t, 1, len(poll.Events)) {
+		assert.Equal(t, tx.Hash().String(), poll.Events[0].Tx.TxID)
+	}
+
+	result, err = server.handleUnsubscribeMempool([]interface{}{id}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, true, result)
+	result, err = server.handleUnsubscribeMempool([]interface{}{id}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, false, result)
+	_, err = server.handlePollMempoolEvents([]interface{}{id}, nil)
+	assert.Equal(t, ErrCodeMessage[ErrRPCInvalidParams].code, err.(*RPCError).Code)
+}
+
+func TestMempoolSubscription_Limits(t *testing.T) {
+	server, cleanup := newTestMempoolServer(t)
+	defer cleanup()
+	for _, params := range [][]interface{}{nil, {"1"}, {float64(1), "1"}, {float64(1)}} {
+		_, err := server.handlePollMempoolEvents(params, nil)
+		assert.Equal(t, ErrCodeMessage[ErrRPCInvalidParams].code, err.(*RPCError).Code, params)
+	}
+	for _, params := range [][]interface{}{nil, {"1"}} {
+		_, err := server.handleUnsubscribeMempool(params, nil)
+		assert.Equal(t, ErrCodeMessage[ErrRPCInvalidParams].code, err.(*RPCError).Code, params)
+	}
+
+	for i := 0; i < maxMempoolSubscriptions; i++ {
+		_, err := server.handleSubscribeMempool(nil, nil)
+		assert.Nil(t, err)
+	}
+	_, err := server.handleSubscribeMempool(nil, nil)
+	assert.Equal(t, ErrCodeMessage[ErrUnexpected].code, err.(*RPCError).Code)
+
+	// a subscription which isn't polled for a while is unsubscribed
+	old := server.mempoolSubscriptions.subscriptions[1]
+	old.lastPoll = time.Now().Add(-mempoolSubscriptionTimeout - time.Second)
+	_, err = server.handleSubscribeMempool(nil, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, server.mempoolSubscriptions.subscriptions[1])
+	assert.Equal(t, maxMempoolSubscriptions, len(server.mempoolSubscriptions.subscriptions))
+	_, ok := <-old.subscription.Events()
+	assert.False(t, ok)
+}
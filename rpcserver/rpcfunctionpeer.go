@@ -0,0 +1,147 @@
+package rpcserver
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/rpcserver/jsonresult"
+)
+
+/*
+handleListBanned - return peers which are banned by this node
+*/
+func (self RpcServer) handleListBanned(params interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	result := jsonresult.ListBannedResult{
+		Banned: []jsonresult.BannedPeerResult{},
+	}
+	for _, banned := range self.config.ConnMgr.BannedPeers() {
+		result.Banned = append(result.Banned, jsonresult.BannedPeerResult{
+			PeerID:  banned.PeerID,
+			Reason:  banned.Reason,
+			BanTime: banned.BanTime.Unix(),
+			Until:   banned.Until.Unix(),
+		})
+	}
+	return result, nil
+}
+
+/*
+handleSetBan - add or remove a peer id from ban list
+params: peer id, "add" or "remove", ban time in seconds (optional, 0 is default ban duration)
+*/
+func (self RpcServer) handleSetBan(params interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	Logger.log.Info(params)
+	arrayParams := common.InterfaceSlice(params)
+	if len(arrayParams) < 2 {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("peer id and command are required"))
+	}
+	peerID, ok := arrayParams[0].(string)
+	if !ok || peerID == "" {
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("invalid peer id"))
+	}
+	command, _ := arrayParams[1].(string)
+
+	switch command {
+	case "add":
+		banTime := time.Duration(0)
+		if len(arrayParams) > 2 {
+			seconds, ok := arrayParams[2].(float64)
+			if !ok || seconds < 0 {
+				return nil, NewRPCError(ErrRPCInvalidParams, errors.New("invalid ban time"))
+			}
+			banTime = time.Duration(seconds) * time.Second
+		}
+		err := self.config.ConnMgr.BanPeer(peerID, banTime, "manually banned")
+		if err != nil {
+			return false, NewRPCError(ErrUnexpected, err)
+		}
+		return true, nil
+	case "remove":
+		removed, err := self.config.ConnMgr.UnbanPeer(peerID)
+		if err != nil {
+			return false, NewRPCError(ErrUnexpected, err)
+		}
+		if !removed {
+			return false, NewRPCError(ErrRPCInvalidParams, errors.New("peer is not banned"))
+		}
+		return true, nil
+	default:
+		return nil, NewRPCError(ErrRPCInvalidParams, errors.New("command must be add or remove"))
+	}
+}
+
+/*
+handleClearBanned - remove all peers from ban list
+*/
+func (self RpcServer) handleClearBanned(params interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	err := self.config.ConnMgr.ClearBanned()
+	if err != nil {
+		return false, NewRPCError(ErrUnexpected, err)
+	}
+	return true, nil
+}
+
+/*
+handleGetValidatorConns - health report of connections to current and next
+committee members, Unreachable lists public keys without any connection
+*/
+func (self RpcServer) handleGetValidatorConns(params interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	result := jsonresult.GetValidatorConnsResult{
+		Validators:  []jsonresult.ValidatorConnResult{},
+		Unreachable: []string{},
+	}
+	validators := self.config.ConnMgr.ValidatorConns()
+	sort.Slice(validators, func(i, j int) bool {
+		return validators[i].PublicKey < validators[j].PublicKey
+	})
+	for _, validator := range validators {
+		item := jsonresult.ValidatorConnResult{
+			PublicKey:  validator.PublicKey,
+			IsCurrent:  validator.IsCurrent,
+			IsNext:     validator.IsNext,
+			Connected:  validator.Connected(),
+			RawAddress: validator.RawAddress,
+			PeerIDs:    validator.PeerIDs,
+			Attempts:   validator.Attempts,
+		}
+		if !validator.LastAttempt.IsZero() {
+			item.LastAttempt = validator.LastAttempt.Unix()
+		}
+		if !validator.LastConnected.IsZero() {
+			item.LastConnected = validator.LastConnected.Unix()
+		}
+		result.Validators = append(result.Validators, item)
+		if !validator.Connected() {
+			result.Unreachable = append(result.Unreachable, validator.PublicKey)
+		}
+	}
+	return result, nil
+}
+
+/*
+handleGetValidatorUptime - produced blocks and missed slots of validators on
+every chain, CurrentMissedSlots is counted for chain leaders only
+*/
+func (self RpcServer) handleGetValidatorUptime(params interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	result := jsonresult.GetValidatorUptimeResult{
+		Validators: []jsonresult.ValidatorUptimeResult{},
+	}
+	if self.config.ConsensusEngine == nil {
+		return result, nil
+	}
+	for _, validator := range self.config.ConsensusEngine.GetValidatorsLiveness() {
+		result.Validators = append(result.Validators, jsonresult.ValidatorUptimeResult{
+			PublicKey:          validator.PublicKey,
+			ChainID:            validator.ChainID,
+			ProducedBlocks:     validator.ProducedBlocks,
+			MissedSlots:        validator.MissedSlots,
+			CurrentMissedSlots: validator.CurrentMissedSlots,
+			ReliablePts:        validator.ReliablePts,
+			LastBlockTime:      validator.LastBlockTime,
+			Uptime:             validator.Uptime(),
+		})
+	}
+	return result, nil
+}
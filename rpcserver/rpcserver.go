@@ -24,6 +24,7 @@ import (
 	"github.com/ninjadotorg/constant/blockchain"
 	"github.com/ninjadotorg/constant/common"
 	"github.com/ninjadotorg/constant/connmanager"
+	"github.com/ninjadotorg/constant/consensus/ppos"
 	"github.com/ninjadotorg/constant/database"
 	"github.com/ninjadotorg/constant/mempool"
 	"github.com/ninjadotorg/constant/wallet"
@@ -57,6 +58,9 @@ type RpcServer struct {
 	authSHA      [sha256.Size]byte
 	limitAuthSHA [sha256.Size]byte
 
+	// subscriptions of mempool events polled by clients
+	mempoolSubscriptions *mempoolSubscriptions
+
 	// channel
 	cRequestProcessShutdown chan struct{}
 }
@@ -91,11 +95,16 @@ type RpcServerConfig struct {
 	// The fee estimator keeps track of how long transactions are left in
 	// the mempool before they are mined into blocks.
 	FeeEstimator map[byte]*mempool.FeeEstimator
+
+	ConsensusEngine *ppos.Engine
 }
 
 func (self *RpcServer) Init(config *RpcServerConfig) {
 	self.config = *config
 	self.statusLines = make(map[int]string)
+	self.mempoolSubscriptions = &mempoolSubscriptions{
+		subscriptions: make(map[uint64]*mempoolSubscription),
+	}
 	if config.RPCUser != "" && config.RPCPass != common.EmptyString {
 		login := config.RPCUser + ":" + config.RPCPass
 		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
@@ -8,6 +8,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -16,6 +17,7 @@ import (
 	peer2 "github.com/libp2p/go-libp2p-peer"
 	"github.com/ninjadotorg/constant/addrmanager"
 	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/cashec"
 	"github.com/ninjadotorg/constant/common"
 	"github.com/ninjadotorg/constant/common/base58"
 	"github.com/ninjadotorg/constant/connmanager"
@@ -26,6 +28,7 @@ import (
 	"github.com/ninjadotorg/constant/peer"
 	"github.com/ninjadotorg/constant/rewardagent"
 	"github.com/ninjadotorg/constant/rpcserver"
+	"github.com/ninjadotorg/constant/signer"
 	"github.com/ninjadotorg/constant/transaction"
 	"github.com/ninjadotorg/constant/wallet"
 	"github.com/ninjadotorg/constant/wire"
@@ -47,6 +50,7 @@ type Server struct {
 	addrManager     *addrmanager.AddrManager
 	wallet          *wallet.Wallet
 	consensusEngine *ppos.Engine
+	producerSigner  signer.Signer
 	blockgen        *blockchain.BlkTmplGenerator
 	rewardAgent     *rewardagent.RewardAgent
 	// The fee estimator keeps track of how long transactions are left in
@@ -121,6 +125,10 @@ func (self *Server) NewServer(listenAddrs []string, db database.DatabaseInterfac
 	self.dataBase = db
 
 	var err error
+	self.producerSigner, err = cfg.GetProducerSigner()
+	if err != nil {
+		return err
+	}
 
 	// Create a new block chain instance with the appropriate configuration.9
 	if cfg.Light {
@@ -157,7 +165,6 @@ func (self *Server) NewServer(listenAddrs []string, db database.DatabaseInterfac
 					Logger.log.Errorf("Failed to restore fee estimator %v", err)
 					Logger.log.Info("Init NewFeeEstimator")
 					self.feeEstimator[chainID] = mempool.NewFeeEstimator(
-						mempool.DefaultEstimateFeeMaxRollback,
 						mempool.DefaultEstimateFeeMinRegisteredBlocks)
 				} else {
 					self.feeEstimator[chainID] = feeEstimator
@@ -195,6 +202,13 @@ func (self *Server) NewServer(listenAddrs []string, db database.DatabaseInterfac
 		DataBase:     self.dataBase,
 		ChainParams:  chainParams,
 		FeeEstimator: self.feeEstimator,
+		MaxSize:      cfg.MaxMempoolSize * 1024,
+		MaxTxs:       cfg.MaxMempoolTxs,
+		MaxTxAge:     cfg.MempoolExpiry,
+
+		EnableReplacement: cfg.MempoolReplace,
+		DumpFile:          filepath.Join(cfg.DataDir, mempool.DumpFileName),
+		MaxOrphanTxs:      cfg.MaxOrphanTxs,
 	})
 
 	self.addrManager = addrmanager.New(cfg.DataDir)
@@ -218,6 +232,7 @@ func (self *Server) NewServer(listenAddrs []string, db database.DatabaseInterfac
 		Server:       self,
 		FeeEstimator: self.feeEstimator,
 		BlockGen:     self.blockgen,
+		DataBase:     self.dataBase,
 	})
 	if err != nil {
 		return err
@@ -244,13 +259,28 @@ func (self *Server) NewServer(listenAddrs []string, db database.DatabaseInterfac
 		}
 	}
 
+	// Only connect to specified peers when connect peers are configured,
+	// otherwise outbound peers are picked from the address manager.
+	var addrManager *addrmanager.AddrManager
+	if len(cfg.ConnectPeers) == 0 {
+		addrManager = self.addrManager
+	}
+
 	connManager := connmanager.ConnManager{}.New(&connmanager.Config{
-		OnInboundAccept:      self.InboundPeerConnected,
-		OnOutboundConnection: self.OutboundPeerConnected,
-		ListenerPeers:        peers,
-		DiscoverPeers:        cfg.DiscoverPeers,
-		DiscoverPeersAddress: cfg.DiscoverPeersAddress,
+		OnInboundAccept:        self.InboundPeerConnected,
+		OnOutboundConnection:   self.OutboundPeerConnected,
+		ListenerPeers:          peers,
+		DiscoverPeers:          cfg.DiscoverPeers,
+		DiscoverPeersAddresses: cfg.discoverPeersAddresses(),
+		DataDir:                cfg.DataDir,
+		BanDuration:            cfg.BanDuration,
+		AddrManager:            addrManager,
+		TargetOutbound:         cfg.MaxOutPeers,
 	})
+	// Validators keep direct connections to current and next committee
+	if self.producerSigner != nil {
+		connManager.Config.Committee = self.consensusEngine
+	}
 	self.connManager = connManager
 
 	// Start up persistent peers.
@@ -293,6 +323,7 @@ func (self *Server) NewServer(listenAddrs []string, db database.DatabaseInterfac
 			IsGenerateNode:  cfg.Generate,
 			FeeEstimator:    self.feeEstimator,
 			ProtocolVersion: self.protocolVersion,
+			ConsensusEngine: self.consensusEngine,
 		}
 		self.rpcServer = &rpcserver.RpcServer{}
 		self.rpcServer.Init(&rpcConfig)
@@ -349,6 +380,16 @@ func (self Server) Stop() error {
 		self.rpcServer.Stop()
 	}
 
+	// Save txs of mempool to load them on next start
+	if !cfg.NoPersistPool {
+		saved, err := self.memPool.Save()
+		if err != nil {
+			Logger.log.Errorf("Can't save mempool: %v", err)
+		} else {
+			Logger.log.Infof("Save %d txs of mempool", saved)
+		}
+	}
+
 	// Save fee estimator in the db
 	for chainId, feeEstimator := range self.feeEstimator {
 		feeEstimatorData := feeEstimator.Save()
@@ -385,13 +426,7 @@ func (self Server) peerHandler() {
 
 	Logger.log.Info("Start peer handler")
 
-	if len(cfg.ConnectPeers) == 0 {
-		for _, addr := range self.addrManager.AddressCache() {
-			go self.connManager.Connect(addr.RawAddress, addr.PublicKey)
-		}
-	}
-
-	go self.connManager.Start(cfg.DiscoverPeersAddress)
+	go self.connManager.Start(cfg.discoverPeersAddresses())
 
 out:
 	for {
@@ -453,13 +488,17 @@ func (self Server) Start() {
 		go self.Stop()
 		return
 	}
-	if cfg.Generate == true && (len(cfg.ProducerSpendingKey) > 0) {
-		producerKeySet, err := cfg.GetProducerKeySet()
+	// txs of the last run are validated again with the validated chain
+	if !cfg.NoPersistPool {
+		loaded, err := self.memPool.Load()
 		if err != nil {
-			Logger.log.Critical(err)
-			return
+			Logger.log.Errorf("Can't load mempool: %v", err)
+		} else {
+			Logger.log.Infof("Load %d txs into mempool", loaded)
 		}
-		self.consensusEngine.StartProducer(*producerKeySet)
+	}
+	if cfg.Generate == true && self.producerSigner != nil {
+		self.consensusEngine.StartProducer(self.producerSigner)
 		self.consensusEngine.StartSwap()
 	}
 }
@@ -521,10 +560,6 @@ func (self *Server) InitListenerPeers(amgr *addrmanager.AddrManager, listenAddrs
 // newPeerConfig returns the configuration for the listening RemotePeer.
 */
 func (self *Server) NewPeerConfig() *peer.Config {
-	KeySetProducer, err := cfg.GetProducerKeySet()
-	if err != nil {
-		Logger.log.Critical(err)
-	}
 	config := &peer.Config{
 		MessageListeners: peer.MessageListeners{
 			OnBlock:     self.OnBlock,
@@ -538,6 +573,7 @@ func (self *Server) NewPeerConfig() *peer.Config {
 			//ppos
 			OnRequestSign:   self.OnRequestSign,
 			OnInvalidBlock:  self.OnInvalidBlock,
+			OnEvidence:      self.OnEvidence,
 			OnBlockSig:      self.OnBlockSig,
 			OnGetChainState: self.OnGetChainState,
 			OnChainState:    self.OnChainState,
@@ -547,10 +583,9 @@ func (self *Server) NewPeerConfig() *peer.Config {
 			OnSwapSig:      self.OnSwapSig,
 			OnSwapUpdate:   self.OnSwapUpdate,
 		},
+		Net: self.chainParams.Net,
 	}
-	if len(KeySetProducer.PrivateKey) != 0 {
-		config.ProducerPrvKey = base58.Base58Check{}.Encode(KeySetProducer.PrivateKey, byte(0x00))
-	}
+	config.ProducerSigner = self.producerSigner
 	return config
 }
 
@@ -600,6 +635,9 @@ func (self Server) OnTx(peer *peer.PeerConn, msg *wire.MessageTx) {
 
 func (self Server) OnSwapRequest(peer *peer.PeerConn, msg *wire.MessageSwapRequest) {
 	Logger.log.Info("Receive a new request swap START")
+	if !self.checkConsensusSender(peer, msg.Requester, msg.MessageType()) {
+		return
+	}
 	var txProcessed chan struct{}
 	self.netSync.QueueMessage(nil, msg, txProcessed)
 	Logger.log.Info("Receive a new request swap END")
@@ -607,6 +645,9 @@ func (self Server) OnSwapRequest(peer *peer.PeerConn, msg *wire.MessageSwapReque
 
 func (self Server) OnSwapSig(peer *peer.PeerConn, msg *wire.MessageSwapSig) {
 	Logger.log.Info("Receive a new sign swap START")
+	if !self.checkConsensusSender(peer, msg.Validator, msg.MessageType()) {
+		return
+	}
 	var txProcessed chan struct{}
 	self.netSync.QueueMessage(nil, msg, txProcessed)
 	Logger.log.Info("Receive a new sign swap END")
@@ -614,11 +655,33 @@ func (self Server) OnSwapSig(peer *peer.PeerConn, msg *wire.MessageSwapSig) {
 
 func (self Server) OnSwapUpdate(peer *peer.PeerConn, msg *wire.MessageSwapUpdate) {
 	Logger.log.Info("Receive a new update swap START")
+	if !self.checkConsensusSender(peer, msg.Requester, msg.MessageType()) {
+		return
+	}
 	var txProcessed chan struct{}
 	self.netSync.QueueMessage(nil, msg, txProcessed)
 	Logger.log.Info("Receive a new update swap END")
 }
 
+/*
+checkConsensusSender - consensus messages are sent directly by the validator
+they claim to come from, reject them when that validator is not the key
+authenticated in handshake of the connection. Peers older than
+ProtocolVersionAuth can not authenticate so they are not penalised for it
+*/
+func (self *Server) checkConsensusSender(peerConn *peer.PeerConn, publicKey string, cmd string) bool {
+	authPublicKey := peerConn.AuthPublicKey()
+	if authPublicKey != "" && authPublicKey == publicKey {
+		return true
+	}
+	Logger.log.Errorf("Reject %s of %s from %s authenticated as %s", cmd, publicKey, peerConn.RemotePeerID.Pretty(), authPublicKey)
+	if peerConn.WireVersion() < wire.ProtocolVersionAuth {
+		return false
+	}
+	peerConn.AddBanScore(0, peer.BanScoreUnauthenticatedConsensus, cmd+" from unauthenticated validator")
+	return false
+}
+
 /*
 // OnVersion is invoked when a peer receives a version message
 // and is used to negotiate the protocol version details as well as kick start
@@ -627,18 +690,6 @@ func (self Server) OnSwapUpdate(peer *peer.PeerConn, msg *wire.MessageSwapUpdate
 func (self *Server) OnVersion(peerConn *peer.PeerConn, msg *wire.MessageVersion) {
 	Logger.log.Info("Receive version message START")
 
-	remotePeer := &peer.Peer{
-		ListeningAddress: msg.LocalAddress,
-		RawAddress:       msg.RawLocalAddress,
-		PeerID:           msg.LocalPeerId,
-		PublicKey:        msg.PublicKey,
-	}
-
-	if msg.PublicKey != "" {
-		peerConn.RemotePeer.PublicKey = msg.PublicKey
-	}
-
-	self.cNewPeers <- remotePeer
 	valid := false
 	if msg.ProtocolVersion == self.protocolVersion {
 		valid = true
@@ -651,6 +702,21 @@ func (self *Server) OnVersion(peerConn *peer.PeerConn, msg *wire.MessageVersion)
 
 	msgV.(*wire.MessageVerAck).Valid = valid
 
+	// prove our producer key by signing the challenge of remote peer
+	producerSigner := peerConn.ListenerPeer.Config.ProducerSigner
+	if producerSigner != nil && msg.Challenge != "" {
+		sig, err := producerSigner.Sign(signer.SignRequest{
+			Type: signer.SignMessage,
+			Data: wire.ChallengeData(msg.Challenge, peerConn.ListenerPeer.PeerID, peerConn.RemotePeerID),
+		})
+		if err != nil {
+			Logger.log.Error(err)
+			return
+		}
+		msgV.(*wire.MessageVerAck).PublicKey = producerSigner.PublicKey()
+		msgV.(*wire.MessageVerAck).ChallengeSig = base58.Base58Check{}.Encode(sig, byte(0x00))
+	}
+
 	peerConn.QueueMessageWithEncoding(msgV, nil)
 
 	//	push version message again
@@ -670,6 +736,31 @@ OnVerAck is invoked when a peer receives a version acknowlege message
 func (self *Server) OnVerAck(peerConn *peer.PeerConn, msg *wire.MessageVerAck) {
 	Logger.log.Info("Receive verack message START")
 
+	// only a public key proven by the challenge signature is bound to the
+	// connection, consensus messages are routed and accepted by it
+	if msg.PublicKey != "" {
+		err := cashec.ValidateDataB58(msg.PublicKey, msg.ChallengeSig, signer.SignedData(signer.SignMessage, wire.ChallengeData(peerConn.Challenge(), peerConn.RemotePeerID, peerConn.ListenerPeer.PeerID)))
+		if err != nil {
+			Logger.log.Errorf("Invalid handshake signature of %s: %+v", peerConn.RemotePeerID.Pretty(), err)
+			peerConn.AddBanScore(peer.BanScoreBadSignature, 0, "verack bad challenge signature: "+err.Error())
+			peerConn.RemotePeer.PublicKey = ""
+		} else {
+			peerConn.SetAuthPublicKey(msg.PublicKey)
+			peerConn.RemotePeer.PublicKey = msg.PublicKey
+		}
+	} else {
+		peerConn.RemotePeer.PublicKey = ""
+	}
+
+	// a public key announced in version message is unverified, the new peer
+	// is known by the key it proved only
+	self.cNewPeers <- &peer.Peer{
+		ListeningAddress: peerConn.RemotePeer.ListeningAddress,
+		RawAddress:       peerConn.RemotePeer.RawAddress,
+		PeerID:           peerConn.RemotePeerID,
+		PublicKey:        peerConn.AuthPublicKey(),
+	}
+
 	if msg.Valid {
 		peerConn.VerValid = true
 
@@ -755,24 +846,69 @@ func (self *Server) OnGetAddr(peerConn *peer.PeerConn, msg *wire.MessageGetAddr)
 
 func (self *Server) OnAddr(peerConn *peer.PeerConn, msg *wire.MessageAddr) {
 	Logger.log.Infof("Receive addr message %v", msg.RawPeers)
+
+	if len(msg.RawPeers) > addrmanager.GetAddrMax {
+		peerConn.AddBanScore(0, peer.BanScoreOversizedMessage, "addr message with too many addresses")
+		return
+	}
+
+	addrs := make([]*peer.Peer, 0, len(msg.RawPeers))
+	for _, rawPeer := range msg.RawPeers {
+		peerID, err := self.connManager.GetPeerIDStr(rawPeer.RawAddress)
+		if err != nil || peerID == peerConn.ListenerPeer.PeerID.String() {
+			continue
+		}
+		addrs = append(addrs, &peer.Peer{
+			RawAddress: rawPeer.RawAddress,
+			PublicKey:  rawPeer.PublicKey,
+		})
+	}
+	self.addrManager.AddAddresses(addrs, peerConn.SourceAddress())
 }
 
-func (self *Server) OnRequestSign(_ *peer.PeerConn, msg *wire.MessageBlockSigReq) {
+func (self *Server) OnRequestSign(peerConn *peer.PeerConn, msg *wire.MessageBlockSigReq) {
 	Logger.log.Info("Receive a requestsign START")
+	if !self.checkConsensusSender(peerConn, msg.Block.BlockProducer, msg.MessageType()) {
+		return
+	}
 	var txProcessed chan struct{}
 	self.netSync.QueueMessage(nil, msg, txProcessed)
 	Logger.log.Info("Receive a requestsign END")
 }
 
-func (self *Server) OnInvalidBlock(_ *peer.PeerConn, msg *wire.MessageInvalidBlock) {
+func (self *Server) OnInvalidBlock(peerConn *peer.PeerConn, msg *wire.MessageInvalidBlock) {
 	Logger.log.Info("Receive a invalidblock START", msg)
+	// message is signed by the validator which found block invalid, a peer
+	// relaying it is only penalised when the signature proves it is forged.
+	// Spam score goes to the validator itself
+	if err := msg.Verify(); err != nil {
+		peerConn.AddBanScore(peer.BanScoreBadSignature, 0, "invalidblock bad signature: "+err.Error())
+		return
+	}
+	if peerConn.AuthPublicKey() == msg.Validator {
+		peerConn.AddBanScore(0, peer.BanScoreInvalidBlockMsg, "invalidblock message")
+	}
 	var txProcessed chan struct{}
 	self.netSync.QueueMessage(nil, msg, txProcessed)
 	Logger.log.Info("Receive a invalidblock END", msg)
 }
 
-func (self *Server) OnBlockSig(_ *peer.PeerConn, msg *wire.MessageBlockSig) {
+func (self *Server) OnEvidence(peerConn *peer.PeerConn, msg *wire.MessageEvidence) {
+	Logger.log.Info("Receive a evidence START")
+	if _, err := msg.Evidence.Verify(); err != nil {
+		peerConn.AddBanScore(peer.BanScoreBadSignature, 0, "evidence is invalid: "+err.Error())
+		return
+	}
+	var txProcessed chan struct{}
+	self.netSync.QueueMessage(nil, msg, txProcessed)
+	Logger.log.Info("Receive a evidence END")
+}
+
+func (self *Server) OnBlockSig(peerConn *peer.PeerConn, msg *wire.MessageBlockSig) {
 	Logger.log.Info("Receive a BlockSig")
+	if !self.checkConsensusSender(peerConn, msg.Validator, msg.MessageType()) {
+		return
+	}
 	var txProcessed chan struct{}
 	self.netSync.QueueMessage(nil, msg, txProcessed)
 }
@@ -791,13 +927,21 @@ func (self *Server) OnChainState(_ *peer.PeerConn, msg *wire.MessageChainState)
 	Logger.log.Info("Receive a chainstate END")
 }
 
+/*
+GetPeerIDsFromPublicKey - return ids of connected peers which proved a public
+key in handshake, ordered by round trip time so that callers using the first
+one prefer low latency
+*/
 func (self *Server) GetPeerIDsFromPublicKey(pubKey string) []peer2.ID {
 	result := []peer2.ID{}
+	pingTimes := map[string]time.Duration{}
 
 	for _, listener := range self.connManager.Config.ListenerPeers {
 		for _, peerConn := range listener.PeerConns {
 			// Logger.log.Info("Test PeerConn", peerConn.RemotePeer.PaymentAddress)
-			if peerConn.RemotePeer.PublicKey == pubKey {
+			// the key of remote peer comes from addresses or bootnode and is
+			// unverified, anyone can announce a validator key at its address
+			if pubKey != "" && peerConn.AuthPublicKey() == pubKey {
 				exist := false
 				for _, item := range result {
 					if item.Pretty() == peerConn.RemotePeer.PeerID.Pretty() {
@@ -807,11 +951,19 @@ func (self *Server) GetPeerIDsFromPublicKey(pubKey string) []peer2.ID {
 
 				if !exist {
 					result = append(result, peerConn.RemotePeer.PeerID)
+					pingTimes[peerConn.RemotePeer.PeerID.Pretty()] = peerConn.PingTime()
 				}
 			}
 		}
 	}
 
+	// lowest latency first, unmeasured connections last
+	sort.SliceStable(result, func(i, j int) bool {
+		pingI := pingTimes[result[i].Pretty()]
+		pingJ := pingTimes[result[j].Pretty()]
+		return pingI != 0 && (pingJ == 0 || pingI < pingJ)
+	})
+
 	return result
 }
 
@@ -890,15 +1042,12 @@ func (self Server) PushVersionMessage(peerConn *peer.PeerConn) error {
 	msg.(*wire.MessageVersion).RawRemoteAddress = peerConn.ListenerPeer.RawAddress
 	msg.(*wire.MessageVersion).RemotePeerId = peerConn.ListenerPeer.PeerID
 	msg.(*wire.MessageVersion).ProtocolVersion = self.protocolVersion
+	msg.(*wire.MessageVersion).WireVersion = wire.ProtocolVersion
+	msg.(*wire.MessageVersion).Challenge = peerConn.Challenge()
 
-	// ValidateTransaction Public Key from ProducerPrvKey
-	if peerConn.ListenerPeer.Config.ProducerPrvKey != "" {
-		keySet, err := cfg.GetProducerKeySet()
-		if err != nil {
-			Logger.log.Critical("Invalid producer's private key")
-			return err
-		}
-		msg.(*wire.MessageVersion).PublicKey = base58.Base58Check{}.Encode(keySet.PaymentAddress.Pk, byte(0x00))
+	// Public Key of producer key, it is proved by signing the challenge
+	if peerConn.ListenerPeer.Config.ProducerSigner != nil {
+		msg.(*wire.MessageVersion).PublicKey = peerConn.ListenerPeer.Config.ProducerSigner.PublicKey()
 	}
 
 	if err != nil {
@@ -0,0 +1,7 @@
+package signer
+
+const (
+	MaxBlockSessions = 64 // open block signing sessions kept by a signer
+
+	DialTimeout = 5 // seconds to connect a remote signer
+)
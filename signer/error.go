@@ -0,0 +1,42 @@
+package signer
+
+import "fmt"
+
+const (
+	ErrUnexpected = iota
+	ErrConflictingBlock
+	ErrOldBlock
+	ErrNoBlockSession
+	ErrProtectionFile
+	ErrUnknownSignType
+)
+
+var ErrCodeMessage = map[int]struct {
+	code    int
+	message string
+}{
+	ErrUnexpected:       {-1, "Unexpected error"},
+	ErrConflictingBlock: {-2, "other data is already signed at this height and round"},
+	ErrOldBlock:         {-3, "a higher height or round of the chain is already signed"},
+	ErrNoBlockSession:   {-4, "no signing session for block"},
+	ErrProtectionFile:   {-5, "can't read or write double sign protection file"},
+	ErrUnknownSignType:  {-6, "unknown type of data to sign"},
+}
+
+type SignerError struct {
+	Code    int
+	Message string
+	Err     error
+}
+
+func (e SignerError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+func NewSignerError(key int, err error) *SignerError {
+	return &SignerError{
+		Code:    ErrCodeMessage[key].code,
+		Message: ErrCodeMessage[key].message,
+		Err:     err,
+	}
+}
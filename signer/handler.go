@@ -0,0 +1,83 @@
+package signer
+
+import (
+	"net"
+	"net/rpc"
+
+	"github.com/ninjadotorg/constant/privacy-protocol"
+)
+
+// InfoReply is the public part of producer key of a signer
+type InfoReply struct {
+	PublicKey      string
+	PaymentAddress privacy.PaymentAddress
+}
+
+type SignArgs struct {
+	Request SignRequest
+}
+
+type BlockNonceArgs struct {
+	Session BlockSession
+}
+
+type SignBlockArgs struct {
+	Session  BlockSession
+	PubKeys  [][]byte
+	AggNonce []byte
+}
+
+/*
+Handler serves a LocalSigner to nodes over net/rpc, every call of a node goes
+through the double sign protection of the signer process
+*/
+type Handler struct {
+	signer *LocalSigner
+}
+
+func (self Handler) Info(args *bool, reply *InfoReply) error {
+	reply.PublicKey = self.signer.PublicKey()
+	reply.PaymentAddress = self.signer.PaymentAddress()
+	return nil
+}
+
+func (self Handler) Sign(args *SignArgs, reply *[]byte) error {
+	sig, err := self.signer.Sign(args.Request)
+	if err != nil {
+		return err
+	}
+	*reply = sig
+	return nil
+}
+
+func (self Handler) BlockNonce(args *BlockNonceArgs, reply *[]byte) error {
+	nonce, err := self.signer.BlockNonce(args.Session)
+	if err != nil {
+		return err
+	}
+	*reply = nonce
+	return nil
+}
+
+func (self Handler) SignBlock(args *SignBlockArgs, reply *[]byte) error {
+	partial, err := self.signer.SignBlock(args.Session, args.PubKeys, args.AggNonce)
+	if err != nil {
+		return err
+	}
+	*reply = partial
+	return nil
+}
+
+/*
+Serve - accept nodes on listener and sign for them with signer until listener
+is closed
+*/
+func Serve(listener net.Listener, signer *LocalSigner) error {
+	server := rpc.NewServer()
+	err := server.Register(&Handler{signer})
+	if err != nil {
+		return err
+	}
+	server.Accept(listener)
+	return nil
+}
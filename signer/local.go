@@ -0,0 +1,113 @@
+package signer
+
+import (
+	"sync"
+
+	"github.com/ninjadotorg/constant/cashec"
+	"github.com/ninjadotorg/constant/common/base58"
+	"github.com/ninjadotorg/constant/privacy-protocol"
+)
+
+/*
+LocalSigner signs with a key set in memory of its process, it is used by a
+node started with producerspendingkey and by the signer process itself
+*/
+type LocalSigner struct {
+	keySet     cashec.KeySet
+	publicKey  string
+	protection *Protection
+
+	// secret nonces of open block signing sessions
+	nonces     map[BlockSession]*privacy.MultiSigNonce
+	nonceOrder []BlockSession
+	noncesMtx  sync.Mutex
+}
+
+func NewLocalSigner(keySet cashec.KeySet, protection *Protection) *LocalSigner {
+	return &LocalSigner{
+		keySet:     keySet,
+		publicKey:  base58.Base58Check{}.Encode(keySet.PaymentAddress.Pk, byte(0x00)),
+		protection: protection,
+		nonces:     make(map[BlockSession]*privacy.MultiSigNonce),
+	}
+}
+
+func (self *LocalSigner) PublicKey() string {
+	return self.publicKey
+}
+
+func (self *LocalSigner) PaymentAddress() privacy.PaymentAddress {
+	return self.keySet.PaymentAddress
+}
+
+/*
+Sign - sign data of req as its type, the protection records it before it is
+signed
+*/
+func (self *LocalSigner) Sign(req SignRequest) ([]byte, error) {
+	if _, ok := signTags[req.Type]; !ok {
+		return nil, NewSignerError(ErrUnknownSignType, nil)
+	}
+	err := self.protection.Set(req)
+	if err != nil {
+		return nil, err
+	}
+	return self.keySet.Sign(SignedData(req.Type, req.Data))
+}
+
+/*
+BlockNonce - keep a fresh secret nonce for the block until SignBlock, oldest
+sessions are dropped first
+*/
+func (self *LocalSigner) BlockNonce(session BlockSession) ([]byte, error) {
+	err := self.protection.Check(session.request())
+	if err != nil {
+		return nil, err
+	}
+	nonce := privacy.NewMultiSigNonce()
+
+	self.noncesMtx.Lock()
+	defer self.noncesMtx.Unlock()
+	if _, ok := self.nonces[session]; !ok {
+		self.nonceOrder = append(self.nonceOrder, session)
+	}
+	self.nonces[session] = nonce
+	for len(self.nonceOrder) > MaxBlockSessions {
+		delete(self.nonces, self.nonceOrder[0])
+		self.nonceOrder = self.nonceOrder[1:]
+	}
+	return nonce.PublicNonce(), nil
+}
+
+/*
+SignBlock - partial signature with the nonce of session, a nonce is used only
+once so a block is never signed twice with it
+*/
+func (self *LocalSigner) SignBlock(session BlockSession, pubKeys [][]byte, aggNonce []byte) ([]byte, error) {
+	nonce := self.takeNonce(session)
+	if nonce == nil {
+		return nil, NewSignerError(ErrNoBlockSession, nil)
+	}
+	err := self.protection.Set(session.request())
+	if err != nil {
+		return nil, err
+	}
+	return privacy.MultiSigPartialSign(session.BlockHash[:], self.keySet.PrivateKey, nonce, pubKeys, aggNonce)
+}
+
+func (self *LocalSigner) takeNonce(session BlockSession) *privacy.MultiSigNonce {
+	self.noncesMtx.Lock()
+	defer self.noncesMtx.Unlock()
+	nonce, ok := self.nonces[session]
+	if !ok {
+		return nil
+	}
+	delete(self.nonces, session)
+	for i, s := range self.nonceOrder {
+		if s == session {
+			self.nonceOrder = append(self.nonceOrder[:i], self.nonceOrder[i+1:]...)
+			break
+		}
+	}
+	return nonce
+}
@@ -0,0 +1,135 @@
+package signer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/ninjadotorg/constant/common"
+)
+
+// signedData is the last data of a type signed on a chain
+type signedData struct {
+	Height   int32
+	Round    int
+	DataHash common.Hash
+}
+
+/*
+Protection is the double sign protection state of a signer, the last data of
+every type it signed on every chain. A signer never signs other data of a type
+at that height and round or data below it. With a file the state survives
+restarts of the signer.
+*/
+type Protection struct {
+	sync.Mutex
+	file   string
+	signed map[SignType]map[byte]signedData
+}
+
+/*
+NewProtection - load protection state from file, it is kept in memory only
+when file is empty
+*/
+func NewProtection(file string) (*Protection, error) {
+	self := &Protection{
+		file:   file,
+		signed: make(map[SignType]map[byte]signedData),
+	}
+	if file == common.EmptyString {
+		return self, nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return self, nil
+	}
+	if err != nil {
+		return nil, NewSignerError(ErrProtectionFile, err)
+	}
+	err = json.Unmarshal(data, &self.signed)
+	if err != nil {
+		return nil, NewSignerError(ErrProtectionFile, err)
+	}
+	return self, nil
+}
+
+/*
+Check - whether data of req may be signed
+*/
+func (self *Protection) Check(req SignRequest) error {
+	self.Lock()
+	defer self.Unlock()
+	return self.check(req)
+}
+
+func (self *Protection) check(req SignRequest) error {
+	if req.Type == SignMessage {
+		return nil
+	}
+	last, ok := self.signed[req.Type][req.ChainID]
+	if !ok {
+		return nil
+	}
+	if req.Height < last.Height || (req.Height == last.Height && req.Round < last.Round) {
+		return NewSignerError(ErrOldBlock, nil)
+	}
+	if req.Height == last.Height && req.Round == last.Round && common.HashH(req.Data) != last.DataHash {
+		return NewSignerError(ErrConflictingBlock, nil)
+	}
+	return nil
+}
+
+/*
+Set - record data of req as signed, the state is saved before the data may be
+signed
+*/
+func (self *Protection) Set(req SignRequest) error {
+	self.Lock()
+	defer self.Unlock()
+	err := self.check(req)
+	if err != nil || req.Type == SignMessage {
+		return err
+	}
+	chains, ok := self.signed[req.Type]
+	if !ok {
+		chains = make(map[byte]signedData)
+		self.signed[req.Type] = chains
+	}
+	last, ok := chains[req.ChainID]
+	chains[req.ChainID] = signedData{req.Height, req.Round, common.HashH(req.Data)}
+	err = self.save()
+	if err != nil {
+		if ok {
+			chains[req.ChainID] = last
+		} else {
+			delete(chains, req.ChainID)
+		}
+		return err
+	}
+	return nil
+}
+
+/*
+save - write state into file through a temporary file, so a crash never
+leaves a truncated state
+*/
+func (self *Protection) save() error {
+	if self.file == common.EmptyString {
+		return nil
+	}
+	data, err := json.Marshal(self.signed)
+	if err != nil {
+		return NewSignerError(ErrProtectionFile, err)
+	}
+	tmpFile := self.file + ".tmp"
+	err = ioutil.WriteFile(tmpFile, data, 0600)
+	if err != nil {
+		return NewSignerError(ErrProtectionFile, err)
+	}
+	err = os.Rename(tmpFile, self.file)
+	if err != nil {
+		return NewSignerError(ErrProtectionFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package signer
+
+import (
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/ninjadotorg/constant/privacy-protocol"
+)
+
+/*
+RemoteSigner signs through a signer process, so the producer key never enters
+the node. A lost connection is dialed again on the next call.
+*/
+type RemoteSigner struct {
+	network string
+	address string
+
+	publicKey      string
+	paymentAddress privacy.PaymentAddress
+
+	client    *rpc.Client
+	clientMtx sync.Mutex
+}
+
+/*
+DialRemoteSigner - connect a signer process, address is a unix socket path
+for network "unix"
+*/
+func DialRemoteSigner(network string, address string) (*RemoteSigner, error) {
+	self := &RemoteSigner{
+		network: network,
+		address: address,
+	}
+	info := InfoReply{}
+	err := self.call("Handler.Info", true, &info)
+	if err != nil {
+		return nil, err
+	}
+	self.publicKey = info.PublicKey
+	self.paymentAddress = info.PaymentAddress
+	return self, nil
+}
+
+func (self *RemoteSigner) PublicKey() string {
+	return self.publicKey
+}
+
+func (self *RemoteSigner) PaymentAddress() privacy.PaymentAddress {
+	return self.paymentAddress
+}
+
+func (self *RemoteSigner) Sign(req SignRequest) ([]byte, error) {
+	var sig []byte
+	err := self.call("Handler.Sign", &SignArgs{Request: req}, &sig)
+	return sig, err
+}
+
+func (self *RemoteSigner) BlockNonce(session BlockSession) ([]byte, error) {
+	var nonce []byte
+	err := self.call("Handler.BlockNonce", &BlockNonceArgs{Session: session}, &nonce)
+	return nonce, err
+}
+
+func (self *RemoteSigner) SignBlock(session BlockSession, pubKeys [][]byte, aggNonce []byte) ([]byte, error) {
+	var partial []byte
+	err := self.call("Handler.SignBlock", &SignBlockArgs{Session: session, PubKeys: pubKeys, AggNonce: aggNonce}, &partial)
+	return partial, err
+}
+
+// Close closes connection to signer process
+func (self *RemoteSigner) Close() error {
+	self.clientMtx.Lock()
+	defer self.clientMtx.Unlock()
+	if self.client == nil {
+		return nil
+	}
+	err := self.client.Close()
+	self.client = nil
+	return err
+}
+
+/*
+call - call method of signer process, errors of the signer itself are
+returned as rpc.ServerError and keep the connection
+*/
+func (self *RemoteSigner) call(method string, args interface{}, reply interface{}) error {
+	client, err := self.getClient()
+	if err != nil {
+		return err
+	}
+	err = client.Call(method, args, reply)
+	if _, ok := err.(rpc.ServerError); err != nil && !ok {
+		self.clientMtx.Lock()
+		if self.client == client {
+			self.client.Close()
+			self.client = nil
+		}
+		self.clientMtx.Unlock()
+	}
+	return err
+}
+
+func (self *RemoteSigner) getClient() (*rpc.Client, error) {
+	self.clientMtx.Lock()
+	defer self.clientMtx.Unlock()
+	if self.client != nil {
+		return self.client, nil
+	}
+	conn, err := net.DialTimeout(self.network, self.address, DialTimeout*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	self.client = rpc.NewClient(conn)
+	return self.client, nil
+}
@@ -0,0 +1,55 @@
+package signer
+
+// SignType is the kind of data a signer signs, every kind is protected on its own
+type SignType byte
+
+const (
+	SignBlockSig      SignType = iota // multi-signature of a block, see BlockSession
+	SignBlockProposal                 // block sent by its producer to the committee
+	SignBlockHeader                   // header of a block finalized by its producer
+	SignSwap                          // committee swap requested or approved
+	SignMessage                       // network message which never moves a chain, it isn't protected
+)
+
+// signTags separate signed data of types, so a signature of one type is
+// never valid as another one
+var signTags = map[SignType]string{
+	SignBlockProposal: "block-proposal:",
+	SignBlockHeader:   "block-header:",
+	SignSwap:          "swap:",
+	SignMessage:       "message:",
+}
+
+/*
+SignRequest is data to sign with what it is. A block is placed by its chain
+and height, Round counts proposals of its producer at that height. A swap is
+placed by the chain and height of its anchor block.
+*/
+type SignRequest struct {
+	Type    SignType
+	ChainID byte
+	Height  int32
+	Round   int
+	Data    []byte
+}
+
+// request is the protected part of a multi-signature session
+func (self BlockSession) request() SignRequest {
+	return SignRequest{
+		Type:    SignBlockSig,
+		ChainID: self.ChainID,
+		Height:  self.Height,
+		Round:   self.Round,
+		Data:    self.BlockHash[:],
+	}
+}
+
+/*
+SignedData - bytes which a signer signs for data of signType, signatures are
+verified against them
+*/
+func SignedData(signType SignType, data []byte) []byte {
+	signed := make([]byte, 0, len(signTags[signType])+len(data))
+	signed = append(signed, signTags[signType]...)
+	return append(signed, data...)
+}
@@ -0,0 +1,37 @@
+package signer
+
+import (
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/privacy-protocol"
+)
+
+/*
+Signer holds the producer key of a validator. The consensus engine and the
+network identity of a node sign only through it, so the key can live in a
+separate signer process.
+*/
+type Signer interface {
+	// PublicKey is the base58check encoded public key of producer key
+	PublicKey() string
+	// PaymentAddress receives rewards of produced blocks
+	PaymentAddress() privacy.PaymentAddress
+	// Sign signs SignedData of a request like cashec.KeySet.Sign, it is refused
+	// when the request conflicts with data of its type signed before
+	Sign(req SignRequest) ([]byte, error)
+	// BlockNonce opens a multi-signature session of a block and returns its
+	// public nonce, the secret nonce never leaves the signer
+	BlockNonce(session BlockSession) ([]byte, error)
+	// SignBlock closes the session of a block with a partial signature, it is
+	// refused when another block was signed at the height of the session
+	SignBlock(session BlockSession, pubKeys [][]byte, aggNonce []byte) ([]byte, error)
+}
+
+// BlockSession is the block of a multi-signature session. Round grows when a
+// block of the height timed out without aggregated signature, another block
+// may be signed at the height in a higher round only
+type BlockSession struct {
+	ChainID   byte
+	Height    int32
+	Round     int
+	BlockHash common.Hash
+}
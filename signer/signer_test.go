@@ -0,0 +1,223 @@
+package signer
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ninjadotorg/constant/cashec"
+	"github.com/ninjadotorg/constant/common"
+	"github.com/ninjadotorg/constant/privacy-protocol"
+)
+
+func testKeySet() cashec.KeySet {
+	keySet := cashec.KeySet{}
+	keySet.GenerateKey([]byte("signer test"))
+	return keySet
+}
+
+func TestProtection(t *testing.T) {
+	dir, err := ioutil.TempDir(common.EmptyString, "signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "protection.json")
+
+	protection, err := NewProtection(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := BlockSession{ChainID: 1, Height: 5, BlockHash: common.HashH([]byte("a"))}.request()
+	if err := protection.Set(signed); err != nil {
+		t.Fatal(err)
+	}
+	if err := protection.Set(signed); err != nil {
+		t.Error("the same block must be signed again", err)
+	}
+
+	// the state is loaded again by a restarted signer
+	protection, err = NewProtection(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conflicting := BlockSession{ChainID: 1, Height: 5, BlockHash: common.HashH([]byte("b"))}.request()
+	if err := protection.Check(conflicting); err == nil {
+		t.Error("another block at a signed height must be refused")
+	}
+	nextRound := BlockSession{ChainID: 1, Height: 5, Round: 1, BlockHash: common.HashH([]byte("b"))}.request()
+	if err := protection.Check(nextRound); err != nil {
+		t.Error("another block at a signed height must be signed in a higher round", err)
+	}
+	old := BlockSession{ChainID: 1, Height: 4, BlockHash: common.HashH([]byte("c"))}.request()
+	if err := protection.Set(old); err == nil {
+		t.Error("a block below a signed height must be refused")
+	}
+	other := BlockSession{ChainID: 2, Height: 5, BlockHash: common.HashH([]byte("b"))}.request()
+	if err := protection.Set(other); err != nil {
+		t.Error("chains are protected separately", err)
+	}
+}
+
+func TestProtection_Types(t *testing.T) {
+	protection, err := NewProtection(common.EmptyString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proposal := SignRequest{Type: SignBlockProposal, ChainID: 1, Height: 5, Round: 10, Data: []byte("a")}
+	if err := protection.Set(proposal); err != nil {
+		t.Fatal(err)
+	}
+
+	// a proposal replaces another one at the same height in a higher round only
+	conflicting := proposal
+	conflicting.Data = []byte("b")
+	if err := protection.Set(conflicting); err == nil {
+		t.Error("other data in a signed round must be refused")
+	}
+	conflicting.Round = 9
+	if err := protection.Set(conflicting); err == nil {
+		t.Error("a lower round must be refused")
+	}
+	conflicting.Round = 11
+	if err := protection.Set(conflicting); err != nil {
+		t.Error("a higher round must be signed", err)
+	}
+	old := SignRequest{Type: SignBlockProposal, ChainID: 1, Height: 4, Round: 20, Data: []byte("c")}
+	if err := protection.Set(old); err == nil {
+		t.Error("a lower height must be refused in any round")
+	}
+
+	// types are protected separately, messages aren't protected
+	header := SignRequest{Type: SignBlockHeader, ChainID: 1, Height: 5, Round: 10, Data: []byte("d")}
+	if err := protection.Set(header); err != nil {
+		t.Error("types are protected separately", err)
+	}
+	swap := SignRequest{Type: SignSwap, ChainID: 1, Height: 5, Data: []byte("e")}
+	if err := protection.Set(swap); err != nil {
+		t.Error("types are protected separately", err)
+	}
+	otherSwap := swap
+	otherSwap.Data = []byte("f")
+	if err := protection.Set(otherSwap); err == nil {
+		t.Error("another swap at a signed anchor must be refused")
+	}
+	for _, data := range []string{"g", "h"} {
+		message := SignRequest{Type: SignMessage, ChainID: 1, Height: 5, Data: []byte(data)}
+		if err := protection.Set(message); err != nil {
+			t.Error("messages must be signed", err)
+		}
+	}
+}
+
+func TestLocalSigner_Sign(t *testing.T) {
+	keySet := testKeySet()
+	protection, err := NewProtection(common.EmptyString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localSigner := NewLocalSigner(keySet, protection)
+
+	header := SignRequest{Type: SignBlockHeader, ChainID: 0, Height: 2, Data: []byte("header")}
+	sig, err := localSigner.Sign(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := keySet.Verify(SignedData(SignBlockHeader, header.Data), sig); !ok {
+		t.Error("signature of block header is invalid")
+	}
+	// a signature of one type is never valid as another one
+	if ok, _ := keySet.Verify(header.Data, sig); ok {
+		t.Error("signature is valid for data without type")
+	}
+	if ok, _ := keySet.Verify(SignedData(SignMessage, header.Data), sig); ok {
+		t.Error("signature of block header is valid as message")
+	}
+
+	conflicting := header
+	conflicting.Data = []byte("another header")
+	if _, err := localSigner.Sign(conflicting); err == nil {
+		t.Error("another header at a signed height must be refused")
+	}
+	// block multi-signatures are only made in a session
+	if _, err := localSigner.Sign(SignRequest{Type: SignBlockSig, Data: []byte("block")}); err == nil {
+		t.Error("a block multi-signature must be refused")
+	}
+}
+
+func TestRemoteSigner(t *testing.T) {
+	dir, err := ioutil.TempDir(common.EmptyString, "signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "signer.sock")
+
+	keySet := testKeySet()
+	protection, err := NewProtection(common.EmptyString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go Serve(listener, NewLocalSigner(keySet, protection))
+
+	remote, err := DialRemoteSigner("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+	if remote.PublicKey() != NewLocalSigner(keySet, protection).PublicKey() {
+		t.Fatal("remote signer has another public key")
+	}
+
+	swap := SignRequest{Type: SignSwap, ChainID: 3, Height: 7, Data: []byte("swap request")}
+	sig, err := remote.Sign(swap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := keySet.Verify(SignedData(SignSwap, swap.Data), sig); !ok {
+		t.Error("signature of remote signer is invalid")
+	}
+	conflicting := swap
+	conflicting.Data = []byte("another swap request")
+	if _, err := remote.Sign(conflicting); err == nil {
+		t.Error("another swap at a signed anchor must be refused")
+	}
+
+	session := BlockSession{ChainID: 0, Height: 2, BlockHash: common.HashH([]byte("block"))}
+	nonce, err := remote.BlockNonce(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKeys := [][]byte{keySet.PaymentAddress.Pk}
+	aggNonce, err := privacy.AggregatePublicNonces([][]byte{nonce})
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial, err := remote.SignBlock(session, pubKeys, aggNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	multiSig, err := privacy.MultiSigCombine(session.BlockHash[:], [][]byte{partial}, pubKeys, aggNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !privacy.MultiSigVerify(multiSig, session.BlockHash[:], pubKeys) {
+		t.Error("block signature of remote signer is invalid")
+	}
+
+	// a nonce is used once and a signed height is never signed again
+	if _, err := remote.SignBlock(session, pubKeys, aggNonce); err == nil {
+		t.Error("a closed session must not be signed")
+	}
+	conflictingSession := BlockSession{ChainID: 0, Height: 2, BlockHash: common.HashH([]byte("another block"))}
+	if _, err := remote.BlockNonce(conflictingSession); err == nil {
+		t.Error("another block at a signed height must be refused")
+	}
+}
@@ -32,3 +32,7 @@ func (thisTx TxSubmitGOVProposal) Hash() *common.Hash {
 func (thisTx TxSubmitDCBProposal) ValidateTransaction() bool {
 	return thisTx.Tx.ValidateTransaction() && thisTx.DCBProposalData.Validate()
 }
+
+func (thisTx TxSubmitGOVProposal) ValidateTransaction() bool {
+	return thisTx.Tx.ValidateTransaction() && thisTx.GOVProposalData.Validate()
+}
@@ -1,6 +1,7 @@
 package transaction
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/ninjadotorg/constant/common"
@@ -34,3 +35,47 @@ type TxDesc struct {
 	// FeePerKB is the fee the transaction pays in coin per 1000 bytes.
 	FeePerKB int32
 }
+
+// NewTxByType returns an empty tx of a type to unmarshal a tx of the type into,
+// embedded txs are allocated so the type of the empty tx can be read
+func NewTxByType(txType string) (Transaction, error) {
+	switch txType {
+	case common.TxNormalType, common.TxSalaryType:
+		return &Tx{}, nil
+	case common.TxCustomTokenType, common.TxBuyFromGOVResponse, common.TxBuyBackResponse, common.TxBuySellDCBResponse:
+		return &TxCustomToken{}, nil
+	case common.TxVoteDCBBoard:
+		return &TxVoteDCBBoard{}, nil
+	case common.TxVoteGOVBoard:
+		return &TxVoteGOVBoard{}, nil
+	case common.TxSubmitDCBProposal:
+		return &TxSubmitDCBProposal{}, nil
+	case common.TxSubmitGOVProposal:
+		return &TxSubmitGOVProposal{}, nil
+	case common.TxVoteDCBProposal:
+		return &TxVoteDCBProposal{}, nil
+	case common.TxVoteGOVProposal:
+		return &TxVoteGOVProposal{}, nil
+	case common.TxAcceptDCBProposal:
+		return &TxAcceptDCBProposal{Tx: &Tx{}}, nil
+	case common.TxAcceptGOVProposal:
+		return &TxAcceptGOVProposal{Tx: &Tx{}}, nil
+	case common.TxLoanRequest:
+		return &TxLoanRequest{}, nil
+	case common.TxLoanResponse:
+		return &TxLoanResponse{}, nil
+	case common.TxLoanPayment:
+		return &TxLoanPayment{}, nil
+	case common.TxLoanWithdraw:
+		return &TxLoanWithdraw{}, nil
+	case common.TxDividendPayout:
+		return &TxDividendPayout{Tx: &Tx{}}, nil
+	case common.TxBuyFromGOVRequest, common.TxBuySellDCBRequest:
+		return &TxBuySellRequest{Tx: &Tx{}}, nil
+	case common.TxBuyBackRequest:
+		return &TxBuyBackRequest{Tx: &Tx{}}, nil
+	case common.TxCrowdsale:
+		return &TxCrowdsale{TxCustomToken: &TxCustomToken{}}, nil
+	}
+	return nil, fmt.Errorf("Unknown tx type %+v", txType)
+}
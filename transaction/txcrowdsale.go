@@ -91,6 +91,10 @@ func (tx *TxCrowdsale) ValidateTransaction() bool {
 	return false
 }
 
+func (tx *TxCrowdsale) GetType() string {
+	return common.TxCrowdsale
+}
+
 // CreateTxCrowdsale ...
 func CreateTxCrowdsale(
 	senderKey *privacy.SpendingKey,
@@ -71,7 +71,7 @@ func (tx *TxLoanResponse) ValidateTransaction() bool {
 	}
 
 	// TODO(@0xbunyip): check if only board members created this tx
-	if tx.Response != Accept || tx.Response != Reject {
+	if tx.Response != Accept && tx.Response != Reject {
 		return false
 	}
 
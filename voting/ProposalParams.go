@@ -1,12 +1,18 @@
 package voting
 
-import "github.com/ninjadotorg/constant/common"
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ninjadotorg/constant/common"
+)
 
 type GOVVotingParams struct {
 	SalaryPerTx  uint64 // salary for each tx in block(mili constant)
 	BasicSalary  uint64 // basic salary per block(mili constant)
 	SellingBonds *SellingBonds
 	RefundInfo   *RefundInfo
+	FeeSchedule  *FeeSchedule // nil keeps the current fee schedule
 }
 
 type SellingBonds struct {
@@ -23,6 +29,16 @@ type RefundInfo struct {
 	RefundAmount       uint64
 }
 
+// FeeSchedule is min fee per kb (mili constant) of txs accepted into mempool,
+// by tx type
+type FeeSchedule struct {
+	Normal      uint64
+	CustomToken uint64
+	Loan        uint64 // loan request, response, payment and withdraw
+	BuyBack     uint64 // buy-back and buy-sell requests
+	Voting      uint64 // proposals and votes of DCB and GOV
+}
+
 type DCBVotingParams struct {
 }
 
@@ -36,6 +52,9 @@ func (GOVParams GOVVotingParams) Hash() *common.Hash {
 	record := string(GOVParams.SalaryPerTx)
 	record += string(GOVParams.BasicSalary)
 	record += string(common.ToBytes(GOVParams.SellingBonds.Hash()))
+	if GOVParams.FeeSchedule != nil {
+		record += string(common.ToBytes(GOVParams.FeeSchedule.Hash()))
+	}
 	hash := common.DoubleHashH([]byte(record))
 	return &hash
 }
@@ -51,6 +70,18 @@ func (SellingBonds SellingBonds) Hash() *common.Hash {
 	return &hash
 }
 
+func (FeeSchedule FeeSchedule) Hash() *common.Hash {
+	record := strings.Join([]string{
+		strconv.FormatUint(FeeSchedule.Normal, 10),
+		strconv.FormatUint(FeeSchedule.CustomToken, 10),
+		strconv.FormatUint(FeeSchedule.Loan, 10),
+		strconv.FormatUint(FeeSchedule.BuyBack, 10),
+		strconv.FormatUint(FeeSchedule.Voting, 10),
+	}, ",")
+	hash := common.DoubleHashH([]byte(record))
+	return &hash
+}
+
 //xxx
 func (GOVParams GOVVotingParams) Validate() bool {
 	return true
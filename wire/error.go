@@ -0,0 +1,53 @@
+package wire
+
+import "fmt"
+
+const (
+	UnexpectedError = iota
+	MessageMagicErr
+	MessageCommandErr
+	MessagePayloadLengthErr
+	MessageChecksumErr
+	MessageParseErr
+)
+
+var ErrCodeMessage = map[int]struct {
+	code    int
+	message string
+}{
+	UnexpectedError:         {-1, "Unexpected error"},
+	MessageMagicErr:         {-2, "Message network magic mismatch"},
+	MessageCommandErr:       {-3, "Message command is unknown"},
+	MessagePayloadLengthErr: {-4, "Message payload exceeds max length"},
+	MessageChecksumErr:      {-5, "Message payload checksum mismatch"},
+	MessageParseErr:         {-6, "Can not parse message payload"},
+}
+
+type MessageError struct {
+	Code    int
+	Message string
+	Err     error
+}
+
+func (e MessageError) Error() string {
+	return fmt.Sprintf("%d: %s %+v", e.Code, e.Message, e.Err)
+}
+
+// IsCode reports whether error was created with the given key
+func (e MessageError) IsCode(key int) bool {
+	return e.Code == ErrCodeMessage[key].code
+}
+
+// Recoverable reports whether the stream is still aligned on a frame boundary
+// after the error, so the reader may skip the message and keep going
+func (e MessageError) Recoverable() bool {
+	return e.IsCode(MessageCommandErr) || e.IsCode(MessageChecksumErr) || e.IsCode(MessageParseErr)
+}
+
+func NewMessageError(key int, err error) *MessageError {
+	return &MessageError{
+		Code:    ErrCodeMessage[key].code,
+		Message: ErrCodeMessage[key].message,
+		Err:     err,
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/libp2p/go-libp2p-peer"
 	"github.com/ninjadotorg/constant/blockchain"
+	"github.com/ninjadotorg/constant/common"
 	"github.com/ninjadotorg/constant/transaction"
 )
 
@@ -19,6 +20,21 @@ const (
 	CmdRegisteration     = "registeration"
 	CmdCustomToken       = "txtoken"
 	CmdCLoanRequestToken = "txloanreq"
+	CmdLoanResponse      = "txloanres"
+	CmdLoanPayment       = "txloanpay"
+	CmdLoanWithdraw      = "txloanwd"
+	CmdDividendPayout    = "txdividend"
+	CmdCrowdsale         = "txcrowdsale"
+	CmdBuySellRequest    = "txbuysell"
+	CmdBuyBackRequest    = "txbuyback"
+	CmdVoteDCBBoard      = "txdcbboard"
+	CmdVoteGOVBoard      = "txgovboard"
+	CmdSubmitDCBProposal = "txdcbprop"
+	CmdSubmitGOVProposal = "txgovprop"
+	CmdVoteDCBProposal   = "txdcbvote"
+	CmdVoteGOVProposal   = "txgovvote"
+	CmdAcceptDCBProposal = "txdcbaccept"
+	CmdAcceptGOVProposal = "txgovaccept"
 	CmdGetBlocks         = "getblocks"
 	CmdInv               = "inv"
 	CmdGetData           = "getdata"
@@ -27,11 +43,13 @@ const (
 	CmdGetAddr           = "getaddr"
 	CmdAddr              = "addr"
 	CmdPing              = "ping"
+	CmdPong              = "pong"
 
 	// POS Cmd
 	CmdBlockSigReq   = "blocksigreq"
 	CmdBlockSig      = "blocksig"
 	CmdInvalidBlock  = "invalidblock"
+	CmdEvidence      = "evidence"
 	CmdGetChainState = "getchstate"
 	CmdChainState    = "chainstate"
 
@@ -41,6 +59,77 @@ const (
 	CmdSwapUpdate  = "swapupdate"
 )
 
+// txTypeCmds is the message cmd of each tx type, txs of types with the same
+// struct are sent with the same cmd
+var txTypeCmds = map[string]string{
+	common.TxNormalType:         CmdTx,
+	common.TxSalaryType:         CmdTx,
+	common.TxCustomTokenType:    CmdCustomToken,
+	common.TxBuyFromGOVResponse: CmdCustomToken,
+	common.TxBuyBackResponse:    CmdCustomToken,
+	common.TxBuySellDCBResponse: CmdCustomToken,
+	common.TxLoanRequest:        CmdCLoanRequestToken,
+	common.TxLoanResponse:       CmdLoanResponse,
+	common.TxLoanPayment:        CmdLoanPayment,
+	common.TxLoanWithdraw:       CmdLoanWithdraw,
+	common.TxDividendPayout:     CmdDividendPayout,
+	common.TxCrowdsale:          CmdCrowdsale,
+	common.TxBuyFromGOVRequest:  CmdBuySellRequest,
+	common.TxBuySellDCBRequest:  CmdBuySellRequest,
+	common.TxBuyBackRequest:     CmdBuyBackRequest,
+	common.TxVoteDCBBoard:       CmdVoteDCBBoard,
+	common.TxVoteGOVBoard:       CmdVoteGOVBoard,
+	common.TxSubmitDCBProposal:  CmdSubmitDCBProposal,
+	common.TxSubmitGOVProposal:  CmdSubmitGOVProposal,
+	common.TxVoteDCBProposal:    CmdVoteDCBProposal,
+	common.TxVoteGOVProposal:    CmdVoteGOVProposal,
+	common.TxAcceptDCBProposal:  CmdAcceptDCBProposal,
+	common.TxAcceptGOVProposal:  CmdAcceptGOVProposal,
+}
+
+// txCmdTypes is a tx type of each tx message cmd, an empty tx of the type
+// has the struct to decode the message into
+var txCmdTypes = map[string]string{
+	CmdTx:                common.TxNormalType,
+	CmdCustomToken:       common.TxCustomTokenType,
+	CmdCLoanRequestToken: common.TxLoanRequest,
+	CmdLoanResponse:      common.TxLoanResponse,
+	CmdLoanPayment:       common.TxLoanPayment,
+	CmdLoanWithdraw:      common.TxLoanWithdraw,
+	CmdDividendPayout:    common.TxDividendPayout,
+	CmdCrowdsale:         common.TxCrowdsale,
+	CmdBuySellRequest:    common.TxBuyFromGOVRequest,
+	CmdBuyBackRequest:    common.TxBuyBackRequest,
+	CmdVoteDCBBoard:      common.TxVoteDCBBoard,
+	CmdVoteGOVBoard:      common.TxVoteGOVBoard,
+	CmdSubmitDCBProposal: common.TxSubmitDCBProposal,
+	CmdSubmitGOVProposal: common.TxSubmitGOVProposal,
+	CmdVoteDCBProposal:   common.TxVoteDCBProposal,
+	CmdVoteGOVProposal:   common.TxVoteGOVProposal,
+	CmdAcceptDCBProposal: common.TxAcceptDCBProposal,
+	CmdAcceptGOVProposal: common.TxAcceptGOVProposal,
+}
+
+// IsTxCommand returns true when cmd is a message cmd of any tx type
+func IsTxCommand(cmd string) bool {
+	_, ok := txCmdTypes[cmd]
+	return ok
+}
+
+// legacyTxCmds are tx cmds which peers older than ProtocolVersionTxTypes
+// decode, txs of other types can't be sent to them
+var legacyTxCmds = map[string]bool{
+	CmdTx:                true,
+	CmdCustomToken:       true,
+	CmdCLoanRequestToken: true,
+}
+
+// IsTxCommandSupported returns true when a peer speaking wire version pver
+// decodes tx message cmd
+func IsTxCommandSupported(cmd string, pver int) bool {
+	return pver >= ProtocolVersionTxTypes || legacyTxCmds[cmd]
+}
+
 // Interface for message wire on P2P network
 type Message interface {
 	MessageType() string
@@ -51,6 +140,15 @@ type Message interface {
 }
 
 func MakeEmptyMessage(messageType string) (Message, error) {
+	if txType, ok := txCmdTypes[messageType]; ok {
+		tx, err := transaction.NewTxByType(txType)
+		if err != nil {
+			return nil, err
+		}
+		return &MessageTx{
+			Transaction: tx,
+		}, nil
+	}
 	var msg Message
 	switch messageType {
 	case CmdBlock:
@@ -60,24 +158,9 @@ func MakeEmptyMessage(messageType string) (Message, error) {
 			},
 		}
 		break
-	case CmdCustomToken:
-		msg = &MessageTx{
-			Transaction: &transaction.TxCustomToken{},
-		}
-		break
-	case CmdCLoanRequestToken:
-		msg = &MessageTx{
-			Transaction: &transaction.TxLoanRequest{},
-		}
-		break
 	case CmdGetBlocks:
 		msg = &MessageGetBlocks{}
 		break
-	case CmdTx:
-		msg = &MessageTx{
-			Transaction: &transaction.Tx{},
-		}
-		break
 	case CmdVersion:
 		msg = &MessageVersion{}
 		break
@@ -93,6 +176,9 @@ func MakeEmptyMessage(messageType string) (Message, error) {
 	case CmdInvalidBlock:
 		msg = &MessageInvalidBlock{}
 		break
+	case CmdEvidence:
+		msg = &MessageEvidence{}
+		break
 	case CmdGetChainState:
 		msg = &MessageGetChainState{}
 	case CmdChainState:
@@ -106,6 +192,9 @@ func MakeEmptyMessage(messageType string) (Message, error) {
 	case CmdPing:
 		msg = &MessagePing{}
 		break
+	case CmdPong:
+		msg = &MessagePong{}
+		break
 	case CmdSwapRequest:
 		msg = &MessageSwapRequest{}
 		break
@@ -143,12 +232,16 @@ func GetCmdType(msgType reflect.Type) (string, error) {
 		return CmdAddr, nil
 	case reflect.TypeOf(&MessagePing{}):
 		return CmdPing, nil
+	case reflect.TypeOf(&MessagePong{}):
+		return CmdPong, nil
 	case reflect.TypeOf(&MessageBlockSig{}):
 		return CmdBlockSig, nil
 	case reflect.TypeOf(&MessageBlockSigReq{}):
 		return CmdBlockSigReq, nil
 	case reflect.TypeOf(&MessageInvalidBlock{}):
 		return CmdInvalidBlock, nil
+	case reflect.TypeOf(&MessageEvidence{}):
+		return CmdEvidence, nil
 	case reflect.TypeOf(&MessageGetChainState{}):
 		return CmdGetChainState, nil
 	case reflect.TypeOf(&MessageChainState{}):
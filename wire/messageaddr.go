@@ -6,7 +6,7 @@ import (
 )
 
 const (
-	MaxGetAddressPayload = 1000 // 1 Kb
+	MaxGetAddressPayload = 1000000 // 1 Mb
 )
 
 type RawPeer struct {
@@ -10,8 +10,12 @@ const (
 	MaxBlockSigPayload = 1000 // 1 Kb
 )
 
+// MessageBlockSig is reply of a validator to MessageBlockSigReq, it carries the
+// public nonce in round 1 and the partial signature in round 2
 type MessageBlockSig struct {
 	Validator string
+	BlockHash string
+	Nonce     string
 	BlockSig  string
 }
 
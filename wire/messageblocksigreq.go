@@ -11,9 +11,16 @@ const (
 	MaxBlockSigReq = 4000000 // 4Mb
 )
 
+// MessageBlockSigReq asks a validator for its part of the block multi-signature.
+// In round 1 AggNonce is empty and the validator replies with its public nonce,
+// in round 2 it replies with its partial signature for signers of the bitmap.
 type MessageBlockSigReq struct {
-	Block    blockchain.Block
-	SenderID string
+	Block       blockchain.Block
+	SenderID    string
+	ProducerSig string // signature of block hash by block producer
+
+	SignersBitmap []byte
+	AggNonce      string
 }
 
 func (self *MessageBlockSigReq) MessageType() string {
@@ -38,3 +45,8 @@ func (self *MessageBlockSigReq) SetSenderID(senderID peer.ID) error {
 	self.SenderID = senderID.Pretty()
 	return nil
 }
+
+// IsNonceRequest returns true when the message is round 1 of block signing
+func (self *MessageBlockSigReq) IsNonceRequest() bool {
+	return self.AggNonce == ""
+}
@@ -0,0 +1,40 @@
+package wire
+
+import (
+	"encoding/json"
+
+	"github.com/libp2p/go-libp2p-peer"
+	"github.com/ninjadotorg/constant/blockchain"
+)
+
+const (
+	MaxEvidencePayload = 1000000 // 1 Mb
+)
+
+// MessageEvidence relays proof of double signing until a producer includes it
+// in a block
+type MessageEvidence struct {
+	Evidence blockchain.DoubleSignEvidence
+}
+
+func (self *MessageEvidence) MessageType() string {
+	return CmdEvidence
+}
+
+func (self *MessageEvidence) MaxPayloadLength(pver int) int {
+	return MaxEvidencePayload
+}
+
+func (self *MessageEvidence) JsonSerialize() ([]byte, error) {
+	jsonBytes, err := json.Marshal(self)
+	return jsonBytes, err
+}
+
+func (self *MessageEvidence) JsonDeserialize(jsonStr string) error {
+	err := json.Unmarshal([]byte(jsonStr), self)
+	return err
+}
+
+func (self *MessageEvidence) SetSenderID(senderID peer.ID) error {
+	return nil
+}
@@ -0,0 +1,158 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/ninjadotorg/constant/common"
+)
+
+const (
+	// ProtocolVersionLegacy is hex encoded json terminated by a delimiter
+	ProtocolVersionLegacy = 1
+	// ProtocolVersionFramed is a binary length-prefixed frame with checksum
+	ProtocolVersionFramed = 2
+	// ProtocolVersionPong is the version which replies ping with pong
+	ProtocolVersionPong = 3
+	// ProtocolVersionAuth is the version which proves producer key with a
+	// challenge in version handshake
+	ProtocolVersionAuth = 4
+	// ProtocolVersionTxTypes is the version which sends a tx with the cmd of
+	// its type, older peers only decode CmdTx, CmdCustomToken and
+	// CmdCLoanRequestToken
+	ProtocolVersionTxTypes = 5
+
+	// ProtocolVersion is the latest wire protocol version this node speaks
+	ProtocolVersion = ProtocolVersionTxTypes
+)
+
+const (
+	MessageMagicSize    = 4
+	MessageLengthSize   = 4
+	MessageChecksumSize = 4
+
+	// MaxMessagePayload is the upper bound on any message payload, regardless
+	// of message type
+	MaxMessagePayload = 32 * 1024 * 1024 // 32Mb
+)
+
+/*
+MessageHeader is the fixed 24 bytes frame header of framed protocol:
+magic(4) | command(12) | length(4) | checksum(4)
+*/
+type MessageHeader struct {
+	Magic    uint32
+	Command  string
+	Length   uint32
+	Checksum [MessageChecksumSize]byte
+}
+
+// checksum - first 4 bytes of double sha256 of payload
+func checksum(payload []byte) [MessageChecksumSize]byte {
+	var sum [MessageChecksumSize]byte
+	copy(sum[:], common.DoubleHashB(payload)[:MessageChecksumSize])
+	return sum
+}
+
+// MagicBytes returns encoded network magic which starts every framed message
+func MagicBytes(net uint32) []byte {
+	buf := make([]byte, MessageMagicSize)
+	binary.LittleEndian.PutUint32(buf, net)
+	return buf
+}
+
+func readMessageHeader(r io.Reader) (*MessageHeader, error) {
+	buf := make([]byte, MessageHeaderSize)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	header := &MessageHeader{}
+	offset := 0
+	header.Magic = binary.LittleEndian.Uint32(buf[offset : offset+MessageMagicSize])
+	offset += MessageMagicSize
+	header.Command = string(bytes.TrimRight(buf[offset:offset+MessageCmdTypeSize], "\x00"))
+	offset += MessageCmdTypeSize
+	header.Length = binary.LittleEndian.Uint32(buf[offset : offset+MessageLengthSize])
+	offset += MessageLengthSize
+	copy(header.Checksum[:], buf[offset:offset+MessageChecksumSize])
+	return header, nil
+}
+
+/*
+WriteMessage - encode message into framed format and write into w
+*/
+func WriteMessage(w io.Writer, msg Message, pver int, net uint32) error {
+	command := msg.MessageType()
+	if len(command) > MessageCmdTypeSize {
+		return NewMessageError(MessageCommandErr, fmt.Errorf("command %s is too long", command))
+	}
+	payload, err := msg.JsonSerialize()
+	if err != nil {
+		return NewMessageError(MessageParseErr, err)
+	}
+	if len(payload) > MaxMessagePayload || len(payload) > msg.MaxPayloadLength(pver) {
+		return NewMessageError(MessagePayloadLengthErr, fmt.Errorf("payload of %s is %d bytes", command, len(payload)))
+	}
+
+	buf := make([]byte, MessageHeaderSize, MessageHeaderSize+len(payload))
+	offset := 0
+	binary.LittleEndian.PutUint32(buf[offset:], net)
+	offset += MessageMagicSize
+	copy(buf[offset:offset+MessageCmdTypeSize], command)
+	offset += MessageCmdTypeSize
+	binary.LittleEndian.PutUint32(buf[offset:], uint32(len(payload)))
+	offset += MessageLengthSize
+	sum := checksum(payload)
+	copy(buf[offset:], sum[:])
+	buf = append(buf, payload...)
+
+	_, err = w.Write(buf)
+	return err
+}
+
+/*
+ReadMessage - read a framed message from r. The payload length is checked against
+the message type's limit before any payload byte is read. Returned *MessageError
+which is Recoverable() means the frame was fully consumed and reader can continue
+*/
+func ReadMessage(r io.Reader, pver int, net uint32) (Message, error) {
+	header, err := readMessageHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if header.Magic != net {
+		return nil, NewMessageError(MessageMagicErr, fmt.Errorf("got %x, expected %x", header.Magic, net))
+	}
+	if header.Length > MaxMessagePayload {
+		return nil, NewMessageError(MessagePayloadLengthErr, fmt.Errorf("payload of %s is %d bytes", header.Command, header.Length))
+	}
+
+	msg, err := MakeEmptyMessage(header.Command)
+	if err != nil {
+		// drop payload to keep stream on frame boundary
+		if _, err := io.CopyN(ioutil.Discard, r, int64(header.Length)); err != nil {
+			return nil, err
+		}
+		return nil, NewMessageError(MessageCommandErr, err)
+	}
+	if int(header.Length) > msg.MaxPayloadLength(pver) {
+		return nil, NewMessageError(MessagePayloadLengthErr, fmt.Errorf("payload of %s is %d bytes, max %d", header.Command, header.Length, msg.MaxPayloadLength(pver)))
+	}
+
+	payload := make([]byte, header.Length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	if checksum(payload) != header.Checksum {
+		return nil, NewMessageError(MessageChecksumErr, errors.New(header.Command))
+	}
+	if err := json.Unmarshal(payload, msg); err != nil {
+		return nil, NewMessageError(MessageParseErr, err)
+	}
+	return msg, nil
+}
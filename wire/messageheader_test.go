@@ -0,0 +1,48 @@
+package wire
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMessageFrameRoundTrip(t *testing.T) {
+	msg := &MessageSwapSig{Validator: "validator", SwapSig: "sig"}
+	buf := &bytes.Buffer{}
+	if err := WriteMessage(buf, msg, ProtocolVersion, 0x01); err != nil {
+		t.Fatalf("write message %+v", err)
+	}
+	if !bytes.Equal(buf.Bytes()[:MessageMagicSize], MagicBytes(0x01)) {
+		t.Fatalf("frame does not start with magic")
+	}
+	res, err := ReadMessage(buf, ProtocolVersion, 0x01)
+	if err != nil {
+		t.Fatalf("read message %+v", err)
+	}
+	if got := res.(*MessageSwapSig); *got != *msg {
+		t.Fatalf("got %+v, expected %+v", got, msg)
+	}
+}
+
+func TestMessageFrameRejects(t *testing.T) {
+	msg := &MessageSwapSig{Validator: "validator", SwapSig: "sig"}
+	buf := &bytes.Buffer{}
+	WriteMessage(buf, msg, ProtocolVersion, 0x01)
+	if _, err := ReadMessage(bytes.NewReader(buf.Bytes()), ProtocolVersion, 0x02); err == nil {
+		t.Fatalf("expected magic mismatch")
+	}
+
+	corrupted := append([]byte{}, buf.Bytes()...)
+	corrupted[len(corrupted)-2] ^= 0xff
+	_, err := ReadMessage(bytes.NewReader(corrupted), ProtocolVersion, 0x01)
+	if msgErr, ok := err.(*MessageError); !ok || !msgErr.Recoverable() {
+		t.Fatalf("expected recoverable checksum error, got %+v", err)
+	}
+
+	// length larger than MaxSwapSigPayload must fail before reading the body
+	oversized := append([]byte{}, buf.Bytes()[:MessageHeaderSize]...)
+	oversized[MessageMagicSize+MessageCmdTypeSize+2] = 0x01
+	_, err = ReadMessage(bytes.NewReader(oversized), ProtocolVersion, 0x01)
+	if msgErr, ok := err.(*MessageError); !ok || !msgErr.IsCode(MessagePayloadLengthErr) {
+		t.Fatalf("expected payload length error, got %+v", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 
 	"github.com/libp2p/go-libp2p-peer"
+	"github.com/ninjadotorg/constant/cashec"
+	"github.com/ninjadotorg/constant/signer"
 )
 
 const (
@@ -39,3 +41,14 @@ func (self *MessageInvalidBlock) JsonDeserialize(jsonStr string) error {
 func (self *MessageInvalidBlock) SetSenderID(senderID peer.ID) error {
 	return nil
 }
+
+// Verify checks ValidatorSig which is signed over json of message without signature
+func (self *MessageInvalidBlock) Verify() error {
+	msg := *self
+	msg.ValidatorSig = ""
+	dataByte, err := msg.JsonSerialize()
+	if err != nil {
+		return err
+	}
+	return cashec.ValidateDataB58(self.Validator, self.ValidatorSig, signer.SignedData(signer.SignMessage, dataByte))
+}
@@ -12,6 +12,7 @@ const (
 )
 
 type MessagePing struct {
+	Nonce uint64
 }
 
 func (self MessagePing) MessageType() string {
@@ -0,0 +1,40 @@
+package wire
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/libp2p/go-libp2p-peer"
+)
+
+const (
+	MaxPongPayload = 1000 // 1 1Kb
+)
+
+// MessagePong replies a MessagePing with the same nonce
+type MessagePong struct {
+	Nonce uint64
+}
+
+func (self *MessagePong) MessageType() string {
+	return CmdPong
+}
+
+func (self *MessagePong) MaxPayloadLength(pver int) int {
+	return MaxPongPayload
+}
+
+func (self *MessagePong) JsonSerialize() ([]byte, error) {
+	jsonBytes, err := json.Marshal(self)
+	return jsonBytes, err
+}
+
+func (self *MessagePong) JsonDeserialize(jsonStr string) error {
+	jsonDecodeString, _ := hex.DecodeString(jsonStr)
+	err := json.Unmarshal([]byte(jsonDecodeString), self)
+	return err
+}
+
+func (self *MessagePong) SetSenderID(senderID peer.ID) error {
+	return nil
+}
@@ -6,19 +6,27 @@ import (
 
 	"github.com/libp2p/go-libp2p-peer"
 	"github.com/ninjadotorg/constant/cashec"
+	"github.com/ninjadotorg/constant/signer"
 )
 
 const (
 	MaxSwapRequestPayload = 1000 // 1 Kb
 )
 
+// MessageSwapRequest asks committee to sign the swap of committee seat ChainID,
+// the swap is a proof which every validator recomputes from the anchor block
 type MessageSwapRequest struct {
 	LockTime     int64
 	ChainID      byte
+	Outgoing     string
 	Candidate    string
 	Requester    string
 	RequesterSig string
 	SenderID     string
+
+	AnchorChainID byte
+	AnchorHeight  int32
+	AnchorHash    string
 }
 
 func (self MessageSwapRequest) MessageType() string {
@@ -45,20 +53,32 @@ func (self *MessageSwapRequest) SetSenderID(senderID peer.ID) error {
 }
 
 func (self *MessageSwapRequest) GetMsgByte() []byte {
+	return SwapMsgBytes(self.LockTime, self.ChainID, self.Outgoing, self.Candidate, self.Requester, self.AnchorChainID, self.AnchorHeight, self.AnchorHash)
+}
+
+/*
+SwapMsgBytes - data of a swap which is signed by requester and validators
+*/
+func SwapMsgBytes(lockTime int64, chainID byte, outgoing string, candidate string, requester string, anchorChainID byte, anchorHeight int32, anchorHash string) []byte {
 	rawBytes := []byte{}
 	bLTime := make([]byte, 8)
-	binary.LittleEndian.PutUint64(bLTime, uint64(self.LockTime))
+	binary.LittleEndian.PutUint64(bLTime, uint64(lockTime))
 	rawBytes = append(rawBytes, bLTime...)
-	rawBytes = append(rawBytes, self.ChainID)
-	rawBytes = append(rawBytes, []byte(self.Candidate)...)
-	rawBytes = append(rawBytes, []byte(self.Requester)...)
-	rawBytes = append(rawBytes, []byte(self.SenderID)...)
+	rawBytes = append(rawBytes, chainID)
+	rawBytes = append(rawBytes, []byte(outgoing)...)
+	rawBytes = append(rawBytes, []byte(candidate)...)
+	rawBytes = append(rawBytes, []byte(requester)...)
+	rawBytes = append(rawBytes, anchorChainID)
+	bHeight := make([]byte, 4)
+	binary.LittleEndian.PutUint32(bHeight, uint32(anchorHeight))
+	rawBytes = append(rawBytes, bHeight...)
+	rawBytes = append(rawBytes, []byte(anchorHash)...)
 	return rawBytes
 }
 
 func (self *MessageSwapRequest) Verify() error {
 	msgBytes := self.GetMsgByte()
-	err := cashec.ValidateDataB58(self.Requester, self.RequesterSig, msgBytes)
+	err := cashec.ValidateDataB58(self.Requester, self.RequesterSig, signer.SignedData(signer.SignSwap, msgBytes))
 
 	if err != nil {
 		return err
@@ -7,15 +7,22 @@ import (
 )
 
 const (
-	MaxSwapUpdatePayload = 1000 // 1 Kb
+	MaxSwapUpdatePayload = 100000 // 100 Kb
 )
 
+// MessageSwapUpdate announces a swap signed by committee, along with the
+// anchor every node recomputes it from
 type MessageSwapUpdate struct {
 	LockTime   int64
 	Requester  string
 	ChainID    byte
+	Outgoing   string
 	Candidate  string
 	Signatures map[string]string
+
+	AnchorChainID byte
+	AnchorHeight  int32
+	AnchorHash    string
 }
 
 func (self MessageSwapUpdate) MessageType() string {
@@ -39,3 +46,7 @@ func (self MessageSwapUpdate) JsonDeserialize(jsonStr string) error {
 func (self MessageSwapUpdate) SetSenderID(senderID peer.ID) error {
 	return nil
 }
+
+func (self MessageSwapUpdate) GetMsgByte() []byte {
+	return SwapMsgBytes(self.LockTime, self.ChainID, self.Outgoing, self.Candidate, self.Requester, self.AnchorChainID, self.AnchorHeight, self.AnchorHash)
+}
@@ -16,7 +16,15 @@ type MessageTx struct {
 	Transaction transaction.Transaction
 }
 
+// MessageType returns the cmd of the tx type, so a peer decodes the tx into
+// the struct of its type
 func (self MessageTx) MessageType() string {
+	if self.Transaction == nil {
+		return CmdTx
+	}
+	if cmd, ok := txTypeCmds[self.Transaction.GetType()]; ok {
+		return cmd
+	}
 	return CmdTx
 }
 
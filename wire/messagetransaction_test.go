@@ -0,0 +1,76 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ninjadotorg/constant/transaction"
+)
+
+func TestMessageTxRoundTripEveryType(t *testing.T) {
+	for txType, cmd := range txTypeCmds {
+		if len(cmd) > MessageCmdTypeSize {
+			t.Fatalf("cmd %s of type %s is longer than %d", cmd, txType, MessageCmdTypeSize)
+		}
+		tx, err := transaction.NewTxByType(txType)
+		if err != nil {
+			t.Fatalf("new tx of type %s %+v", txType, err)
+		}
+		// Type of the embedded normal tx is promoted to the top of every tx
+		if err := json.Unmarshal([]byte(`{"Type":"`+txType+`"}`), tx); err != nil {
+			t.Fatalf("set type %s %+v", txType, err)
+		}
+		msg := &MessageTx{Transaction: tx}
+		if msg.MessageType() != cmd {
+			t.Fatalf("tx of type %s is sent as %s, expected %s", txType, msg.MessageType(), cmd)
+		}
+
+		buf := &bytes.Buffer{}
+		if err := WriteMessage(buf, msg, ProtocolVersion, 0x01); err != nil {
+			t.Fatalf("write tx of type %s %+v", txType, err)
+		}
+		res, err := ReadMessage(buf, ProtocolVersion, 0x01)
+		if err != nil {
+			t.Fatalf("read tx of type %s %+v", txType, err)
+		}
+		got := res.(*MessageTx).Transaction
+		if reflect.TypeOf(got) != reflect.TypeOf(tx) {
+			t.Fatalf("tx of type %s is decoded as %T, expected %T", txType, got, tx)
+		}
+		if got.GetType() != txType {
+			t.Fatalf("tx of type %s is decoded with type %s", txType, got.GetType())
+		}
+	}
+}
+
+func TestMakeEmptyMessageTxCommands(t *testing.T) {
+	for cmd := range txCmdTypes {
+		if !IsTxCommand(cmd) {
+			t.Fatalf("%s is not a tx command", cmd)
+		}
+		msg, err := MakeEmptyMessage(cmd)
+		if err != nil {
+			t.Fatalf("make message %s %+v", cmd, err)
+		}
+		if _, ok := msg.(*MessageTx); !ok {
+			t.Fatalf("message %s is %T, expected a tx message", cmd, msg)
+		}
+	}
+	if IsTxCommand(CmdBlock) {
+		t.Fatalf("%s is a tx command", CmdBlock)
+	}
+}
+
+func TestIsTxCommandSupported(t *testing.T) {
+	for cmd := range txCmdTypes {
+		if !IsTxCommandSupported(cmd, ProtocolVersionTxTypes) {
+			t.Fatalf("%s is not supported by version %d", cmd, ProtocolVersionTxTypes)
+		}
+		legacy := cmd == CmdTx || cmd == CmdCustomToken || cmd == CmdCLoanRequestToken
+		if IsTxCommandSupported(cmd, ProtocolVersionAuth) != legacy {
+			t.Fatalf("%s is supported by version %d: %v", cmd, ProtocolVersionAuth, !legacy)
+		}
+	}
+}
@@ -3,12 +3,24 @@ package wire
 import (
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 
 	"github.com/libp2p/go-libp2p-peer"
 )
 
 type MessageVerAck struct {
 	Valid bool
+	// PublicKey is producer key of sender, ChallengeSig proves sender holds
+	// it by signing the challenge of version message, see ChallengeData
+	PublicKey    string
+	ChallengeSig string
+}
+
+// ChallengeData returns data signed by producer key in verack, it binds the
+// challenge to both peer ids so that a signature can't be relayed to another
+// connection
+func ChallengeData(challenge string, signer peer.ID, verifier peer.ID) []byte {
+	return []byte(fmt.Sprintf("%s|%s|%s", challenge, signer.Pretty(), verifier.Pretty()))
 }
 
 func (self MessageVerAck) MessageType() string {
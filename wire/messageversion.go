@@ -10,11 +10,12 @@ import (
 )
 
 const (
-	MaxVersionPayload = 1000 // 1 1Kb
+	MaxVersionPayload = 4000 // 4 Kb
 )
 
 type MessageVersion struct {
 	ProtocolVersion  string
+	WireVersion      int // framing version, see ProtocolVersion in messageheader.go
 	Timestamp        time.Time
 	RemoteAddress    common.SimpleAddr
 	RawRemoteAddress string
@@ -23,6 +24,7 @@ type MessageVersion struct {
 	RawLocalAddress  string
 	LocalPeerId      peer.ID
 	PublicKey        string
+	Challenge        string // random hex, remote validator signs it in verack
 }
 
 func (self MessageVersion) MessageType() string {